@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/findings"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/logging"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var blastRadiusCmd = &cobra.Command{
+	Use:   "blast-radius [plan_file]",
+	Short: "Export the resources downstream of security findings",
+	Long: `Load a findings file, select the resources with findings at or above
+--min-severity and export them together with every resource that transitively
+depends on them, so reviewers see exactly what is downstream of risky resources.
+
+The findings file is a JSON array:
+  [{"address": "aws_security_group.open", "severity": "HIGH", "message": "..."}]
+
+The resulting subgraph is written as JSON to stdout or to --output.
+
+Example:
+  terraform-graphx blast-radius --findings findings.json --min-severity HIGH`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runBlastRadius,
+}
+
+func runBlastRadius(cmd *cobra.Command, args []string) error {
+	findingsPath, _ := cmd.Flags().GetString("findings")
+	minSeverityName, _ := cmd.Flags().GetString("min-severity")
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	minSeverity, err := findings.ParseSeverity(minSeverityName)
+	if err != nil {
+		return err
+	}
+
+	results, err := findings.Load(findingsPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	flagged := findings.AddressesAtOrAbove(results, minSeverity)
+	logging.WithFields(logging.Fields{"resources": len(flagged)}).Infof("Found %d resources with findings at or above %s", len(flagged), minSeverityName)
+
+	blast := graph.BlastRadius(g, flagged)
+
+	data, err := json.MarshalIndent(blast, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode graph: %w", err)
+	}
+
+	if outputPath == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	logging.WithFields(logging.Fields{"nodes": len(blast.Nodes)}).Infof("Wrote blast radius (%d nodes) to %s", len(blast.Nodes), outputPath)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(blastRadiusCmd)
+
+	blastRadiusCmd.Flags().String("findings", "", "Path to a JSON findings file")
+	blastRadiusCmd.Flags().String("min-severity", "HIGH", "Minimum severity to include (LOW, MEDIUM, HIGH, CRITICAL)")
+	blastRadiusCmd.Flags().String("output", "", "Write the subgraph to this file instead of stdout")
+	blastRadiusCmd.MarkFlagRequired("findings")
+}
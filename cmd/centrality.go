@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var centralityCmd = &cobra.Command{
+	Use:   "centrality [plan_file]",
+	Short: "List the most connected resources",
+	Long: `Build the dependency graph and list the resources with the most edges,
+counting both their dependents (in) and their dependencies (out). Highly
+connected resources are where refactors have the widest effect. Ties are
+listed by address.
+
+Example:
+  terraform-graphx centrality --top=20
+  terraform-graphx centrality --format=json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runCentrality,
+}
+
+func runCentrality(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q (supported: text, json)", format)
+	}
+	top, _ := cmd.Flags().GetInt("top")
+	if top < 0 {
+		return fmt.Errorf("--top must not be negative, got %d", top)
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	ranked := graph.TopByDegree(g, top)
+	if format == "json" {
+		data, err := json.MarshalIndent(ranked, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode centrality: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("%-6s %-6s %-6s %s\n", "TOTAL", "IN", "OUT", "RESOURCE")
+	for _, r := range ranked {
+		fmt.Printf("%-6d %-6d %-6d %s\n", r.Total, r.In, r.Out, r.ID)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(centralityCmd)
+
+	centralityCmd.Flags().Int("top", 10, "Number of resources to list (0 lists all)")
+	centralityCmd.Flags().String("format", "text", "Output format: text or json")
+	centralityCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	centralityCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	centralityCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	centralityCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	centralityCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	centralityCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	centralityCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	centralityCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	centralityCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+}
@@ -3,9 +3,11 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/logging"
 	"terraform-graphx/internal/neo4j"
+	"terraform-graphx/internal/runner"
 
 	"github.com/spf13/cobra"
 )
@@ -35,7 +37,7 @@ Example:
 
 func runCheckDatabase(cmd *cobra.Command, args []string) error {
 	// Load configuration
-	log.Println("Loading configuration from .terraform-graphx.yaml...")
+	logging.Debugf("Loading configuration from .terraform-graphx.yaml...")
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -52,26 +54,32 @@ func runCheckDatabase(cmd *cobra.Command, args []string) error {
 	// Display connection info (without password)
 	fmt.Println("Neo4j Connection Settings:")
 	fmt.Printf("  URI:  %s\n", cfg.Neo4j.URI)
+	if endpoint, err := cfg.Neo4j.BoltEndpoint(); err == nil {
+		fmt.Printf("  Bolt: %s\n", endpoint)
+	}
 	fmt.Printf("  User: %s\n", cfg.Neo4j.User)
 	fmt.Println()
 
+	ctx := cmd.Context()
+
 	// Validate configuration
-	if cfg.Neo4j.Password == "" {
-		return fmt.Errorf("neo4j password is not set in configuration file")
+	if err := cfg.Neo4j.ResolvePassword(ctx); err != nil {
+		return err
+	}
+	if err := runner.Neo4jCredentials(&cfg.Neo4j).Validate(); err != nil {
+		return fmt.Errorf("neo4j credentials are not set in configuration file: %w", err)
 	}
 
 	// Create Neo4j client
-	log.Printf("Connecting to Neo4j at %s...", cfg.Neo4j.URI)
-	ctx := context.Background()
-
-	client, err := neo4j.NewClient(cfg.Neo4j.URI, cfg.Neo4j.User, cfg.Neo4j.Password)
+	logging.Infof("Connecting to Neo4j at %s...", cfg.Neo4j.URI)
+	client, err := runner.NewNeo4jClient(ctx, &cfg.Neo4j)
 	if err != nil {
 		return fmt.Errorf("failed to create neo4j client: %w", err)
 	}
 	defer client.Close(ctx)
 
 	// Verify connectivity
-	log.Println("Verifying connectivity...")
+	logging.Debugf("Verifying connectivity...")
 	if err := client.VerifyConnectivity(ctx); err != nil {
 		return fmt.Errorf("failed to connect to neo4j: %w", err)
 	}
@@ -83,7 +91,188 @@ func runCheckDatabase(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var checkConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Validate the configuration file",
+	Long: `Load .terraform-graphx.yaml and report every problem found in it, without
+connecting to Neo4j:
+
+  - the file can't be found or parsed, or has invalid ports
+  - the Neo4j URI has no host or a scheme other than bolt or neo4j
+  - the user is empty or the password is neither set nor resolvable from
+    password_file or password_command (with basic auth)
+  - the file is accessible to other users (warning)
+
+The command fails if any error is found; warnings alone don't fail it.
+
+Example:
+  terraform-graphx check config`,
+	Args: cobra.NoArgs,
+	RunE: runCheckConfig,
+}
+
+// configProblem is a problem found by check config. Errors fail the command,
+// warnings don't.
+type configProblem struct {
+	Error   bool
+	Message string
+}
+
+func runCheckConfig(cmd *cobra.Command, args []string) error {
+	problems := checkConfigFile(cmd.Context())
+	if len(problems) == 0 {
+		fmt.Println("✓ Configuration is valid.")
+		return nil
+	}
+
+	failed := 0
+	for _, problem := range problems {
+		if problem.Error {
+			failed++
+			fmt.Printf("✗ Error: %s\n", problem.Message)
+		} else {
+			fmt.Printf("⚠ Warning: %s\n", problem.Message)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("configuration has %d error(s)", failed)
+	}
+	return nil
+}
+
+// checkConfigFile loads the config file and returns its problems.
+func checkConfigFile(ctx context.Context) []configProblem {
+	path := config.Path()
+	if path == "" {
+		return []configProblem{{true, "no configuration file found; run 'terraform graphx init config' to create one"}}
+	}
+
+	var problems []configProblem
+	if err := config.CheckPermissions(path); err != nil {
+		problems = append(problems, configProblem{false, err.Error()})
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return append(problems, configProblem{true, err.Error()})
+	}
+
+	if err := neo4j.ValidateURI(cfg.Neo4j.URI); err != nil {
+		problems = append(problems, configProblem{true, fmt.Sprintf("neo4j.uri: %v", err)})
+	}
+	if err := cfg.Neo4j.ResolvePassword(ctx); err != nil {
+		return append(problems, configProblem{true, err.Error()})
+	}
+	creds := runner.Neo4jCredentials(&cfg.Neo4j)
+	switch creds.Mode {
+	case "", neo4j.AuthBasic:
+		if cfg.Neo4j.User == "" {
+			problems = append(problems, configProblem{true, "neo4j.user is empty"})
+		}
+		if cfg.Neo4j.Password == "" {
+			problems = append(problems, configProblem{true, "no password: set neo4j.password, neo4j.password_file or neo4j.password_command"})
+		}
+	default:
+		if err := creds.Validate(); err != nil {
+			problems = append(problems, configProblem{true, fmt.Sprintf("neo4j.auth: %v", err)})
+		}
+	}
+	return problems
+}
+
+var checkCollisionsCmd = &cobra.Command{
+	Use:   "collisions [plan_file]",
+	Short: "Report resources that share a type.name across modules",
+	Long: `Report resources whose type.name is declared in more than one module path,
+e.g. null_resource.setup and module.app.null_resource.setup. Their full
+addresses differ, but any ID scheme shorter than the address would collide.
+
+Example:
+  terraform-graphx check collisions
+  terraform-graphx check collisions --state terraform.tfstate`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runCheckCollisions,
+}
+
+func runCheckCollisions(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	collisions := graph.FindCollisions(g)
+	if len(collisions) == 0 {
+		fmt.Println("✓ No type.name collisions found.")
+		return nil
+	}
+
+	fmt.Printf("Found %d type.name collision(s):\n", len(collisions))
+	for _, collision := range collisions {
+		fmt.Printf("  %s\n", collision.Key)
+		for _, address := range collision.Addresses {
+			fmt.Printf("    - %s\n", address)
+		}
+	}
+	return nil
+}
+
+var checkUnconfiguredCmd = &cobra.Command{
+	Use:   "unconfigured <plan_file>",
+	Short: "Report planned resources without a resource block in the configuration",
+	Long: `Cross-reference the planned resources of a plan against its configuration
+and report the ones with no matching resource block. Dependencies of such
+resources can't be discovered from the configuration, so their edges may be
+missing from the graph.
+
+The plan file is either a saved plan (read with 'terraform show -json') or
+the JSON output of that command, when its name ends in .json.
+
+Example:
+  terraform-graphx check unconfigured tfplan
+  terraform-graphx check unconfigured plan.json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runCheckUnconfigured,
+}
+
+func runCheckUnconfigured(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	plan, err := runner.LoadPlan(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	missing := plan.UnconfiguredResources()
+	if len(missing) == 0 {
+		fmt.Println("✓ Every planned resource has a configuration entry.")
+		return nil
+	}
+
+	fmt.Printf("Found %d planned resource(s) without a configuration entry:\n", len(missing))
+	for _, address := range missing {
+		fmt.Printf("  - %s\n", address)
+	}
+	return fmt.Errorf("%d planned resource(s) have no configuration entry", len(missing))
+}
+
 func init() {
 	rootCmd.AddCommand(checkCmd)
 	checkCmd.AddCommand(checkDatabaseCmd)
+	checkCmd.AddCommand(checkConfigCmd)
+	checkCmd.AddCommand(checkCollisionsCmd)
+	checkCmd.AddCommand(checkUnconfiguredCmd)
+
+	checkCollisionsCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	checkCollisionsCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	checkCollisionsCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfigFile(t *testing.T) {
+	for _, key := range []string{"TFGRAPHX_NEO4J_URI", "TFGRAPHX_NEO4J_USER", "TFGRAPHX_NEO4J_PASSWORD", "TFGRAPHX_NEO4J_AUTH"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name     string
+		content  string
+		mode     os.FileMode
+		errors   []string
+		warnings []string
+	}{
+		{
+			name:    "valid",
+			content: "neo4j:\n  uri: neo4j://db.example.com:7687\n  user: neo4j\n  password: secret\n",
+			mode:    0600,
+		},
+		{
+			name:    "password from file",
+			content: "neo4j:\n  user: neo4j\n  password_file: secret.txt\n",
+			mode:    0600,
+		},
+		{
+			name:    "invalid scheme and empty user",
+			content: "neo4j:\n  uri: http://localhost:7474\n  user: \"\"\n  password: secret\n",
+			mode:    0600,
+			errors:  []string{"unsupported scheme", "neo4j.user is empty"},
+		},
+		{
+			name:    "missing password",
+			content: "neo4j:\n  user: neo4j\n  password: \"\"\n",
+			mode:    0600,
+			errors:  []string{"no password"},
+		},
+		{
+			name:    "unresolvable password",
+			content: "neo4j:\n  user: neo4j\n  password: \"\"\n  password_file: missing.txt\n",
+			mode:    0600,
+			errors:  []string{"neo4j.password_file"},
+		},
+		{
+			name:     "insecure permissions",
+			content:  "neo4j:\n  user: neo4j\n  password: secret\n",
+			mode:     0644,
+			warnings: []string{"0644"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile("secret.txt", []byte("secret\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(".terraform-graphx.yaml", []byte(tt.content), tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(".terraform-graphx.yaml", tt.mode); err != nil {
+				t.Fatal(err)
+			}
+
+			var errors, warnings []string
+			for _, problem := range checkConfigFile(context.Background()) {
+				if problem.Error {
+					errors = append(errors, problem.Message)
+				} else {
+					warnings = append(warnings, problem.Message)
+				}
+			}
+			if runtime.GOOS == "windows" {
+				tt.warnings = nil
+			}
+			assertProblems(t, "error", errors, tt.errors)
+			assertProblems(t, "warning", warnings, tt.warnings)
+		})
+	}
+}
+
+func TestCheckConfigFileMissing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	problems := checkConfigFile(context.Background())
+	if len(problems) != 1 || !problems[0].Error || !strings.Contains(problems[0].Message, "no configuration file") {
+		t.Errorf("Expected a missing file error, got %+v", problems)
+	}
+}
+
+// assertProblems checks that each of got contains the matching want substring.
+func assertProblems(t *testing.T, kind string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d %s(s), got %d: %q", len(want), kind, len(got), got)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("Expected %s %q to contain %q", kind, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/runner"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Write a completion script for the given shell to stdout.
+
+To load completions in the current bash session:
+  source <(terraform-graphx completion bash)
+
+To install them for every zsh session:
+  terraform-graphx completion zsh > "${fpath[1]}/_terraform-graphx"
+
+For fish:
+  terraform-graphx completion fish > ~/.config/fish/completions/terraform-graphx.fish
+
+For PowerShell:
+  terraform-graphx completion powershell | Out-String | Invoke-Expression`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(out, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(out)
+	case "fish":
+		return rootCmd.GenFishCompletion(out, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(out)
+	}
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+const (
+	// completionLimit bounds the number of resource addresses offered for completion
+	completionLimit = 200
+	// completionTimeout bounds how long completion waits for the password
+	// command and Neo4j
+	completionTimeout = 2 * time.Second
+	// completionCloseTimeout bounds how long completion waits for the driver
+	// to close
+	completionCloseTimeout = 500 * time.Millisecond
+)
+
+// completeResourceAddress offers resource addresses stored in Neo4j for the
+// first positional argument. It silently offers nothing if Neo4j is not
+// configured or unreachable.
+func completeResourceAddress(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	// The password command runs on every TAB press, so it shares the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	cfg, err := config.Load()
+	if err != nil || cfg.Neo4j.ResolvePassword(ctx) != nil || runner.Neo4jCredentials(&cfg.Neo4j).Validate() != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	client, err := runner.NewNeo4jClient(ctx, &cfg.Neo4j)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer func() {
+		// ctx may have expired by now, so closing gets a deadline of its own
+		closeCtx, cancel := context.WithTimeout(context.Background(), completionCloseTimeout)
+		defer cancel()
+		client.Close(closeCtx)
+	}()
+
+	if err := client.SetNodeLabel(cfg.Neo4j.NodeLabel); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// The driver keeps retrying an unreachable database past the deadline of
+	// ctx, so stop waiting for it when the deadline passes
+	type completion struct {
+		ids []string
+		err error
+	}
+	done := make(chan completion, 1)
+	go func() {
+		ids, err := client.ResourceIDs(ctx, toComplete, completionLimit)
+		done <- completion{ids, err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return result.ids, cobra.ShellCompDirectiveNoFileComp
+	case <-ctx.Done():
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completePlanFile offers file names for the optional plan file argument.
+func completePlanFile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveDefault
+}
+
+// completeExportFormat offers the supported export formats for --format.
+func completeExportFormat(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return runner.SupportedFormats(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
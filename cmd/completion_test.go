@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCompletionBash(t *testing.T) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+	rootCmd.SetArgs([]string{"completion", "bash"})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("completion bash failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "__start_terraform-graphx") {
+		t.Errorf("Expected a bash completion script, got:\n%.200s", out.String())
+	}
+}
+
+func TestCompleteResourceAddress(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// Only the first argument is a resource address
+	if ids, directive := completeResourceAddress(impactCmd, []string{"aws_vpc.main"}, ""); ids != nil || directive != cobra.ShellCompDirectiveDefault {
+		t.Errorf("Expected no completions after the address, got %v, %v", ids, directive)
+	}
+
+	// Without a password nothing is offered, and no files either
+	if ids, directive := completeResourceAddress(impactCmd, nil, "aws_"); ids != nil || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected no completions without credentials, got %v, %v", ids, directive)
+	}
+
+	// An unreachable database is not an error, and doesn't hold up the shell
+	t.Setenv("TFGRAPHX_NEO4J_URI", "bolt://127.0.0.1:1")
+	t.Setenv("TFGRAPHX_NEO4J_PASSWORD", "secret")
+	started := time.Now()
+	if ids, directive := completeResourceAddress(impactCmd, nil, "aws_"); ids != nil || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected no completions for an unreachable database, got %v, %v", ids, directive)
+	}
+	if elapsed := time.Since(started); elapsed > completionTimeout+time.Second {
+		t.Errorf("Expected completion to give up after %s, took %s", completionTimeout, elapsed)
+	}
+}
+
+func TestCompleteResourceAddressSlowPasswordCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the password command uses sh")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	config := "neo4j:\n  password_command: sleep 30; echo secret\n"
+	if err := os.WriteFile(".terraform-graphx.yaml", []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	started := time.Now()
+	if ids, directive := completeResourceAddress(impactCmd, nil, "aws_"); ids != nil || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected no completions while the password command hangs, got %v, %v", ids, directive)
+	}
+	if elapsed := time.Since(started); elapsed > completionTimeout+2*time.Second {
+		t.Errorf("Expected the password command to be stopped after %s, took %s", completionTimeout, elapsed)
+	}
+}
+
+func TestImpactCompletesResourceAddresses(t *testing.T) {
+	if impactCmd.ValidArgsFunction == nil {
+		t.Fatal("Expected impact to complete its argument")
+	}
+}
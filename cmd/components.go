@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var componentsCmd = &cobra.Command{
+	Use:   "components [plan_file]",
+	Short: "List the clusters of connected resources",
+	Long: `Build the dependency graph and list its connected components: groups of
+resources linked by dependencies in either direction. A root module made of
+several unrelated components may be worth splitting. Resources without any
+dependency form components of their own.
+
+Components are listed largest first, and their members by address.
+
+Example:
+  terraform-graphx components
+  terraform-graphx components --format=json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runComponents,
+}
+
+// component is the JSON output of one component.
+type component struct {
+	Size    int      `json:"size"`
+	Members []string `json:"members"`
+}
+
+func runComponents(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q (supported: text, json)", format)
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	components := graph.ConnectedComponents(g)
+	if format == "json" {
+		result := make([]component, len(components))
+		for i, members := range components {
+			result[i] = component{Size: len(members), Members: members}
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode components: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("%d component(s)\n", len(components))
+	for i, members := range components {
+		fmt.Printf("\nComponent %d (%d resources):\n", i+1, len(members))
+		for _, id := range members {
+			fmt.Printf("  %s\n", id)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(componentsCmd)
+
+	componentsCmd.Flags().String("format", "text", "Output format: text or json")
+	componentsCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	componentsCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	componentsCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	componentsCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	componentsCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	componentsCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	componentsCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	componentsCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	componentsCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+}
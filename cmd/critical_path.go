@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var criticalPathCmd = &cobra.Command{
+	Use:   "critical-path [plan_file]",
+	Short: "Show the longest dependency chain",
+	Long: `Build the dependency graph and show its longest dependency chain, from the
+resource that depends on the others down to the one the whole chain depends
+on. Changes along it ripple the furthest, so its length is a rough measure of
+change risk. Among chains of equal length, the one starting with the smallest
+address is shown. The graph must not contain cycles, apart from self-loops
+when --allow-self-edges is set.
+
+Example:
+  terraform-graphx critical-path
+  terraform-graphx critical-path --format=json plan.tfplan`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runCriticalPath,
+}
+
+// criticalPath is the JSON output of critical-path.
+type criticalPath struct {
+	// Length is the number of dependencies in the chain.
+	Length int      `json:"length"`
+	Path   []string `json:"path"`
+}
+
+func runCriticalPath(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q (supported: text, json)", format)
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	path, err := graph.LongestPath(g, cfg.AllowSelfEdges)
+	if err != nil {
+		return err
+	}
+	result := criticalPath{Length: max(len(path)-1, 0), Path: path}
+	if result.Path == nil {
+		result.Path = []string{}
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode critical path: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(path) == 0 {
+		fmt.Println("The graph is empty")
+		return nil
+	}
+	fmt.Printf("Longest dependency chain: %d dependencies\n", result.Length)
+	for i, id := range path {
+		if i == 0 {
+			fmt.Printf("  %s\n", id)
+			continue
+		}
+		fmt.Printf("  -> %s\n", id)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(criticalPathCmd)
+
+	criticalPathCmd.Flags().String("format", "text", "Output format: text or json")
+	criticalPathCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	criticalPathCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	criticalPathCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	criticalPathCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	criticalPathCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	criticalPathCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	criticalPathCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	criticalPathCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	criticalPathCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+	criticalPathCmd.Flags().Bool("allow-self-edges", false, "Keep references of a resource to itself, such as self in provisioners, as self-loop edges and ignore them")
+}
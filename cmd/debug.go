@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:    "debug",
+	Short:  "Debugging helpers",
+	Hidden: true,
+}
+
+var debugParseCmd = &cobra.Command{
+	Use:   "parse [plan_file...]",
+	Short: "Print the parser's view of the Terraform graph as JSON",
+	Long: `Print exactly what the parser saw, as pretty JSON, to help diagnose missing
+nodes or edges and to attach to bug reports. It reads the same input as update
+and export.
+
+For a plan file this shows the parsed plan JSON: the planned resources and the
+configuration with its depends_on and references. A saved plan is read through
+'terraform show -json'; the dependencies 'terraform graph' adds to it are shown
+by running debug parse without a plan. Given several plan files, each is shown
+in turn. With --state or --state-s3 it shows the decoded state resources,
+instances and dependencies. Without any of them it shows the DOT nodes (name,
+label and derived address) and edges of 'terraform graph'.
+
+Example:
+  terraform-graphx debug parse
+  terraform-graphx debug parse plan.json
+  terraform-graphx debug parse --state terraform.tfstate`,
+	ValidArgsFunction: completePlanFile,
+	RunE:              runDebugParse,
+}
+
+func runDebugParse(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	view, err := runner.Inspect(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(view, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode parser output: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugParseCmd)
+
+	debugParseCmd.Flags().String("state", "", "Inspect a terraform.tfstate file instead of a plan")
+	debugParseCmd.Flags().String("state-s3", "", "Inspect state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	debugParseCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+}
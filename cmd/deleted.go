@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var deletedCmd = &cobra.Command{
+	Use:   "deleted",
+	Short: "List or purge soft-deleted resources",
+	Long: `List the resources that were marked as deleted by 'update --prune --soft-delete'.
+
+Use --purge to permanently remove them (and their relationships) from Neo4j.
+
+Example:
+  terraform-graphx deleted
+  terraform-graphx deleted --purge`,
+	RunE: runDeleted,
+}
+
+func runDeleted(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx := cmd.Context()
+	client, err := runner.NewNeo4jClient(ctx, &cfg.Neo4j)
+	if err != nil {
+		return fmt.Errorf("failed to create neo4j client: %w", err)
+	}
+	defer client.Close(ctx)
+
+	if err := client.SetNodeLabel(cfg.Neo4j.NodeLabel); err != nil {
+		return fmt.Errorf("invalid neo4j.node_label: %w", err)
+	}
+
+	purge, _ := cmd.Flags().GetBool("purge")
+	if purge {
+		count, err := client.PurgeSoftDeleted(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Purged %d soft-deleted resources\n", count)
+		return nil
+	}
+
+	ids, err := client.SoftDeletedResources(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(deletedCmd)
+
+	deletedCmd.Flags().Bool("purge", false, "Permanently remove soft-deleted resources")
+}
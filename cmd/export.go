@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/formatter"
+	"terraform-graphx/internal/logging"
+	"terraform-graphx/internal/runner"
+	"terraform-graphx/internal/viewer"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [plan_file...]",
+	Short: "Export the Terraform dependency graph to a file format",
+	Long: `Generate the Terraform dependency graph and write it in the format selected
+by a subcommand (or --format) to stdout, or to the file given with --output.
+Given several plan files, their graphs are read in parallel, up to
+--concurrency at a time, and merged.
+
+Supported formats:
+  json       Nodes and edges as JSON
+  cypher     Cypher script that merges the graph, for cypher-shell or Neo4j Browser
+  graphml    GraphML document for yEd, Gephi and similar tools
+  dot        Graphviz DOT; --group-by=provider|module clusters the nodes,
+             --rankdir=LR|TB|RL|BT and --fontname change the layout
+  cytoscape  Cytoscape.js elements JSON
+  plantuml   PlantUML component diagram
+  gexf       GEXF document for Gephi
+  adjacency  JSON object mapping each resource to the resources it depends on
+  deps       One "target dependent" line per dependency, for tsort and make;
+             --separator changes the space between them
+  table      Aligned tables of the resources and dependencies, for the terminal
+
+Use --bloom to also write a Neo4j Bloom perspective that styles resource nodes
+by provider and shows DEPENDS_ON relationships; import it from Bloom's
+perspective gallery.
+
+Use --open to preview the graph instead: it is written to a temporary file,
+rendered to SVG if Graphviz 'dot' is installed, and opened with the default viewer.
+
+Example:
+  terraform-graphx export cypher > graph.cypher
+  terraform-graphx export graphml --output=graph.graphml
+  terraform-graphx export dot --group-by=module plan.tfplan
+  terraform-graphx export --bloom=perspective.json
+  terraform-graphx export --open`,
+	ValidArgsFunction: completePlanFile,
+	RunE:              runExport,
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Format != "" {
+		if err := runner.ValidateFormat(cfg.Format); err != nil {
+			return err
+		}
+	}
+
+	open, _ := cmd.Flags().GetBool("open")
+	if open {
+		return previewGraph(cmd.Context(), cfg)
+	}
+
+	bloomPath, _ := cmd.Flags().GetString("bloom")
+	if bloomPath != "" {
+		if err := writeBloomPerspective(cmd.Context(), cfg, bloomPath); err != nil {
+			return err
+		}
+		if cfg.Format == "" {
+			return nil
+		}
+	}
+
+	if cfg.Format == "" {
+		return fmt.Errorf("--format is required unless --open or --bloom is set")
+	}
+	return runner.Export(cmd.Context(), cfg)
+}
+
+// exportFormatShort describes each export subcommand.
+var exportFormatShort = map[string]string{
+	"json":      "Export the graph as nodes and edges JSON",
+	"cypher":    "Export the graph as a Cypher script for cypher-shell or Neo4j Browser",
+	"graphml":   "Export the graph as a GraphML document for yEd, Gephi and similar tools",
+	"dot":       "Export the graph as Graphviz DOT",
+	"cytoscape": "Export the graph as Cytoscape.js elements JSON",
+	"plantuml":  "Export the graph as a PlantUML component diagram",
+	"gexf":      "Export the graph as a GEXF document for Gephi",
+	"adjacency": "Export the graph as JSON mapping each resource to its dependencies",
+	"deps":      "Export the dependencies as 'target dependent' lines for tsort",
+	"table":     "Print the resources and dependencies as aligned tables",
+}
+
+// newExportFormatCmd returns the export subcommand writing the graph in format.
+// It shares the filters and --output of export, which are persistent flags.
+func newExportFormatCmd(format string) *cobra.Command {
+	return &cobra.Command{
+		Use:               format + " [plan_file...]",
+		Short:             exportFormatShort[format],
+		ValidArgsFunction: completePlanFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadAndMerge(cmd, args)
+			if err != nil {
+				return err
+			}
+			cfg.Format = format
+			return runner.Export(cmd.Context(), cfg)
+		},
+	}
+}
+
+// writeBloomPerspective writes a Bloom perspective for the graph's label,
+// providers and persisted attributes and tags to path.
+func writeBloomPerspective(ctx context.Context, cfg *config.Config, path string) error {
+	g, err := runner.BuildGraph(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := formatter.ToBloomPerspective(g, formatter.BloomOptions{
+		NodeLabel: cfg.Neo4j.NodeLabel,
+		Properties: formatter.AttributeKeys(formatter.CypherOptions{
+			AttributeAllowlist: cfg.Attributes.Allowlist,
+			AttributeDenylist:  cfg.Attributes.Denylist,
+			TagProperties:      cfg.Neo4j.TagProperties,
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write Bloom perspective: %w", err)
+	}
+	logging.Infof("Bloom perspective written to %s", path)
+	return nil
+}
+
+// previewGraph renders the graph as DOT and opens it with the OS default viewer.
+func previewGraph(ctx context.Context, cfg *config.Config) error {
+	g, err := runner.BuildGraph(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	dot, err := formatter.ToDOT(g, runner.DOTOptions(cfg))
+	if err != nil {
+		return err
+	}
+
+	path, err := viewer.Preview(dot)
+	if err != nil {
+		return err
+	}
+	logging.Infof("Opened %s", path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	// Kept for backward compatibility; the format subcommands are preferred
+	exportCmd.Flags().String("format", "", "Output format ("+strings.Join(runner.SupportedFormats(), ", ")+")")
+	exportCmd.RegisterFlagCompletionFunc("format", completeExportFormat)
+	exportCmd.Flags().String("bloom", "", "Write a Neo4j Bloom perspective to this file")
+	exportCmd.Flags().Bool("open", false, "Open the graph in the default viewer instead of writing it")
+	exportCmd.Flags().Bool("sanitize", false, "Redact sensitive attribute values from JSON output")
+	exportCmd.Flags().String("group-by", "", "Cluster DOT nodes by provider or module (provider, module, none)")
+	exportCmd.Flags().String("rankdir", "", "Direction of the DOT layout (LR, TB, RL, BT; default RL like terraform graph)")
+	exportCmd.Flags().String("fontname", "", "Font of the DOT nodes (default sans-serif)")
+
+	// Shared by export and its format subcommands
+	exportCmd.PersistentFlags().String("output", "", "Write the graph to this file instead of stdout")
+	exportCmd.PersistentFlags().Int("concurrency", 0, "How many plan files to read at once when several are given (default: number of CPUs)")
+	exportCmd.PersistentFlags().Bool("reuse-plan", false, "Without a plan argument, use tfplan.binary from the working directory if it is newer than every .tf file")
+	exportCmd.PersistentFlags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	exportCmd.PersistentFlags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	exportCmd.PersistentFlags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	exportCmd.PersistentFlags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	exportCmd.PersistentFlags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	exportCmd.PersistentFlags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	exportCmd.PersistentFlags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	exportCmd.PersistentFlags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	exportCmd.PersistentFlags().String("module", "", "Only include resources within this module (e.g. module.network)")
+	exportCmd.PersistentFlags().StringSlice("entry-type", nil, "Keep only resources of this type (repeatable) and everything they depend on")
+	exportCmd.PersistentFlags().Bool("with-modules", false, "Add a Module node per module with CONTAINS relationships to its resources and child modules")
+	exportCmd.PersistentFlags().Bool("with-providers", false, "Add a Provider node per provider with PROVIDED_BY relationships from its resources")
+	exportCmd.PersistentFlags().Bool("allow-self-edges", false, "Keep references of a resource to itself, such as self in provisioners, as self-loop edges")
+	exportCmd.PersistentFlags().StringSlice("relation-kind", nil, "Only keep dependency edges of this kind: explicit, implicit or data (repeatable)")
+	exportCmd.PersistentFlags().String("edge-direction", "depends-on", "Direction of dependency edges: depends-on (X DEPENDS_ON Y) or required-by (Y REQUIRED_BY X)")
+	exportCmd.PersistentFlags().String("root-module", "", "Treat resources in this module as the top of the graph and drop what they do not reach")
+
+	for _, format := range runner.SupportedFormats() {
+		formatCmd := newExportFormatCmd(format)
+		switch format {
+		case "json":
+			formatCmd.Flags().Bool("sanitize", false, "Redact the values of attributes whose keys look sensitive (password, secret, token, key, private)")
+		case "dot":
+			formatCmd.Flags().String("group-by", "", "Cluster nodes by provider or module (provider, module, none)")
+			formatCmd.Flags().String("rankdir", "", "Direction of the layout (LR, TB, RL, BT; default RL like terraform graph)")
+			formatCmd.Flags().String("fontname", "", "Font of the nodes (default sans-serif)")
+		case "cypher":
+			formatCmd.Flags().Bool("type-labels", false, "Also label each node with its resource type (e.g. :aws_instance)")
+			formatCmd.Flags().String("run-id", "", "Stamp this run ID on every node and relationship written (e.g. a CI build number)")
+			formatCmd.Flags().Bool("compact", false, "Write the script as a single statement on one line")
+		case "deps":
+			formatCmd.Flags().String("separator", formatter.DefaultDependencySeparator, "Separator between the target and the dependent")
+		}
+		exportCmd.AddCommand(formatCmd)
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"terraform-graphx/internal/runner"
+	"testing"
+)
+
+const exportTestState = `{
+  "version": 4,
+  "resources": [
+    {"mode": "managed", "type": "aws_vpc", "name": "main", "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]", "instances": [{}]},
+    {"module": "module.network", "mode": "managed", "type": "aws_subnet", "name": "a", "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{"dependencies": ["aws_vpc.main"]}]}
+  ]
+}`
+
+func TestExportHasSubcommandPerFormat(t *testing.T) {
+	for _, format := range runner.SupportedFormats() {
+		cmd, _, err := exportCmd.Find([]string{format})
+		if err != nil || cmd == exportCmd {
+			t.Errorf("Expected an 'export %s' subcommand", format)
+			continue
+		}
+		if cmd.Short == "" {
+			t.Errorf("Expected a description for 'export %s'", format)
+		}
+	}
+}
+
+func TestExportSubcommands(t *testing.T) {
+	dir := t.TempDir()
+	state := filepath.Join(dir, "terraform.tfstate")
+	if err := os.WriteFile(state, []byte(exportTestState), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"export", "json"}, []string{`"id": "aws_vpc.main"`}},
+		{[]string{"export", "dot", "--group-by=module", "--rankdir=LR"}, []string{"subgraph", "module.network", `rankdir = "LR"`}},
+		{[]string{"export", "--format=cypher"}, []string{"MERGE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			output := filepath.Join(dir, "graph.out")
+			rootCmd.SetArgs(append(tt.args, "--state", state, "--output", output))
+			t.Cleanup(func() { rootCmd.SetArgs(nil) })
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("%s failed: %v", strings.Join(tt.args, " "), err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			for _, e := range tt.expected {
+				if !strings.Contains(string(data), e) {
+					t.Errorf("Expected %q in output:\n%s", e, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact <address> [plan_file]",
+	Short: "List resources transitively connected to a resource",
+	Long: `Answer "if I change this resource, what breaks?" by listing every resource
+that transitively depends on the given address.
+
+Directions:
+  up    resources that depend on the address (default)
+  down  resources the address depends on
+
+Example:
+  terraform-graphx impact aws_vpc.main
+  terraform-graphx impact aws_instance.web --direction=down`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeResourceAddress,
+	RunE:              runImpact,
+}
+
+func runImpact(cmd *cobra.Command, args []string) error {
+	address := args[0]
+	direction, _ := cmd.Flags().GetString("direction")
+	if direction != "up" && direction != "down" {
+		return fmt.Errorf("invalid direction %q (expected up or down)", direction)
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args[1:])
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	if !hasNode(g, address) {
+		return fmt.Errorf("resource %s not found in graph", address)
+	}
+
+	var results []string
+	if direction == "up" {
+		results = graph.Dependents(g, address)
+	} else {
+		results = graph.Dependencies(g, address)
+	}
+
+	for _, id := range results {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+// hasNode reports whether g contains a node with the given ID.
+func hasNode(g *graph.Graph, id string) bool {
+	for _, node := range g.Nodes {
+		if node.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+
+	impactCmd.Flags().String("direction", "up", "Traversal direction: up (dependents) or down (dependencies)")
+	impactCmd.Flags().String("root-module", "", "Treat resources in this module as the top of the graph and drop what they do not reach")
+}
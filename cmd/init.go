@@ -7,6 +7,7 @@ import (
 	"terraform-graphx/internal/git"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var initCmd = &cobra.Command{
@@ -24,16 +25,41 @@ The configuration file will be created with the following default values:
   - neo4j.password: (randomly generated)
   - neo4j.docker_image: neo4j:community
 
+Use --external-db (or --no-data-dir) when connecting to an external or managed
+Neo4j instead of the bundled Docker container: no neo4j-data directory,
+docker_image or password is generated.
+
+Use 'init config' to only write the configuration file.
+
 Example:
-  terraform-graphx init`,
+  terraform-graphx init
+  terraform-graphx init --external-db
+  terraform-graphx init config`,
 	RunE: runInit,
 }
 
+var initConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Only write the .terraform-graphx.yaml configuration file",
+	Long: `Write a .terraform-graphx.yaml configuration file for an externally managed
+Neo4j, and nothing else: unlike init, no neo4j-data directory is created and
+.gitignore is left untouched. Fails if the file already exists.
+
+Set neo4j.uri, neo4j.user and neo4j.password in the file for your Neo4j
+instance afterwards.
+
+Example:
+  terraform-graphx init config`,
+	Args: cobra.NoArgs,
+	RunE: runInitConfig,
+}
+
 func runInit(cmd *cobra.Command, args []string) error {
 	configPath := ".terraform-graphx.yaml"
+	externalDB, _ := cmd.Flags().GetBool("external-db")
 
 	// Initialize configuration and data directory
-	result, err := config.Initialize(configPath)
+	result, err := config.Initialize(configPath, config.InitializeOptions{ExternalDB: externalDB})
 	if err != nil {
 		return err
 	}
@@ -43,21 +69,62 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Println("Default configuration:")
 	fmt.Printf("  neo4j.uri: %s\n", result.Config.Neo4j.URI)
 	fmt.Printf("  neo4j.user: %s\n", result.Config.Neo4j.User)
-	fmt.Printf("  neo4j.password: %s\n", result.Config.Neo4j.Password)
-	fmt.Printf("  neo4j.docker_image: %s\n\n", result.Config.Neo4j.DockerImage)
-	fmt.Printf("✓ Created data directory: %s\n\n", result.DataDir)
+
+	entriesToIgnore := []string{".terraform-graphx.yaml"}
+	if externalDB {
+		fmt.Println()
+		fmt.Println("Set neo4j.uri, neo4j.user and neo4j.password for your Neo4j instance,")
+		fmt.Println("or provide them via TFGRAPHX_NEO4J_URI, TFGRAPHX_NEO4J_USER and TFGRAPHX_NEO4J_PASSWORD.")
+	} else {
+		fmt.Printf("  neo4j.password: %s\n", result.Config.Neo4j.Password)
+		fmt.Printf("  neo4j.docker_image: %s\n\n", result.Config.Neo4j.DockerImage)
+		fmt.Printf("✓ Created data directory: %s\n\n", result.DataDir)
+		entriesToIgnore = append(entriesToIgnore, "neo4j-data/")
+	}
 
 	// Attempt to update .gitignore
-	entriesToIgnore := []string{".terraform-graphx.yaml", "neo4j-data/"}
 	if err := git.UpdateGitignore(entriesToIgnore); err != nil {
 		// If gitignore update fails, print a warning but don't fail the command
 		fmt.Fprintf(os.Stderr, "Warning: failed to update .gitignore: %v\n", err)
-		fmt.Println("Please manually add '.terraform-graphx.yaml' and 'neo4j-data/' to your .gitignore file.")
+		fmt.Printf("Please manually add %s to your .gitignore file.\n", quoteAll(entriesToIgnore))
 	}
 
 	return nil
 }
 
+func runInitConfig(cmd *cobra.Command, args []string) error {
+	result, err := config.Initialize(".terraform-graphx.yaml", config.InitializeOptions{ExternalDB: true})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created configuration file: %s\n\n", result.ConfigPath)
+	fmt.Println("Set neo4j.uri, neo4j.user and neo4j.password for your Neo4j instance,")
+	fmt.Println("and keep the file out of version control, as it will hold credentials.")
+	return nil
+}
+
+// quoteAll formats entries as single-quoted strings joined with "and".
+func quoteAll(entries []string) string {
+	quoted := ""
+	for i, entry := range entries {
+		if i > 0 {
+			quoted += " and "
+		}
+		quoted += "'" + entry + "'"
+	}
+	return quoted
+}
+
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.AddCommand(initConfigCmd)
+
+	initCmd.Flags().Bool("external-db", false, "Configure an external Neo4j: skip the neo4j-data directory and Docker settings")
+	initCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "no-data-dir" {
+			name = "external-db"
+		}
+		return pflag.NormalizedName(name)
+	})
 }
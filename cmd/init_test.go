@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInitConfigAlreadyExists(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := runInitConfig(initConfigCmd, nil); err != nil {
+		t.Fatalf("init config failed: %v", err)
+	}
+	if _, err := os.Stat(".terraform-graphx.yaml"); err != nil {
+		t.Fatalf("Expected the configuration file to be written: %v", err)
+	}
+	for _, path := range []string{"neo4j-data", ".gitignore"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be created, stat returned: %v", path, err)
+		}
+	}
+
+	before, _ := os.ReadFile(".terraform-graphx.yaml")
+	err := runInitConfig(initConfigCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an already exists error, got %v", err)
+	}
+	if after, _ := os.ReadFile(".terraform-graphx.yaml"); string(after) != string(before) {
+		t.Error("Expected the existing configuration file to be left unchanged")
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/docker"
+
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the logs of the Neo4j Docker container",
+	Long: `Show the logs of the Neo4j Docker container started with 'terraform-graphx start',
+i.e. the container named by neo4j.container_name. Use --tail to show only the
+last lines and --follow to keep streaming new output until interrupted.
+
+Example:
+  terraform-graphx logs --tail=100
+  terraform-graphx logs --follow`,
+	Args: cobra.NoArgs,
+	RunE: runLogs,
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	follow, _ := cmd.Flags().GetBool("follow")
+	tail, _ := cmd.Flags().GetString("tail")
+	opts := docker.LogsOptions{Follow: follow, Tail: tail}
+	return docker.ContainerLogs(cmd.Context(), cfg, opts, cmd.OutOrStdout(), cmd.ErrOrStderr())
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep streaming new log output")
+	logsCmd.Flags().String("tail", "all", "Number of lines to show from the end of the logs, or 'all'")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var orphansCmd = &cobra.Command{
+	Use:   "orphans [plan_file]",
+	Short: "List resources with no dependencies and no dependents",
+	Long: `List resources that appear in no dependency edge. Isolated resources are
+often a smell, such as copy-pasted blocks or missing references.
+
+Use --strict to exit with an error when orphans are found, e.g. in CI.
+
+Example:
+  terraform-graphx orphans
+  terraform-graphx orphans --strict --exclude-type=random_id`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runOrphans,
+}
+
+func runOrphans(cmd *cobra.Command, args []string) error {
+	strict, _ := cmd.Flags().GetBool("strict")
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	orphans := graph.Orphans(g)
+	for _, id := range orphans {
+		fmt.Println(id)
+	}
+
+	if strict && len(orphans) > 0 {
+		return fmt.Errorf("found %d orphan resource(s)", len(orphans))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(orphansCmd)
+
+	orphansCmd.Flags().Bool("strict", false, "Exit with an error if any orphans are found")
+	orphansCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	orphansCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	orphansCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	orphansCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	orphansCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	orphansCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	orphansCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	orphansCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	orphansCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+}
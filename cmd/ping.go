@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Check that Neo4j is reachable, for monitoring",
+	Long: `Verify that the configured Neo4j database accepts connections with the
+configured credentials. Nothing is printed on success and the exit code is 0;
+on failure a one-line error is written to stderr and the exit code is 1.
+
+Use --timeout to bound how long the check may take, e.g. in a Kubernetes
+liveness probe or a cron job.
+
+Example:
+  terraform-graphx ping --timeout 5s`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runPing,
+}
+
+func runPing(cmd *cobra.Command, args []string) error {
+	if err := ping(cmd, args); err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), strings.ReplaceAll(err.Error(), "\n", " "))
+		return err
+	}
+	return nil
+}
+
+// ping verifies connectivity to the configured Neo4j database.
+func ping(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	client, err := runner.NewNeo4jClient(ctx, &cfg.Neo4j)
+	if err != nil {
+		return fmt.Errorf("failed to create neo4j client: %w", err)
+	}
+	defer client.Close(ctx)
+
+	if err := client.VerifyConnectivity(ctx); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("neo4j at %s did not answer: %w", cfg.Neo4j.URI, context.Cause(ctx))
+		}
+		return fmt.Errorf("neo4j at %s is unreachable: %w", cfg.Neo4j.URI, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pingCmd)
+
+	pingCmd.Flags().String("neo4j-uri", "bolt://localhost:7687", "URI for the Neo4j database")
+	pingCmd.Flags().String("neo4j-user", "neo4j", "Username for the Neo4j database")
+	pingCmd.Flags().String("neo4j-pass", "", "Password for the Neo4j database")
+	pingCmd.Flags().String("neo4j-auth", "basic", "Authentication mode: basic, bearer (token from neo4j.bearer_token) or none")
+	pingCmd.Flags().String("neo4j-realm", "", "Realm for basic authentication, e.g. ldap")
+}
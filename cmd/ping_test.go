@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPingUnreachable(t *testing.T) {
+	var stderr bytes.Buffer
+	rootCmd.SetArgs([]string{"ping", "--neo4j-uri", "bolt://127.0.0.1:1", "--neo4j-pass", "secret", "--timeout", "5s"})
+	rootCmd.SetErr(&stderr)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetErr(nil)
+		resetFlags(pingCmd, "neo4j-uri", "neo4j-pass", "timeout")
+	})
+
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("Expected ping to fail for an unreachable database")
+	}
+
+	output := stderr.String()
+	if strings.Count(output, "\n") != 1 || !strings.Contains(output, "bolt://127.0.0.1:1") {
+		t.Errorf("Expected a one-line error naming the URI, got %q", output)
+	}
+}
+
+// resetFlags restores the named flags of cmd to their defaults and marks them
+// unchanged, as Flags().Set would leave them marked as given.
+func resetFlags(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}
+}
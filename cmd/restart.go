@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/docker"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart the Neo4j Docker container",
+	Long: `Stop and remove the Neo4j Docker container, then start it again with the
+current configuration, e.g. after changing the image, credentials or ports.
+The data in the neo4j-data directory is preserved. If no container exists,
+restart just starts one.
+
+As with start, the command waits until Neo4j accepts connections, for up to
+--wait-timeout. Use --no-wait to return as soon as the container is started.
+
+Example:
+  terraform-graphx restart`,
+	RunE: runRestart,
+}
+
+func runRestart(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	ctx := cmd.Context()
+	if err := docker.RestartContainer(ctx, docker.StartContainerOptions{Config: cfg}); err != nil {
+		return err
+	}
+
+	noWait, _ := cmd.Flags().GetBool("no-wait")
+	if noWait {
+		return nil
+	}
+	timeout, _ := cmd.Flags().GetDuration("wait-timeout")
+	return waitForNeo4j(ctx, cfg, timeout)
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+
+	restartCmd.Flags().Duration("wait-timeout", 60*time.Second, "How long to wait for Neo4j to accept connections")
+	restartCmd.Flags().Bool("no-wait", false, "Return as soon as the container is started, without waiting for Neo4j")
+}
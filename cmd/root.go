@@ -1,20 +1,134 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/logging"
 
 	"github.com/spf13/cobra"
 )
 
+// Build metadata, set at link time with
+// -ldflags "-X terraform-graphx/cmd.version=... -X terraform-graphx/cmd.commit=... -X terraform-graphx/cmd.date=...".
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "terraform-graphx [command]",
 	Short: "Generate dependency graphs from Terraform infrastructure",
 	Long: `terraform-graphx is a CLI tool that generates dependency graphs of your 
 Terraform infrastructure and can export them to JSON, Cypher, or Neo4j.`,
+	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("profile") {
+			profile, _ := cmd.Flags().GetString("profile")
+			config.SelectProfile(profile)
+		}
+		if cmd.Flags().Changed("env-file") {
+			envFile, _ := cmd.Flags().GetString("env-file")
+			config.SelectEnvFile(envFile)
+		}
+
+		if err := setupLogging(cmd); err != nil {
+			return err
+		}
+		return setupTimeout(cmd)
+	},
 }
 
+// errTimeout is the cancellation cause of commands that exceed --timeout.
+var errTimeout = errors.New("timed out")
+
+// cancelTimeout releases the --timeout context once the command returns.
+var cancelTimeout context.CancelFunc = func() {}
+
+// Execute runs the root command with a context that is cancelled on SIGINT or
+// SIGTERM, so that terraform is killed and open Neo4j transactions roll back.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	// Cancellation is checked before the contexts are released, which cancels them
+	if err != nil && cmd != nil && !cmd.SilenceErrors {
+		// Commands that silence errors report them, cancellation included, themselves
+		reportCancellation(cmd.Context())
+	}
+	cancelTimeout()
+	stop()
+	if err != nil {
 		os.Exit(1)
 	}
 }
+
+// setupTimeout applies the global --timeout flag to the command's context.
+func setupTimeout(cmd *cobra.Command) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative, got %s", timeout)
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeoutCause(cmd.Context(), timeout, fmt.Errorf("%w after %s (--timeout)", errTimeout, timeout))
+		cmd.SetContext(ctx)
+		cancelTimeout = cancel
+	}
+	return nil
+}
+
+// reportCancellation explains why a command failed when its context was
+// cancelled, as the error itself usually only says "context canceled".
+func reportCancellation(ctx context.Context) {
+	if ctx == nil || ctx.Err() == nil {
+		return
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errTimeout) {
+		logging.Errorf("%v; the operation was aborted and any open Neo4j transaction rolled back", cause)
+		return
+	}
+	logging.Errorf("interrupted; the operation was aborted and any open Neo4j transaction rolled back")
+}
+
+// setupLogging applies the global --log-format, --log-json, --quiet and --verbose flags.
+func setupLogging(cmd *cobra.Command) error {
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	logJSON, _ := cmd.Flags().GetBool("log-json")
+	if logJSON {
+		logFormat = logging.FormatJSON
+	}
+	if err := logging.Setup(logFormat, os.Stderr); err != nil {
+		return err
+	}
+
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	switch {
+	case quiet && verbose:
+		return fmt.Errorf("--quiet and --verbose can't be used together")
+	case quiet:
+		logging.SetLevel(logging.LevelError)
+	case verbose:
+		logging.SetLevel(logging.LevelDebug)
+	default:
+		logging.SetLevel(logging.LevelInfo)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.SetVersionTemplate(versionString() + "\n")
+
+	rootCmd.PersistentFlags().String("profile", "", "Apply the settings of this profile from the config file (or set TFGRAPHX_PROFILE)")
+	rootCmd.PersistentFlags().String("env-file", "", "Read environment variables such as TFGRAPHX_NEO4J_PASSWORD from this dotenv file, e.g. .env")
+	rootCmd.PersistentFlags().String("log-format", logging.FormatText, "Log format: text or json (logs go to stderr)")
+	rootCmd.PersistentFlags().Bool("log-json", false, "Write logs as one JSON object per line (same as --log-format=json)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only print command output and errors")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Also print debug logs")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Abort the command after this long, e.g. 5m (0 means no timeout)")
+}
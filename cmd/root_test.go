@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestEnvFileTakesSeparateValue(t *testing.T) {
+	if err := updateCmd.ParseFlags([]string{"--env-file", "prod.env", "plan.json"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	t.Cleanup(func() { updateCmd.Flags().Set("env-file", "") })
+
+	if envFile, _ := updateCmd.Flags().GetString("env-file"); envFile != "prod.env" {
+		t.Errorf("Expected --env-file prod.env, got %q", envFile)
+	}
+	if args := updateCmd.Flags().Args(); len(args) != 1 || args[0] != "plan.json" {
+		t.Errorf("Expected plan.json as the only argument, got %v", args)
+	}
+}
+
+func TestSetupTimeout(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().Duration("timeout", 0, "")
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		cmd.SetContext(context.Background())
+		return cmd
+	}
+	t.Cleanup(func() { cancelTimeout() })
+
+	cmd := newCmd()
+	if err := setupTimeout(cmd); err != nil {
+		t.Fatalf("setupTimeout failed: %v", err)
+	}
+	if _, ok := cmd.Context().Deadline(); ok {
+		t.Error("Expected no deadline without --timeout")
+	}
+
+	cmd = newCmd("--timeout=1ms")
+	if err := setupTimeout(cmd); err != nil {
+		t.Fatalf("setupTimeout failed: %v", err)
+	}
+	<-cmd.Context().Done()
+	if cause := context.Cause(cmd.Context()); !errors.Is(cause, errTimeout) {
+		t.Errorf("Expected a timeout cause, got %v", cause)
+	}
+
+	if err := setupTimeout(newCmd("--timeout=-1s")); err == nil {
+		t.Error("Expected an error for a negative timeout")
+	}
+}
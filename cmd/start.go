@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"terraform-graphx/internal/config"
 	"terraform-graphx/internal/docker"
+	"terraform-graphx/internal/runner"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -22,8 +24,23 @@ This command will:
   - Use the credentials from the configuration file
   - Mount the neo4j-data directory as a volume
 
+If the container is already running but the Docker-relevant configuration
+(image, user, ports or data directory) changed since it was started, start
+warns that it is outdated; use --recreate to replace it. The password is not
+compared, so pass --recreate yourself after changing it.
+
+After starting, the command waits until Neo4j accepts connections with the
+configured credentials, for up to --wait-timeout. Use --no-wait to return as
+soon as the container is started.
+
+Use --print-command to print the equivalent 'docker run' command without
+starting anything. The password is left out of it: docker reads NEO4J_AUTH
+from the environment, so export NEO4J_AUTH=<user>/<password> before running
+the command.
+
 Example:
-  terraform-graphx start`,
+  terraform-graphx start
+  terraform-graphx start --print-command`,
 	RunE: runStart,
 }
 
@@ -34,13 +51,67 @@ func runStart(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	printCommand, _ := cmd.Flags().GetBool("print-command")
+	if printCommand {
+		runCommand, err := docker.RunCommand(cmd.Context(), cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Println(runCommand)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Export NEO4J_AUTH=%s/<password> before running it.\n", cfg.Neo4j.User)
+		return nil
+	}
+
 	// Start the Neo4j container
-	ctx := context.Background()
-	return docker.StartContainer(ctx, docker.StartContainerOptions{
-		Config: cfg,
+	ctx := cmd.Context()
+	recreate, _ := cmd.Flags().GetBool("recreate")
+	err = docker.StartContainer(ctx, docker.StartContainerOptions{
+		Config:   cfg,
+		Recreate: recreate,
+	})
+	if err != nil {
+		return err
+	}
+
+	noWait, _ := cmd.Flags().GetBool("no-wait")
+	if noWait {
+		fmt.Printf("\nYou can verify the connection with:\n")
+		fmt.Printf("  terraform-graphx check database\n")
+		return nil
+	}
+
+	timeout, _ := cmd.Flags().GetDuration("wait-timeout")
+	return waitForNeo4j(ctx, cfg, timeout)
+}
+
+// waitForNeo4j blocks until Neo4j accepts connections with the configured
+// credentials, or timeout elapses.
+func waitForNeo4j(ctx context.Context, cfg *config.Config, timeout time.Duration) error {
+	client, err := runner.NewNeo4jClient(ctx, &cfg.Neo4j)
+	if err != nil {
+		return fmt.Errorf("failed to create neo4j client: %w", err)
+	}
+	defer client.Close(ctx)
+
+	fmt.Printf("\nWaiting up to %s for Neo4j to accept connections", timeout)
+	started := time.Now()
+	err = client.WaitForConnectivity(ctx, timeout, 2*time.Second, func(error) {
+		fmt.Print(".")
 	})
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("neo4j did not become ready: %w", err)
+	}
+
+	fmt.Printf("✓ Neo4j is ready (%s)\n", time.Since(started).Round(time.Second))
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(startCmd)
+
+	startCmd.Flags().Duration("wait-timeout", 60*time.Second, "How long to wait for Neo4j to accept connections")
+	startCmd.Flags().Bool("no-wait", false, "Return as soon as the container is started, without waiting for Neo4j")
+	startCmd.Flags().Bool("recreate", false, "Replace a running container whose configuration is outdated")
+	startCmd.Flags().Bool("print-command", false, "Print the equivalent 'docker run' command without starting the container")
 }
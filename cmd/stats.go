@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [plan_file]",
+	Short: "Summarize the Terraform dependency graph",
+	Long: `Build the dependency graph and print an overview without touching Neo4j:
+node and edge counts, counts per resource type and provider, the number of
+roots (nothing depends on them) and leaves (they depend on nothing), and the
+nodes with the largest fan-in and fan-out.
+
+Example:
+  terraform-graphx stats
+  terraform-graphx stats --format=json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runStats,
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q (supported: text, json)", format)
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	stats := graph.Summarize(g)
+	if format == "json" {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode stats: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printStats(stats)
+	return nil
+}
+
+// printStats writes stats as a human-readable report.
+func printStats(stats graph.Stats) {
+	fmt.Printf("Nodes:  %d\n", stats.Nodes)
+	fmt.Printf("Edges:  %d\n", stats.Edges)
+	fmt.Printf("Roots:  %d\n", stats.Roots)
+	fmt.Printf("Leaves: %d\n", stats.Leaves)
+	if stats.MaxFanIn != nil {
+		fmt.Printf("Largest fan-in:  %s (%d dependents)\n", stats.MaxFanIn.ID, stats.MaxFanIn.Count)
+	}
+	if stats.MaxFanOut != nil {
+		fmt.Printf("Largest fan-out: %s (%d dependencies)\n", stats.MaxFanOut.ID, stats.MaxFanOut.Count)
+	}
+	printCounts("By type", stats.ByType)
+	printCounts("By provider", stats.ByProvider)
+}
+
+// printCounts prints counts sorted by descending count, then by key.
+func printCounts(title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n%s:\n", title)
+	for _, key := range keys {
+		name := key
+		if name == "" {
+			name = "(unknown)"
+		}
+		fmt.Printf("  %-40s %d\n", name, counts[key])
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().String("format", "text", "Output format: text or json")
+	statsCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	statsCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	statsCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	statsCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	statsCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	statsCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	statsCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	statsCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	statsCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+}
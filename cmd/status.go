@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/docker"
+	"terraform-graphx/internal/runner"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the Neo4j container is running and reachable",
+	Long: `Show the state of the Neo4j Docker container started with 'terraform-graphx start'
+and whether Neo4j accepts connections with the configured credentials.
+
+A missing container is reported as not running rather than as an error.
+
+Example:
+  terraform-graphx status`,
+	RunE: runStatus,
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	ctx := cmd.Context()
+
+	status, err := docker.GetContainerStatus(ctx, cfg)
+	switch {
+	case err != nil:
+		fmt.Printf("Container: unknown (%v)\n", err)
+	case !status.Found:
+		fmt.Printf("Container: not running\n")
+	case status.Running():
+		fmt.Printf("Container: running (%s, %s)\n", docker.ContainerName(cfg), status.ID[:12])
+	default:
+		fmt.Printf("Container: not running (%s, %s)\n", docker.ContainerName(cfg), status.Status)
+	}
+	if err == nil && status.Running() {
+		if len(status.Ports) > 0 {
+			fmt.Printf("Ports: %s\n", strings.Join(status.Ports, ", "))
+		} else {
+			fmt.Printf("Ports: none published\n")
+		}
+	}
+
+	if err := checkReachable(ctx, cfg); err != nil {
+		fmt.Printf("Neo4j: unreachable at %s (%v)\n", cfg.Neo4j.URI, err)
+	} else {
+		fmt.Printf("Neo4j: reachable at %s\n", cfg.Neo4j.URI)
+	}
+	return nil
+}
+
+// checkReachable verifies that Neo4j accepts connections with the configured credentials.
+func checkReachable(ctx context.Context, cfg *config.Config) error {
+	client, err := runner.NewNeo4jClient(ctx, &cfg.Neo4j)
+	if err != nil {
+		return err
+	}
+	defer client.Close(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return client.VerifyConnectivity(ctx)
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -1,7 +1,8 @@
 package cmd
 
 import (
-	"context"
+	"fmt"
+	"terraform-graphx/internal/config"
 	"terraform-graphx/internal/docker"
 
 	"github.com/spf13/cobra"
@@ -10,7 +11,8 @@ import (
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop and remove the Neo4j Docker container",
-	Long: `Stop and remove the Neo4j Docker container started with 'terraform-graphx start'.
+	Long: `Stop and remove the Neo4j Docker container started with 'terraform-graphx start',
+i.e. the container named by neo4j.container_name.
 
 This command will:
   - Stop the running Neo4j container
@@ -23,8 +25,13 @@ Example:
 }
 
 func runStop(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
-	return docker.StopContainer(ctx)
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	ctx := cmd.Context()
+	return docker.StopContainer(ctx, cfg)
 }
 
 func init() {
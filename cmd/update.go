@@ -8,14 +8,57 @@ import (
 )
 
 var updateCmd = &cobra.Command{
-	Use:   "update [plan_file]",
+	Use:   "update [plan_file...]",
 	Short: "Update a Neo4j database with the Terraform dependency graph",
-	Long: `terraform-graphx update generates a dependency graph of your Terraform
-resources by invoking 'terraform graph' and pushes the resulting graph to a Neo4j database.
+	Long: `terraform-graphx update builds a dependency graph of your Terraform
+resources and pushes it to a Neo4j database.
+
+A plan is the preferred source: a plan JSON file is read directly, and a saved
+plan through 'terraform show -json', with 'terraform graph' adding the
+dependencies the plan JSON leaves out. With --state or --state-s3 the graph is
+read from a state file instead, and a plan is ignored. Without either, update
+falls back to 'terraform graph', which has no providers or attributes.
 
 The graph is stored as nodes (resources) and relationships (dependencies) in Neo4j,
-allowing you to query and visualize your infrastructure dependencies.`,
-	RunE: runUpdate,
+allowing you to query and visualize your infrastructure dependencies.
+
+Given several plan files, e.g. one per root module, update merges their graphs.
+They are read in parallel, up to --concurrency at a time.
+
+Use --type and --exclude-type (repeatable) to restrict the graph to specific
+resource types, e.g. --type=aws_vpc --type=aws_subnet, and --include and
+--exclude (repeatable) to keep or drop resources by address or glob, e.g.
+--exclude='random_*.*'. Use --module to scope the graph to a module subtree,
+e.g. --module=module.network. Use --filter-attr (repeatable) to keep resources
+whose attributes match, e.g. --filter-attr tags.Environment=prod; attributes
+come from a plan or state file. Use --root-module to treat a module's
+resources as the top of the graph, keeping only them and what they
+transitively depend on, even when other resources depend on them.
+
+By default update only creates and updates resources. With --prune, resources
+that are no longer part of the graph are deleted. Before deleting, update asks
+for confirmation when run interactively; pass --yes to skip the question.
+When not run interactively (e.g. in CI), obsolete resources are only deleted
+with --yes and are otherwise kept with a warning. With --soft-delete they are
+instead flagged with deleted=true and a deleted_at timestamp, and are restored
+if they reappear. Use 'terraform-graphx deleted' to list or purge them.
+
+Each update stores a fingerprint of the graph and of the options it was
+written with. When a later update would write the same, it is skipped and
+reports no changes; pass --force to write anyway, e.g. after editing the
+database by hand.
+
+Use --fail-on-change in CI to detect drift: update compares the graph with
+the database, lists what it would add (+), update (~) and delete (-), and
+exits non-zero if anything would change. Nothing is written.
+
+Use --dry-run to print the Cypher query and its parameters instead of writing.
+With --prune, if the database is reachable, the obsolete resources that would
+be deleted are also listed. With --type-labels, the query only removes stale
+type labels when the database is reachable, since it reads the stored types;
+nothing is written either way.`,
+	ValidArgsFunction: completePlanFile,
+	RunE:              runUpdate,
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
@@ -24,14 +67,47 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return runner.Run(cfg)
+	return runner.Run(cmd.Context(), cfg)
 }
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
 
 	updateCmd.Flags().String("plan", "", "Path to a terraform plan file (optional)")
+	updateCmd.Flags().Int("concurrency", 0, "How many plan files to read at once when several are given (default: number of CPUs)")
+	updateCmd.Flags().Bool("reuse-plan", false, "Without a plan argument, use tfplan.binary from the working directory if it is newer than every .tf file")
 	updateCmd.Flags().String("neo4j-uri", "bolt://localhost:7687", "URI for the Neo4j database")
 	updateCmd.Flags().String("neo4j-user", "neo4j", "Username for the Neo4j database")
 	updateCmd.Flags().String("neo4j-pass", "", "Password for the Neo4j database")
+	updateCmd.Flags().String("neo4j-auth", "basic", "Authentication mode: basic, bearer (token from neo4j.bearer_token) or none")
+	updateCmd.Flags().String("neo4j-realm", "", "Realm for basic authentication, e.g. ldap")
+	updateCmd.Flags().String("engine", "auto", "Terraform engine to run: terraform, tofu or auto")
+	updateCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	updateCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	updateCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	updateCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	updateCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	updateCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	updateCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	updateCmd.Flags().StringSlice("relation-kind", nil, "Only keep dependency edges of this kind: explicit, implicit or data (repeatable)")
+	updateCmd.Flags().String("edge-direction", "depends-on", "Direction of dependency relationships: depends-on (X DEPENDS_ON Y) or required-by (Y REQUIRED_BY X)")
+	updateCmd.Flags().Bool("fail-on-cycle", false, "Abort without writing if the graph contains a dependency cycle")
+	updateCmd.Flags().Bool("fail-on-change", false, "Exit non-zero, without writing, if the update would change the database")
+	updateCmd.Flags().Int("batch-size", 0, "Write nodes and relationships in batches of this size, each retried on failure (0 writes everything in one transaction)")
+	updateCmd.Flags().Bool("force", false, "Write even if the graph is unchanged since the last update")
+	updateCmd.Flags().Bool("incremental", false, "Only write the resources the plan changes (and with --prune delete the ones it destroys), without reconciling the rest")
+	updateCmd.Flags().String("run-id", "", "Stamp this run ID on every node and relationship written (e.g. a CI build number)")
+	updateCmd.Flags().Bool("type-labels", false, "Also label each node with its resource type (e.g. :aws_instance)")
+	updateCmd.Flags().Bool("dry-run", false, "Print the Cypher query and parameters instead of writing to Neo4j")
+	updateCmd.Flags().Bool("prune", false, "Delete resources that are no longer part of the graph (or are destroyed by the plan with --incremental); not allowed with resource filters")
+	updateCmd.Flags().BoolP("yes", "y", false, "Delete obsolete resources without asking for confirmation")
+	updateCmd.Flags().Bool("soft-delete", false, "Mark obsolete resources as deleted instead of removing them (with --prune)")
+	updateCmd.Flags().Bool("soft-delete-detach", false, "Also remove the relationships of soft-deleted resources (with --soft-delete)")
+	updateCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	updateCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+	updateCmd.Flags().StringSlice("entry-type", nil, "Keep only resources of this type (repeatable) and everything they depend on")
+	updateCmd.Flags().Bool("with-modules", false, "Add a Module node per module with CONTAINS relationships to its resources and child modules")
+	updateCmd.Flags().Bool("with-providers", false, "Add a Provider node per provider with PROVIDED_BY relationships from its resources")
+	updateCmd.Flags().Bool("allow-self-edges", false, "Keep references of a resource to itself, such as self in provisioners, as self-loop edges")
+	updateCmd.Flags().String("root-module", "", "Treat resources in this module as the top of the graph and drop what they do not reach")
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/runner"
+
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [plan_file]",
+	Short: "Check the Terraform dependency graph for cycles",
+	Long: `Build the dependency graph and report every dependency cycle, without
+touching Neo4j. The command fails if any cycle is found, so it can gate CI.
+
+With --format=json the findings are written as a JSON object for dashboards:
+
+  {"findings": [{"rule": "dependency-cycle", "severity": "error",
+                 "members": ["a", "b"], "message": "..."}]}
+
+Example:
+  terraform-graphx validate
+  terraform-graphx validate --format=json > cycles.json`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE:              runValidate,
+}
+
+// cycleReport is the JSON document written by validate --format=json.
+type cycleReport struct {
+	Findings []graph.CycleFinding `json:"findings"`
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported format %q (supported: text, json)", format)
+	}
+
+	cfg, err := config.LoadAndMerge(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	g, err := runner.BuildGraph(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	findings := cycleFindings(g, cfg.AllowSelfEdges)
+	if err := writeCycleReport(os.Stdout, findings, format); err != nil {
+		return err
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("graph contains %d dependency cycle(s)", len(findings))
+	}
+	return nil
+}
+
+// cycleFindings returns the cycle findings of g. Self-loops are left out when
+// allowSelfEdges is set, as update does.
+func cycleFindings(g *graph.Graph, allowSelfEdges bool) []graph.CycleFinding {
+	findings := make([]graph.CycleFinding, 0)
+	for _, finding := range graph.CycleFindings(g) {
+		if len(finding.Members) > 1 || !allowSelfEdges {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// writeCycleReport writes findings as text or as a cycleReport JSON document.
+func writeCycleReport(w io.Writer, findings []graph.CycleFinding, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(cycleReport{Findings: findings}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode findings: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "✓ No dependency cycles found.")
+		return err
+	}
+
+	fmt.Fprintf(w, "Found %d dependency cycle(s):\n", len(findings))
+	for i, finding := range findings {
+		fmt.Fprintf(w, "\n%d. %s\n", i+1, finding.Message)
+		for _, member := range finding.Members {
+			fmt.Fprintf(w, "   - %s\n", member)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().String("format", "text", "Output format: text or json")
+	validateCmd.Flags().String("state", "", "Read resources from a terraform.tfstate file instead of running terraform graph")
+	validateCmd.Flags().String("state-s3", "", "Read state from S3 (s3://bucket/key[?versionId=...]) using the standard AWS credentials")
+	validateCmd.Flags().String("workspace", "", "Run Terraform against this workspace without switching the selected one")
+	validateCmd.Flags().StringSlice("type", nil, "Only include resources of this type (repeatable)")
+	validateCmd.Flags().StringSlice("exclude-type", nil, "Exclude resources of this type (repeatable)")
+	validateCmd.Flags().StringSlice("include", nil, "Only include resources whose address matches this address or glob, e.g. 'module.app.*' (repeatable)")
+	validateCmd.Flags().StringSlice("exclude", nil, "Exclude resources whose address matches this address or glob, e.g. 'random_*.*' (repeatable)")
+	validateCmd.Flags().StringArray("filter-attr", nil, "Only include resources whose attribute matches key=value, e.g. tags.Environment=prod (repeatable)")
+	validateCmd.Flags().String("module", "", "Only include resources within this module (e.g. module.network)")
+	validateCmd.Flags().Bool("allow-self-edges", false, "Keep references of a resource to itself, such as self in provisioners, as self-loop edges and don't report them as cycles")
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+// twoCycles has two independent cycles: a <-> b and c -> d -> e -> c.
+var twoCycles = &graph.Graph{
+	Nodes: []graph.Node{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}, {ID: "f"}},
+	Edges: []graph.Edge{
+		{From: "a", To: "b"},
+		{From: "b", To: "a"},
+		{From: "c", To: "d"},
+		{From: "d", To: "e"},
+		{From: "e", To: "c"},
+		{From: "f", To: "a"},
+	},
+}
+
+func TestWriteCycleReportText(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeCycleReport(&out, graph.CycleFindings(twoCycles), "text"); err != nil {
+		t.Fatalf("writeCycleReport failed: %v", err)
+	}
+
+	expected := []string{
+		"Found 2 dependency cycle(s):",
+		"1. 2 resources depend on each other: a, b.",
+		"2. 3 resources depend on each other: c, d, e.",
+		"   - e\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("Expected %q in report:\n%s", e, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "- f") {
+		t.Errorf("Node f is not part of a cycle:\n%s", out.String())
+	}
+}
+
+func TestWriteCycleReportJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeCycleReport(&out, graph.CycleFindings(twoCycles), "json"); err != nil {
+		t.Fatalf("writeCycleReport failed: %v", err)
+	}
+
+	var report struct {
+		Findings []struct {
+			Rule     string   `json:"rule"`
+			Severity string   `json:"severity"`
+			Members  []string `json:"members"`
+			Message  string   `json:"message"`
+		} `json:"findings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Report is not valid JSON: %v\n%s", err, out.String())
+	}
+
+	if len(report.Findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d", len(report.Findings))
+	}
+	for i, members := range []string{"a,b", "c,d,e"} {
+		f := report.Findings[i]
+		if f.Rule != "dependency-cycle" || f.Severity != "error" || strings.Join(f.Members, ",") != members || f.Message == "" {
+			t.Errorf("Unexpected finding %d: %+v", i, f)
+		}
+	}
+}
+
+func TestWriteCycleReportNoCycles(t *testing.T) {
+	var text, data bytes.Buffer
+	if err := writeCycleReport(&text, nil, "text"); err != nil {
+		t.Fatalf("writeCycleReport failed: %v", err)
+	}
+	if err := writeCycleReport(&data, []graph.CycleFinding{}, "json"); err != nil {
+		t.Fatalf("writeCycleReport failed: %v", err)
+	}
+
+	if !strings.Contains(text.String(), "No dependency cycles found") {
+		t.Errorf("Unexpected text report: %s", text.String())
+	}
+	if !strings.Contains(data.String(), `"findings": []`) {
+		t.Errorf("Expected an empty findings array, got: %s", data.String())
+	}
+}
+
+func TestCycleFindingsAllowSelfEdges(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []graph.Edge{
+			{From: "a", To: "b"},
+			{From: "b", To: "a"},
+			{From: "c", To: "c"},
+		},
+	}
+
+	if findings := cycleFindings(g, false); len(findings) != 2 {
+		t.Errorf("Expected the self-loop to be reported by default, got %+v", findings)
+	}
+
+	findings := cycleFindings(g, true)
+	if len(findings) != 1 || strings.Join(findings[0].Members, ",") != "a,b" {
+		t.Errorf("Expected only the a <-> b cycle with self edges allowed, got %+v", findings)
+	}
+
+	selfLoop := &graph.Graph{Nodes: []graph.Node{{ID: "c"}}, Edges: []graph.Edge{{From: "c", To: "c"}}}
+	if findings := cycleFindings(selfLoop, true); findings == nil || len(findings) != 0 {
+		t.Errorf("Expected an empty, non-nil list of findings, got %#v", findings)
+	}
+}
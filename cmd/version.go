@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version and build information",
+	Long: `Print the version of terraform-graphx, the git commit and date it was built
+from, and the Go version it was built with. Include this in bug reports.
+
+Example:
+  terraform-graphx version
+  terraform-graphx --version`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), versionString())
+	},
+}
+
+// versionString describes the build, e.g.
+// "terraform-graphx 1.2.0 (commit abc1234, built 2024-05-01T10:00:00Z, go1.24.1 linux/amd64)".
+func versionString() string {
+	return fmt.Sprintf("terraform-graphx %s (commit %s, built %s, %s %s/%s)",
+		version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
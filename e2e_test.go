@@ -52,6 +52,10 @@ func TestE2E_FullWorkflow(t *testing.T) {
 	}
 	defer client.Close(ctx)
 
+	if err := client.SetNodeLabel(cfg.Neo4j.NodeLabel); err != nil {
+		t.Fatalf("Invalid neo4j.node_label: %v", err)
+	}
+
 	if err := client.VerifyConnectivity(ctx); err != nil {
 		t.Skipf("Cannot connect to Neo4j at %s: %v", cfg.Neo4j.URI, err)
 	}
@@ -204,10 +208,11 @@ func TestE2E_FullWorkflow(t *testing.T) {
 		result, err := session.ExecuteRead(ctx, func(tx neo4jdriver.ManagedTransaction) (interface{}, error) {
 			// Find all resources that have dependencies
 			query := `
-				MATCH (source:Resource)-[:DEPENDS_ON]->(target:Resource)
+				MATCH (source:%[1]s)-[:DEPENDS_ON]->(target:%[1]s)
 				RETURN source.id as source_id, target.id as target_id, 
 				       source.type as source_type, target.type as target_type
 			`
+			query = fmt.Sprintf(query, client.NodeLabel())
 			res, err := tx.Run(ctx, query, nil)
 			if err != nil {
 				return nil, err
@@ -330,7 +335,7 @@ func clearNeo4jDatabase(t *testing.T, ctx context.Context, client *neo4j.Client)
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4jdriver.ManagedTransaction) (interface{}, error) {
-		_, err := tx.Run(ctx, "MATCH (n:Resource) DETACH DELETE n", nil)
+		_, err := tx.Run(ctx, fmt.Sprintf("MATCH (n:%s) DETACH DELETE n", client.NodeLabel()), nil)
 		return nil, err
 	})
 
@@ -344,7 +349,7 @@ func countNodesInNeo4j(t *testing.T, ctx context.Context, client *neo4j.Client)
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4jdriver.ManagedTransaction) (interface{}, error) {
-		res, err := tx.Run(ctx, "MATCH (n:Resource) RETURN count(n) as count", nil)
+		res, err := tx.Run(ctx, fmt.Sprintf("MATCH (n:%s) RETURN count(n) as count", client.NodeLabel()), nil)
 		if err != nil {
 			return int64(0), err
 		}
@@ -396,7 +401,7 @@ func getResourceFromNeo4j(t *testing.T, ctx context.Context, client *neo4j.Clien
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4jdriver.ManagedTransaction) (interface{}, error) {
-		query := "MATCH (n:Resource {id: $id}) RETURN n.id as id, n.type as type, n.name as name, n.provider as provider"
+		query := fmt.Sprintf("MATCH (n:%s {id: $id}) RETURN n.id as id, n.type as type, n.name as name, n.provider as provider", client.NodeLabel())
 		res, err := tx.Run(ctx, query, map[string]interface{}{"id": resourceID})
 		if err != nil {
 			return nil, err
@@ -441,10 +446,10 @@ func verifyDependency(t *testing.T, ctx context.Context, client *neo4j.Client, s
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4jdriver.ManagedTransaction) (interface{}, error) {
-		query := `
-			MATCH (source:Resource {id: $sourceID})-[:DEPENDS_ON]->(target:Resource {id: $targetID})
+		query := fmt.Sprintf(`
+			MATCH (source:%[1]s {id: $sourceID})-[:DEPENDS_ON]->(target:%[1]s {id: $targetID})
 			RETURN count(*) as count
-		`
+		`, client.NodeLabel())
 		res, err := tx.Run(ctx, query, map[string]interface{}{
 			"sourceID": sourceID,
 			"targetID": targetID,
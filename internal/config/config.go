@@ -1,50 +1,393 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/subosito/gotenv"
 )
 
 const (
 	ConfigFileName = ".terraform-graphx"
 	ConfigFileType = "yaml"
+
+	// EnvPrefix is the prefix for environment variable overrides,
+	// e.g. TFGRAPHX_NEO4J_PASSWORD overrides neo4j.password.
+	EnvPrefix = "TFGRAPHX"
+
+	// DefaultHTTPPort and DefaultBoltPort are the host ports Neo4j is published on.
+	DefaultHTTPPort = 7474
+	DefaultBoltPort = 7687
+
+	// DefaultContainerName is the name of the Neo4j Docker container.
+	DefaultContainerName = "terraform-graphx-neo4j"
+
+	// DefaultBatchRetries is how many times a failed batch is retried.
+	DefaultBatchRetries = 3
+
+	// FileMode is the permission mode of the config file, which holds secrets.
+	FileMode os.FileMode = 0600
 )
 
+// containerNamePattern matches the container names Docker accepts.
+var containerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
 // Config holds the configuration for terraform-graphx.
 type Config struct {
-	Neo4j    Neo4jConfig `mapstructure:"neo4j"`
-	PlanFile string      `mapstructure:"planfile"`
+	Neo4j      Neo4jConfig      `mapstructure:"neo4j"`
+	Terraform  TerraformConfig  `mapstructure:"terraform"`
+	Attributes AttributesConfig `mapstructure:"attributes"`
+	DOT        DOTConfig        `mapstructure:"dot"`
+	Docker     DockerConfig     `mapstructure:"docker"`
+	PlanFile   string           `mapstructure:"planfile"`
+	// PlanFiles lists the plan files whose graphs are merged when several
+	// are given; PlanFile is then the first of them.
+	PlanFiles []string `mapstructure:"-"`
+	// Concurrency bounds how many plan files are read at once when merging;
+	// the number of CPUs when not positive.
+	Concurrency int `mapstructure:"concurrency"`
+	// ReusePlan uses a saved tfplan.binary in the working directory when no
+	// plan is given and it is newer than every .tf file.
+	ReusePlan     bool     `mapstructure:"reuse_plan"`
+	StateFile     string   `mapstructure:"state"`
+	StateS3       string   `mapstructure:"state_s3"`
+	IncludeTypes  []string `mapstructure:"include_types"`
+	ExcludeTypes  []string `mapstructure:"exclude_types"`
+	Include       []string `mapstructure:"include"`
+	Exclude       []string `mapstructure:"exclude"`
+	Module        string   `mapstructure:"module"`
+	RootModule    string   `mapstructure:"root_module"`
+	EntryTypes    []string `mapstructure:"entry_types"`
+	RelationKinds []string `mapstructure:"relation_kinds"`
+	WithModules   bool     `mapstructure:"with_modules"`
+	WithProviders bool     `mapstructure:"with_providers"`
+	// AllowSelfEdges keeps resources' references to themselves as self-loop edges.
+	AllowSelfEdges bool   `mapstructure:"allow_self_edges"`
+	RunID          string `mapstructure:"run_id"`
+	// CompactCypher writes Cypher exports as a single line.
+	CompactCypher bool `mapstructure:"compact_cypher"`
+	// DepsSeparator separates the target and the dependent in deps exports;
+	// a space when empty.
+	DepsSeparator string `mapstructure:"deps_separator"`
+	// Sanitize redacts sensitive attribute values from JSON exports.
+	Sanitize bool `mapstructure:"sanitize"`
+	// Prune deletes (or with SoftDelete, marks) resources missing from the graph.
+	Prune      bool `mapstructure:"prune"`
+	SoftDelete bool `mapstructure:"soft_delete"`
+	// SoftDeleteDetach also removes the relationships of soft-deleted resources.
+	SoftDeleteDetach bool `mapstructure:"soft_delete_detach"`
+	DryRun           bool `mapstructure:"dry_run"`
+	// AssumeYes confirms the deletion of obsolete resources. It is only set
+	// by the --yes flag, so that no config file or environment can turn the
+	// confirmation off for every run.
+	AssumeYes   bool `mapstructure:"-"`
+	FailOnCycle bool `mapstructure:"fail_on_cycle"`
+	// FailOnChange makes update fail, without writing, when it would change the database.
+	FailOnChange bool `mapstructure:"fail_on_change"`
+	// Force makes update write even when the graph matches the fingerprint
+	// stored by the last update.
+	Force bool `mapstructure:"force"`
+	// Incremental only writes the resources the plan changes and deletes.
+	Incremental bool `mapstructure:"incremental"`
+	// EdgeDirection is depends-on (X DEPENDS_ON Y) or required-by (Y REQUIRED_BY X).
+	EdgeDirection string `mapstructure:"edge_direction"`
+	// MaxReferenceDepth limits how deeply plan expressions are searched for
+	// references; the parser's default when 0.
+	MaxReferenceDepth int    `mapstructure:"max_reference_depth"`
+	Format            string `mapstructure:"format"`
+	Output            string `mapstructure:"output"`
+}
+
+// DOTConfig holds the layout settings of DOT output.
+type DOTConfig struct {
+	GroupBy  string `mapstructure:"group_by"`
+	RankDir  string `mapstructure:"rankdir"`
+	FontName string `mapstructure:"fontname"`
+}
+
+// DockerConfig holds alternative keys for the Docker settings of Neo4jConfig.
+type DockerConfig struct {
+	// ContainerName is an alternative key for neo4j.container_name; Load uses
+	// it when neo4j.container_name is left at its default.
+	ContainerName string `mapstructure:"container_name"`
 }
 
 // Neo4jConfig holds the Neo4j connection settings.
 type Neo4jConfig struct {
-	URI         string `mapstructure:"uri"`
-	User        string `mapstructure:"user"`
-	Password    string `mapstructure:"password"`
+	URI string `mapstructure:"uri"`
+	// RoutingContext is sent to a cluster reached through a neo4j:// URI to
+	// select its routing policy, e.g. {region: eu}.
+	RoutingContext map[string]string `mapstructure:"routing_context"`
+	User           string            `mapstructure:"user"`
+	Password       string            `mapstructure:"password"`
+	// PasswordFile and PasswordCommand are alternatives to an inline password:
+	// a file whose trimmed contents are the password, and a shell command
+	// printing it. ResolvePassword resolves them into Password.
+	PasswordFile    string `mapstructure:"password_file"`
+	PasswordCommand string `mapstructure:"password_command"`
+	// passwordResolved records that ResolvePassword succeeded, so the
+	// command runs at most once.
+	passwordResolved bool
+	// Auth is the authentication mode: basic (the default), bearer for SSO
+	// tokens, or none. Realm applies to basic auth, BearerToken to bearer.
+	Auth        string `mapstructure:"auth"`
+	Realm       string `mapstructure:"realm"`
+	BearerToken string `mapstructure:"bearer_token"`
 	DockerImage string `mapstructure:"docker_image"`
+	// ContainerName names the Docker container, so that projects on one host
+	// can each manage their own.
+	ContainerName string `mapstructure:"container_name"`
+	NodeLabel     string `mapstructure:"node_label"`
+	TypeLabels    bool   `mapstructure:"type_labels"`
+	// TagProperties lists the tag keys lifted from the tags (AWS) or labels
+	// (GCP) of each resource into node properties.
+	TagProperties []string `mapstructure:"tag_properties"`
+	// AttributesInclude and AttributesExclude are alternative keys for
+	// attributes.allowlist and attributes.denylist; Load adds them to those lists.
+	AttributesInclude []string `mapstructure:"attributes_include"`
+	AttributesExclude []string `mapstructure:"attributes_exclude"`
+	// HTTPPort and BoltPort are the host ports of the Docker container. The
+	// default URI points at BoltPort so that start and update agree.
+	HTTPPort int `mapstructure:"http_port"`
+	BoltPort int `mapstructure:"bolt_port"`
+	// BatchSize, when positive, makes update write in batches of this many
+	// nodes or relationships, each retried up to BatchRetries times.
+	BatchSize    int `mapstructure:"batch_size"`
+	BatchRetries int `mapstructure:"batch_retries"`
+}
+
+// DefaultURI returns the Bolt URI of a local Neo4j published on boltPort.
+func DefaultURI(boltPort int) string {
+	return fmt.Sprintf("bolt://localhost:%d", boltPort)
+}
+
+// ValidatePorts checks that the HTTP and Bolt ports are valid and distinct.
+func (c Neo4jConfig) ValidatePorts() error {
+	for _, p := range []struct {
+		key  string
+		port int
+	}{{"neo4j.http_port", c.HTTPPort}, {"neo4j.bolt_port", c.BoltPort}} {
+		if p.port < 1 || p.port > 65535 {
+			return fmt.Errorf("%s must be between 1 and 65535, got %d", p.key, p.port)
+		}
+	}
+	if c.HTTPPort == c.BoltPort {
+		return fmt.Errorf("neo4j.http_port and neo4j.bolt_port must differ, both are %d", c.BoltPort)
+	}
+	return nil
+}
+
+// ValidateContainerName checks that the container name is one Docker accepts.
+func (c Neo4jConfig) ValidateContainerName() error {
+	if !containerNamePattern.MatchString(c.ContainerName) {
+		return fmt.Errorf("neo4j.container_name %q is not a valid container name: use letters, digits, '_', '.' and '-', starting with a letter or digit", c.ContainerName)
+	}
+	return nil
+}
+
+// ResolvePassword sets Password from PasswordFile or PasswordCommand. An inline
+// password takes precedence over the file, which takes precedence over the
+// command; sources that are set must agree. Errors never include the password.
+//
+// Load doesn't resolve the password, so commands that never connect to Neo4j
+// don't run the password command; call it before connecting. The command is
+// killed when ctx is done. Later calls do nothing once it has succeeded.
+func (c *Neo4jConfig) ResolvePassword(ctx context.Context) error {
+	if c.passwordResolved {
+		return nil
+	}
+
+	type source struct {
+		key      string
+		password string
+	}
+	var sources []source
+
+	if c.Password != "" {
+		sources = append(sources, source{"neo4j.password", c.Password})
+	}
+	if c.PasswordFile != "" {
+		data, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read neo4j.password_file: %w", err)
+		}
+		sources = append(sources, source{"neo4j.password_file", strings.TrimSpace(string(data))})
+	}
+	if c.PasswordCommand != "" {
+		password, err := runPasswordCommand(ctx, c.PasswordCommand)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source{"neo4j.password_command", password})
+	}
+
+	if len(sources) == 0 {
+		c.passwordResolved = true
+		return nil
+	}
+	for _, s := range sources[1:] {
+		if s.password != sources[0].password {
+			return fmt.Errorf("%s and %s resolve to different passwords; set only one", sources[0].key, s.key)
+		}
+	}
+	c.Password = sources[0].password
+	c.passwordResolved = true
+	return nil
+}
+
+// passwordCommandWaitDelay bounds how long a cancelled password command may
+// keep its output open.
+const passwordCommandWaitDelay = time.Second
+
+// runPasswordCommand runs command with the system shell and returns its
+// trimmed standard output. The command is killed when ctx is done.
+func runPasswordCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Children of the shell may keep its output open after it is killed
+	cmd.WaitDelay = passwordCommandWaitDelay
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return "", fmt.Errorf("neo4j.password_command did not finish: %w", context.Cause(ctx))
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("neo4j.password_command failed: %w - %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("neo4j.password_command failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// BoltEndpoint returns the host:port the URI connects to, using the default
+// Bolt port when the URI has none.
+func (c Neo4jConfig) BoltEndpoint() (string, error) {
+	u, err := url.Parse(c.URI)
+	if err != nil {
+		return "", fmt.Errorf("invalid neo4j uri %q: %w", c.URI, err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid neo4j uri %q: missing host", c.URI)
+	}
+	port := u.Port()
+	if port == "" {
+		port = fmt.Sprint(DefaultBoltPort)
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// TerraformConfig holds the settings used to invoke the Terraform CLI.
+type TerraformConfig struct {
+	// Engine selects the CLI: terraform, tofu or auto (terraform, falling back to tofu).
+	Engine string `mapstructure:"engine"`
+	// Binary overrides the engine with an explicit executable name or path.
+	Binary string `mapstructure:"binary"`
+	Chdir  string `mapstructure:"chdir"`
+	// Workspace runs Terraform against this workspace instead of the selected one.
+	Workspace string `mapstructure:"workspace"`
+}
+
+// AttributesConfig controls which resource attributes are persisted.
+type AttributesConfig struct {
+	// Allowlist lists the attribute keys stored as Neo4j node properties; all others are dropped.
+	Allowlist []string `mapstructure:"allowlist"`
+	// Denylist lists key substrings that are never stored, in addition to the
+	// built-in sensitive ones such as "password" and "secret".
+	Denylist []string `mapstructure:"denylist"`
+	// Filter lists key=value expressions; only resources matching all of them are kept.
+	Filter []string `mapstructure:"filter"`
 }
 
 // DefaultConfig returns a Config with default values.
 func DefaultConfig() *Config {
 	return &Config{
 		Neo4j: Neo4jConfig{
-			URI:         "bolt://localhost:7687",
-			User:        "neo4j",
-			Password:    "",
-			DockerImage: "neo4j:community",
+			URI:           DefaultURI(DefaultBoltPort),
+			User:          "neo4j",
+			Password:      "",
+			DockerImage:   "neo4j:community",
+			ContainerName: DefaultContainerName,
+			NodeLabel:     "Resource",
+			HTTPPort:      DefaultHTTPPort,
+			BoltPort:      DefaultBoltPort,
+			BatchRetries:  DefaultBatchRetries,
+		},
+		Terraform: TerraformConfig{
+			Engine: "auto",
 		},
 		PlanFile: "",
 	}
 }
 
+// selectedProfile is the profile chosen with SelectProfile; when empty the
+// TFGRAPHX_PROFILE environment variable is used.
+var selectedProfile string
+
+// SelectProfile makes Load apply the named profile, overriding TFGRAPHX_PROFILE.
+func SelectProfile(name string) {
+	selectedProfile = name
+}
+
+// selectedEnvFile is the dotenv file chosen with SelectEnvFile; none is read
+// when empty.
+var selectedEnvFile string
+
+// SelectEnvFile makes Load export the variables of the dotenv file at path,
+// such as TFGRAPHX_NEO4J_PASSWORD, before reading environment overrides.
+func SelectEnvFile(path string) {
+	selectedEnvFile = path
+}
+
+// loadEnvFile exports the variables of the dotenv file at path that are not
+// already set, so the real environment wins. A missing file is not an error.
+func loadEnvFile(path string) error {
+	if err := gotenv.Load(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+	return nil
+}
+
 // Load reads the configuration from the .terraform-graphx.yaml file.
 // It searches for the config file in the current directory and parent directories.
+// Environment variables prefixed with TFGRAPHX_ (e.g. TFGRAPHX_NEO4J_PASSWORD)
+// override values from the file.
+//
+// When a profile is selected (SelectProfile or TFGRAPHX_PROFILE), the settings
+// under profiles.<name> in the file override the top-level ones.
+//
+// When an env file is selected (SelectEnvFile), its variables are exported
+// first, so they apply like any other environment variable.
 func Load() (*Config, error) {
+	if selectedEnvFile != "" {
+		if err := loadEnvFile(selectedEnvFile); err != nil {
+			return nil, err
+		}
+	}
+
 	v := viper.New()
 	v.SetConfigName(ConfigFileName)
 	v.SetConfigType(ConfigFileType)
@@ -55,29 +398,113 @@ func Load() (*Config, error) {
 
 	// Set defaults
 	defaults := DefaultConfig()
-	v.SetDefault("neo4j.uri", defaults.Neo4j.URI)
 	v.SetDefault("neo4j.user", defaults.Neo4j.User)
 	v.SetDefault("neo4j.password", defaults.Neo4j.Password)
+	v.SetDefault("neo4j.password_file", defaults.Neo4j.PasswordFile)
+	v.SetDefault("neo4j.password_command", defaults.Neo4j.PasswordCommand)
+	v.SetDefault("neo4j.auth", defaults.Neo4j.Auth)
+	v.SetDefault("neo4j.realm", defaults.Neo4j.Realm)
+	v.SetDefault("neo4j.bearer_token", defaults.Neo4j.BearerToken)
+	v.SetDefault("neo4j.docker_image", defaults.Neo4j.DockerImage)
+	v.SetDefault("neo4j.container_name", defaults.Neo4j.ContainerName)
+	v.SetDefault("docker.container_name", defaults.Docker.ContainerName)
+	v.SetDefault("neo4j.node_label", defaults.Neo4j.NodeLabel)
+	v.SetDefault("neo4j.http_port", defaults.Neo4j.HTTPPort)
+	v.SetDefault("neo4j.bolt_port", defaults.Neo4j.BoltPort)
+	v.SetDefault("neo4j.batch_retries", defaults.Neo4j.BatchRetries)
+	v.SetDefault("terraform.engine", defaults.Terraform.Engine)
+	v.SetDefault("terraform.binary", defaults.Terraform.Binary)
+	v.SetDefault("terraform.chdir", defaults.Terraform.Chdir)
+	v.SetDefault("terraform.workspace", defaults.Terraform.Workspace)
+
+	// Bind environment overrides: neo4j.password -> TFGRAPHX_NEO4J_PASSWORD
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
 
 	// Read config file
 	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; return defaults
-			return defaults, nil
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		// Config file not found; continue with defaults and environment
 	}
 
+	profile := selectedProfile
+	if profile == "" {
+		profile = v.GetString("profile")
+	}
+	if profile != "" {
+		if err := applyProfile(v, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	// The default URI follows the configured Bolt port
+	v.SetDefault("neo4j.uri", DefaultURI(v.GetInt("neo4j.bolt_port")))
+
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	cfg.Attributes.Allowlist = appendMissing(cfg.Attributes.Allowlist, cfg.Neo4j.AttributesInclude)
+	cfg.Attributes.Denylist = appendMissing(cfg.Attributes.Denylist, cfg.Neo4j.AttributesExclude)
+	if cfg.Docker.ContainerName != "" && cfg.Neo4j.ContainerName == DefaultContainerName {
+		cfg.Neo4j.ContainerName = cfg.Docker.ContainerName
+	}
+
+	if err := cfg.Neo4j.ValidatePorts(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := cfg.Neo4j.ValidateContainerName(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	return &cfg, nil
 }
 
+// appendMissing appends the values of extra that list doesn't contain yet.
+func appendMissing(list, extra []string) []string {
+	for _, value := range extra {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// applyProfile merges the settings of profiles.<name> over the top-level ones.
+func applyProfile(v *viper.Viper, name string) error {
+	profiles := v.GetStringMap("profiles")
+	settings, ok := profiles[strings.ToLower(name)].(map[string]interface{})
+	if !ok {
+		available := make([]string, 0, len(profiles))
+		for profile := range profiles {
+			available = append(available, profile)
+		}
+		sort.Strings(available)
+		if len(available) == 0 {
+			return fmt.Errorf("profile %q not found: the configuration defines no profiles", name)
+		}
+		return fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(available, ", "))
+	}
+
+	if err := v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to apply profile %q: %w", name, err)
+	}
+	return nil
+}
+
 // LoadAndMerge loads configuration from file and merges it with CLI flags.
-// Priority: flags > config file > defaults
+// Priority: flags > environment > config file > defaults
 func LoadAndMerge(cmd *cobra.Command, args []string) (*Config, error) {
 	cfg, err := Load()
 	if err != nil {
@@ -85,17 +512,189 @@ func LoadAndMerge(cmd *cobra.Command, args []string) (*Config, error) {
 	}
 
 	// Override with flags
+	if cmd.Flags().Changed("neo4j-uri") {
+		cfg.Neo4j.URI, _ = cmd.Flags().GetString("neo4j-uri")
+	}
+
 	if cmd.Flags().Changed("neo4j-user") {
 		cfg.Neo4j.User, _ = cmd.Flags().GetString("neo4j-user")
 	}
 
 	if cmd.Flags().Changed("neo4j-pass") {
 		cfg.Neo4j.Password, _ = cmd.Flags().GetString("neo4j-pass")
+		// The flag overrides the password file and command too
+		cfg.Neo4j.PasswordFile, cfg.Neo4j.PasswordCommand = "", ""
+	}
+
+	if cmd.Flags().Changed("neo4j-auth") {
+		cfg.Neo4j.Auth, _ = cmd.Flags().GetString("neo4j-auth")
+	}
+
+	if cmd.Flags().Changed("neo4j-realm") {
+		cfg.Neo4j.Realm, _ = cmd.Flags().GetString("neo4j-realm")
+	}
+
+	if cmd.Flags().Changed("batch-size") {
+		cfg.Neo4j.BatchSize, _ = cmd.Flags().GetInt("batch-size")
+	}
+
+	if cmd.Flags().Changed("state") {
+		cfg.StateFile, _ = cmd.Flags().GetString("state")
+	}
+
+	if cmd.Flags().Changed("state-s3") {
+		cfg.StateS3, _ = cmd.Flags().GetString("state-s3")
+	}
+
+	if cmd.Flags().Changed("concurrency") {
+		cfg.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+	}
+
+	if cmd.Flags().Changed("reuse-plan") {
+		cfg.ReusePlan, _ = cmd.Flags().GetBool("reuse-plan")
+	}
+
+	if cmd.Flags().Changed("type") {
+		cfg.IncludeTypes, _ = cmd.Flags().GetStringSlice("type")
+	}
+
+	if cmd.Flags().Changed("exclude-type") {
+		cfg.ExcludeTypes, _ = cmd.Flags().GetStringSlice("exclude-type")
+	}
+
+	if cmd.Flags().Changed("include") {
+		cfg.Include, _ = cmd.Flags().GetStringSlice("include")
+	}
+
+	if cmd.Flags().Changed("exclude") {
+		cfg.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+	}
+
+	if cmd.Flags().Changed("filter-attr") {
+		cfg.Attributes.Filter, _ = cmd.Flags().GetStringArray("filter-attr")
+	}
+
+	if cmd.Flags().Changed("module") {
+		cfg.Module, _ = cmd.Flags().GetString("module")
+	}
+
+	if cmd.Flags().Changed("root-module") {
+		cfg.RootModule, _ = cmd.Flags().GetString("root-module")
+	}
+
+	if cmd.Flags().Changed("entry-type") {
+		cfg.EntryTypes, _ = cmd.Flags().GetStringSlice("entry-type")
+	}
+
+	if cmd.Flags().Changed("with-modules") {
+		cfg.WithModules, _ = cmd.Flags().GetBool("with-modules")
+	}
+
+	if cmd.Flags().Changed("with-providers") {
+		cfg.WithProviders, _ = cmd.Flags().GetBool("with-providers")
+	}
+
+	if cmd.Flags().Changed("relation-kind") {
+		cfg.RelationKinds, _ = cmd.Flags().GetStringSlice("relation-kind")
+	}
+
+	if cmd.Flags().Changed("incremental") {
+		cfg.Incremental, _ = cmd.Flags().GetBool("incremental")
+	}
+
+	if cmd.Flags().Changed("allow-self-edges") {
+		cfg.AllowSelfEdges, _ = cmd.Flags().GetBool("allow-self-edges")
+	}
+
+	if cmd.Flags().Changed("run-id") {
+		cfg.RunID, _ = cmd.Flags().GetString("run-id")
+	}
+
+	if cmd.Flags().Changed("sanitize") {
+		cfg.Sanitize, _ = cmd.Flags().GetBool("sanitize")
+	}
+
+	if cmd.Flags().Changed("separator") {
+		cfg.DepsSeparator, _ = cmd.Flags().GetString("separator")
+	}
+
+	if cmd.Flags().Changed("compact") {
+		cfg.CompactCypher, _ = cmd.Flags().GetBool("compact")
+	}
+
+	if cmd.Flags().Changed("type-labels") {
+		cfg.Neo4j.TypeLabels, _ = cmd.Flags().GetBool("type-labels")
+	}
+
+	if cmd.Flags().Changed("prune") {
+		cfg.Prune, _ = cmd.Flags().GetBool("prune")
+	}
+
+	if cmd.Flags().Changed("soft-delete") {
+		cfg.SoftDelete, _ = cmd.Flags().GetBool("soft-delete")
+	}
+	if cmd.Flags().Changed("soft-delete-detach") {
+		cfg.SoftDeleteDetach, _ = cmd.Flags().GetBool("soft-delete-detach")
+	}
+
+	if cmd.Flags().Changed("yes") {
+		cfg.AssumeYes, _ = cmd.Flags().GetBool("yes")
+	}
+
+	if cmd.Flags().Changed("dry-run") {
+		cfg.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	}
+
+	if cmd.Flags().Changed("engine") {
+		cfg.Terraform.Engine, _ = cmd.Flags().GetString("engine")
+	}
+
+	if cmd.Flags().Changed("workspace") {
+		cfg.Terraform.Workspace, _ = cmd.Flags().GetString("workspace")
+	}
+
+	if cmd.Flags().Changed("edge-direction") {
+		cfg.EdgeDirection, _ = cmd.Flags().GetString("edge-direction")
+	}
+
+	if cmd.Flags().Changed("fail-on-change") {
+		cfg.FailOnChange, _ = cmd.Flags().GetBool("fail-on-change")
+	}
+
+	if cmd.Flags().Changed("force") {
+		cfg.Force, _ = cmd.Flags().GetBool("force")
+	}
+
+	if cmd.Flags().Changed("fail-on-cycle") {
+		cfg.FailOnCycle, _ = cmd.Flags().GetBool("fail-on-cycle")
+	}
+
+	if cmd.Flags().Changed("format") {
+		cfg.Format, _ = cmd.Flags().GetString("format")
+	}
+
+	if cmd.Flags().Changed("group-by") {
+		cfg.DOT.GroupBy, _ = cmd.Flags().GetString("group-by")
+	}
+
+	if cmd.Flags().Changed("rankdir") {
+		cfg.DOT.RankDir, _ = cmd.Flags().GetString("rankdir")
+	}
+
+	if cmd.Flags().Changed("fontname") {
+		cfg.DOT.FontName, _ = cmd.Flags().GetString("fontname")
+	}
+
+	if cmd.Flags().Changed("output") {
+		cfg.Output, _ = cmd.Flags().GetString("output")
 	}
 
 	// Handle plan file from args or flag
 	if len(args) > 0 {
 		cfg.PlanFile = args[0]
+		if len(args) > 1 {
+			cfg.PlanFiles = args
+		}
 	} else if cmd.Flags().Changed("plan") {
 		cfg.PlanFile, _ = cmd.Flags().GetString("plan")
 	}
@@ -110,10 +709,29 @@ func Save(cfg *Config, path string) error {
 	}
 
 	v := viper.New()
-	v.Set("neo4j.uri", cfg.Neo4j.URI)
+	// Ports and the URI derived from them are only written when customized,
+	// so that editing bolt_port later also moves the URI.
+	if cfg.Neo4j.HTTPPort != 0 && cfg.Neo4j.HTTPPort != DefaultHTTPPort {
+		v.Set("neo4j.http_port", cfg.Neo4j.HTTPPort)
+	}
+	boltPort := DefaultBoltPort
+	if cfg.Neo4j.BoltPort != 0 {
+		boltPort = cfg.Neo4j.BoltPort
+	}
+	if boltPort != DefaultBoltPort {
+		v.Set("neo4j.bolt_port", boltPort)
+	}
+	if cfg.Neo4j.URI != DefaultURI(boltPort) {
+		v.Set("neo4j.uri", cfg.Neo4j.URI)
+	}
 	v.Set("neo4j.user", cfg.Neo4j.User)
 	v.Set("neo4j.password", cfg.Neo4j.Password)
-	v.Set("neo4j.docker_image", cfg.Neo4j.DockerImage)
+	if cfg.Neo4j.DockerImage != "" {
+		v.Set("neo4j.docker_image", cfg.Neo4j.DockerImage)
+	}
+	if cfg.Neo4j.ContainerName != "" && cfg.Neo4j.ContainerName != DefaultContainerName {
+		v.Set("neo4j.container_name", cfg.Neo4j.ContainerName)
+	}
 
 	// Ensure the directory exists
 	dir := filepath.Dir(path)
@@ -128,7 +746,7 @@ func Save(cfg *Config, path string) error {
 	}
 
 	// Ensure the config file is only readable/writable by the owner (contains secrets)
-	if err := os.Chmod(path, 0600); err != nil {
+	if err := os.Chmod(path, FileMode); err != nil {
 		// Not fatal: warn the caller but return success (file was written)
 		return fmt.Errorf("failed to set secure permissions on config file: %w", err)
 	}
@@ -136,6 +754,37 @@ func Save(cfg *Config, path string) error {
 	return nil
 }
 
+// Path returns the config file Load reads, or "" when there is none.
+func Path() string {
+	v := viper.New()
+	v.SetConfigName(ConfigFileName)
+	v.SetConfigType(ConfigFileType)
+	v.AddConfigPath(".")
+	v.AddConfigPath("$HOME")
+
+	if err := v.ReadInConfig(); err != nil {
+		return ""
+	}
+	return v.ConfigFileUsed()
+}
+
+// CheckPermissions returns an error if the config file at path can be read or
+// written by anyone but its owner, i.e. is less strict than the FileMode Save
+// sets. Windows does not have these permission bits, so it is not checked there.
+func CheckPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if mode := info.Mode().Perm(); mode&^FileMode != 0 {
+		return fmt.Errorf("%s has permissions %04o and is accessible to other users; run 'chmod %o %s'", path, mode, FileMode, path)
+	}
+	return nil
+}
+
 // Exists checks if a config file exists in the current directory or parent directories.
 func Exists() bool {
 	v := viper.New()
@@ -164,13 +813,20 @@ func GenerateRandomPassword(length int) (string, error) {
 // InitializeResult holds the result of the initialization process
 type InitializeResult struct {
 	ConfigPath string
-	DataDir    string
+	DataDir    string // empty when no data directory was created
 	Config     *Config
 }
 
+// InitializeOptions controls what Initialize scaffolds.
+type InitializeOptions struct {
+	// ExternalDB skips the Docker-oriented setup for users of an external or
+	// managed Neo4j: no neo4j-data directory, docker_image or generated password.
+	ExternalDB bool
+}
+
 // Initialize creates a new configuration file with a random password and the neo4j-data directory.
 // Returns an error if the configuration file already exists or if any step fails.
-func Initialize(configPath string) (*InitializeResult, error) {
+func Initialize(configPath string, opts InitializeOptions) (*InitializeResult, error) {
 	// Check if config file already exists
 	if _, err := os.Stat(configPath); err == nil {
 		return nil, fmt.Errorf("configuration file already exists at %s", configPath)
@@ -179,6 +835,15 @@ func Initialize(configPath string) (*InitializeResult, error) {
 	// Create default config
 	cfg := DefaultConfig()
 
+	if opts.ExternalDB {
+		// The password is set by whoever manages the database
+		cfg.Neo4j.DockerImage = ""
+		if err := Save(cfg, configPath); err != nil {
+			return nil, fmt.Errorf("failed to create config file: %w", err)
+		}
+		return &InitializeResult{ConfigPath: configPath, Config: cfg}, nil
+	}
+
 	// Generate random password
 	password, err := GenerateRandomPassword(16)
 	if err != nil {
@@ -0,0 +1,578 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// chdirTemp switches to an empty temporary directory with an isolated $HOME.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Chdir(dir)
+	return dir
+}
+
+func writeConfigFile(t *testing.T, content string) {
+	t.Helper()
+	if err := os.WriteFile(ConfigFileName+"."+ConfigFileType, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, `neo4j:
+  uri: bolt://file:7687
+  user: file-user
+  password: file-pass
+`)
+
+	t.Setenv("TFGRAPHX_NEO4J_URI", "bolt://env:7687")
+	t.Setenv("TFGRAPHX_NEO4J_PASSWORD", "env-pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Neo4j.URI != "bolt://env:7687" {
+		t.Errorf("Expected URI from env, got %s", cfg.Neo4j.URI)
+	}
+	if cfg.Neo4j.Password != "env-pass" {
+		t.Errorf("Expected password from env, got %s", cfg.Neo4j.Password)
+	}
+	if cfg.Neo4j.User != "file-user" {
+		t.Errorf("Expected user from file, got %s", cfg.Neo4j.User)
+	}
+}
+
+func TestLoadEnvWithoutConfigFile(t *testing.T) {
+	chdirTemp(t)
+	t.Setenv("TFGRAPHX_NEO4J_USER", "env-user")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Neo4j.User != "env-user" {
+		t.Errorf("Expected user from env, got %s", cfg.Neo4j.User)
+	}
+	if cfg.Neo4j.URI != DefaultConfig().Neo4j.URI {
+		t.Errorf("Expected default URI, got %s", cfg.Neo4j.URI)
+	}
+	if cfg.Neo4j.DockerImage != DefaultConfig().Neo4j.DockerImage {
+		t.Errorf("Expected default docker image, got %s", cfg.Neo4j.DockerImage)
+	}
+}
+
+func TestLoadAndMergeFlagsOverrideEnv(t *testing.T) {
+	chdirTemp(t)
+	t.Setenv("TFGRAPHX_NEO4J_PASSWORD", "env-pass")
+	t.Setenv("TFGRAPHX_NEO4J_URI", "bolt://env:7687")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("neo4j-uri", "", "")
+	cmd.Flags().String("neo4j-pass", "", "")
+	if err := cmd.Flags().Set("neo4j-pass", "flag-pass"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+
+	cfg, err := LoadAndMerge(cmd, nil)
+	if err != nil {
+		t.Fatalf("LoadAndMerge failed: %v", err)
+	}
+
+	if cfg.Neo4j.Password != "flag-pass" {
+		t.Errorf("Expected password from flag, got %s", cfg.Neo4j.Password)
+	}
+	if cfg.Neo4j.URI != "bolt://env:7687" {
+		t.Errorf("Expected URI from env when flag is unset, got %s", cfg.Neo4j.URI)
+	}
+}
+
+func TestLoadAndMergeAssumeYesFromFlagOnly(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, "assume_yes: true\n")
+	t.Setenv("TFGRAPHX_ASSUME_YES", "true")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("yes", false, "")
+
+	cfg, err := LoadAndMerge(cmd, nil)
+	if err != nil {
+		t.Fatalf("LoadAndMerge failed: %v", err)
+	}
+	if cfg.AssumeYes {
+		t.Error("Expected assume_yes from the config file and environment to be ignored")
+	}
+
+	if err := cmd.Flags().Set("yes", "true"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+	cfg, err = LoadAndMerge(cmd, nil)
+	if err != nil {
+		t.Fatalf("LoadAndMerge failed: %v", err)
+	}
+	if !cfg.AssumeYes {
+		t.Error("Expected --yes to set AssumeYes")
+	}
+}
+
+func TestInitializeCreatesDataDir(t *testing.T) {
+	chdirTemp(t)
+
+	result, err := Initialize(".terraform-graphx.yaml", InitializeOptions{})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	if _, err := os.Stat(result.DataDir); err != nil {
+		t.Errorf("Expected data directory %q to be created: %v", result.DataDir, err)
+	}
+	if result.Config.Neo4j.Password == "" {
+		t.Error("Expected a generated password")
+	}
+}
+
+func TestInitializeExternalDB(t *testing.T) {
+	chdirTemp(t)
+
+	result, err := Initialize(".terraform-graphx.yaml", InitializeOptions{ExternalDB: true})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	if result.DataDir != "" {
+		t.Errorf("Expected no data directory, got %q", result.DataDir)
+	}
+	if _, err := os.Stat("neo4j-data"); !os.IsNotExist(err) {
+		t.Errorf("Expected neo4j-data not to be created, stat returned: %v", err)
+	}
+
+	data, err := os.ReadFile(".terraform-graphx.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if strings.Contains(string(data), "docker_image") {
+		t.Errorf("Expected no docker_image in an external database config, got:\n%s", data)
+	}
+}
+
+func TestLoadDerivesURIFromBoltPort(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, `neo4j:
+  http_port: 17474
+  bolt_port: 17687
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Neo4j.URI != "bolt://localhost:17687" {
+		t.Errorf("Expected URI on the configured bolt port, got %s", cfg.Neo4j.URI)
+	}
+	if cfg.Neo4j.HTTPPort != 17474 {
+		t.Errorf("Expected HTTP port 17474, got %d", cfg.Neo4j.HTTPPort)
+	}
+}
+
+func TestLoadRejectsInvalidPorts(t *testing.T) {
+	tests := map[string]string{
+		"out of range": "neo4j:\n  bolt_port: 70000\n",
+		"equal":        "neo4j:\n  http_port: 7687\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			chdirTemp(t)
+			writeConfigFile(t, content)
+
+			if _, err := Load(); err == nil {
+				t.Error("Expected an invalid configuration error")
+			}
+		})
+	}
+}
+
+func TestLoadAttributeKeyAliases(t *testing.T) {
+	chdirTemp(t)
+
+	writeConfigFile(t, `neo4j:
+  attributes_include: [instance_type, availability_zone]
+  attributes_exclude: [connection_string]
+attributes:
+  allowlist: [instance_type, ami]
+  denylist: [endpoint]
+`)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := []string{"instance_type", "ami", "availability_zone"}; !reflect.DeepEqual(cfg.Attributes.Allowlist, want) {
+		t.Errorf("Expected allowlist %v, got %v", want, cfg.Attributes.Allowlist)
+	}
+	if want := []string{"endpoint", "connection_string"}; !reflect.DeepEqual(cfg.Attributes.Denylist, want) {
+		t.Errorf("Expected denylist %v, got %v", want, cfg.Attributes.Denylist)
+	}
+}
+
+func TestLoadContainerName(t *testing.T) {
+	chdirTemp(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.ContainerName != DefaultContainerName {
+		t.Errorf("Expected the default container name, got %q", cfg.Neo4j.ContainerName)
+	}
+
+	writeConfigFile(t, "neo4j:\n  container_name: graphx-project-b\n")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.ContainerName != "graphx-project-b" {
+		t.Errorf("Expected the configured container name, got %q", cfg.Neo4j.ContainerName)
+	}
+
+	writeConfigFile(t, "docker:\n  container_name: graphx-project-c\n")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.ContainerName != "graphx-project-c" {
+		t.Errorf("Expected the container name from docker.container_name, got %q", cfg.Neo4j.ContainerName)
+	}
+
+	writeConfigFile(t, "neo4j:\n  container_name: graphx-project-b\ndocker:\n  container_name: graphx-project-c\n")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.ContainerName != "graphx-project-b" {
+		t.Errorf("Expected neo4j.container_name to take precedence, got %q", cfg.Neo4j.ContainerName)
+	}
+
+	writeConfigFile(t, "neo4j:\n  container_name: \"my neo4j\"\n")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "neo4j.container_name") {
+		t.Errorf("Expected an invalid container name error, got %v", err)
+	}
+}
+
+func TestBoltEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"bolt://localhost:17687":     "localhost:17687",
+		"neo4j+s://db.example.com":   "db.example.com:7687",
+		"bolt://[::1]:7687":          "[::1]:7687",
+		"neo4j://10.0.0.5:9999/data": "10.0.0.5:9999",
+	}
+
+	for uri, expected := range tests {
+		got, err := Neo4jConfig{URI: uri}.BoltEndpoint()
+		if err != nil {
+			t.Errorf("BoltEndpoint(%s) failed: %v", uri, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("BoltEndpoint(%s): expected %s, got %s", uri, expected, got)
+		}
+	}
+
+	if _, err := (Neo4jConfig{URI: "localhost"}).BoltEndpoint(); err == nil {
+		t.Error("Expected an error for a URI without host")
+	}
+}
+
+const profilesConfig = `neo4j:
+  user: neo4j
+  password: local-pass
+profiles:
+  staging:
+    neo4j:
+      uri: bolt://staging.example.com:7687
+      password: staging-pass
+  prod:
+    neo4j:
+      uri: neo4j+s://prod.example.com
+    soft_delete: true
+`
+
+func TestLoadProfile(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, profilesConfig)
+	t.Setenv("TFGRAPHX_PROFILE", "staging")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Neo4j.URI != "bolt://staging.example.com:7687" || cfg.Neo4j.Password != "staging-pass" {
+		t.Errorf("Expected staging connection settings, got %+v", cfg.Neo4j)
+	}
+	if cfg.Neo4j.User != "neo4j" {
+		t.Errorf("Expected top-level user to be kept, got %s", cfg.Neo4j.User)
+	}
+}
+
+func TestSelectProfileOverridesEnv(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, profilesConfig)
+	t.Setenv("TFGRAPHX_PROFILE", "staging")
+	SelectProfile("prod")
+	t.Cleanup(func() { SelectProfile("") })
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Neo4j.URI != "neo4j+s://prod.example.com" || !cfg.SoftDelete {
+		t.Errorf("Expected prod settings, got URI %s, soft delete %v", cfg.Neo4j.URI, cfg.SoftDelete)
+	}
+	if cfg.Neo4j.Password != "local-pass" {
+		t.Errorf("Expected top-level password, got %s", cfg.Neo4j.Password)
+	}
+}
+
+func TestLoadWithoutProfile(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, profilesConfig)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Neo4j.URI != DefaultConfig().Neo4j.URI || cfg.Neo4j.Password != "local-pass" {
+		t.Errorf("Expected top-level settings, got %+v", cfg.Neo4j)
+	}
+}
+
+func TestLoadUnknownProfile(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, profilesConfig)
+	t.Setenv("TFGRAPHX_PROFILE", "qa")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected an error for an unknown profile")
+	}
+	if !strings.Contains(err.Error(), `profile "qa" not found (available: prod, staging)`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestResolvePasswordSources(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := dir + "/neo4j-password"
+	if err := os.WriteFile(passwordFile, []byte("file-pass\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Neo4jConfig
+		expected string
+	}{
+		{"inline", Neo4jConfig{Password: "inline-pass"}, "inline-pass"},
+		{"file", Neo4jConfig{PasswordFile: passwordFile}, "file-pass"},
+		{"command", Neo4jConfig{PasswordCommand: "echo ' command-pass '"}, "command-pass"},
+		{"agreeing sources", Neo4jConfig{Password: "file-pass", PasswordFile: passwordFile, PasswordCommand: "echo file-pass"}, "file-pass"},
+		{"none", Neo4jConfig{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if err := cfg.ResolvePassword(context.Background()); err != nil {
+				t.Fatalf("ResolvePassword failed: %v", err)
+			}
+			if cfg.Password != tt.expected {
+				t.Errorf("Expected password %q, got %q", tt.expected, cfg.Password)
+			}
+		})
+	}
+}
+
+func TestResolvePasswordErrors(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := dir + "/neo4j-password"
+	if err := os.WriteFile(passwordFile, []byte("file-pass"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+
+	tests := map[string]Neo4jConfig{
+		"conflict":       {Password: "inline-pass", PasswordFile: passwordFile},
+		"missing file":   {PasswordFile: dir + "/missing"},
+		"failed command": {PasswordCommand: "exit 3"},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := cfg.ResolvePassword(context.Background())
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, secret := range []string{"inline-pass", "file-pass"} {
+				if strings.Contains(err.Error(), secret) {
+					t.Errorf("Error leaks the password: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestResolvePasswordCommandCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hung command uses sh")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The shell waits for sleep, which keeps the output pipe open after the
+	// shell is killed
+	cfg := Neo4jConfig{PasswordCommand: "sleep 30; echo late-pass"}
+	start := time.Now()
+	err := cfg.ResolvePassword(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline of ctx as the error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the password command to be killed when ctx is done, took %s", elapsed)
+	}
+}
+
+func TestLoadPasswordFile(t *testing.T) {
+	dir := chdirTemp(t)
+	if err := os.WriteFile(dir+"/secret", []byte("file-pass\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	writeConfigFile(t, "neo4j:\n  password_file: "+dir+"/secret\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.Password != "" {
+		t.Errorf("Expected Load not to resolve the password, got %q", cfg.Neo4j.Password)
+	}
+	if err := cfg.Neo4j.ResolvePassword(context.Background()); err != nil {
+		t.Fatalf("ResolvePassword failed: %v", err)
+	}
+	if cfg.Neo4j.Password != "file-pass" {
+		t.Errorf("Expected password from file, got %q", cfg.Neo4j.Password)
+	}
+}
+
+func TestLoadDoesNotRunPasswordCommand(t *testing.T) {
+	dir := chdirTemp(t)
+	writeConfigFile(t, "neo4j:\n  password_command: echo run >> "+dir+"/runs && echo command-pass\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, err := os.Stat(dir + "/runs"); !os.IsNotExist(err) {
+		t.Fatalf("Expected Load not to run the password command, stat returned: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cfg.Neo4j.ResolvePassword(context.Background()); err != nil {
+			t.Fatalf("ResolvePassword failed: %v", err)
+		}
+	}
+	if cfg.Neo4j.Password != "command-pass" {
+		t.Errorf("Expected password from command, got %q", cfg.Neo4j.Password)
+	}
+	if runs, _ := os.ReadFile(dir + "/runs"); string(runs) != "run\n" {
+		t.Errorf("Expected the password command to run once, got %q", runs)
+	}
+}
+
+// unsetEnv removes the environment variables for the duration of the test.
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	chdirTemp(t)
+	writeConfigFile(t, `neo4j:
+  uri: bolt://file:7687
+  password: file-pass
+`)
+	dotenv := "# local secrets\nTFGRAPHX_NEO4J_PASSWORD=dotenv-pass\nexport TFGRAPHX_NEO4J_USER=\"dotenv-user\"\n"
+	if err := os.WriteFile(".env", []byte(dotenv), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	unsetEnv(t, "TFGRAPHX_NEO4J_PASSWORD", "TFGRAPHX_NEO4J_USER")
+
+	// The file is ignored unless selected
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.Password != "file-pass" {
+		t.Errorf("Expected .env to be ignored by default, got password %s", cfg.Neo4j.Password)
+	}
+
+	SelectEnvFile(".env")
+	t.Cleanup(func() { SelectEnvFile("") })
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.Password != "dotenv-pass" || cfg.Neo4j.User != "dotenv-user" {
+		t.Errorf("Expected credentials from .env, got %+v", cfg.Neo4j)
+	}
+	if cfg.Neo4j.URI != "bolt://file:7687" {
+		t.Errorf("Expected the file's URI to be kept, got %s", cfg.Neo4j.URI)
+	}
+}
+
+func TestLoadEnvFileKeepsEnvironment(t *testing.T) {
+	chdirTemp(t)
+	if err := os.WriteFile(".env", []byte("TFGRAPHX_NEO4J_PASSWORD=dotenv-pass\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+	t.Setenv("TFGRAPHX_NEO4J_PASSWORD", "env-pass")
+	SelectEnvFile(".env")
+	t.Cleanup(func() { SelectEnvFile("") })
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Neo4j.Password != "env-pass" {
+		t.Errorf("Expected the environment to win over .env, got %s", cfg.Neo4j.Password)
+	}
+}
+
+func TestLoadMissingEnvFile(t *testing.T) {
+	chdirTemp(t)
+	SelectEnvFile(".env")
+	t.Cleanup(func() { SelectEnvFile("") })
+
+	if _, err := Load(); err != nil {
+		t.Errorf("Expected a missing .env to be ignored, got %v", err)
+	}
+}
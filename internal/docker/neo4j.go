@@ -2,27 +2,247 @@ package docker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"terraform-graphx/internal/config"
-	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
-const (
-	// ContainerName is the name of the Neo4j Docker container
-	ContainerName = "terraform-graphx-neo4j"
-)
+// ConfigHashLabel is the container label holding the hash of the
+// configuration the container was created with.
+const ConfigHashLabel = "terraform-graphx.config-hash"
+
+// dockerAPI is the part of the Docker client used to manage the Neo4j container.
+type dockerAPI interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error)
+	ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+	Close() error
+}
+
+// newClient creates the Docker client from the environment. Tests replace it
+// with a fake.
+var newClient = func() (dockerAPI, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
 
 // StartContainerOptions contains options for starting the Neo4j container
 type StartContainerOptions struct {
 	Config *config.Config
+	// Recreate replaces a running container whose configuration is outdated.
+	Recreate bool
+}
+
+// ContainerSpec describes the Neo4j container created by StartContainer.
+type ContainerSpec struct {
+	Name       string
+	Config     *container.Config
+	HostConfig *container.HostConfig
+}
+
+// NewContainerSpec builds the container spec for the given configuration,
+// mounting dataDir as the Neo4j /data volume.
+func NewContainerSpec(cfg *config.Config, dataDir string) *ContainerSpec {
+	httpPort, boltPort := hostPorts(cfg)
+	spec := &ContainerSpec{
+		Name: ContainerName(cfg),
+		Config: &container.Config{
+			Image: cfg.Neo4j.DockerImage,
+			Env: []string{
+				fmt.Sprintf("%s=%s/%s", authEnv, cfg.Neo4j.User, cfg.Neo4j.Password),
+				"NEO4J_ACCEPT_LICENSE_AGREEMENT=yes",
+			},
+			ExposedPorts: nat.PortSet{
+				"7474/tcp": struct{}{},
+				"7687/tcp": struct{}{},
+			},
+		},
+		HostConfig: &container.HostConfig{
+			PortBindings: nat.PortMap{
+				"7474/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: fmt.Sprint(httpPort)}},
+				"7687/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: fmt.Sprint(boltPort)}},
+			},
+			Binds: []string{
+				fmt.Sprintf("%s:/data", dataDir),
+			},
+		},
+	}
+	spec.Config.Labels = map[string]string{ConfigHashLabel: spec.ConfigHash()}
+	return spec
+}
+
+// hostPorts returns the host HTTP and Bolt ports, falling back to the defaults
+// for configurations built without them.
+func hostPorts(cfg *config.Config) (httpPort, boltPort int) {
+	httpPort, boltPort = cfg.Neo4j.HTTPPort, cfg.Neo4j.BoltPort
+	if httpPort == 0 {
+		httpPort = config.DefaultHTTPPort
+	}
+	if boltPort == 0 {
+		boltPort = config.DefaultBoltPort
+	}
+	return httpPort, boltPort
+}
+
+// ContainerName returns the configured name of the Neo4j container, falling
+// back to the default for configurations built without one.
+func ContainerName(cfg *config.Config) string {
+	if cfg.Neo4j.ContainerName == "" {
+		return config.DefaultContainerName
+	}
+	return cfg.Neo4j.ContainerName
+}
+
+// ConfigHash returns a hash of the Docker-relevant settings of the spec: image,
+// environment, port bindings and volumes. Labels are not part of the hash. The
+// hash is published as a label any Docker user can read, so of NEO4J_AUTH only
+// the user is hashed: a password change is not detected.
+func (s *ContainerSpec) ConfigHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "image=%s\n", s.Config.Image)
+
+	env := make([]string, 0, len(s.Config.Env))
+	for _, e := range s.Config.Env {
+		if auth, ok := strings.CutPrefix(e, authEnv+"="); ok {
+			user, _, _ := strings.Cut(auth, "/")
+			e = authEnv + "=" + user
+		}
+		env = append(env, e)
+	}
+	sort.Strings(env)
+	for _, e := range env {
+		fmt.Fprintf(h, "env=%s\n", e)
+	}
+
+	ports := make([]string, 0, len(s.HostConfig.PortBindings))
+	for port, bindings := range s.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			ports = append(ports, fmt.Sprintf("%s=%s:%s", port, binding.HostIP, binding.HostPort))
+		}
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		fmt.Fprintf(h, "port=%s\n", port)
+	}
+
+	binds := append([]string(nil), s.HostConfig.Binds...)
+	sort.Strings(binds)
+	for _, bind := range binds {
+		fmt.Fprintf(h, "bind=%s\n", bind)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// configChanged reports whether a container with the given labels was created
+// from a different configuration than spec. Containers without the label
+// predate it and are assumed current.
+func configChanged(labels map[string]string, spec *ContainerSpec) bool {
+	hash, ok := labels[ConfigHashLabel]
+	return ok && hash != spec.ConfigHash()
+}
+
+// authEnv is the environment variable holding the Neo4j credentials.
+const authEnv = "NEO4J_AUTH"
+
+// RunCommand renders the spec as the equivalent `docker run` command line.
+// The command is meant to be shared, so NEO4J_AUTH is passed by name and
+// docker reads the credentials from the environment it runs in.
+func (s *ContainerSpec) RunCommand() string {
+	args := []string{"docker", "run", "-d", "--name", s.Name}
+
+	// Sort ports so the rendered command is stable
+	ports := make([]string, 0, len(s.HostConfig.PortBindings))
+	for port := range s.HostConfig.PortBindings {
+		ports = append(ports, string(port))
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		containerPort := nat.Port(port).Port()
+		for _, binding := range s.HostConfig.PortBindings[nat.Port(port)] {
+			args = append(args, "-p", fmt.Sprintf("%s:%s:%s", binding.HostIP, binding.HostPort, containerPort))
+		}
+	}
+
+	for _, bind := range s.HostConfig.Binds {
+		args = append(args, "-v", bind)
+	}
+
+	labels := make([]string, 0, len(s.Config.Labels))
+	for key, value := range s.Config.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		args = append(args, "--label", label)
+	}
+	for _, env := range s.Config.Env {
+		if strings.HasPrefix(env, authEnv+"=") {
+			env = authEnv
+		}
+		args = append(args, "-e", env)
+	}
+	args = append(args, s.Config.Image)
+
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote single-quotes arg if it contains characters the shell would interpret.
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r)) {
+			return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return arg
+}
+
+// RunCommand returns the `docker run` command equivalent to what StartContainer
+// would execute for the given configuration, without contacting Docker.
+func RunCommand(ctx context.Context, cfg *config.Config) (string, error) {
+	if err := cfg.Neo4j.ResolvePassword(ctx); err != nil {
+		return "", err
+	}
+	dataDir, err := dataDirPath()
+	if err != nil {
+		return "", err
+	}
+	return NewContainerSpec(cfg, dataDir).RunCommand(), nil
+}
+
+// dataDirPath returns the absolute path to the neo4j-data directory.
+func dataDirPath() (string, error) {
+	dataDir, err := filepath.Abs("neo4j-data")
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for neo4j-data: %w", err)
+	}
+	return dataDir, nil
 }
 
 // StartContainer starts a Neo4j Docker container with the provided configuration
@@ -30,14 +250,17 @@ func StartContainer(ctx context.Context, opts StartContainerOptions) error {
 	cfg := opts.Config
 
 	// Validate config
+	if err := cfg.Neo4j.ResolvePassword(ctx); err != nil {
+		return err
+	}
 	if cfg.Neo4j.Password == "" {
 		return fmt.Errorf("neo4j password not set in configuration file")
 	}
 
 	// Get absolute path to neo4j-data directory
-	dataDir, err := filepath.Abs("neo4j-data")
+	dataDir, err := dataDirPath()
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for neo4j-data: %w", err)
+		return err
 	}
 
 	// Check neo4j-data directory
@@ -79,12 +302,15 @@ func StartContainer(ctx context.Context, opts StartContainerOptions) error {
 	}
 
 	// Create Docker client
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := newClient()
 	if err != nil {
 		return fmt.Errorf("failed to create Docker client: %w", err)
 	}
 	defer cli.Close()
 
+	spec := NewContainerSpec(cfg, dataDir)
+	name := spec.Name
+
 	// Check if container already exists
 	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
 	if err != nil {
@@ -92,13 +318,25 @@ func StartContainer(ctx context.Context, opts StartContainerOptions) error {
 	}
 
 	for _, c := range containers {
-		for _, name := range c.Names {
-			if name == "/"+ContainerName {
+		for _, containerName := range c.Names {
+			if containerName == "/"+name {
 				if c.State == "running" {
-					return fmt.Errorf("container %s is already running", ContainerName)
+					if !configChanged(c.Labels, spec) {
+						return fmt.Errorf("container %s is already running", name)
+					}
+					if !opts.Recreate {
+						fmt.Println("⚠ Warning: The configuration changed since the running container was started.")
+						fmt.Println("  The changes will not take effect until it is recreated.")
+						return fmt.Errorf("container %s is running with an outdated configuration; use 'terraform-graphx start --recreate' to replace it", name)
+					}
+					fmt.Printf("Recreating container %s with the new configuration...\n", name)
+					if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+						return fmt.Errorf("failed to remove outdated container: %w", err)
+					}
+					continue
 				}
 				// Remove stopped container
-				fmt.Printf("Removing stopped container %s...\n", ContainerName)
+				fmt.Printf("Removing stopped container %s...\n", name)
 				if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
 					return fmt.Errorf("failed to remove stopped container: %w", err)
 				}
@@ -124,29 +362,7 @@ func StartContainer(ctx context.Context, opts StartContainerOptions) error {
 	// Create container
 	fmt.Printf("Creating Neo4j container...\n")
 
-	containerConfig := &container.Config{
-		Image: cfg.Neo4j.DockerImage,
-		Env: []string{
-			fmt.Sprintf("NEO4J_AUTH=%s/%s", cfg.Neo4j.User, cfg.Neo4j.Password),
-			"NEO4J_ACCEPT_LICENSE_AGREEMENT=yes",
-		},
-		ExposedPorts: nat.PortSet{
-			"7474/tcp": struct{}{},
-			"7687/tcp": struct{}{},
-		},
-	}
-
-	hostConfig := &container.HostConfig{
-		PortBindings: nat.PortMap{
-			"7474/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "7474"}},
-			"7687/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "7687"}},
-		},
-		Binds: []string{
-			fmt.Sprintf("%s:/data", dataDir),
-		},
-	}
-
-	resp, err := cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, ContainerName)
+	resp, err := cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, nil, nil, spec.Name)
 	if err != nil {
 		return fmt.Errorf("failed to create container: %w", err)
 	}
@@ -158,26 +374,21 @@ func StartContainer(ctx context.Context, opts StartContainerOptions) error {
 
 	fmt.Printf("✓ Neo4j container started successfully\n")
 	fmt.Printf("  Container ID: %s\n", resp.ID[:12])
-	fmt.Printf("  Container Name: %s\n", ContainerName)
+	fmt.Printf("  Container Name: %s\n", name)
 	fmt.Printf("  Data Directory: %s\n", dataDir)
-	fmt.Printf("  Neo4j Browser: http://localhost:7474\n")
+	httpPort, _ := hostPorts(cfg)
+	fmt.Printf("  Neo4j Browser: http://localhost:%d\n", httpPort)
 	fmt.Printf("  Bolt URI: %s\n", cfg.Neo4j.URI)
-	fmt.Printf("\nWaiting for Neo4j to be ready (this may take a few seconds)...\n")
-
-	// Give Neo4j some time to start
-	time.Sleep(5 * time.Second)
-
-	fmt.Printf("✓ Neo4j should now be ready\n")
-	fmt.Printf("\nYou can verify the connection with:\n")
-	fmt.Printf("  terraform-graphx check database\n")
 
 	return nil
 }
 
-// StopContainer stops and removes the Neo4j Docker container
-func StopContainer(ctx context.Context) error {
+// StopContainer stops and removes the Neo4j Docker container named in cfg.
+func StopContainer(ctx context.Context, cfg *config.Config) error {
+	name := ContainerName(cfg)
+
 	// Create Docker client
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := newClient()
 	if err != nil {
 		return fmt.Errorf("failed to create Docker client: %w", err)
 	}
@@ -189,28 +400,14 @@ func StopContainer(ctx context.Context) error {
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	containerFound := false
-	var containerID string
-
-	for _, c := range containers {
-		for _, name := range c.Names {
-			if name == "/"+ContainerName {
-				containerFound = true
-				containerID = c.ID
-				break
-			}
-		}
-		if containerFound {
-			break
-		}
-	}
-
-	if !containerFound {
-		return fmt.Errorf("container %s not found", ContainerName)
+	c := findContainer(containers, name)
+	if c == nil {
+		return fmt.Errorf("no container named %s found (neo4j.container_name); start it with 'terraform-graphx start'", name)
 	}
+	containerID := c.ID
 
 	// Stop container
-	fmt.Printf("Stopping container %s...\n", ContainerName)
+	fmt.Printf("Stopping container %s...\n", name)
 	timeout := 10 // seconds
 	if err := cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout}); err != nil {
 		// Container might already be stopped, try to remove anyway
@@ -220,13 +417,177 @@ func StopContainer(ctx context.Context) error {
 	}
 
 	// Remove container
-	fmt.Printf("Removing container %s...\n", ContainerName)
+	fmt.Printf("Removing container %s...\n", name)
 	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
 
-	fmt.Printf("✓ Container %s removed successfully\n", ContainerName)
+	fmt.Printf("✓ Container %s removed successfully\n", name)
 	fmt.Printf("\nNote: Data has been preserved in the neo4j-data directory\n")
 
 	return nil
 }
+
+// RestartContainer stops and removes the Neo4j container, if there is one,
+// then starts it again with opts. The container is not started if it could
+// not be removed.
+func RestartContainer(ctx context.Context, opts StartContainerOptions) error {
+	status, err := GetContainerStatus(ctx, opts.Config)
+	if err != nil {
+		return err
+	}
+	if status.Found {
+		if err := StopContainer(ctx, opts.Config); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return StartContainer(ctx, opts)
+}
+
+// ContainerStatus describes the Neo4j container as reported by Docker.
+type ContainerStatus struct {
+	// Found is false when no container with the configured name exists.
+	Found bool
+	ID    string
+	// State is the Docker state, such as "running" or "exited".
+	State string
+	// Status is the human readable status, such as "Up 5 minutes".
+	Status string
+	// Ports lists the published ports as host:port->port/proto.
+	Ports []string
+}
+
+// Running reports whether the container exists and is running.
+func (s *ContainerStatus) Running() bool {
+	return s.Found && s.State == "running"
+}
+
+// GetContainerStatus looks up the Neo4j container named in cfg. A missing
+// container is not an error; the returned status has Found set to false.
+func GetContainerStatus(ctx context.Context, cfg *config.Config) (*ContainerStatus, error) {
+	cli, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	c := findContainer(containers, ContainerName(cfg))
+	if c == nil {
+		return &ContainerStatus{}, nil
+	}
+	return &ContainerStatus{
+		Found:  true,
+		ID:     c.ID,
+		State:  c.State,
+		Status: c.Status,
+		Ports:  formatPorts(c.Ports),
+	}, nil
+}
+
+// LogsOptions controls what ContainerLogs streams.
+type LogsOptions struct {
+	// Follow keeps streaming new output until the context is cancelled.
+	Follow bool
+	// Tail is the number of lines to show from the end of the logs, or "all".
+	Tail string
+}
+
+// ValidateTail checks that tail is "all" or a non-negative number of lines.
+func ValidateTail(tail string) error {
+	if tail == "all" {
+		return nil
+	}
+	if n, err := strconv.Atoi(tail); err != nil || n < 0 {
+		return fmt.Errorf("invalid tail %q: must be a non-negative number of lines or 'all'", tail)
+	}
+	return nil
+}
+
+// ContainerLogs writes the logs of the Neo4j container named in cfg to
+// stdout and stderr. Unlike GetContainerStatus, a missing container is an error.
+func ContainerLogs(ctx context.Context, cfg *config.Config, opts LogsOptions, stdout, stderr io.Writer) error {
+	tail := opts.Tail
+	if tail == "" {
+		tail = "all"
+	}
+	if err := ValidateTail(tail); err != nil {
+		return err
+	}
+
+	name := ContainerName(cfg)
+
+	cli, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	c := findContainer(containers, name)
+	if c == nil {
+		return fmt.Errorf("no container named %s found (neo4j.container_name); start it with 'terraform-graphx start'", name)
+	}
+
+	info, err := cli.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+
+	logs, err := cli.ContainerLogs(ctx, c.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Tail:       tail,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read logs of container %s: %w", name, err)
+	}
+	defer logs.Close()
+
+	// Without a TTY, Docker multiplexes stdout and stderr into one stream
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(stdout, logs)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, logs)
+	}
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to stream logs of container %s: %w", name, err)
+	}
+	return nil
+}
+
+// findContainer returns the container called name, or nil.
+func findContainer(containers []container.Summary, name string) *container.Summary {
+	for i, c := range containers {
+		for _, containerName := range c.Names {
+			if containerName == "/"+name {
+				return &containers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// formatPorts renders published ports like 'docker ps', in sorted order.
+// Ports that are exposed but not published are skipped.
+func formatPorts(ports []container.Port) []string {
+	var formatted []string
+	for _, p := range ports {
+		if p.PublicPort == 0 {
+			continue
+		}
+		formatted = append(formatted, fmt.Sprintf("%s:%d->%d/%s", p.IP, p.PublicPort, p.PrivatePort, p.Type))
+	}
+	sort.Strings(formatted)
+	return formatted
+}
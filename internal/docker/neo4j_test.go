@@ -0,0 +1,400 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"terraform-graphx/internal/config"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestContainerSpecRunCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Neo4j.Password = "secret"
+
+	got := NewContainerSpec(cfg, "/tmp/project dir/neo4j-data").RunCommand()
+
+	expected := []string{
+		"docker run -d --name terraform-graphx-neo4j",
+		"-p 0.0.0.0:7474:7474 -p 0.0.0.0:7687:7687",
+		"-v '/tmp/project dir/neo4j-data:/data'",
+		"-e NEO4J_AUTH -e NEO4J_ACCEPT_LICENSE_AGREEMENT=yes",
+	}
+	for _, want := range expected {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected command to contain %q, got: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("Expected the password to be left out of the command, got: %s", got)
+	}
+	if !strings.HasSuffix(got, " neo4j:community") {
+		t.Errorf("Expected command to end with the image, got: %s", got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"neo4j:community": "neo4j:community",
+		"a b":             "'a b'",
+		"it's":            `'it'\''s'`,
+		"":                "''",
+	}
+	for input, want := range tests {
+		if got := shellQuote(input); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestConfigChanged(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Neo4j.Password = "secret"
+	spec := NewContainerSpec(cfg, "/data/neo4j-data")
+
+	if configChanged(spec.Config.Labels, spec) {
+		t.Error("Expected a container created from the same spec to be current")
+	}
+	if configChanged(map[string]string{}, spec) {
+		t.Error("Expected containers without a config hash label to be treated as current")
+	}
+
+	changed := config.DefaultConfig()
+	changed.Neo4j.Password = "secret"
+	changed.Neo4j.DockerImage = "neo4j:5.15.0"
+	if !configChanged(spec.Config.Labels, NewContainerSpec(changed, "/data/neo4j-data")) {
+		t.Error("Expected an image change to be detected")
+	}
+	if !configChanged(spec.Config.Labels, NewContainerSpec(cfg, "/other/neo4j-data")) {
+		t.Error("Expected a data directory change to be detected")
+	}
+
+	changed = config.DefaultConfig()
+	changed.Neo4j.Password = "secret"
+	changed.Neo4j.User = "admin"
+	if !configChanged(spec.Config.Labels, NewContainerSpec(changed, "/data/neo4j-data")) {
+		t.Error("Expected a user change to be detected")
+	}
+}
+
+func TestConfigHashLeavesOutPassword(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Neo4j.Password = "secret"
+	other := config.DefaultConfig()
+	other.Neo4j.Password = "another-secret"
+
+	// The hash is a readable label, so it must not depend on the password
+	if got, want := NewContainerSpec(other, "/data").ConfigHash(), NewContainerSpec(cfg, "/data").ConfigHash(); got != want {
+		t.Errorf("Expected the hash not to depend on the password, got %s and %s", got, want)
+	}
+}
+
+func TestRunCommandIncludesConfigHash(t *testing.T) {
+	cfg := config.DefaultConfig()
+	spec := NewContainerSpec(cfg, "/data/neo4j-data")
+
+	want := "--label " + ConfigHashLabel + "=" + spec.ConfigHash()
+	if got := spec.RunCommand(); !strings.Contains(got, want) {
+		t.Errorf("Expected command to contain %q, got: %s", want, got)
+	}
+}
+
+func TestFindContainer(t *testing.T) {
+	containers := []container.Summary{
+		{ID: "other", Names: []string{"/postgres"}},
+		{ID: "neo4j", Names: []string{"/" + config.DefaultContainerName}},
+		{ID: "project-b", Names: []string{"/graphx-project-b"}},
+	}
+
+	if c := findContainer(containers, config.DefaultContainerName); c == nil || c.ID != "neo4j" {
+		t.Errorf("Expected to find the neo4j container, got %+v", c)
+	}
+	if c := findContainer(containers, "graphx-project-b"); c == nil || c.ID != "project-b" {
+		t.Errorf("Expected to find the project-b container, got %+v", c)
+	}
+	if c := findContainer(containers[:1], config.DefaultContainerName); c != nil {
+		t.Errorf("Expected no container, got %+v", c)
+	}
+}
+
+func TestContainerName(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if got := ContainerName(cfg); got != config.DefaultContainerName {
+		t.Errorf("Expected the default name, got %s", got)
+	}
+
+	cfg.Neo4j.ContainerName = ""
+	if got := ContainerName(cfg); got != config.DefaultContainerName {
+		t.Errorf("Expected the default name for an unset name, got %s", got)
+	}
+
+	cfg.Neo4j.ContainerName = "graphx-project-b"
+	if got := ContainerName(cfg); got != "graphx-project-b" {
+		t.Errorf("Expected the configured name, got %s", got)
+	}
+	spec := NewContainerSpec(cfg, "/data")
+	if spec.Name != "graphx-project-b" {
+		t.Errorf("Expected the spec to use the configured name, got %s", spec.Name)
+	}
+	if got := spec.RunCommand(); !strings.Contains(got, "--name graphx-project-b ") {
+		t.Errorf("Expected the run command to use the configured name, got: %s", got)
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	ports := []container.Port{
+		{IP: "0.0.0.0", PrivatePort: 7687, PublicPort: 7687, Type: "tcp"},
+		{PrivatePort: 7473, Type: "tcp"},
+		{IP: "0.0.0.0", PrivatePort: 7474, PublicPort: 7474, Type: "tcp"},
+	}
+
+	got := formatPorts(ports)
+	expected := []string{"0.0.0.0:7474->7474/tcp", "0.0.0.0:7687->7687/tcp"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestContainerSpecUsesConfiguredPorts(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Neo4j.Password = "secret"
+	cfg.Neo4j.HTTPPort = 17474
+	cfg.Neo4j.BoltPort = 17687
+
+	got := NewContainerSpec(cfg, "/data").RunCommand()
+	if !strings.Contains(got, "-p 0.0.0.0:17474:7474 -p 0.0.0.0:17687:7687") {
+		t.Errorf("Expected the configured host ports, got: %s", got)
+	}
+}
+
+func TestValidateTail(t *testing.T) {
+	for _, tail := range []string{"all", "0", "100"} {
+		if err := ValidateTail(tail); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", tail, err)
+		}
+	}
+	for _, tail := range []string{"", "-1", "ten", "1.5"} {
+		if err := ValidateTail(tail); err == nil {
+			t.Errorf("Expected an error for %q", tail)
+		}
+	}
+}
+
+// fakeDocker is a Docker client holding a list of containers. It records the
+// calls made to it as "method id" strings.
+type fakeDocker struct {
+	dockerAPI
+	containers []container.Summary
+	logs       string
+	// errors makes the named methods fail.
+	errors map[string]error
+	calls  []string
+}
+
+func (f *fakeDocker) call(method, id string) error {
+	f.calls = append(f.calls, strings.TrimSpace(method+" "+id))
+	return f.errors[method]
+}
+
+func (f *fakeDocker) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	return f.containers, f.call("ContainerList", "")
+}
+
+func (f *fakeDocker) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return container.InspectResponse{Config: &container.Config{Tty: true}}, f.call("ContainerInspect", containerID)
+}
+
+func (f *fakeDocker) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(f.logs)), f.call("ContainerLogs", containerID)
+}
+
+func (f *fakeDocker) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	return f.call("ContainerStop", containerID)
+}
+
+func (f *fakeDocker) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	if err := f.call("ContainerRemove", containerID); err != nil {
+		return err
+	}
+	for i, c := range f.containers {
+		if c.ID == containerID {
+			f.containers = append(f.containers[:i:i], f.containers[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+	return image.InspectResponse{}, nil, f.call("ImageInspectWithRaw", imageID)
+}
+
+func (f *fakeDocker) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	if err := f.call("ContainerCreate", containerName); err != nil {
+		return container.CreateResponse{}, err
+	}
+	f.containers = append(f.containers, container.Summary{ID: "created-container", Names: []string{"/" + containerName}, State: "created"})
+	return container.CreateResponse{ID: "created-container"}, nil
+}
+
+func (f *fakeDocker) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	return f.call("ContainerStart", containerID)
+}
+
+func (f *fakeDocker) Close() error {
+	return nil
+}
+
+// useFakeDocker makes the package use fake as its Docker client for the test.
+func useFakeDocker(t *testing.T, fake *fakeDocker) {
+	t.Helper()
+	original := newClient
+	newClient = func() (dockerAPI, error) { return fake, nil }
+	t.Cleanup(func() { newClient = original })
+}
+
+func TestContainerLogs(t *testing.T) {
+	fake := &fakeDocker{
+		containers: []container.Summary{{ID: "neo4j", Names: []string{"/" + config.DefaultContainerName}}},
+		logs:       "Started.\n",
+	}
+	useFakeDocker(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	if err := ContainerLogs(context.Background(), config.DefaultConfig(), LogsOptions{Tail: "10"}, &stdout, &stderr); err != nil {
+		t.Fatalf("ContainerLogs failed: %v", err)
+	}
+	if stdout.String() != "Started.\n" {
+		t.Errorf("Expected the container's logs, got %q", stdout.String())
+	}
+}
+
+func TestContainerLogsMissingContainer(t *testing.T) {
+	fake := &fakeDocker{containers: []container.Summary{{ID: "other", Names: []string{"/postgres"}}}}
+	useFakeDocker(t, fake)
+
+	var stdout, stderr bytes.Buffer
+	err := ContainerLogs(context.Background(), config.DefaultConfig(), LogsOptions{}, &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "no container named "+config.DefaultContainerName) {
+		t.Fatalf("Expected a missing container error, got %v", err)
+	}
+	if !reflect.DeepEqual(fake.calls, []string{"ContainerList"}) {
+		t.Errorf("Expected no logs to be requested, got calls %v", fake.calls)
+	}
+}
+
+// restartConfig returns a configuration StartContainer accepts, run from a
+// temporary directory with an empty neo4j-data directory.
+func restartConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "neo4j-data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	cfg := config.DefaultConfig()
+	cfg.Neo4j.Password = "secret"
+	return cfg
+}
+
+func TestRestartContainer(t *testing.T) {
+	cfg := restartConfig(t)
+	fake := &fakeDocker{containers: []container.Summary{{ID: "old", Names: []string{"/" + config.DefaultContainerName}, State: "running"}}}
+	useFakeDocker(t, fake)
+
+	if err := RestartContainer(context.Background(), StartContainerOptions{Config: cfg}); err != nil {
+		t.Fatalf("RestartContainer failed: %v", err)
+	}
+
+	expected := []string{
+		"ContainerList",
+		"ContainerList",
+		"ContainerStop old",
+		"ContainerRemove old",
+		"ContainerList",
+		"ImageInspectWithRaw " + cfg.Neo4j.DockerImage,
+		"ContainerCreate " + config.DefaultContainerName,
+		"ContainerStart created-container",
+	}
+	if !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, fake.calls)
+	}
+}
+
+func TestRestartContainerWithoutContainer(t *testing.T) {
+	cfg := restartConfig(t)
+	fake := &fakeDocker{}
+	useFakeDocker(t, fake)
+
+	if err := RestartContainer(context.Background(), StartContainerOptions{Config: cfg}); err != nil {
+		t.Fatalf("RestartContainer failed: %v", err)
+	}
+	for _, call := range fake.calls {
+		if strings.HasPrefix(call, "ContainerStop") || strings.HasPrefix(call, "ContainerRemove") {
+			t.Errorf("Expected nothing to be stopped, got calls %v", fake.calls)
+		}
+	}
+	if last := fake.calls[len(fake.calls)-1]; last != "ContainerStart created-container" {
+		t.Errorf("Expected the container to be started, got calls %v", fake.calls)
+	}
+}
+
+func TestRestartContainerErrors(t *testing.T) {
+	tests := []struct {
+		failing string
+		want    string
+		// lastCall is the last call expected before giving up
+		lastCall string
+	}{
+		{"ContainerList", "failed to list containers", "ContainerList"},
+		{"ContainerRemove", "failed to remove container", "ContainerRemove old"},
+		{"ContainerCreate", "failed to create container", "ContainerCreate " + config.DefaultContainerName},
+		{"ContainerStart", "failed to start container", "ContainerStart created-container"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.failing, func(t *testing.T) {
+			cfg := restartConfig(t)
+			fake := &fakeDocker{
+				containers: []container.Summary{{ID: "old", Names: []string{"/" + config.DefaultContainerName}, State: "running"}},
+				errors:     map[string]error{tt.failing: errors.New("docker failed")},
+			}
+			useFakeDocker(t, fake)
+
+			err := RestartContainer(context.Background(), StartContainerOptions{Config: cfg})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+			if last := fake.calls[len(fake.calls)-1]; last != tt.lastCall {
+				t.Errorf("Expected to stop after %q, got calls %v", tt.lastCall, fake.calls)
+			}
+		})
+	}
+}
+
+func TestRestartContainerStopFailureStillRemoves(t *testing.T) {
+	cfg := restartConfig(t)
+	fake := &fakeDocker{
+		containers: []container.Summary{{ID: "old", Names: []string{"/" + config.DefaultContainerName}, State: "exited"}},
+		errors:     map[string]error{"ContainerStop": errors.New("already stopped")},
+	}
+	useFakeDocker(t, fake)
+
+	// A container that can't be stopped, e.g. one already stopped, is still removed and replaced
+	if err := RestartContainer(context.Background(), StartContainerOptions{Config: cfg}); err != nil {
+		t.Fatalf("RestartContainer failed: %v", err)
+	}
+	if last := fake.calls[len(fake.calls)-1]; last != "ContainerStart created-container" {
+		t.Errorf("Expected the container to be started, got calls %v", fake.calls)
+	}
+}
@@ -0,0 +1,77 @@
+package findings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Severity ranks how serious a finding is.
+type Severity int
+
+const (
+	SeverityLow Severity = iota + 1
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = map[string]Severity{
+	"LOW":      SeverityLow,
+	"MEDIUM":   SeverityMedium,
+	"HIGH":     SeverityHigh,
+	"CRITICAL": SeverityCritical,
+}
+
+// ParseSeverity converts a case-insensitive severity name (LOW, MEDIUM, HIGH, CRITICAL).
+func ParseSeverity(name string) (Severity, error) {
+	severity, ok := severityNames[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("unknown severity %q (expected LOW, MEDIUM, HIGH or CRITICAL)", name)
+	}
+	return severity, nil
+}
+
+// Finding is a security finding attached to a resource address.
+type Finding struct {
+	Address  string `json:"address"`
+	Severity string `json:"severity"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Load reads a JSON array of findings from path.
+func Load(path string) ([]Finding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read findings file: %w", err)
+	}
+
+	var findings []Finding
+	if err := json.Unmarshal(data, &findings); err != nil {
+		return nil, fmt.Errorf("failed to parse findings file: %w", err)
+	}
+
+	for _, f := range findings {
+		if _, err := ParseSeverity(f.Severity); err != nil {
+			return nil, fmt.Errorf("invalid finding for %s: %w", f.Address, err)
+		}
+	}
+
+	return findings, nil
+}
+
+// AddressesAtOrAbove returns the unique addresses with a finding at or above min.
+func AddressesAtOrAbove(findings []Finding, min Severity) []string {
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, f := range findings {
+		severity, err := ParseSeverity(f.Severity)
+		if err != nil || severity < min || seen[f.Address] {
+			continue
+		}
+		seen[f.Address] = true
+		addresses = append(addresses, f.Address)
+	}
+	return addresses
+}
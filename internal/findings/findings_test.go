@@ -0,0 +1,44 @@
+package findings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAndFilterBySeverity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.json")
+	content := `[
+		{"address": "aws_security_group.open", "severity": "critical", "message": "0.0.0.0/0 ingress"},
+		{"address": "aws_s3_bucket.logs", "severity": "HIGH"},
+		{"address": "aws_instance.app", "severity": "LOW"},
+		{"address": "aws_s3_bucket.logs", "severity": "MEDIUM"}
+	]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write findings file: %v", err)
+	}
+
+	findings, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	addresses := AddressesAtOrAbove(findings, SeverityHigh)
+	if len(addresses) != 2 {
+		t.Fatalf("Expected 2 addresses, got %v", addresses)
+	}
+	if addresses[0] != "aws_security_group.open" || addresses[1] != "aws_s3_bucket.logs" {
+		t.Errorf("Unexpected addresses: %v", addresses)
+	}
+}
+
+func TestLoadRejectsUnknownSeverity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.json")
+	if err := os.WriteFile(path, []byte(`[{"address": "a.b", "severity": "SEVERE"}]`), 0644); err != nil {
+		t.Fatalf("Failed to write findings file: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
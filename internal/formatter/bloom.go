@@ -0,0 +1,240 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"terraform-graphx/internal/graph"
+	"time"
+)
+
+// BloomOptions controls the perspective written by ToBloomPerspective.
+type BloomOptions struct {
+	// Name is the perspective name shown in Bloom.
+	Name string
+	// NodeLabel is the label of resource nodes; DefaultNodeLabel when empty.
+	NodeLabel string
+	// Properties lists extra node properties to expose, e.g. allowlisted
+	// attributes and lifted tags, see AttributeKeys.
+	Properties []string
+}
+
+// bloomPerspective follows the layout of perspectives exported from Bloom 2.
+type bloomPerspective struct {
+	ID                      string                        `json:"id"`
+	Name                    string                        `json:"name"`
+	Categories              []bloomCategory               `json:"categories"`
+	Labels                  map[string][]bloomPropertyKey `json:"labels"`
+	RelationshipTypes       []bloomRelationship           `json:"relationshipTypes"`
+	Palette                 bloomPalette                  `json:"palette"`
+	HiddenRelationshipTypes []string                      `json:"hiddenRelationshipTypes"`
+	HideUncategorisedData   bool                          `json:"hideUncategorisedData"`
+	Templates               []interface{}                 `json:"templates"`
+	CreatedAt               int64                         `json:"createdAt"`
+	LastEditedAt            int64                         `json:"lastEditedAt"`
+}
+
+type bloomCategory struct {
+	ID           int             `json:"id"`
+	Name         string          `json:"name"`
+	Color        string          `json:"color"`
+	Size         int             `json:"size"`
+	Icon         string          `json:"icon"`
+	Labels       []string        `json:"labels"`
+	Properties   []bloomProperty `json:"properties"`
+	HiddenLabels []string        `json:"hiddenLabels"`
+	Caption      []string        `json:"caption"`
+	TextSize     int             `json:"textSize"`
+	StyleRules   []bloomRule     `json:"styleRules"`
+	CreatedAt    int64           `json:"createdAt"`
+	LastEditedAt int64           `json:"lastEditedAt"`
+}
+
+type bloomProperty struct {
+	Name      string `json:"name"`
+	Exclude   bool   `json:"exclude"`
+	IsCaption bool   `json:"isCaption"`
+	DataType  string `json:"dataType"`
+}
+
+// bloomPropertyKey describes a property of a label or relationship type, as
+// db.schema.nodeTypeProperties reports it.
+type bloomPropertyKey struct {
+	PropertyKey string `json:"propertyKey"`
+	Type        string `json:"type"`
+	DataType    string `json:"dataType"`
+}
+
+type bloomRule struct {
+	ID             int               `json:"id"`
+	Type           string            `json:"type"`
+	BasedOn        string            `json:"basedOn"`
+	DataType       string            `json:"dataType"`
+	Condition      string            `json:"condition"`
+	ConditionValue string            `json:"conditionValue"`
+	ApplyColor     bool              `json:"applyColor"`
+	ApplySize      bool              `json:"applySize"`
+	ApplyCaption   bool              `json:"applyCaption"`
+	Styles         map[string]string `json:"styles"`
+	Disabled       bool              `json:"disabled"`
+}
+
+type bloomRelationship struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Color      string             `json:"color"`
+	Size       int                `json:"size"`
+	Properties []bloomPropertyKey `json:"properties"`
+}
+
+type bloomPalette struct {
+	Colors       []string `json:"colors"`
+	CurrentIndex int      `json:"currentIndex"`
+}
+
+// providerColors are the brand colours of common providers; other providers
+// take colours from bloomColors in order.
+var providerColors = map[string]string{
+	"aws":        "#FF9900",
+	"azurerm":    "#0078D4",
+	"google":     "#4285F4",
+	"kubernetes": "#326CE5",
+	"helm":       "#0F1689",
+	"random":     "#9E9E9E",
+	"null":       "#BDBDBD",
+}
+
+var bloomColors = []string{"#68BDF6", "#6DCE9E", "#FF756E", "#DE9BF9", "#FB95AF", "#FFD86E", "#A5ABB6"}
+
+// ToBloomPerspective returns a Neo4j Bloom perspective, as JSON, with a category
+// for resource nodes that is coloured by provider, and the DEPENDS_ON
+// relationship type. Providers are taken from the graph's nodes.
+func ToBloomPerspective(g *graph.Graph, opts BloomOptions) ([]byte, error) {
+	label := opts.NodeLabel
+	if label == "" {
+		label = DefaultNodeLabel
+	}
+	if err := ValidateLabel(label); err != nil {
+		return nil, err
+	}
+	name := opts.Name
+	if name == "" {
+		name = "Terraform GraphX"
+	}
+	now := time.Now().UnixMilli()
+
+	properties := []bloomProperty{
+		{Name: "id", IsCaption: true, DataType: "string"},
+		{Name: "type", DataType: "string"},
+		{Name: "provider", DataType: "string"},
+		{Name: "name", DataType: "string"},
+		{Name: "dependents_count", DataType: "number"},
+		{Name: "dependencies_count", DataType: "number"},
+		{Name: "created_at", DataType: "number"},
+		{Name: "updated_at", DataType: "number"},
+	}
+	for _, property := range opts.Properties {
+		properties = append(properties, bloomProperty{Name: property, DataType: "string"})
+	}
+
+	rules := []bloomRule{}
+	for i, provider := range providers(g) {
+		color, ok := providerColors[provider]
+		if !ok {
+			color = bloomColors[i%len(bloomColors)]
+		}
+		rules = append(rules, bloomRule{
+			ID:             i + 1,
+			Type:           "single",
+			BasedOn:        "provider",
+			DataType:       "string",
+			Condition:      "equals",
+			ConditionValue: provider,
+			ApplyColor:     true,
+			Styles:         map[string]string{"color": color},
+		})
+	}
+
+	perspective := bloomPerspective{
+		ID:   "terraform-graphx",
+		Name: name,
+		// Bloom keeps nodes without a category in the "Other" category, id 0
+		Categories: []bloomCategory{{
+			ID:           0,
+			Name:         "Other",
+			Color:        "#A5ABB6",
+			Size:         1,
+			Icon:         "no-icon",
+			Labels:       []string{},
+			Properties:   []bloomProperty{},
+			HiddenLabels: []string{},
+			Caption:      []string{""},
+			TextSize:     1,
+			StyleRules:   []bloomRule{},
+			CreatedAt:    now,
+			LastEditedAt: now,
+		}, {
+			ID:           1,
+			Name:         label,
+			Color:        bloomColors[0],
+			Size:         1,
+			Icon:         "no-icon",
+			Labels:       []string{label},
+			Properties:   properties,
+			HiddenLabels: []string{},
+			Caption:      []string{""},
+			TextSize:     1,
+			StyleRules:   rules,
+			CreatedAt:    now,
+			LastEditedAt: now,
+		}},
+		Labels: map[string][]bloomPropertyKey{label: propertyKeys(properties)},
+		RelationshipTypes: []bloomRelationship{{
+			ID:         "DEPENDS_ON",
+			Name:       "DEPENDS_ON",
+			Color:      "#A5ABB6",
+			Size:       1,
+			Properties: propertyKeys([]bloomProperty{{Name: "created_at", DataType: "number"}}),
+		}},
+		Palette:                 bloomPalette{Colors: bloomColors, CurrentIndex: 1},
+		HiddenRelationshipTypes: []string{},
+		Templates:               []interface{}{},
+		CreatedAt:               now,
+		LastEditedAt:            now,
+	}
+
+	data, err := json.MarshalIndent(perspective, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Bloom perspective: %w", err)
+	}
+	return data, nil
+}
+
+// providers returns the distinct non-empty providers of g's nodes, sorted.
+func providers(g *graph.Graph) []string {
+	seen := make(map[string]bool)
+	for _, node := range g.Nodes {
+		if node.Provider != "" {
+			seen[node.Provider] = true
+		}
+	}
+	result := make([]string, 0, len(seen))
+	for provider := range seen {
+		result = append(result, provider)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// propertyKeys describes properties for the labels and relationship types of
+// a perspective. Numbers are the integer counts and timestamps update writes.
+func propertyKeys(properties []bloomProperty) []bloomPropertyKey {
+	keys := make([]bloomPropertyKey, len(properties))
+	for i, property := range properties {
+		keys[i] = bloomPropertyKey{PropertyKey: property.Name, Type: "String", DataType: property.DataType}
+		if property.DataType == "number" {
+			keys[i].Type = "Long"
+		}
+	}
+	return keys
+}
@@ -0,0 +1,168 @@
+package formatter
+
+import (
+	"encoding/json"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToBloomPerspective(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", Provider: "aws"},
+			{ID: "random_id.suffix", Provider: "random"},
+			{ID: "acme_thing.x", Provider: "acme"},
+			{ID: "aws_subnet.a", Provider: "aws"},
+		},
+	}
+
+	data, err := ToBloomPerspective(g, BloomOptions{NodeLabel: "TerraformResource", Properties: []string{"instance_type"}})
+	if err != nil {
+		t.Fatalf("ToBloomPerspective failed: %v", err)
+	}
+
+	var perspective bloomPerspective
+	if err := json.Unmarshal(data, &perspective); err != nil {
+		t.Fatalf("Perspective is not valid JSON: %v", err)
+	}
+
+	if len(perspective.Categories) != 2 {
+		t.Fatalf("Expected the Other category and a resource category, got %d", len(perspective.Categories))
+	}
+	category := perspective.Categories[1]
+	if len(category.Labels) != 1 || category.Labels[0] != "TerraformResource" {
+		t.Errorf("Expected category for the configured label, got %v", category.Labels)
+	}
+	if keys := perspective.Labels["TerraformResource"]; len(keys) == 0 || keys[len(keys)-1].PropertyKey != "instance_type" {
+		t.Errorf("Expected extra properties to be exposed, got %v", keys)
+	}
+
+	colors := map[string]string{}
+	for _, rule := range category.StyleRules {
+		if rule.BasedOn != "provider" || !rule.ApplyColor {
+			t.Errorf("Expected provider colour rule, got %+v", rule)
+		}
+		colors[rule.ConditionValue] = rule.Styles["color"]
+	}
+	if len(colors) != 3 {
+		t.Errorf("Expected one rule per provider, got %v", colors)
+	}
+	if colors["aws"] != "#FF9900" {
+		t.Errorf("Expected aws brand colour, got %q", colors["aws"])
+	}
+	if colors["acme"] == "" {
+		t.Error("Expected unknown providers to get a palette colour")
+	}
+
+	if len(perspective.RelationshipTypes) != 1 || perspective.RelationshipTypes[0].Name != "DEPENDS_ON" {
+		t.Errorf("Expected DEPENDS_ON relationship type, got %+v", perspective.RelationshipTypes)
+	}
+}
+
+// TestToBloomPerspectiveLayout checks the generated JSON against the layout of
+// perspectives exported from Bloom 2, key by key, so that renamed or retyped
+// fields are caught.
+func TestToBloomPerspectiveLayout(t *testing.T) {
+	data, err := ToBloomPerspective(testGraph, BloomOptions{})
+	if err != nil {
+		t.Fatalf("ToBloomPerspective failed: %v", err)
+	}
+	var perspective map[string]interface{}
+	if err := json.Unmarshal(data, &perspective); err != nil {
+		t.Fatalf("Perspective is not valid JSON: %v", err)
+	}
+
+	requireKeys(t, "perspective", perspective, map[string]string{
+		"id": "string", "name": "string", "categories": "array", "labels": "object",
+		"relationshipTypes": "array", "palette": "object", "hiddenRelationshipTypes": "array",
+		"hideUncategorisedData": "bool", "templates": "array", "createdAt": "number", "lastEditedAt": "number",
+	})
+	requireKeys(t, "palette", perspective["palette"].(map[string]interface{}), map[string]string{
+		"colors": "array", "currentIndex": "number",
+	})
+
+	labelled := map[string]bool{}
+	for i, c := range perspective["categories"].([]interface{}) {
+		category := c.(map[string]interface{})
+		requireKeys(t, "category", category, map[string]string{
+			"id": "number", "name": "string", "color": "string", "size": "number", "icon": "string",
+			"labels": "array", "properties": "array", "hiddenLabels": "array", "caption": "array",
+			"textSize": "number", "styleRules": "array", "createdAt": "number", "lastEditedAt": "number",
+		})
+		if id := category["id"].(float64); int(id) != i {
+			t.Errorf("Expected category ids to count from 0, got %v at %d", id, i)
+		}
+		for _, p := range category["properties"].([]interface{}) {
+			requireKeys(t, "category property", p.(map[string]interface{}), map[string]string{
+				"name": "string", "exclude": "bool", "isCaption": "bool", "dataType": "string",
+			})
+		}
+		for _, label := range category["labels"].([]interface{}) {
+			labelled[label.(string)] = true
+		}
+	}
+	if first := perspective["categories"].([]interface{})[0].(map[string]interface{}); first["name"] != "Other" {
+		t.Errorf("Expected the first category to be Other, got %v", first["name"])
+	}
+
+	labels := perspective["labels"].(map[string]interface{})
+	for label := range labelled {
+		keys, ok := labels[label].([]interface{})
+		if !ok {
+			t.Errorf("Expected the properties of category label %s under labels", label)
+			continue
+		}
+		for _, key := range keys {
+			requireKeys(t, "label property", key.(map[string]interface{}), map[string]string{
+				"propertyKey": "string", "type": "string", "dataType": "string",
+			})
+		}
+	}
+
+	for _, r := range perspective["relationshipTypes"].([]interface{}) {
+		relationship := r.(map[string]interface{})
+		requireKeys(t, "relationship type", relationship, map[string]string{
+			"id": "string", "name": "string", "properties": "array",
+		})
+		for _, key := range relationship["properties"].([]interface{}) {
+			requireKeys(t, "relationship property", key.(map[string]interface{}), map[string]string{
+				"propertyKey": "string", "type": "string", "dataType": "string",
+			})
+		}
+	}
+}
+
+// requireKeys checks that object has each key of kinds with a value of that
+// JSON kind: string, number, bool, array or object.
+func requireKeys(t *testing.T, what string, object map[string]interface{}, kinds map[string]string) {
+	t.Helper()
+	for key, kind := range kinds {
+		value, ok := object[key]
+		if !ok {
+			t.Errorf("Expected %s to have %q", what, key)
+			continue
+		}
+		var got string
+		switch value.(type) {
+		case string:
+			got = "string"
+		case float64:
+			got = "number"
+		case bool:
+			got = "bool"
+		case []interface{}:
+			got = "array"
+		case map[string]interface{}:
+			got = "object"
+		}
+		if got != kind {
+			t.Errorf("Expected %s %q to be a %s, got %T", what, key, kind, value)
+		}
+	}
+}
+
+func TestToBloomPerspectiveInvalidLabel(t *testing.T) {
+	if _, err := ToBloomPerspective(testGraph, BloomOptions{NodeLabel: "bad label"}); err == nil {
+		t.Error("Expected an error for an invalid label")
+	}
+}
@@ -0,0 +1,130 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"terraform-graphx/internal/graph"
+)
+
+const (
+	// NodesCSVFile and EdgesCSVFile are the file names written by ToImportCSV.
+	NodesCSVFile = "nodes.csv"
+	EdgesCSVFile = "edges.csv"
+	// LoadCSVScriptFile is the file name of the script returned by ToLoadCSVScript.
+	LoadCSVScriptFile = "import.cypher"
+)
+
+// ToImportCSV writes nodes.csv and edges.csv into dir using the header
+// conventions of `neo4j-admin database import`, so the files can be loaded with:
+//
+//	neo4j-admin database import full --nodes=nodes.csv --relationships=edges.csv
+//
+// Nodes are labelled with label, DefaultNodeLabel when empty.
+func ToImportCSV(g *graph.Graph, dir, label string) error {
+	if label == "" {
+		label = DefaultNodeLabel
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	writeNodes := func(w io.Writer, g *graph.Graph) error {
+		return writeNodesCSV(w, g, label)
+	}
+	if err := writeCSVFile(filepath.Join(dir, NodesCSVFile), g, writeNodes); err != nil {
+		return err
+	}
+	return writeCSVFile(filepath.Join(dir, EdgesCSVFile), g, writeEdgesCSV)
+}
+
+func writeCSVFile(path string, g *graph.Graph, write func(io.Writer, *graph.Graph) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := write(file, g); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// writeNodesCSV writes one row per node, labelled with nodeLabel; the id column
+// doubles as the import ID and the stored id property so MERGE-based updates
+// keep matching the nodes.
+func writeNodesCSV(w io.Writer, g *graph.Graph, nodeLabel string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id:ID", "type", "provider", "name", ":LABEL"}); err != nil {
+		return err
+	}
+	for _, node := range g.Nodes {
+		label := nodeLabel
+		switch node.Type {
+		case graph.ModuleType:
+			label += ";" + ModuleLabel
+		case graph.ProviderType:
+			label += ";" + ProviderLabel
+		}
+		if err := writer.Write([]string{node.ID, node.Type, node.Provider, node.Name, label}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeEdgesCSV writes one row per edge, typed by its relation.
+func writeEdgesCSV(w io.Writer, g *graph.Graph) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{":START_ID", ":END_ID", ":TYPE"}); err != nil {
+		return err
+	}
+	for _, edge := range g.Edges {
+		if err := writer.Write([]string{edge.From, edge.To, EdgeRelation(edge)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ToLoadCSVScript returns a Cypher script that merges the files written by
+// ToImportCSV with LOAD CSV, for Neo4j instances where neo4j-admin import is not
+// available. The files are read from file:/// URLs, so they must be copied into
+// Neo4j's import directory first. Like update, the script can be run again to
+// refresh an existing graph. Nodes are labelled with label, DefaultNodeLabel
+// when empty.
+func ToLoadCSVScript(g *graph.Graph, label string) string {
+	if label == "" {
+		label = DefaultNodeLabel
+	}
+
+	var script bytes.Buffer
+
+	fmt.Fprintf(&script, "LOAD CSV WITH HEADERS FROM 'file:///%s' AS row\n", NodesCSVFile)
+	fmt.Fprintf(&script, "MERGE (n:%s {id: row.`id:ID`})\n", label)
+	script.WriteString("ON CREATE SET n.created_at = timestamp(), n.updated_at = timestamp()\n")
+	script.WriteString("ON MATCH SET n.updated_at = timestamp()\n")
+	script.WriteString("SET n.type = row.type, n.provider = row.provider, n.name = row.name\n")
+	// The :LABEL column can't be applied without APOC, so the extra labels are derived from the type
+	fmt.Fprintf(&script, "FOREACH (_ IN CASE WHEN row.type = '%s' THEN [1] ELSE [] END | SET n:%s)\n", graph.ModuleType, ModuleLabel)
+	fmt.Fprintf(&script, "FOREACH (_ IN CASE WHEN row.type = '%s' THEN [1] ELSE [] END | SET n:%s);\n", graph.ProviderType, ProviderLabel)
+
+	// Relationship types can't be parameterized, so edges are loaded once per type
+	for _, relation := range edgeRelations(g) {
+		script.WriteString("\n")
+		fmt.Fprintf(&script, "LOAD CSV WITH HEADERS FROM 'file:///%s' AS row\n", EdgesCSVFile)
+		fmt.Fprintf(&script, "WITH row WHERE row.`:TYPE` = %s\n", cypherString(relation))
+		fmt.Fprintf(&script, "MATCH (from:%s {id: row.`:START_ID`})\n", label)
+		fmt.Fprintf(&script, "MATCH (to:%s {id: row.`:END_ID`})\n", label)
+		fmt.Fprintf(&script, "MERGE (from)-[r:%s]->(to)\n", relation)
+		script.WriteString("ON CREATE SET r.created_at = timestamp();\n")
+	}
+
+	return script.String()
+}
@@ -0,0 +1,135 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToImportCSV(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws", Name: "main"},
+			{ID: `aws_subnet.public["a,b"]`, Type: "aws_subnet", Provider: "aws", Name: "public"},
+		},
+		Edges: []graph.Edge{
+			{From: `aws_subnet.public["a,b"]`, To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		},
+	}
+
+	if err := ToImportCSV(g, dir, ""); err != nil {
+		t.Fatalf("ToImportCSV failed: %v", err)
+	}
+
+	nodes, err := os.ReadFile(filepath.Join(dir, NodesCSVFile))
+	if err != nil {
+		t.Fatalf("Failed to read nodes.csv: %v", err)
+	}
+	wantNodes := "id:ID,type,provider,name,:LABEL\n" +
+		"aws_vpc.main,aws_vpc,aws,main,Resource\n" +
+		`"aws_subnet.public[""a,b""]",aws_subnet,aws,public,Resource` + "\n"
+	if string(nodes) != wantNodes {
+		t.Errorf("Unexpected nodes.csv:\n%s\nwant:\n%s", nodes, wantNodes)
+	}
+
+	edges, err := os.ReadFile(filepath.Join(dir, EdgesCSVFile))
+	if err != nil {
+		t.Fatalf("Failed to read edges.csv: %v", err)
+	}
+	wantEdges := ":START_ID,:END_ID,:TYPE\n" +
+		`"aws_subnet.public[""a,b""]",aws_vpc.main,DEPENDS_ON` + "\n"
+	if string(edges) != wantEdges {
+		t.Errorf("Unexpected edges.csv:\n%s\nwant:\n%s", edges, wantEdges)
+	}
+}
+
+func TestToImportCSVNodeLabel(t *testing.T) {
+	dir := t.TempDir()
+
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "module.net", Type: graph.ModuleType},
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws", Name: "main"},
+		},
+	}
+
+	if err := ToImportCSV(g, dir, "TerraformResource"); err != nil {
+		t.Fatalf("ToImportCSV failed: %v", err)
+	}
+
+	nodes, err := os.ReadFile(filepath.Join(dir, NodesCSVFile))
+	if err != nil {
+		t.Fatalf("Failed to read nodes.csv: %v", err)
+	}
+	wantNodes := "id:ID,type,provider,name,:LABEL\n" +
+		"module.net,module,,,TerraformResource;Module\n" +
+		"aws_vpc.main,aws_vpc,aws,main,TerraformResource\n"
+	if string(nodes) != wantNodes {
+		t.Errorf("Unexpected nodes.csv:\n%s\nwant:\n%s", nodes, wantNodes)
+	}
+}
+
+func TestToLoadCSVScript(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "module.net", Type: graph.ModuleType},
+			{ID: "module.net.aws_vpc.main", Type: "aws_vpc"},
+			{ID: "aws_instance.web", Type: "aws_instance"},
+		},
+		Edges: []graph.Edge{
+			{From: "aws_instance.web", To: "module.net.aws_vpc.main"},
+			{From: "module.net", To: "module.net.aws_vpc.main", Relation: graph.ContainsRelation},
+		},
+	}
+
+	script := ToLoadCSVScript(g, "TerraformResource")
+
+	statements := strings.Split(strings.TrimSpace(script), ";\n")
+	if len(statements) != 3 {
+		t.Fatalf("Expected a node statement and one edge statement per relation, got %d:\n%s", len(statements), script)
+	}
+	if !strings.HasSuffix(script, ";\n") {
+		t.Errorf("Expected the script to end with a semicolon, got:\n%s", script)
+	}
+
+	nodes := statements[0]
+	for _, want := range []string{
+		"LOAD CSV WITH HEADERS FROM 'file:///nodes.csv' AS row",
+		"MERGE (n:TerraformResource {id: row.`id:ID`})",
+		"SET n.type = row.type, n.provider = row.provider, n.name = row.name",
+		"THEN [1] ELSE [] END | SET n:Module)",
+	} {
+		if !strings.Contains(nodes, want) {
+			t.Errorf("Expected %q in the node statement:\n%s", want, nodes)
+		}
+	}
+
+	for i, relation := range []string{DefaultRelation, graph.ContainsRelation} {
+		edges := statements[i+1]
+		for _, want := range []string{
+			"LOAD CSV WITH HEADERS FROM 'file:///edges.csv' AS row",
+			"WITH row WHERE row.`:TYPE` = '" + relation + "'",
+			"MATCH (from:TerraformResource {id: row.`:START_ID`})",
+			"MATCH (to:TerraformResource {id: row.`:END_ID`})",
+			"MERGE (from)-[r:" + relation + "]->(to)",
+		} {
+			if !strings.Contains(edges, want) {
+				t.Errorf("Expected %q in the %s statement:\n%s", want, relation, edges)
+			}
+		}
+	}
+}
+
+func TestToLoadCSVScriptDefaultLabel(t *testing.T) {
+	script := ToLoadCSVScript(&graph.Graph{}, "")
+	if !strings.Contains(script, "MERGE (n:Resource {id: row.`id:ID`})") {
+		t.Errorf("Expected the default label, got:\n%s", script)
+	}
+	if strings.Contains(script, "edges.csv") {
+		t.Errorf("Expected no edge statement without edges, got:\n%s", script)
+	}
+}
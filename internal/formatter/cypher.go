@@ -0,0 +1,127 @@
+package formatter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"terraform-graphx/internal/graph"
+)
+
+// ToCypher converts a graph to a standalone Cypher script that can be run with
+// cypher-shell or pasted into Neo4j Browser. It is the query of
+// ToCypherTransaction with the parameters inlined as literals.
+func ToCypher(g *graph.Graph, opts CypherOptions) (string, error) {
+	query, params := ToCypherTransaction(g, opts)
+
+	// Longer names first, so $edges does not clobber $edges_contains
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	replacements := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		literal, err := cypherLiteral(params[key])
+		if err != nil {
+			return "", fmt.Errorf("failed to encode parameter %s: %w", key, err)
+		}
+		replacements = append(replacements, "$"+key, literal)
+	}
+
+	script := strings.NewReplacer(replacements...).Replace(query)
+	return strings.TrimSuffix(script, "\n") + ";\n", nil
+}
+
+// ToCypherCompact converts a graph to the statement of ToCypher on a single
+// line, for embedding in scripts. String literals escape their line breaks, so
+// only the layout of the query itself is joined.
+func ToCypherCompact(g *graph.Graph, opts CypherOptions) (string, error) {
+	script, err := ToCypher(g, opts)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(script, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, " ") + "\n", nil
+}
+
+// cypherLiteral renders a query parameter as a Cypher literal. Map keys are
+// sorted so the output is stable.
+func cypherLiteral(value interface{}) (string, error) {
+	if value == nil {
+		return "null", nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return cypherString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice:
+		items := make([]string, rv.Len())
+		for i := range items {
+			item, err := cypherLiteral(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			if key.Kind() != reflect.String {
+				return "", fmt.Errorf("unsupported map key type %s", key.Type())
+			}
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		entries := make([]string, len(keys))
+		for i, key := range keys {
+			item, err := cypherLiteral(rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).Interface())
+			if err != nil {
+				return "", err
+			}
+			entries[i] = cypherKey(key) + ": " + item
+		}
+		return "{" + strings.Join(entries, ", ") + "}", nil
+	}
+
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
+
+// cypherString quotes s as a single-quoted Cypher string.
+func cypherString(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+	return "'" + escaped + "'"
+}
+
+// cypherKey returns key as a map key, backquoted unless it is a plain identifier.
+func cypherKey(key string) string {
+	if labelPattern.MatchString(key) {
+		return key
+	}
+	return "`" + strings.ReplaceAll(key, "`", "``") + "`"
+}
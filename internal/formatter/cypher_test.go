@@ -0,0 +1,104 @@
+package formatter
+
+import (
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToCypher(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: append([]graph.Node{{ID: "module.net", Type: graph.ModuleType, Name: "net"}}, testGraph.Nodes...),
+		Edges: append([]graph.Edge{{From: "module.net", To: "aws_vpc.main", Relation: graph.ContainsRelation}}, testGraph.Edges...),
+	}
+
+	script, err := ToCypher(g, CypherOptions{RunID: "run-1"})
+	if err != nil {
+		t.Fatalf("ToCypher failed: %v", err)
+	}
+
+	if strings.Contains(script, "$") {
+		t.Errorf("Expected every parameter to be inlined, got:\n%s", script)
+	}
+	if !strings.HasSuffix(script, ";\n") {
+		t.Errorf("Expected the script to end with a semicolon, got:\n%s", script)
+	}
+
+	expected := []string{
+		"UNWIND [{attributes: {}, dependencies_count: 0, dependents_count: 0, id: 'module.net', module: 'module.net', name: 'net', provider: '', type: 'module'}",
+		"UNWIND [{from: 'aws_subnet.public', to: 'aws_vpc.main'}] AS edge_data",
+		"UNWIND [{from: 'module.net', to: 'aws_vpc.main'}] AS edge_data",
+		"SET n.run_id = 'run-1'",
+	}
+	for _, e := range expected {
+		if !strings.Contains(script, e) {
+			t.Errorf("Expected %q in script:\n%s", e, script)
+		}
+	}
+}
+
+func TestToCypherCompact(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: append([]graph.Node{{ID: `aws_s3_bucket.it's`, Type: "aws_s3_bucket", Name: "it's\nlogs"}}, testGraph.Nodes...),
+		Edges: testGraph.Edges,
+	}
+
+	statement, err := ToCypherCompact(g, CypherOptions{})
+	if err != nil {
+		t.Fatalf("ToCypherCompact failed: %v", err)
+	}
+
+	body := strings.TrimSuffix(statement, "\n")
+	if strings.Contains(body, "\n") {
+		t.Errorf("Expected a single line, got:\n%s", statement)
+	}
+	if strings.Contains(body, "$") {
+		t.Errorf("Expected every parameter to be inlined, got:\n%s", statement)
+	}
+	// The only semicolon outside string literals terminates the statement
+	if strings.Count(body, ";") != 1 || !strings.HasSuffix(body, ";") {
+		t.Errorf("Expected a single statement, got:\n%s", statement)
+	}
+	if !strings.Contains(body, `id: 'aws_s3_bucket.it\'s'`) || !strings.Contains(body, `name: 'it\'s\nlogs'`) {
+		t.Errorf("Expected quotes and line breaks to be escaped, got:\n%s", statement)
+	}
+
+	script, err := ToCypher(g, CypherOptions{})
+	if err != nil {
+		t.Fatalf("ToCypher failed: %v", err)
+	}
+	if strings.Join(strings.Fields(script), " ") != strings.Join(strings.Fields(statement), " ") {
+		t.Errorf("Expected the compact statement to match the script")
+	}
+}
+
+func TestCypherLiteral(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{`it's a \ test`, `'it\'s a \\ test'`},
+		{"line\nbreak", `'line\nbreak'`},
+		{true, "true"},
+		{42, "42"},
+		{2.5, "2.5"},
+		{[]interface{}{"a", 1.0}, "['a', 1]"},
+		{map[string]interface{}{"b": 1, "a-b": "x"}, "{`a-b`: 'x', b: 1}"},
+	}
+
+	for _, tt := range tests {
+		got, err := cypherLiteral(tt.value)
+		if err != nil {
+			t.Errorf("cypherLiteral(%v) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("cypherLiteral(%v): expected %s, got %s", tt.value, tt.expected, got)
+		}
+	}
+
+	if _, err := cypherLiteral(struct{}{}); err == nil {
+		t.Error("Expected an error for an unsupported type")
+	}
+}
@@ -0,0 +1,83 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"terraform-graphx/internal/graph"
+)
+
+type cytoscapeDocument struct {
+	Elements cytoscapeElements `json:"elements"`
+}
+
+type cytoscapeElements struct {
+	Nodes []cytoscapeNode `json:"nodes"`
+	Edges []cytoscapeEdge `json:"edges"`
+}
+
+type cytoscapeNode struct {
+	Data cytoscapeNodeData `json:"data"`
+}
+
+type cytoscapeNodeData struct {
+	ID                string `json:"id"`
+	Type              string `json:"type"`
+	Provider          string `json:"provider"`
+	Name              string `json:"name"`
+	DependentsCount   int    `json:"dependents_count"`
+	DependenciesCount int    `json:"dependencies_count"`
+}
+
+type cytoscapeEdge struct {
+	Data cytoscapeEdgeData `json:"data"`
+}
+
+type cytoscapeEdgeData struct {
+	ID       string `json:"id"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Relation string `json:"relation"`
+}
+
+// ToCytoscape converts a graph to Cytoscape.js elements JSON. Edge IDs are
+// "from->to", with the relation inserted ("from-[CONTAINS]->to") for edges
+// other than DEPENDS_ON, so they are stable across runs.
+func ToCytoscape(g *graph.Graph) (string, error) {
+	doc := cytoscapeDocument{
+		Elements: cytoscapeElements{
+			Nodes: make([]cytoscapeNode, 0, len(g.Nodes)),
+			Edges: make([]cytoscapeEdge, 0, len(g.Edges)),
+		},
+	}
+
+	for _, node := range g.Nodes {
+		doc.Elements.Nodes = append(doc.Elements.Nodes, cytoscapeNode{Data: cytoscapeNodeData{
+			ID:                node.ID,
+			Type:              node.Type,
+			Provider:          node.Provider,
+			Name:              node.Name,
+			DependentsCount:   node.DependentsCount,
+			DependenciesCount: node.DependenciesCount,
+		}})
+	}
+
+	for _, edge := range g.Edges {
+		relation := EdgeRelation(edge)
+		id := edge.From + "->" + edge.To
+		if relation != DefaultRelation {
+			id = edge.From + "-[" + relation + "]->" + edge.To
+		}
+		doc.Elements.Edges = append(doc.Elements.Edges, cytoscapeEdge{Data: cytoscapeEdgeData{
+			ID:       id,
+			Source:   edge.From,
+			Target:   edge.To,
+			Relation: relation,
+		}})
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Cytoscape JSON: %w", err)
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,51 @@
+package formatter
+
+import (
+	"encoding/json"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToCytoscape(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: append([]graph.Node{{ID: "module.net", Type: graph.ModuleType, Name: "net"}}, testGraph.Nodes...),
+		Edges: append([]graph.Edge{{From: "module.net", To: "aws_vpc.main", Relation: graph.ContainsRelation}}, testGraph.Edges...),
+	}
+
+	output, err := ToCytoscape(g)
+	if err != nil {
+		t.Fatalf("ToCytoscape failed: %v", err)
+	}
+
+	var doc struct {
+		Elements struct {
+			Nodes []struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+
+	if len(doc.Elements.Nodes) != 3 || len(doc.Elements.Edges) != 2 {
+		t.Fatalf("Expected 3 nodes and 2 edges, got %d and %d", len(doc.Elements.Nodes), len(doc.Elements.Edges))
+	}
+
+	vpc := doc.Elements.Nodes[1].Data
+	if vpc["id"] != "aws_vpc.main" || vpc["type"] != "aws_vpc" || vpc["provider"] != "aws" || vpc["name"] != "main" {
+		t.Errorf("Unexpected node data: %v", vpc)
+	}
+
+	contains := doc.Elements.Edges[0].Data
+	if contains["id"] != "module.net-[CONTAINS]->aws_vpc.main" || contains["source"] != "module.net" || contains["target"] != "aws_vpc.main" {
+		t.Errorf("Unexpected edge data: %v", contains)
+	}
+	dependsOn := doc.Elements.Edges[1].Data
+	if dependsOn["id"] != "aws_subnet.public->aws_vpc.main" || dependsOn["relation"] != "DEPENDS_ON" {
+		t.Errorf("Unexpected edge data: %v", dependsOn)
+	}
+}
@@ -0,0 +1,56 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"terraform-graphx/internal/graph"
+)
+
+// DefaultDependencySeparator separates the two addresses of a dependency list
+// line, as tsort expects.
+const DefaultDependencySeparator = " "
+
+// ToDependencyList writes one line per dependency of g, the target first and
+// the dependent second, e.g. "aws_vpc.main aws_subnet.a", so that tsort prints
+// a build order. Reversed REQUIRED_BY edges are read back as dependencies, and
+// CONTAINS edges, which are not dependencies, are left out. Lines are sorted
+// and repeated pairs written once. An empty separator means
+// DefaultDependencySeparator.
+func ToDependencyList(g *graph.Graph, w io.Writer, separator string) error {
+	if separator == "" {
+		separator = DefaultDependencySeparator
+	}
+
+	type pair struct{ target, dependent string }
+	seen := make(map[pair]bool)
+	var pairs []pair
+	for _, edge := range g.Edges {
+		var p pair
+		switch edge.Relation {
+		case graph.ContainsRelation:
+			continue
+		case graph.RequiredByRelation:
+			p = pair{edge.From, edge.To}
+		default:
+			p = pair{edge.To, edge.From}
+		}
+		if !seen[p] {
+			seen[p] = true
+			pairs = append(pairs, p)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].target != pairs[j].target {
+			return pairs[i].target < pairs[j].target
+		}
+		return pairs[i].dependent < pairs[j].dependent
+	})
+
+	for _, p := range pairs {
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", p.target, separator, p.dependent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
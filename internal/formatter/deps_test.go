@@ -0,0 +1,50 @@
+package formatter
+
+import (
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToDependencyList(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "null_resource.app"}, {ID: "null_resource.cluster"}, {ID: "null_resource.db"}, {ID: "module.app", Type: graph.ModuleType},
+		},
+		Edges: []graph.Edge{
+			{From: "null_resource.db", To: "null_resource.cluster"},
+			{From: "null_resource.app", To: "null_resource.db"},
+			{From: "null_resource.app", To: "null_resource.cluster", Relation: graph.DependsOnRelation},
+			{From: "null_resource.app", To: "null_resource.cluster", Relation: graph.DependsOnRelation, Kind: graph.KindExplicit},
+			{From: "module.app", To: "null_resource.app", Relation: graph.ContainsRelation},
+		},
+	}
+
+	var out strings.Builder
+	if err := ToDependencyList(g, &out, ""); err != nil {
+		t.Fatalf("ToDependencyList failed: %v", err)
+	}
+	want := "null_resource.cluster null_resource.app\n" +
+		"null_resource.cluster null_resource.db\n" +
+		"null_resource.db null_resource.app\n"
+	if out.String() != want {
+		t.Errorf("Unexpected dependency list:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	// Reversed edges give the same list
+	out.Reset()
+	if err := ToDependencyList(graph.OrientEdges(g, graph.DirectionRequiredBy), &out, ""); err != nil {
+		t.Fatalf("ToDependencyList failed: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("Expected REQUIRED_BY edges to give the same list, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := ToDependencyList(g, &out, " <- "); err != nil {
+		t.Fatalf("ToDependencyList failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "null_resource.cluster <- null_resource.app\n") {
+		t.Errorf("Expected the custom separator, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,157 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"terraform-graphx/internal/graph"
+)
+
+// DOT grouping modes for DOTOptions.GroupBy.
+const (
+	GroupByNone     = "none"
+	GroupByProvider = "provider"
+	GroupByModule   = "module"
+)
+
+const (
+	// DefaultRankDir is the rank direction of `terraform graph`, right to left.
+	DefaultRankDir = "RL"
+	// DefaultFontName is the node font used unless configured otherwise.
+	DefaultFontName = "sans-serif"
+)
+
+// rankDirs are the rank directions Graphviz supports.
+var rankDirs = []string{"LR", "TB", "RL", "BT"}
+
+// DOTOptions controls the layout written by ToDOT.
+type DOTOptions struct {
+	// GroupBy wraps nodes in colored clusters per provider or module;
+	// GroupByNone (or "") disables clustering.
+	GroupBy string
+	// RankDir is the direction of the layout (LR, TB, RL or BT);
+	// DefaultRankDir when empty.
+	RankDir string
+	// FontName is the font of the nodes; DefaultFontName when empty.
+	FontName string
+}
+
+// ValidateRankDir checks that rankDir is a Graphviz rank direction. An empty
+// rankDir selects DefaultRankDir and is valid.
+func ValidateRankDir(rankDir string) error {
+	if rankDir == "" {
+		return nil
+	}
+	for _, valid := range rankDirs {
+		if rankDir == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported rankdir %q (supported: %s)", rankDir, strings.Join(rankDirs, ", "))
+}
+
+// clusterColors are the fill colors of DOT clusters, assigned in the order of
+// the group names so that up to this many groups all get different colors.
+var clusterColors = []string{
+	"#E3F2FD", "#E8F5E9", "#FFF3E0", "#F3E5F5", "#FCE4EC",
+	"#E0F7FA", "#FFFDE7", "#EFEBE9", "#F1F8E9", "#EDE7F6",
+}
+
+// ToDOT converts a graph to Graphviz DOT, using the same layout conventions as
+// `terraform graph` (right-to-left ranks, rectangular nodes) unless opts
+// select another rank direction or font.
+func ToDOT(g *graph.Graph, opts DOTOptions) (string, error) {
+	groupOf, err := dotGrouping(opts.GroupBy)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateRankDir(opts.RankDir); err != nil {
+		return "", err
+	}
+	rankDir := opts.RankDir
+	if rankDir == "" {
+		rankDir = DefaultRankDir
+	}
+	fontName := opts.FontName
+	if fontName == "" {
+		fontName = DefaultFontName
+	}
+
+	var out bytes.Buffer
+
+	out.WriteString("digraph G {\n")
+	fmt.Fprintf(&out, "  rankdir = %s;\n", dotQuote(rankDir))
+	fmt.Fprintf(&out, "  node [shape = rect, fontname = %s];\n", dotQuote(fontName))
+
+	// Nodes outside any group are written at the top level
+	groups := make(map[string][]string)
+	for _, node := range g.Nodes {
+		group := ""
+		if groupOf != nil {
+			group = groupOf(node)
+		}
+		if group == "" {
+			fmt.Fprintf(&out, "  %s [label=%s];\n", dotQuote(node.ID), dotQuote(node.ID))
+			continue
+		}
+		groups[group] = append(groups[group], node.ID)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		fmt.Fprintf(&out, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&out, "    label = %s;\n", dotQuote(name))
+		fmt.Fprintf(&out, "    style = filled;\n")
+		fmt.Fprintf(&out, "    fillcolor = %s;\n", dotQuote(clusterColors[i%len(clusterColors)]))
+		for _, id := range groups[name] {
+			fmt.Fprintf(&out, "    %s [label=%s];\n", dotQuote(id), dotQuote(id))
+		}
+		out.WriteString("  }\n")
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+	}
+
+	out.WriteString("}\n")
+	return out.String(), nil
+}
+
+// dotGrouping returns the function naming a node's cluster for groupBy, or nil
+// when nodes are not grouped.
+func dotGrouping(groupBy string) (func(graph.Node) string, error) {
+	switch groupBy {
+	case "", GroupByNone:
+		return nil, nil
+	case GroupByProvider:
+		return nodeProvider, nil
+	case GroupByModule:
+		return func(node graph.Node) string {
+			return graph.ModulePathOf(node.ID)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported group-by %q (supported: provider, module, none)", groupBy)
+	}
+}
+
+// nodeProvider returns the node's provider, falling back to the prefix of its
+// resource type (aws_instance -> aws) when the input did not record one.
+func nodeProvider(node graph.Node) string {
+	if node.Provider != "" {
+		return node.Provider
+	}
+	provider, _, _ := strings.Cut(node.Type, "_")
+	return provider
+}
+
+// dotQuote returns s as a double-quoted DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,180 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+
+	"github.com/awalterschulze/gographviz"
+)
+
+func TestToDOT(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: append([]graph.Node{{ID: `aws_subnet.each["a"]`, Type: "aws_subnet", Name: "each"}}, testGraph.Nodes...),
+		Edges: append([]graph.Edge{{From: `aws_subnet.each["a"]`, To: "aws_vpc.main"}}, testGraph.Edges...),
+	}
+
+	output, err := ToDOT(g, DOTOptions{})
+	if err != nil {
+		t.Fatalf("ToDOT failed: %v", err)
+	}
+
+	if !strings.Contains(output, `"aws_subnet.public" -> "aws_vpc.main";`) {
+		t.Errorf("Expected edge in output, got:\n%s", output)
+	}
+	if !strings.Contains(output, `"aws_subnet.each[\"a\"]"`) {
+		t.Errorf("Expected quotes in IDs to be escaped, got:\n%s", output)
+	}
+
+	// The output must be valid DOT
+	graphAst, err := gographviz.ParseString(output)
+	if err != nil {
+		t.Fatalf("Output is not valid DOT: %v\n%s", err, output)
+	}
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		t.Fatalf("Failed to analyse DOT output: %v", err)
+	}
+	if len(dotGraph.Nodes.Nodes) != 3 || len(dotGraph.Edges.Edges) != 2 {
+		t.Errorf("Expected 3 nodes and 2 edges, got %d and %d", len(dotGraph.Nodes.Nodes), len(dotGraph.Edges.Edges))
+	}
+}
+
+// parseDOT parses output and fails the test if it is not valid DOT.
+func parseDOT(t *testing.T, output string) *gographviz.Graph {
+	t.Helper()
+	graphAst, err := gographviz.ParseString(output)
+	if err != nil {
+		t.Fatalf("Output is not valid DOT: %v\n%s", err, output)
+	}
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		t.Fatalf("Failed to analyse DOT output: %v", err)
+	}
+	return dotGraph
+}
+
+var groupedGraph = &graph.Graph{
+	Nodes: []graph.Node{
+		{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws"},
+		{ID: "module.app.aws_instance.web", Type: "aws_instance"},
+		{ID: "module.app.random_id.suffix", Type: "random_id", Provider: "random"},
+		{ID: "module.db.aws_db_instance.main", Type: "aws_db_instance", Provider: "aws"},
+	},
+	Edges: []graph.Edge{
+		{From: "module.app.aws_instance.web", To: "aws_vpc.main"},
+		{From: "module.db.aws_db_instance.main", To: "aws_vpc.main"},
+	},
+}
+
+func TestToDOTGroupByProvider(t *testing.T) {
+	output, err := ToDOT(groupedGraph, DOTOptions{GroupBy: GroupByProvider})
+	if err != nil {
+		t.Fatalf("ToDOT failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"subgraph cluster_0 {\n    label = \"aws\";",
+		"subgraph cluster_1 {\n    label = \"random\";",
+		"fillcolor = " + dotQuote(clusterColors[0]),
+		"fillcolor = " + dotQuote(clusterColors[1]),
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	assertNodesOnce(t, output, groupedGraph)
+}
+
+func TestToDOTGroupByModule(t *testing.T) {
+	output, err := ToDOT(groupedGraph, DOTOptions{GroupBy: GroupByModule})
+	if err != nil {
+		t.Fatalf("ToDOT failed: %v", err)
+	}
+
+	if strings.Count(output, "subgraph cluster_") != 2 {
+		t.Errorf("Expected one cluster per module, got:\n%s", output)
+	}
+	for _, want := range []string{`label = "module.app";`, `label = "module.db";`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	// Root module resources stay outside any cluster
+	if !strings.Contains(output, "\n  \"aws_vpc.main\" [label=") {
+		t.Errorf("Expected root module node at the top level, got:\n%s", output)
+	}
+	assertNodesOnce(t, output, groupedGraph)
+}
+
+func TestToDOTGroupByInvalid(t *testing.T) {
+	if _, err := ToDOT(groupedGraph, DOTOptions{GroupBy: "region"}); err == nil {
+		t.Error("Expected an error for an unsupported group-by")
+	}
+}
+
+func TestToDOTLayout(t *testing.T) {
+	output, err := ToDOT(groupedGraph, DOTOptions{})
+	if err != nil {
+		t.Fatalf("ToDOT failed: %v", err)
+	}
+	dotGraph := parseDOT(t, output)
+	if got := dotGraph.Attrs["rankdir"]; got != `"RL"` {
+		t.Errorf("Expected terraform graph's rankdir by default, got %s", got)
+	}
+	if !strings.Contains(output, `fontname = "sans-serif"`) {
+		t.Errorf("Expected the default font, got:\n%s", output)
+	}
+
+	output, err = ToDOT(groupedGraph, DOTOptions{RankDir: "TB", FontName: "Helvetica"})
+	if err != nil {
+		t.Fatalf("ToDOT failed: %v", err)
+	}
+	dotGraph = parseDOT(t, output)
+	if got := dotGraph.Attrs["rankdir"]; got != `"TB"` {
+		t.Errorf("Expected the configured rankdir, got %s", got)
+	}
+	if !strings.Contains(output, `fontname = "Helvetica"`) {
+		t.Errorf("Expected the configured font, got:\n%s", output)
+	}
+}
+
+func TestToDOTInvalidRankDir(t *testing.T) {
+	for _, rankDir := range []string{"lr", "XY", "left"} {
+		if _, err := ToDOT(groupedGraph, DOTOptions{RankDir: rankDir}); err == nil {
+			t.Errorf("Expected an error for rankdir %q", rankDir)
+		}
+	}
+}
+
+func TestToDOTClusterColorsDistinct(t *testing.T) {
+	g := &graph.Graph{}
+	for i := range clusterColors {
+		g.Nodes = append(g.Nodes, graph.Node{ID: fmt.Sprintf("module.m%02d.null_resource.x", i), Type: "null_resource"})
+	}
+	output, err := ToDOT(g, DOTOptions{GroupBy: GroupByModule})
+	if err != nil {
+		t.Fatalf("ToDOT failed: %v", err)
+	}
+
+	for _, color := range clusterColors {
+		if n := strings.Count(output, "fillcolor = "+dotQuote(color)); n != 1 {
+			t.Errorf("Expected color %s on exactly one cluster, got %d in:\n%s", color, n, output)
+		}
+	}
+}
+
+// assertNodesOnce checks that output is valid DOT and declares every node of g exactly once.
+func assertNodesOnce(t *testing.T, output string, g *graph.Graph) {
+	t.Helper()
+	dotGraph := parseDOT(t, output)
+	if len(dotGraph.Nodes.Nodes) != len(g.Nodes) {
+		t.Errorf("Expected %d nodes, got %d", len(g.Nodes), len(dotGraph.Nodes.Nodes))
+	}
+	for _, node := range g.Nodes {
+		if n := strings.Count(output, dotQuote(node.ID)+" [label="); n != 1 {
+			t.Errorf("Expected %s to be declared once, got %d", node.ID, n)
+		}
+	}
+}
@@ -2,15 +2,119 @@ package formatter
 
 import (
 	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"terraform-graphx/internal/graph"
 )
 
+const (
+	// DefaultNodeLabel is the label applied to resource nodes unless configured otherwise.
+	DefaultNodeLabel = "Resource"
+	// ModuleLabel is the additional label of module nodes.
+	ModuleLabel = "Module"
+	// ProviderLabel is the additional label of provider nodes.
+	ProviderLabel = "Provider"
+	// DefaultRelation is the relationship type of edges without a Relation.
+	DefaultRelation = "DEPENDS_ON"
+)
+
+// DefaultAttributeDenylist lists the key substrings of attributes that are
+// never persisted because they commonly hold secrets.
+var DefaultAttributeDenylist = []string{
+	"password",
+	"secret",
+	"private_key",
+	"token",
+	"access_key",
+	"credential",
+}
+
+// ReservedProperties lists the node properties written by terraform-graphx
+// itself. Attributes and tags with these names are never persisted, as they
+// would overwrite them; id in particular is the key nodes are merged on.
+var ReservedProperties = []string{
+	"id",
+	"type",
+	"provider",
+	"name",
+	"module",
+	"dependents_count",
+	"dependencies_count",
+	"created_at",
+	"updated_at",
+	"run_id",
+	"deleted",
+	"deleted_at",
+}
+
+var (
+	labelPattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// ValidateLabel checks that label is a legal unquoted Cypher label. Labels
+// can't be passed as query parameters, so they must be validated before
+// being templated into queries.
+func ValidateLabel(label string) error {
+	if !labelPattern.MatchString(label) {
+		return fmt.Errorf("invalid node label %q: must start with a letter or underscore and contain only letters, digits and underscores", label)
+	}
+	return nil
+}
+
+// SanitizeLabel turns an arbitrary string such as a resource type into a legal
+// label by replacing invalid characters with underscores and prefixing an
+// underscore when it starts with a digit. It returns "" for an empty string.
+func SanitizeLabel(s string) string {
+	if s == "" {
+		return ""
+	}
+	label := invalidLabelChars.ReplaceAllString(s, "_")
+	if label[0] >= '0' && label[0] <= '9' {
+		label = "_" + label
+	}
+	return label
+}
+
+// CypherOptions controls what ToCypherTransaction writes.
+type CypherOptions struct {
+	// NodeLabel is the label of resource nodes; DefaultNodeLabel when empty.
+	NodeLabel string
+	// AttributeAllowlist lists the attribute keys persisted as node properties.
+	// Attributes are not persisted when it is empty.
+	AttributeAllowlist []string
+	// AttributeDenylist lists key substrings that are never persisted, on top
+	// of DefaultAttributeDenylist, even when the key is allowlisted.
+	AttributeDenylist []string
+	// TypeLabels additionally labels each node with its sanitized resource type,
+	// e.g. :aws_instance.
+	TypeLabels bool
+	// StaleTypeLabels lists type labels stored nodes may still carry, such as
+	// those of the types already in the database. With TypeLabels, a node
+	// loses these and the graph's other type labels before getting its own,
+	// so a resource whose type changed is not left with the old label.
+	StaleTypeLabels []string
+	// TagProperties lists the keys of resource tags (AWS tags, GCP labels)
+	// lifted into node properties, e.g. owner or environment.
+	TagProperties []string
+	// RunID, when set, is stamped as run_id on every node and relationship
+	// written, so a run's changes can be queried afterwards.
+	RunID string
+}
+
 // ToCypherTransaction converts a graph to a parameterized Cypher query.
 // This is the recommended approach for Neo4j driver execution as it:
 // - Prevents Cypher injection
 // - Improves performance through query plan caching
 // - Handles special characters automatically
-func ToCypherTransaction(g *graph.Graph) (string, map[string]interface{}) {
+func ToCypherTransaction(g *graph.Graph, opts CypherOptions) (string, map[string]interface{}) {
+	label := opts.NodeLabel
+	if label == "" {
+		label = DefaultNodeLabel
+	}
+
 	var query bytes.Buffer
 	params := make(map[string]interface{})
 
@@ -18,36 +122,291 @@ func ToCypherTransaction(g *graph.Graph) (string, map[string]interface{}) {
 	nodesData := make([]map[string]interface{}, len(g.Nodes))
 	for i, node := range g.Nodes {
 		nodesData[i] = map[string]interface{}{
-			"id":       node.ID,
-			"type":     node.Type,
-			"provider": node.Provider,
-			"name":     node.Name,
+			"id":                 node.ID,
+			"type":               node.Type,
+			"provider":           node.Provider,
+			"name":               node.Name,
+			"module":             graph.ModulePathOf(node.ID),
+			"dependents_count":   node.DependentsCount,
+			"dependencies_count": node.DependenciesCount,
+			"attributes":         NodeAttributes(node, opts),
 		}
 	}
 	params["nodes"] = nodesData
+	if opts.RunID != "" {
+		params["run_id"] = opts.RunID
+	}
+
+	var typeLabels []string
+	if opts.TypeLabels {
+		typeLabels = sanitizedTypeLabels(g, nodesData)
+	}
 
 	// Create/update nodes using UNWIND for batch processing
 	query.WriteString("UNWIND $nodes AS node_data\n")
-	query.WriteString("MERGE (n:Resource {id: node_data.id})\n")
-	query.WriteString("SET n.type = node_data.type, n.provider = node_data.provider, n.name = node_data.name\n")
-
-	// Build edge data and create relationships if any exist
-	if len(g.Edges) > 0 {
-		edgesData := make([]map[string]string, len(g.Edges))
-		for i, edge := range g.Edges {
-			edgesData[i] = map[string]string{
+	fmt.Fprintf(&query, "MERGE (n:%s {id: node_data.id})\n", label)
+	query.WriteString("ON CREATE SET n.created_at = timestamp(), n.updated_at = timestamp()\n")
+	query.WriteString("ON MATCH SET n.updated_at = timestamp()\n")
+	// Attributes never include the core properties, see ReservedProperties
+	query.WriteString("SET n += node_data.attributes\n")
+	// module is the module path of the address, "" in the root module
+	query.WriteString("SET n.type = node_data.type, n.provider = node_data.provider, n.name = node_data.name, n.module = node_data.module,\n")
+	query.WriteString("    n.dependents_count = node_data.dependents_count, n.dependencies_count = node_data.dependencies_count\n")
+	if opts.RunID != "" {
+		query.WriteString("SET n.run_id = $run_id\n")
+	}
+	// Resources that reappear after a soft delete are restored
+	query.WriteString("REMOVE n.deleted, n.deleted_at\n")
+	// Labels can't be parameterized, so each sanitized type gets a conditional
+	// REMOVE of a stale label and a conditional SET
+	for _, typeLabel := range RemovableTypeLabels(typeLabels, opts.StaleTypeLabels, label) {
+		fmt.Fprintf(&query, "FOREACH (_ IN CASE WHEN node_data.type_label <> '%s' THEN [1] ELSE [] END | REMOVE n:%s)\n", typeLabel, typeLabel)
+	}
+	for _, typeLabel := range typeLabels {
+		fmt.Fprintf(&query, "FOREACH (_ IN CASE WHEN node_data.type_label = '%s' THEN [1] ELSE [] END | SET n:%s)\n", typeLabel, typeLabel)
+	}
+	if hasNodeType(g, graph.ModuleType) {
+		fmt.Fprintf(&query, "FOREACH (_ IN CASE WHEN node_data.type = '%s' THEN [1] ELSE [] END | SET n:%s)\n", graph.ModuleType, ModuleLabel)
+	}
+	if hasNodeType(g, graph.ProviderType) {
+		fmt.Fprintf(&query, "FOREACH (_ IN CASE WHEN node_data.type = '%s' THEN [1] ELSE [] END | SET n:%s)\n", graph.ProviderType, ProviderLabel)
+	}
+
+	// Relationship types can't be parameterized either, so edges are written
+	// in one batch per type. Aggregating first collapses the node rows, so each
+	// batch runs once rather than once per node.
+	for _, relation := range edgeRelations(g) {
+		key := "edges"
+		if relation != DefaultRelation {
+			key = "edges_" + strings.ToLower(relation)
+		}
+		params[key] = edgesData(g, relation)
+
+		query.WriteString("WITH count(*) AS written\n")
+		fmt.Fprintf(&query, "UNWIND $%s AS edge_data\n", key)
+		fmt.Fprintf(&query, "MATCH (from:%s {id: edge_data.from})\n", label)
+		fmt.Fprintf(&query, "MATCH (to:%s {id: edge_data.to})\n", label)
+		fmt.Fprintf(&query, "MERGE (from)-[r:%s]->(to)\n", relation)
+		// Only stamp new relationships so created_at records when a dependency first appeared
+		query.WriteString("ON CREATE SET r.created_at = timestamp()\n")
+		query.WriteString("SET r.relation_kind = edge_data.relation_kind\n")
+		if opts.RunID != "" {
+			query.WriteString("SET r.run_id = $run_id\n")
+		}
+	}
+
+	return query.String(), params
+}
+
+// EdgeRelation returns the sanitized relationship type of edge, DefaultRelation when unset.
+func EdgeRelation(edge graph.Edge) string {
+	if edge.Relation == "" {
+		return DefaultRelation
+	}
+	return SanitizeLabel(edge.Relation)
+}
+
+// edgeRelations returns the distinct relationship types of g's edges, with
+// DefaultRelation first and the others sorted.
+func edgeRelations(g *graph.Graph) []string {
+	seen := make(map[string]bool)
+	for _, edge := range g.Edges {
+		seen[EdgeRelation(edge)] = true
+	}
+
+	var relations []string
+	for relation := range seen {
+		if relation != DefaultRelation {
+			relations = append(relations, relation)
+		}
+	}
+	sort.Strings(relations)
+	if seen[DefaultRelation] {
+		relations = append([]string{DefaultRelation}, relations...)
+	}
+	return relations
+}
+
+// edgesData returns the query parameters of g's edges of the given relationship
+// type. Edges without a kind have no relation_kind, which reads as null and so
+// clears the property.
+func edgesData(g *graph.Graph, relation string) []map[string]string {
+	var data []map[string]string
+	for _, edge := range g.Edges {
+		if EdgeRelation(edge) == relation {
+			edgeData := map[string]string{
 				"from": edge.From,
 				"to":   edge.To,
 			}
+			if edge.Kind != "" {
+				edgeData["relation_kind"] = edge.Kind
+			}
+			data = append(data, edgeData)
+		}
+	}
+	return data
+}
+
+// hasNodeType reports whether g contains nodes of the given type.
+func hasNodeType(g *graph.Graph, nodeType string) bool {
+	for _, node := range g.Nodes {
+		if node.Type == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizedTypeLabels stores the sanitized type label of each node in its
+// parameters under "type_label" and returns the distinct labels in sorted order.
+func sanitizedTypeLabels(g *graph.Graph, nodesData []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	for i, node := range g.Nodes {
+		typeLabel := SanitizeLabel(node.Type)
+		nodesData[i]["type_label"] = typeLabel
+		if typeLabel != "" {
+			seen[typeLabel] = true
+		}
+	}
+
+	labels := make([]string, 0, len(seen))
+	for typeLabel := range seen {
+		labels = append(labels, typeLabel)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// RemovableTypeLabels returns the type labels a node may lose: typeLabels and
+// the valid stale labels, sorted, without the node label and the labels of
+// Module and Provider nodes.
+func RemovableTypeLabels(typeLabels, stale []string, nodeLabel string) []string {
+	if len(typeLabels) == 0 {
+		return nil
+	}
+	seen := map[string]bool{nodeLabel: true, ModuleLabel: true, ProviderLabel: true}
+	var labels []string
+	for _, typeLabel := range append(append([]string(nil), typeLabels...), stale...) {
+		if !seen[typeLabel] && ValidateLabel(typeLabel) == nil {
+			seen[typeLabel] = true
+			labels = append(labels, typeLabel)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// AllowedAttributeKeys returns the keys of allowlist that are not
+// ReservedProperties and contain none of the substrings of
+// DefaultAttributeDenylist or denylist, ignoring case.
+func AllowedAttributeKeys(allowlist, denylist []string) []string {
+	denied := append(append([]string(nil), DefaultAttributeDenylist...), denylist...)
+	var allowed []string
+	for _, key := range allowlist {
+		if !isReservedProperty(key) && !containsAny(strings.ToLower(key), denied) {
+			allowed = append(allowed, key)
+		}
+	}
+	return allowed
+}
+
+// isReservedProperty reports whether key is one of ReservedProperties.
+func isReservedProperty(key string) bool {
+	for _, reserved := range ReservedProperties {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether s contains any of substrings, ignoring their case.
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if sub != "" && strings.Contains(s, strings.ToLower(sub)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeAttributes returns the properties written for node besides the core
+// ones: its allowlisted attributes and lifted tags. Allowlisted attributes win
+// over tags of the same name.
+func NodeAttributes(node graph.Node, opts CypherOptions) map[string]interface{} {
+	attributes := AllowedAttributes(node.Attributes, AllowedAttributeKeys(opts.AttributeAllowlist, opts.AttributeDenylist))
+	for key, value := range TagProperties(node.Attributes, AllowedAttributeKeys(opts.TagProperties, opts.AttributeDenylist)) {
+		if _, ok := attributes[key]; !ok {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
+
+// AttributeKeys returns the keys of the properties NodeAttributes may write:
+// the allowed attribute keys followed by the allowed tag keys not among them.
+func AttributeKeys(opts CypherOptions) []string {
+	keys := AllowedAttributeKeys(opts.AttributeAllowlist, opts.AttributeDenylist)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, key := range AllowedAttributeKeys(opts.TagProperties, opts.AttributeDenylist) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// TagProperties returns the values of the given tag keys found in the AWS
+// "tags" map or the GCP "labels" map of attributes. Tags take precedence
+// over labels.
+func TagProperties(attributes map[string]interface{}, keys []string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, source := range []string{"labels", "tags"} {
+		tags, ok := attributes[source].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range keys {
+			if value, ok := tags[key].(string); ok {
+				properties[key] = value
+			}
 		}
-		params["edges"] = edgesData
+	}
+	return properties
+}
 
-		query.WriteString("WITH *\n")
-		query.WriteString("UNWIND $edges AS edge_data\n")
-		query.WriteString("MATCH (from:Resource {id: edge_data.from})\n")
-		query.WriteString("MATCH (to:Resource {id: edge_data.to})\n")
-		query.WriteString("MERGE (from)-[:DEPENDS_ON]->(to)\n")
+// AllowedAttributes returns the allowlisted attributes that can be stored as
+// Neo4j properties, i.e. scalars and lists of scalars.
+func AllowedAttributes(attributes map[string]interface{}, allowlist []string) map[string]interface{} {
+	allowed := make(map[string]interface{})
+	for _, key := range allowlist {
+		value, ok := attributes[key]
+		if ok && isPropertyValue(value) {
+			allowed[key] = value
+		}
 	}
+	return allowed
+}
 
-	return query.String(), params
+// isPropertyValue reports whether value can be stored as a Neo4j property.
+func isPropertyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string, bool, float64, int, int64:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			switch item.(type) {
+			case string, bool, float64, int, int64:
+			default:
+				return false
+			}
+		}
+		return len(v) > 0
+	default:
+		return false
+	}
 }
@@ -1,6 +1,7 @@
 package formatter
 
 import (
+	"reflect"
 	"strings"
 	"terraform-graphx/internal/graph"
 	"testing"
@@ -17,7 +18,7 @@ var testGraph = &graph.Graph{
 }
 
 func TestToCypherTransaction(t *testing.T) {
-	query, params := ToCypherTransaction(testGraph)
+	query, params := ToCypherTransaction(testGraph, CypherOptions{})
 
 	// Check the query string
 	if !strings.Contains(query, "UNWIND $nodes AS node_data") {
@@ -45,3 +46,504 @@ func TestToCypherTransaction(t *testing.T) {
 		t.Errorf("Expected 1 edge in params, got %d", len(edges))
 	}
 }
+
+func TestToCypherTransactionEdgeCreatedAt(t *testing.T) {
+	query, _ := ToCypherTransaction(testGraph, CypherOptions{})
+
+	if !strings.Contains(query, "MERGE (from)-[r:DEPENDS_ON]->(to)\nON CREATE SET r.created_at = timestamp()") {
+		t.Errorf("Expected relationship created_at to be set on create, got:\n%s", query)
+	}
+
+	// created_at must only be written when the relationship is created
+	if strings.Count(query, "r.created_at") != 1 {
+		t.Errorf("Expected r.created_at to be set exactly once (ON CREATE), got:\n%s", query)
+	}
+	if strings.Contains(query, "ON MATCH SET r.created_at") {
+		t.Error("created_at must not be reset on subsequent matches")
+	}
+}
+
+func TestToCypherTransactionRelationKind(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_instance.web"}, {ID: "aws_subnet.a"}, {ID: "data.aws_ami.ubuntu"}},
+		Edges: []graph.Edge{
+			{From: "aws_instance.web", To: "aws_subnet.a", Kind: graph.KindImplicit},
+			{From: "aws_instance.web", To: "data.aws_ami.ubuntu"},
+		},
+	}
+
+	query, params := ToCypherTransaction(g, CypherOptions{})
+	if !strings.Contains(query, "SET r.relation_kind = edge_data.relation_kind") {
+		t.Errorf("Expected the relation kind to be set, got:\n%s", query)
+	}
+
+	edges := params["edges"].([]map[string]string)
+	if edges[0]["relation_kind"] != graph.KindImplicit {
+		t.Errorf("Expected relation_kind %q, got %v", graph.KindImplicit, edges[0])
+	}
+	if _, ok := edges[1]["relation_kind"]; ok {
+		t.Errorf("Expected no relation_kind for an edge without kind, got %v", edges[1])
+	}
+}
+
+func TestToCypherTransactionModulePath(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main"},
+			{ID: "module.net.aws_subnet.a"},
+			{ID: `module.app["eu"].module.db.aws_db_instance.main[0]`},
+		},
+	}
+
+	query, params := ToCypherTransaction(g, CypherOptions{})
+	if !strings.Contains(query, "n.module = node_data.module") {
+		t.Errorf("Expected the module property to be set, got:\n%s", query)
+	}
+
+	nodes := params["nodes"].([]map[string]interface{})
+	for i, want := range []string{"", "module.net", `module.app["eu"].module.db`} {
+		if nodes[i]["module"] != want {
+			t.Errorf("%s: expected module %q, got %v", g.Nodes[i].ID, want, nodes[i]["module"])
+		}
+	}
+}
+
+func TestToCypherTransactionTagProperties(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_instance.web", Attributes: map[string]interface{}{
+				"instance_type": "t3.micro",
+				"tags":          map[string]interface{}{"Owner": "platform", "environment": "prod", "Name": "web"},
+			}},
+			{ID: "google_compute_instance.vm", Attributes: map[string]interface{}{
+				"labels": map[string]interface{}{"environment": "staging", "api_token": "x"},
+			}},
+			{ID: "aws_subnet.a"},
+		},
+	}
+
+	_, params := ToCypherTransaction(g, CypherOptions{
+		AttributeAllowlist: []string{"instance_type"},
+		TagProperties:      []string{"Owner", "environment", "api_token"},
+	})
+	nodes := params["nodes"].([]map[string]interface{})
+
+	want := []map[string]interface{}{
+		{"instance_type": "t3.micro", "Owner": "platform", "environment": "prod"},
+		{"environment": "staging"},
+		{},
+	}
+	for i, node := range nodes {
+		if !reflect.DeepEqual(node["attributes"], want[i]) {
+			t.Errorf("%s: expected properties %v, got %v", g.Nodes[i].ID, want[i], node["attributes"])
+		}
+	}
+}
+
+func TestToCypherTransactionNodeTimestamps(t *testing.T) {
+	query, _ := ToCypherTransaction(testGraph, CypherOptions{})
+
+	want := "MERGE (n:Resource {id: node_data.id})\n" +
+		"ON CREATE SET n.created_at = timestamp(), n.updated_at = timestamp()\n" +
+		"ON MATCH SET n.updated_at = timestamp()\n"
+	if !strings.Contains(query, want) {
+		t.Errorf("Expected node timestamps split between ON CREATE and ON MATCH, got:\n%s", query)
+	}
+	if strings.Count(query, "n.created_at") != 1 {
+		t.Errorf("Expected n.created_at to be set only on create, got:\n%s", query)
+	}
+}
+
+func TestToCypherTransactionRunID(t *testing.T) {
+	query, params := ToCypherTransaction(testGraph, CypherOptions{RunID: "ci-1234"})
+
+	for _, want := range []string{"SET n.run_id = $run_id", "SET r.run_id = $run_id"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+	if params["run_id"] != "ci-1234" {
+		t.Errorf("Expected run_id parameter, got %v", params["run_id"])
+	}
+
+	query, params = ToCypherTransaction(testGraph, CypherOptions{})
+	if strings.Contains(query, "run_id") {
+		t.Errorf("Expected no run_id without one configured, got:\n%s", query)
+	}
+	if _, ok := params["run_id"]; ok {
+		t.Error("Expected no run_id parameter without one configured")
+	}
+}
+
+func TestToCypherTransactionModules(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc"},
+			{ID: "module.app", Type: graph.ModuleType, Name: "app"},
+			{ID: "module.app.aws_instance.web", Type: "aws_instance"},
+		},
+		Edges: []graph.Edge{
+			{From: "module.app.aws_instance.web", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+			{From: "module.app", To: "module.app.aws_instance.web", Relation: graph.ContainsRelation},
+		},
+	}
+
+	query, params := ToCypherTransaction(g, CypherOptions{})
+
+	for _, want := range []string{
+		"FOREACH (_ IN CASE WHEN node_data.type = 'module' THEN [1] ELSE [] END | SET n:Module)",
+		"UNWIND $edges AS edge_data",
+		"MERGE (from)-[r:DEPENDS_ON]->(to)",
+		"UNWIND $edges_contains AS edge_data",
+		"MERGE (from)-[r:CONTAINS]->(to)",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+	if strings.Index(query, "r:DEPENDS_ON") > strings.Index(query, "r:CONTAINS") {
+		t.Errorf("Expected DEPENDS_ON edges to be written first, got:\n%s", query)
+	}
+
+	if edges := params["edges"].([]map[string]string); len(edges) != 1 {
+		t.Errorf("Expected 1 DEPENDS_ON edge, got %v", edges)
+	}
+	if edges := params["edges_contains"].([]map[string]string); len(edges) != 1 || edges[0]["from"] != "module.app" {
+		t.Errorf("Expected 1 CONTAINS edge, got %v", edges)
+	}
+}
+
+func TestToCypherTransactionProviders(t *testing.T) {
+	g := graph.AddProviders(&graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws"},
+			{ID: "google_project.main", Type: "google_project", Provider: "google"},
+		},
+	})
+
+	query, params := ToCypherTransaction(g, CypherOptions{})
+
+	for _, want := range []string{
+		"FOREACH (_ IN CASE WHEN node_data.type = 'provider' THEN [1] ELSE [] END | SET n:Provider)",
+		"UNWIND $edges_provided_by AS edge_data",
+		"MERGE (from)-[r:PROVIDED_BY]->(to)",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+	if strings.Contains(query, "SET n:Module") {
+		t.Errorf("Expected no Module label without modules, got:\n%s", query)
+	}
+
+	if nodes := params["nodes"].([]map[string]interface{}); len(nodes) != 4 {
+		t.Errorf("Expected 2 resources and 2 providers, got %v", nodes)
+	}
+	if edges := params["edges_provided_by"].([]map[string]string); len(edges) != 2 || edges[0]["to"] != "provider.aws" {
+		t.Errorf("Expected 2 PROVIDED_BY edges, got %v", edges)
+	}
+}
+
+func TestToCypherTransactionNoModuleLabelWithoutModules(t *testing.T) {
+	query, _ := ToCypherTransaction(testGraph, CypherOptions{})
+
+	if strings.Contains(query, "SET n:Module") || strings.Contains(query, "edges_") {
+		t.Errorf("Expected no module handling without module nodes, got:\n%s", query)
+	}
+}
+
+func TestToCypherTransactionRestoresSoftDeleted(t *testing.T) {
+	query, _ := ToCypherTransaction(testGraph, CypherOptions{})
+
+	if !strings.Contains(query, "REMOVE n.deleted, n.deleted_at") {
+		t.Errorf("Expected upsert to clear soft-delete markers, got:\n%s", query)
+	}
+}
+
+func TestToCypherTransactionDegreeCounts(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", DependentsCount: 1},
+			{ID: "aws_subnet.public", DependenciesCount: 1},
+		},
+	}
+
+	query, params := ToCypherTransaction(g, CypherOptions{})
+
+	if !strings.Contains(query, "n.dependents_count = node_data.dependents_count") ||
+		!strings.Contains(query, "n.dependencies_count = node_data.dependencies_count") {
+		t.Errorf("Expected degree counts to be persisted, got:\n%s", query)
+	}
+
+	nodes := params["nodes"].([]map[string]interface{})
+	if nodes[0]["dependents_count"] != 1 || nodes[1]["dependencies_count"] != 1 {
+		t.Errorf("Unexpected degree params: %v", nodes)
+	}
+}
+
+func TestToCypherTransactionAttributeAllowlist(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{
+			ID:   "aws_instance.web",
+			Type: "aws_instance",
+			Attributes: map[string]interface{}{
+				"instance_type":   "t3.micro",
+				"ami":             "ami-123",
+				"user_data":       "#!/bin/bash",
+				"security_groups": []interface{}{"sg-1", "sg-2"},
+				"tags":            map[string]interface{}{"Name": "web"},
+			},
+		}},
+	}
+
+	query, params := ToCypherTransaction(g, CypherOptions{
+		AttributeAllowlist: []string{"instance_type", "security_groups", "tags", "missing"},
+	})
+
+	if !strings.Contains(query, "SET n += node_data.attributes") {
+		t.Errorf("Expected attributes to be set as properties, got:\n%s", query)
+	}
+
+	nodes := params["nodes"].([]map[string]interface{})
+	attributes := nodes[0]["attributes"].(map[string]interface{})
+	if len(attributes) != 2 {
+		t.Errorf("Expected only the allowlisted scalar attributes, got %v", attributes)
+	}
+	if attributes["instance_type"] != "t3.micro" {
+		t.Errorf("Expected instance_type to be persisted, got %v", attributes)
+	}
+	if _, ok := attributes["security_groups"]; !ok {
+		t.Error("Expected list of scalars to be persisted")
+	}
+	for _, key := range []string{"ami", "user_data", "tags"} {
+		if _, ok := attributes[key]; ok {
+			t.Errorf("Attribute %s must not be persisted", key)
+		}
+	}
+}
+
+func TestToCypherTransactionAttributeDenylist(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{
+			ID:   "aws_db_instance.main",
+			Type: "aws_db_instance",
+			Attributes: map[string]interface{}{
+				"engine":             "postgres",
+				"password":           "hunter2",
+				"master_password":    "hunter2",
+				"DB_Secret":          "s3cr3t",
+				"iam_token":          "tok",
+				"instance_class":     "db.t3.micro",
+				"kms_key_id":         "arn:aws:kms:key",
+				"storage_encrypted":  true,
+				"private_key_pem":    "-----BEGIN",
+				"access_key_id":      "AKIA",
+				"backup_window":      "03:00-04:00",
+				"credentials_source": "env",
+			},
+		}},
+	}
+
+	allowlist := make([]string, 0, len(g.Nodes[0].Attributes))
+	for key := range g.Nodes[0].Attributes {
+		allowlist = append(allowlist, key)
+	}
+	_, params := ToCypherTransaction(g, CypherOptions{
+		AttributeAllowlist: allowlist,
+		AttributeDenylist:  []string{"KMS", "window"},
+	})
+
+	nodes := params["nodes"].([]map[string]interface{})
+	attributes := nodes[0]["attributes"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"engine":            "postgres",
+		"instance_class":    "db.t3.micro",
+		"storage_encrypted": true,
+	}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, attributes)
+	}
+}
+
+func TestToCypherTransactionReservedAttributes(t *testing.T) {
+	// AWS resources commonly have an id attribute, which must not replace the
+	// address nodes are merged on
+	g := &graph.Graph{
+		Nodes: []graph.Node{{
+			ID:   "aws_instance.web",
+			Type: "aws_instance",
+			Attributes: map[string]interface{}{
+				"id":            "i-0abc",
+				"arn":           "arn:aws:ec2:instance/i-0abc",
+				"instance_type": "t3.micro",
+				"tags":          map[string]interface{}{"name": "web", "created_at": "2024-01-01", "team": "platform"},
+			},
+		}},
+	}
+
+	_, params := ToCypherTransaction(g, CypherOptions{
+		AttributeAllowlist: []string{"id", "arn", "instance_type", "run_id"},
+		TagProperties:      []string{"name", "created_at", "team"},
+	})
+
+	nodes := params["nodes"].([]map[string]interface{})
+	if nodes[0]["id"] != "aws_instance.web" {
+		t.Errorf("Expected the address as id, got %v", nodes[0]["id"])
+	}
+	expected := map[string]interface{}{
+		"arn":           "arn:aws:ec2:instance/i-0abc",
+		"instance_type": "t3.micro",
+		"team":          "platform",
+	}
+	if attributes := nodes[0]["attributes"]; !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("Expected reserved properties to be left out, got %v", attributes)
+	}
+}
+
+func TestAllowedAttributeKeys(t *testing.T) {
+	got := AllowedAttributeKeys([]string{"instance_type", "admin_password", "Token", "ami", "id"}, []string{"AMI"})
+	expected := []string{"instance_type"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestAttributeKeys(t *testing.T) {
+	got := AttributeKeys(CypherOptions{
+		AttributeAllowlist: []string{"instance_type", "owner"},
+		TagProperties:      []string{"owner", "environment", "name", "team"},
+		AttributeDenylist:  []string{"team"},
+	})
+	expected := []string{"instance_type", "owner", "environment"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestToCypherTransactionNoAllowlist(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_instance.web", Attributes: map[string]interface{}{"ami": "ami-123"}}},
+	}
+
+	_, params := ToCypherTransaction(g, CypherOptions{})
+
+	nodes := params["nodes"].([]map[string]interface{})
+	if attributes := nodes[0]["attributes"].(map[string]interface{}); len(attributes) != 0 {
+		t.Errorf("Expected no attributes without an allowlist, got %v", attributes)
+	}
+}
+
+func TestToCypherTransactionNodeLabel(t *testing.T) {
+	query, _ := ToCypherTransaction(testGraph, CypherOptions{NodeLabel: "TerraformResource"})
+
+	if strings.Contains(query, ":Resource ") {
+		t.Errorf("Expected default label to be replaced, got:\n%s", query)
+	}
+	for _, want := range []string{
+		"MERGE (n:TerraformResource {id: node_data.id})",
+		"MATCH (from:TerraformResource {id: edge_data.from})",
+		"MATCH (to:TerraformResource {id: edge_data.to})",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+}
+
+func TestToCypherTransactionTypeLabels(t *testing.T) {
+	query, params := ToCypherTransaction(testGraph, CypherOptions{TypeLabels: true})
+
+	for _, want := range []string{
+		"FOREACH (_ IN CASE WHEN node_data.type_label = 'aws_subnet' THEN [1] ELSE [] END | SET n:aws_subnet)",
+		"FOREACH (_ IN CASE WHEN node_data.type_label = 'aws_vpc' THEN [1] ELSE [] END | SET n:aws_vpc)",
+		"MERGE (n:Resource {id: node_data.id})",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+
+	nodes := params["nodes"].([]map[string]interface{})
+	if nodes[0]["type_label"] != "aws_vpc" {
+		t.Errorf("Expected type_label parameter aws_vpc, got %v", nodes[0]["type_label"])
+	}
+}
+
+func TestToCypherTransactionRemovesStaleTypeLabels(t *testing.T) {
+	opts := CypherOptions{TypeLabels: true, StaleTypeLabels: []string{"aws_instance", "aws_vpc", "Resource", "bad label"}}
+	query, _ := ToCypherTransaction(testGraph, opts)
+
+	for _, want := range []string{
+		"FOREACH (_ IN CASE WHEN node_data.type_label <> 'aws_instance' THEN [1] ELSE [] END | REMOVE n:aws_instance)",
+		"FOREACH (_ IN CASE WHEN node_data.type_label <> 'aws_subnet' THEN [1] ELSE [] END | REMOVE n:aws_subnet)",
+		"FOREACH (_ IN CASE WHEN node_data.type_label <> 'aws_vpc' THEN [1] ELSE [] END | REMOVE n:aws_vpc)",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+	if strings.Contains(query, "REMOVE n:Resource") || strings.Contains(query, "bad label") {
+		t.Errorf("Expected the node label and invalid labels to be kept, got:\n%s", query)
+	}
+	// Stale labels are removed before the current ones are set
+	if strings.LastIndex(query, "REMOVE n:") > strings.Index(query, "SET n:aws_") {
+		t.Errorf("Expected removals before the type labels are set, got:\n%s", query)
+	}
+}
+
+func TestToCypherTransactionTypeLabelsSanitized(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "x.a", Type: "weird-type'}) DETACH DELETE n //"}},
+	}
+	query, _ := ToCypherTransaction(g, CypherOptions{TypeLabels: true})
+
+	if strings.Contains(query, "DETACH DELETE") || strings.Contains(query, "weird-type") {
+		t.Errorf("Expected the type to be sanitized, got:\n%s", query)
+	}
+	if !strings.Contains(query, "SET n:weird_type____DETACH_DELETE_n___)") {
+		t.Errorf("Expected sanitized type label, got:\n%s", query)
+	}
+}
+
+func TestToCypherTransactionWithoutTypeLabels(t *testing.T) {
+	query, _ := ToCypherTransaction(testGraph, CypherOptions{})
+
+	if strings.Contains(query, "FOREACH") {
+		t.Errorf("Expected no type labels unless enabled, got:\n%s", query)
+	}
+}
+
+func TestSanitizeLabel(t *testing.T) {
+	tests := map[string]string{
+		"aws_instance": "aws_instance",
+		"my-type":      "my_type",
+		"1password":    "_1password",
+		"":             "",
+	}
+	for input, want := range tests {
+		if got := SanitizeLabel(input); got != want {
+			t.Errorf("SanitizeLabel(%q) = %q, want %q", input, got, want)
+		}
+	}
+	for input := range tests {
+		if input == "" {
+			continue
+		}
+		if err := ValidateLabel(SanitizeLabel(input)); err != nil {
+			t.Errorf("Sanitized label for %q is not valid: %v", input, err)
+		}
+	}
+}
+
+func TestValidateLabel(t *testing.T) {
+	for _, label := range []string{"Resource", "TerraformResource", "_tf", "Infra2"} {
+		if err := ValidateLabel(label); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", label, err)
+		}
+	}
+	for _, label := range []string{"", "2Infra", "Terraform Resource", "Resource) DETACH DELETE (n", "a-b", "`Resource`"} {
+		if err := ValidateLabel(label); err == nil {
+			t.Errorf("Expected %q to be rejected", label)
+		}
+	}
+}
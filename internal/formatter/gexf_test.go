@@ -0,0 +1,59 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToGEXF(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: append(testGraph.Nodes, graph.Node{ID: `aws_iam_policy.p["a&b<c>"]`, Type: "aws_iam_policy", Provider: "aws", Name: "p"}),
+		Edges: append(testGraph.Edges, graph.Edge{From: `aws_iam_policy.p["a&b<c>"]`, To: "aws_vpc.main"}),
+	}
+
+	output, err := ToGEXF(g)
+	if err != nil {
+		t.Fatalf("ToGEXF failed: %v", err)
+	}
+
+	// Round-trip through encoding/xml to confirm the document is well-formed
+	var doc gexfDocument
+	if err := xml.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v\n%s", err, output)
+	}
+
+	if doc.Version != "1.3" || doc.Graph.DefaultEdgeType != "directed" {
+		t.Errorf("Expected a directed GEXF 1.3 graph, got version %q, edge type %q", doc.Version, doc.Graph.DefaultEdgeType)
+	}
+	if len(doc.Graph.Attributes.Attributes) != 2 {
+		t.Errorf("Expected type and provider attribute columns, got %+v", doc.Graph.Attributes.Attributes)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("Expected 3 nodes and 2 edges, got %d and %d", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	policy := doc.Graph.Nodes[2]
+	if policy.ID != "2" || policy.Label != `aws_iam_policy.p["a&b<c>"]` {
+		t.Errorf("Expected escaped label to round-trip with id 2, got %+v", policy)
+	}
+	if policy.AttValues[0].Value != "aws_iam_policy" || policy.AttValues[1].Value != "aws" {
+		t.Errorf("Unexpected attribute values: %+v", policy.AttValues)
+	}
+
+	edge := doc.Graph.Edges[1]
+	if edge.ID != "1" || edge.Source != "2" || edge.Target != "0" || edge.Label != "DEPENDS_ON" {
+		t.Errorf("Unexpected edge: %+v", edge)
+	}
+}
+
+func TestToGEXFUnknownEdgeNode(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_vpc.main"}},
+		Edges: []graph.Edge{{From: "aws_vpc.main", To: "aws_subnet.a"}},
+	}
+
+	if _, err := ToGEXF(g); err == nil {
+		t.Error("Expected an error for an edge to an unknown node")
+	}
+}
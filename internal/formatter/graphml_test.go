@@ -0,0 +1,53 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToGraphML(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: append(testGraph.Nodes, graph.Node{ID: `aws_iam_policy.p["a&b<c>"]`, Type: "aws_iam_policy", Name: "p"}),
+		Edges: testGraph.Edges,
+	}
+
+	output, err := ToGraphML(g)
+	if err != nil {
+		t.Fatalf("ToGraphML failed: %v", err)
+	}
+
+	// Round-trip through encoding/xml to confirm the document is well-formed
+	var doc graphMLDocument
+	if err := xml.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v\n%s", err, output)
+	}
+
+	if doc.Graph.EdgeDefault != "directed" {
+		t.Errorf("Expected directed graph, got %q", doc.Graph.EdgeDefault)
+	}
+	if len(doc.Keys) != 4 {
+		t.Errorf("Expected 4 key declarations, got %d", len(doc.Keys))
+	}
+	if len(doc.Graph.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %d", len(doc.Graph.Nodes))
+	}
+	if doc.Graph.Nodes[2].ID != `aws_iam_policy.p["a&b<c>"]` {
+		t.Errorf("Special characters not preserved, got %q", doc.Graph.Nodes[2].ID)
+	}
+	if !strings.Contains(output, "&amp;b&lt;c&gt;") {
+		t.Error("Expected special characters to be XML-escaped")
+	}
+
+	if len(doc.Graph.Edges) != 1 {
+		t.Fatalf("Expected 1 edge, got %d", len(doc.Graph.Edges))
+	}
+	edge := doc.Graph.Edges[0]
+	if edge.Source != "aws_subnet.public" || edge.Target != "aws_vpc.main" {
+		t.Errorf("Unexpected edge: %+v", edge)
+	}
+	if len(edge.Data) != 1 || edge.Data[0].Value != "DEPENDS_ON" {
+		t.Errorf("Expected edge relation data, got %+v", edge.Data)
+	}
+}
@@ -0,0 +1,72 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"terraform-graphx/internal/graph"
+)
+
+// ToJSON converts a graph to indented JSON with "nodes" and "edges" arrays,
+// using the JSON field names of graph.Node and graph.Edge.
+func ToJSON(g *graph.Graph) (string, error) {
+	out := graph.Graph{
+		Nodes: g.Nodes,
+		Edges: g.Edges,
+	}
+	// Empty graphs are written as empty arrays rather than null
+	if out.Nodes == nil {
+		out.Nodes = []graph.Node{}
+	}
+	if out.Edges == nil {
+		out.Edges = []graph.Edge{}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph JSON: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// ToAdjacencyJSON converts a graph to an indented JSON object mapping each
+// node ID to the sorted IDs it depends on. Like ToDependencyList, reversed
+// REQUIRED_BY edges are read back as dependencies and CONTAINS edges, which
+// are not dependencies, are left out; a PROVIDED_BY edge lists the provider a
+// resource depends on. Nodes without dependencies map to an empty array.
+// encoding/json writes the keys in sorted order.
+func ToAdjacencyJSON(g *graph.Graph) (string, error) {
+	adjacency := make(map[string][]string, len(g.Nodes))
+	for _, node := range g.Nodes {
+		adjacency[node.ID] = []string{}
+	}
+
+	type pair struct{ dependent, target string }
+	seen := make(map[pair]bool, len(g.Edges))
+	for _, edge := range g.Edges {
+		var p pair
+		switch edge.Relation {
+		case graph.ContainsRelation:
+			continue
+		case graph.RequiredByRelation:
+			p = pair{edge.To, edge.From}
+		default:
+			p = pair{edge.From, edge.To}
+		}
+		// Edges differing only in relation or kind name the same target
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		adjacency[p.dependent] = append(adjacency[p.dependent], p.target)
+	}
+	for _, targets := range adjacency {
+		sort.Strings(targets)
+	}
+
+	data, err := json.MarshalIndent(adjacency, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode adjacency JSON: %w", err)
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,127 @@
+package formatter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	output, err := ToJSON(testGraph)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+
+	var decoded graph.Graph
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+	if !reflect.DeepEqual(&decoded, testGraph) {
+		t.Errorf("Expected %+v, got %+v", testGraph, decoded)
+	}
+}
+
+func TestToJSONEmptyGraph(t *testing.T) {
+	output, err := ToJSON(&graph.Graph{})
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+
+	expected := "{\n  \"nodes\": [],\n  \"edges\": []\n}\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestToAdjacencyJSON(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "null_resource.app"},
+			{ID: "null_resource.cluster"},
+			{ID: "null_resource.db"},
+			{ID: "null_resource.isolated"},
+		},
+		Edges: []graph.Edge{
+			{From: "null_resource.app", To: "null_resource.db"},
+			{From: "null_resource.app", To: "null_resource.cluster"},
+			{From: "null_resource.app", To: "null_resource.cluster", Kind: graph.KindExplicit},
+			{From: "null_resource.db", To: "null_resource.cluster"},
+		},
+	}
+
+	output, err := ToAdjacencyJSON(g)
+	if err != nil {
+		t.Fatalf("ToAdjacencyJSON failed: %v", err)
+	}
+
+	var decoded map[string][]string
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+	expected := map[string][]string{
+		"null_resource.app":      {"null_resource.cluster", "null_resource.db"},
+		"null_resource.cluster":  {},
+		"null_resource.db":       {"null_resource.cluster"},
+		"null_resource.isolated": {},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded)
+	}
+	if !strings.Contains(output, `"null_resource.isolated": []`) {
+		t.Errorf("Expected isolated nodes to map to [], got:\n%s", output)
+	}
+}
+
+func TestToAdjacencyJSONSkipsContains(t *testing.T) {
+	g := graph.AddModules(&graph.Graph{
+		Nodes: []graph.Node{{ID: "module.app.aws_instance.web"}, {ID: "aws_vpc.main"}},
+		Edges: []graph.Edge{{From: "module.app.aws_instance.web", To: "aws_vpc.main", Relation: "DEPENDS_ON"}},
+	})
+
+	output, err := ToAdjacencyJSON(g)
+	if err != nil {
+		t.Fatalf("ToAdjacencyJSON failed: %v", err)
+	}
+
+	var decoded map[string][]string
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+	expected := map[string][]string{
+		"aws_vpc.main":                {},
+		"module.app":                  {},
+		"module.app.aws_instance.web": {"aws_vpc.main"},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded)
+	}
+}
+
+func TestToAdjacencyJSONRequiredBy(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_subnet.a"}, {ID: "aws_vpc.main"}},
+		Edges: []graph.Edge{{From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"}},
+	}
+
+	// The map lists dependencies whichever way the edges point
+	for _, direction := range []string{graph.DirectionDependsOn, graph.DirectionRequiredBy} {
+		output, err := ToAdjacencyJSON(graph.OrientEdges(g, direction))
+		if err != nil {
+			t.Fatalf("ToAdjacencyJSON failed: %v", err)
+		}
+
+		var decoded map[string][]string
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+		}
+		expected := map[string][]string{
+			"aws_subnet.a": {"aws_vpc.main"},
+			"aws_vpc.main": {},
+		}
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Errorf("%s: expected %v, got %v", direction, expected, decoded)
+		}
+	}
+}
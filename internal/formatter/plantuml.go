@@ -0,0 +1,81 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"terraform-graphx/internal/graph"
+)
+
+// ToPlantUML converts a graph to a PlantUML component diagram. Each resource is
+// a component labeled with its full address and declared under an alias that
+// is a valid PlantUML identifier; dependencies are drawn as arrows between aliases.
+func ToPlantUML(g *graph.Graph) (string, error) {
+	var out bytes.Buffer
+	out.WriteString("@startuml\n")
+
+	aliases := make(map[string]string, len(g.Nodes))
+	used := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		alias := uniqueAlias(plantUMLAlias(node.ID), used)
+		aliases[node.ID] = alias
+		fmt.Fprintf(&out, "component \"%s\" as %s\n", plantUMLLabel(node.ID), alias)
+	}
+
+	if len(g.Edges) > 0 {
+		out.WriteString("\n")
+	}
+	for _, edge := range g.Edges {
+		from, ok := aliases[edge.From]
+		if !ok {
+			return "", fmt.Errorf("edge references unknown node %q", edge.From)
+		}
+		to, ok := aliases[edge.To]
+		if !ok {
+			return "", fmt.Errorf("edge references unknown node %q", edge.To)
+		}
+		if relation := EdgeRelation(edge); relation != DefaultRelation {
+			fmt.Fprintf(&out, "%s --> %s : %s\n", from, to, relation)
+		} else {
+			fmt.Fprintf(&out, "%s --> %s\n", from, to)
+		}
+	}
+
+	out.WriteString("@enduml\n")
+	return out.String(), nil
+}
+
+// plantUMLAlias turns an address into an identifier made of letters, digits
+// and underscores that does not start with a digit.
+func plantUMLAlias(id string) string {
+	var b strings.Builder
+	for _, r := range id {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	alias := b.String()
+	if alias == "" || (alias[0] >= '0' && alias[0] <= '9') {
+		alias = "_" + alias
+	}
+	return alias
+}
+
+// uniqueAlias returns alias, suffixed with _2, _3, ... if it is already used,
+// and marks the result as used.
+func uniqueAlias(alias string, used map[string]bool) string {
+	candidate := alias
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", alias, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// plantUMLLabel escapes double quotes, which would end the quoted component
+// name, with PlantUML's Unicode escape.
+func plantUMLLabel(id string) string {
+	return strings.ReplaceAll(id, `"`, "<U+0022>")
+}
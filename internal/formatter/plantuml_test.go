@@ -0,0 +1,90 @@
+package formatter
+
+import (
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToPlantUML(t *testing.T) {
+	output, err := ToPlantUML(testGraph)
+	if err != nil {
+		t.Fatalf("ToPlantUML failed: %v", err)
+	}
+
+	if !strings.HasPrefix(output, "@startuml\n") || !strings.HasSuffix(output, "@enduml\n") {
+		t.Errorf("Expected @startuml/@enduml delimiters, got:\n%s", output)
+	}
+
+	for _, node := range testGraph.Nodes {
+		expected := `component "` + node.ID + `" as ` + plantUMLAlias(node.ID)
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, output)
+		}
+	}
+	for _, edge := range testGraph.Edges {
+		expected := plantUMLAlias(edge.From) + " --> " + plantUMLAlias(edge.To)
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+func TestToPlantUMLUniqueAliases(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: `aws_instance.web["a-b"]`},
+			{ID: `aws_instance.web["a_b"]`},
+			{ID: "aws_instance.web_a_b_"},
+			{ID: "1st.resource"},
+		},
+		Edges: []graph.Edge{
+			{From: `aws_instance.web["a-b"]`, To: `aws_instance.web["a_b"]`},
+		},
+	}
+
+	output, err := ToPlantUML(g)
+	if err != nil {
+		t.Fatalf("ToPlantUML failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "component ") {
+			continue
+		}
+		alias := line[strings.LastIndex(line, " as ")+4:]
+		if seen[alias] {
+			t.Errorf("Duplicate alias %q in output:\n%s", alias, output)
+		}
+		seen[alias] = true
+		if alias[0] >= '0' && alias[0] <= '9' {
+			t.Errorf("Alias %q starts with a digit", alias)
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 components, got %d:\n%s", len(seen), output)
+	}
+
+	expected := []string{
+		`component "aws_instance.web[<U+0022>a-b<U+0022>]" as aws_instance_web__a_b__`,
+		`component "aws_instance.web[<U+0022>a_b<U+0022>]" as aws_instance_web__a_b___2`,
+		"aws_instance_web__a_b__ --> aws_instance_web__a_b___2",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected %q in output:\n%s", e, output)
+		}
+	}
+}
+
+func TestToPlantUMLUnknownEdgeNode(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_vpc.main"}},
+		Edges: []graph.Edge{{From: "aws_subnet.a", To: "aws_vpc.main"}},
+	}
+
+	if _, err := ToPlantUML(g); err == nil {
+		t.Error("Expected an error for an edge to an unknown node")
+	}
+}
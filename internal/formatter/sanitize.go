@@ -0,0 +1,66 @@
+package formatter
+
+import (
+	"strings"
+	"terraform-graphx/internal/graph"
+)
+
+// RedactedValue replaces the values of sensitive attributes in sanitized output.
+const RedactedValue = "***"
+
+// SensitiveKeyPatterns lists the key substrings whose attribute values
+// SanitizeGraph redacts. It is broader than DefaultAttributeDenylist, as JSON
+// output includes every attribute rather than an allowlist.
+var SensitiveKeyPatterns = []string{
+	"password",
+	"secret",
+	"token",
+	"key",
+	"private",
+}
+
+// SanitizeGraph returns a copy of g whose node attributes have the values of
+// sensitive keys, at any depth of nested maps and lists, replaced with
+// RedactedValue. Keys match SensitiveKeyPatterns ignoring case; g is not modified.
+func SanitizeGraph(g *graph.Graph) *graph.Graph {
+	result := &graph.Graph{
+		Nodes: make([]graph.Node, len(g.Nodes)),
+		Edges: append([]graph.Edge(nil), g.Edges...),
+	}
+	for i, node := range g.Nodes {
+		if node.Attributes != nil {
+			node.Attributes = sanitizeMap(node.Attributes)
+		}
+		result.Nodes[i] = node
+	}
+	return result
+}
+
+// sanitizeMap returns a copy of attributes with sensitive values redacted.
+func sanitizeMap(attributes map[string]interface{}) map[string]interface{} {
+	sanitized := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		if containsAny(strings.ToLower(key), SensitiveKeyPatterns) {
+			sanitized[key] = RedactedValue
+			continue
+		}
+		sanitized[key] = sanitizeValue(value)
+	}
+	return sanitized
+}
+
+// sanitizeValue returns value with the sensitive keys of nested maps redacted.
+func sanitizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return sanitizeMap(v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = sanitizeValue(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
@@ -0,0 +1,65 @@
+package formatter
+
+import (
+	"reflect"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestSanitizeGraph(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{
+				ID:   "aws_db_instance.main",
+				Type: "aws_db_instance",
+				Attributes: map[string]interface{}{
+					"engine":   "postgres",
+					"password": "hunter2",
+					"tags": map[string]interface{}{
+						"Name":        "main",
+						"ApiToken":    "abc123",
+						"Environment": "prod",
+					},
+					"users": []interface{}{
+						map[string]interface{}{"name": "app", "private_key": "-----BEGIN"},
+					},
+				},
+			},
+			{ID: "aws_vpc.main", Type: "aws_vpc"},
+		},
+		Edges: []graph.Edge{{From: "aws_db_instance.main", To: "aws_vpc.main"}},
+	}
+
+	sanitized := SanitizeGraph(g)
+
+	expected := map[string]interface{}{
+		"engine":   "postgres",
+		"password": RedactedValue,
+		"tags": map[string]interface{}{
+			"Name":        "main",
+			"ApiToken":    RedactedValue,
+			"Environment": "prod",
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "app", "private_key": RedactedValue},
+		},
+	}
+	if !reflect.DeepEqual(sanitized.Nodes[0].Attributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, sanitized.Nodes[0].Attributes)
+	}
+	if sanitized.Nodes[1].Attributes != nil {
+		t.Errorf("Expected nodes without attributes to stay without, got %v", sanitized.Nodes[1].Attributes)
+	}
+	if !reflect.DeepEqual(sanitized.Edges, g.Edges) {
+		t.Errorf("Expected the edges to be kept, got %v", sanitized.Edges)
+	}
+
+	// The original graph is untouched
+	attributes := g.Nodes[0].Attributes
+	if attributes["password"] != "hunter2" || attributes["tags"].(map[string]interface{})["ApiToken"] != "abc123" {
+		t.Errorf("Expected the original attributes to be unchanged, got %v", attributes)
+	}
+	if attributes["users"].([]interface{})[0].(map[string]interface{})["private_key"] != "-----BEGIN" {
+		t.Errorf("Expected the original nested lists to be unchanged, got %v", attributes["users"])
+	}
+}
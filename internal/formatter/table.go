@@ -0,0 +1,56 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"terraform-graphx/internal/graph"
+	"text/tabwriter"
+)
+
+// ToTable writes g as two aligned tables for reading in a terminal: the nodes
+// (ID, type and provider) sorted by ID, then the edges (from, relation and
+// to) sorted by from, relation and to. Empty values are shown as "-".
+func ToTable(g *graph.Graph, w io.Writer) error {
+	nodes := append([]graph.Node(nil), g.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tPROVIDER")
+	for _, node := range nodes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", node.ID, orDash(node.Type), orDash(node.Provider))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	type row struct{ From, Relation, To string }
+	edges := make([]row, len(g.Edges))
+	for i, edge := range g.Edges {
+		edges[i] = row{edge.From, EdgeRelation(edge), edge.To}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		if edges[i].Relation != edges[j].Relation {
+			return edges[i].Relation < edges[j].Relation
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "FROM\tRELATION\tTO")
+	for _, edge := range edges {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", edge.From, edge.Relation, edge.To)
+	}
+	return tw.Flush()
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
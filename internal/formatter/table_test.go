@@ -0,0 +1,39 @@
+package formatter
+
+import (
+	"strings"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+func TestToTable(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_subnet.a", Type: "aws_subnet", Provider: "aws"},
+			{ID: "aws_instance.web", Type: "aws_instance", Provider: "aws"},
+			{ID: "module.app", Type: graph.ModuleType},
+		},
+		Edges: []graph.Edge{
+			{From: "module.app", To: "aws_instance.web", Relation: "CONTAINS"},
+			{From: "aws_instance.web", To: "aws_subnet.a"},
+		},
+	}
+
+	var out strings.Builder
+	if err := ToTable(g, &out); err != nil {
+		t.Fatalf("ToTable failed: %v", err)
+	}
+
+	want := "" +
+		"ID                TYPE          PROVIDER\n" +
+		"aws_instance.web  aws_instance  aws\n" +
+		"aws_subnet.a      aws_subnet    aws\n" +
+		"module.app        module        -\n" +
+		"\n" +
+		"FROM              RELATION    TO\n" +
+		"aws_instance.web  DEPENDS_ON  aws_subnet.a\n" +
+		"module.app        CONTAINS    aws_instance.web\n"
+	if out.String() != want {
+		t.Errorf("Unexpected table:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
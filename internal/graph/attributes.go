@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AttributeMatcher matches nodes whose attribute at Key equals Value. Key is
+// a dotted path into nested attributes, e.g. "tags.Environment".
+type AttributeMatcher struct {
+	Key   string
+	Value string
+}
+
+// ParseAttributeMatcher parses a "key=value" filter expression.
+func ParseAttributeMatcher(s string) (AttributeMatcher, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return AttributeMatcher{}, fmt.Errorf("invalid attribute filter %q: expected key=value", s)
+	}
+	return AttributeMatcher{Key: key, Value: value}, nil
+}
+
+// Matches reports whether the node's attribute at m.Key is a scalar equal to
+// m.Value. Numbers and booleans are compared by their usual string form.
+func (m AttributeMatcher) Matches(node Node) bool {
+	value, ok := lookupAttribute(node.Attributes, strings.Split(m.Key, "."))
+	if !ok {
+		return false
+	}
+	formatted, ok := formatScalar(value)
+	return ok && formatted == m.Value
+}
+
+// FilterByAttribute returns the subgraph of nodes matching every matcher,
+// together with the edges among them. Nodes without attributes, such as those
+// read from `terraform graph`, never match.
+func FilterByAttribute(g *Graph, matchers []AttributeMatcher) *Graph {
+	return Subgraph(g, func(node Node) bool {
+		for _, m := range matchers {
+			if !m.Matches(node) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// lookupAttribute follows path through nested maps and lists. Since map keys
+// may themselves contain dots (e.g. tags."kubernetes.io/role"), the longest
+// key matching a prefix of the path is tried first.
+func lookupAttribute(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for i := len(path); i > 0; i-- {
+			if nested, ok := v[strings.Join(path[:i], ".")]; ok {
+				if found, ok := lookupAttribute(nested, path[i:]); ok {
+					return found, true
+				}
+			}
+		}
+	case []interface{}:
+		index, err := strconv.Atoi(path[0])
+		if err == nil && index >= 0 && index < len(v) {
+			return lookupAttribute(v[index], path[1:])
+		}
+	}
+	return nil, false
+}
+
+// formatScalar renders a string, number or boolean attribute for comparison.
+func formatScalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAttributeMatcher(t *testing.T) {
+	m, err := ParseAttributeMatcher("tags.Name=web=1")
+	if err != nil {
+		t.Fatalf("ParseAttributeMatcher failed: %v", err)
+	}
+	if m.Key != "tags.Name" || m.Value != "web=1" {
+		t.Errorf("Expected key tags.Name and value web=1, got %+v", m)
+	}
+
+	for _, invalid := range []string{"tags.Name", "=prod", ""} {
+		if _, err := ParseAttributeMatcher(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestAttributeMatcherMatches(t *testing.T) {
+	node := Node{ID: "aws_instance.web", Attributes: map[string]interface{}{
+		"instance_type": "t3.micro",
+		"monitoring":    true,
+		"cpu_count":     float64(2),
+		"tags": map[string]interface{}{
+			"Environment":        "prod",
+			"kubernetes.io/role": "node",
+		},
+		"network_interface": []interface{}{
+			map[string]interface{}{"device_index": float64(0)},
+		},
+	}}
+
+	tests := []struct {
+		matcher  AttributeMatcher
+		expected bool
+	}{
+		{AttributeMatcher{"instance_type", "t3.micro"}, true},
+		{AttributeMatcher{"monitoring", "true"}, true},
+		{AttributeMatcher{"cpu_count", "2"}, true},
+		{AttributeMatcher{"tags.Environment", "prod"}, true},
+		{AttributeMatcher{"tags.Environment", "dev"}, false},
+		{AttributeMatcher{"tags.kubernetes.io/role", "node"}, true},
+		{AttributeMatcher{"network_interface.0.device_index", "0"}, true},
+		{AttributeMatcher{"network_interface.1.device_index", "0"}, false},
+		{AttributeMatcher{"tags", "prod"}, false},
+		{AttributeMatcher{"missing", ""}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.matcher.Matches(node); got != tt.expected {
+			t.Errorf("%+v: expected %v, got %v", tt.matcher, tt.expected, got)
+		}
+	}
+}
+
+func TestFilterByAttribute(t *testing.T) {
+	prod := map[string]interface{}{"tags": map[string]interface{}{"Environment": "prod"}}
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_instance.web", Attributes: prod},
+			{ID: "aws_subnet.a", Attributes: prod},
+			{ID: "aws_instance.dev", Attributes: map[string]interface{}{"tags": map[string]interface{}{"Environment": "dev"}}},
+			{ID: "aws_vpc.main"},
+		},
+		Edges: []Edge{
+			{From: "aws_instance.web", To: "aws_subnet.a"},
+			{From: "aws_instance.dev", To: "aws_subnet.a"},
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+		},
+	}
+
+	filtered := FilterByAttribute(g, []AttributeMatcher{{Key: "tags.Environment", Value: "prod"}})
+
+	if got := nodeIDs(filtered); !reflect.DeepEqual(got, map[string]bool{"aws_instance.web": true, "aws_subnet.a": true}) {
+		t.Errorf("Unexpected nodes: %v", got)
+	}
+	if !reflect.DeepEqual(filtered.Edges, []Edge{{From: "aws_instance.web", To: "aws_subnet.a"}}) {
+		t.Errorf("Unexpected edges: %v", filtered.Edges)
+	}
+}
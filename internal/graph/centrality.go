@@ -0,0 +1,60 @@
+package graph
+
+import "sort"
+
+// Degree counts the edges of a node: In are edges pointing to it (its
+// dependents), Out edges leaving it (its dependencies).
+type Degree struct {
+	In    int `json:"in"`
+	Out   int `json:"out"`
+	Total int `json:"total"`
+}
+
+// RankedDegree pairs a node ID with its Degree.
+type RankedDegree struct {
+	ID string `json:"id"`
+	Degree
+}
+
+// DegreeCentrality returns the Degree of every node of g, including nodes
+// without edges. A self-loop counts as both an in and an out edge.
+func DegreeCentrality(g *Graph) map[string]Degree {
+	degrees := make(map[string]Degree, len(g.Nodes))
+	for _, node := range g.Nodes {
+		degrees[node.ID] = Degree{}
+	}
+	for _, edge := range g.Edges {
+		if d, ok := degrees[edge.From]; ok {
+			d.Out++
+			d.Total++
+			degrees[edge.From] = d
+		}
+		if d, ok := degrees[edge.To]; ok {
+			d.In++
+			d.Total++
+			degrees[edge.To] = d
+		}
+	}
+	return degrees
+}
+
+// TopByDegree returns the n nodes of g with the highest total degree, ties
+// broken by ascending ID. All nodes are returned when n is not positive.
+func TopByDegree(g *Graph, n int) []RankedDegree {
+	degrees := DegreeCentrality(g)
+	ranked := make([]RankedDegree, 0, len(degrees))
+	for id, degree := range degrees {
+		ranked = append(ranked, RankedDegree{ID: id, Degree: degree})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Total != ranked[j].Total {
+			return ranked[i].Total > ranked[j].Total
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+
+	if n > 0 && n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// starGraph has four subnets depending on one VPC, and an unconnected bucket.
+func starGraph() *Graph {
+	return &Graph{
+		Nodes: []Node{
+			{ID: "aws_vpc.main"},
+			{ID: "aws_subnet.d"},
+			{ID: "aws_subnet.c"},
+			{ID: "aws_subnet.b"},
+			{ID: "aws_subnet.a"},
+			{ID: "aws_s3_bucket.logs"},
+		},
+		Edges: []Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_subnet.b", To: "aws_vpc.main"},
+			{From: "aws_subnet.c", To: "aws_vpc.main"},
+			{From: "aws_subnet.d", To: "aws_vpc.main"},
+		},
+	}
+}
+
+func TestDegreeCentrality(t *testing.T) {
+	got := DegreeCentrality(starGraph())
+	want := map[string]Degree{
+		"aws_vpc.main":       {In: 4, Total: 4},
+		"aws_subnet.a":       {Out: 1, Total: 1},
+		"aws_subnet.b":       {Out: 1, Total: 1},
+		"aws_subnet.c":       {Out: 1, Total: 1},
+		"aws_subnet.d":       {Out: 1, Total: 1},
+		"aws_s3_bucket.logs": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DegreeCentrality() = %v, want %v", got, want)
+	}
+}
+
+func TestTopByDegree(t *testing.T) {
+	got := TopByDegree(starGraph(), 3)
+	want := []RankedDegree{
+		{ID: "aws_vpc.main", Degree: Degree{In: 4, Total: 4}},
+		{ID: "aws_subnet.a", Degree: Degree{Out: 1, Total: 1}},
+		{ID: "aws_subnet.b", Degree: Degree{Out: 1, Total: 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopByDegree(3) = %v, want %v", got, want)
+	}
+
+	if all := TopByDegree(starGraph(), 0); len(all) != 6 || all[5].ID != "aws_s3_bucket.logs" {
+		t.Errorf("Expected every node, the unconnected one last, got %v", all)
+	}
+}
+
+func TestDegreeCentralitySelfLoop(t *testing.T) {
+	g := &Graph{Nodes: []Node{{ID: "null_resource.a"}}, Edges: []Edge{{From: "null_resource.a", To: "null_resource.a"}}}
+	if got := DegreeCentrality(g)["null_resource.a"]; got != (Degree{In: 1, Out: 1, Total: 2}) {
+		t.Errorf("Expected a self-loop to count both ways, got %+v", got)
+	}
+}
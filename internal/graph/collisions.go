@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Collision is a type.name shared by resources in different module paths.
+type Collision struct {
+	Key       string   `json:"key"`
+	Addresses []string `json:"addresses"`
+}
+
+// FindCollisions groups nodes by type.name and returns the groups whose
+// members live in more than one module path, e.g. "null_resource.setup" and
+// "module.app.null_resource.setup". Instances of the same resource (count or
+// for_each keys) do not collide with each other. Addresses are sorted, and
+// collisions are sorted by key.
+func FindCollisions(g *Graph) []Collision {
+	addresses := make(map[string][]string)
+	modules := make(map[string]map[string]bool)
+	for _, node := range g.Nodes {
+		if node.Type == "" || node.Name == "" {
+			continue
+		}
+		key := node.Type + "." + node.Name
+		addresses[key] = append(addresses[key], node.ID)
+		if modules[key] == nil {
+			modules[key] = make(map[string]bool)
+		}
+		modules[key][ModulePathOf(node.ID)] = true
+	}
+
+	var collisions []Collision
+	for key, ids := range addresses {
+		if len(modules[key]) < 2 {
+			continue
+		}
+		sort.Strings(ids)
+		collisions = append(collisions, Collision{Key: key, Addresses: ids})
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Key < collisions[j].Key
+	})
+	return collisions
+}
+
+// ModulePathOf returns the module path of a resource address, e.g.
+// "module.app.module.db" for "module.app.module.db.aws_db_instance.main",
+// or "" for a resource in the root module. Dots inside instance keys such as
+// module.app["eu.west"] are not treated as separators.
+func ModulePathOf(address string) string {
+	segments := splitAddress(address)
+
+	var path []string
+	for i := 0; i+1 < len(segments) && segments[i] == "module"; i += 2 {
+		path = append(path, segments[i], segments[i+1])
+	}
+	return strings.Join(path, ".")
+}
+
+// IsDataSource reports whether address is the address of a data source, in
+// the root module or a child module.
+func IsDataSource(address string) bool {
+	modulePath := ModulePathOf(address)
+	if modulePath == "" {
+		return strings.HasPrefix(address, "data.")
+	}
+	return strings.HasPrefix(address[len(modulePath):], ".data.")
+}
+
+// splitAddress splits a resource address on the dots outside brackets.
+func splitAddress(address string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i, r := range address {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, address[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, address[start:])
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// collisionGraph declares null_resource.setup in the root module and in two
+// modules, plus a counted resource that must not collide with itself.
+var collisionGraph = &Graph{
+	Nodes: []Node{
+		{ID: "null_resource.setup", Type: "null_resource", Name: "setup"},
+		{ID: "module.app.null_resource.setup", Type: "null_resource", Name: "setup"},
+		{ID: `module.db["eu.west"].null_resource.setup`, Type: "null_resource", Name: "setup"},
+		{ID: "aws_instance.web[0]", Type: "aws_instance", Name: "web[0]"},
+		{ID: "aws_instance.web[1]", Type: "aws_instance", Name: "web[1]"},
+		{ID: "module.app.aws_vpc.main", Type: "aws_vpc", Name: "main"},
+	},
+}
+
+func TestFindCollisions(t *testing.T) {
+	got := FindCollisions(collisionGraph)
+	want := []Collision{
+		{
+			Key: "null_resource.setup",
+			Addresses: []string{
+				`module.app.null_resource.setup`,
+				`module.db["eu.west"].null_resource.setup`,
+				"null_resource.setup",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindCollisions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFindCollisionsNone(t *testing.T) {
+	if got := FindCollisions(chainGraph); len(got) != 0 {
+		t.Errorf("Expected no collisions, got %+v", got)
+	}
+}
+
+func TestModulePathOf(t *testing.T) {
+	tests := map[string]string{
+		"aws_vpc.main":                        "",
+		"data.aws_ami.ubuntu":                 "",
+		"module.app.aws_vpc.main":             "module.app",
+		"module.app.module.db.aws_db.main[0]": "module.app.module.db",
+		`module.db["eu.west"].aws_db.main`:    `module.db["eu.west"]`,
+		"module.app":                          "module.app",
+	}
+	for address, want := range tests {
+		if got := ModulePathOf(address); got != want {
+			t.Errorf("ModulePathOf(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestIsDataSource(t *testing.T) {
+	tests := map[string]bool{
+		"data.aws_ami.ubuntu":                true,
+		"module.app.data.aws_ami.ubuntu":     true,
+		`module.app["eu"].data.aws_ami.x[0]`: true,
+		"aws_vpc.main":                       false,
+		"module.data.aws_vpc.main":           false,
+		"module.app":                         false,
+		"aws_s3_bucket.data":                 false,
+	}
+	for address, want := range tests {
+		if got := IsDataSource(address); got != want {
+			t.Errorf("IsDataSource(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
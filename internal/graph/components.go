@@ -0,0 +1,54 @@
+package graph
+
+import "sort"
+
+// ConnectedComponents returns the groups of node IDs connected by edges,
+// ignoring edge direction. Nodes without edges form components of their own.
+// Components are sorted by size, largest first, then by their first member,
+// and members are sorted by ID.
+func ConnectedComponents(g *Graph) [][]string {
+	neighbors := make(map[string][]string, len(g.Nodes))
+	known := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		known[node.ID] = true
+	}
+	for _, edge := range g.Edges {
+		if !known[edge.From] || !known[edge.To] {
+			continue
+		}
+		neighbors[edge.From] = append(neighbors[edge.From], edge.To)
+		neighbors[edge.To] = append(neighbors[edge.To], edge.From)
+	}
+
+	visited := make(map[string]bool, len(g.Nodes))
+	var components [][]string
+	for _, node := range g.Nodes {
+		if visited[node.ID] {
+			continue
+		}
+
+		visited[node.ID] = true
+		component := []string{node.ID}
+		for stack := []string{node.ID}; len(stack) > 0; {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, next := range neighbors[id] {
+				if !visited[next] {
+					visited[next] = true
+					component = append(component, next)
+					stack = append(stack, next)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConnectedComponents(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "random_id.orphan"},
+			{ID: "aws_s3_bucket.logs"},
+			{ID: "aws_instance.web"},
+			{ID: "aws_subnet.a"},
+			{ID: "aws_vpc.main"},
+			{ID: "aws_s3_bucket_policy.logs"},
+		},
+		Edges: []Edge{
+			// Direction is ignored: web and the subnet only share the vpc
+			{From: "aws_instance.web", To: "aws_vpc.main"},
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_s3_bucket_policy.logs", To: "aws_s3_bucket.logs"},
+		},
+	}
+
+	expected := [][]string{
+		{"aws_instance.web", "aws_subnet.a", "aws_vpc.main"},
+		{"aws_s3_bucket.logs", "aws_s3_bucket_policy.logs"},
+		{"random_id.orphan"},
+	}
+	if got := ConnectedComponents(g); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestConnectedComponentsEqualSizes(t *testing.T) {
+	g := &Graph{Nodes: []Node{{ID: "c"}, {ID: "a"}, {ID: "b"}}}
+
+	expected := [][]string{{"a"}, {"b"}, {"c"}}
+	if got := ConnectedComponents(g); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected components ordered by member, got %v", got)
+	}
+	if got := ConnectedComponents(&Graph{}); len(got) != 0 {
+		t.Errorf("Expected no components for an empty graph, got %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FindCycles returns the dependency cycles in g as groups of node IDs. Each group
+// is a strongly connected component with more than one node, or a single node
+// with an edge to itself. Members are sorted, and groups are sorted by their
+// first member, so the result is stable.
+func FindCycles(g *Graph) [][]string {
+	adjacency := make(map[string][]string)
+	selfLoops := make(map[string]bool)
+	for _, edge := range g.Edges {
+		adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+		if edge.From == edge.To {
+			selfLoops[edge.From] = true
+		}
+	}
+
+	// Tarjan's strongly connected components algorithm
+	index := 0
+	indices := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var strongConnect func(id string)
+	strongConnect = func(id string) {
+		indices[id] = index
+		lowlink[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, next := range adjacency[id] {
+			if _, visited := indices[next]; !visited {
+				strongConnect(next)
+				lowlink[id] = min(lowlink[id], lowlink[next])
+			} else if onStack[next] {
+				lowlink[id] = min(lowlink[id], indices[next])
+			}
+		}
+
+		if lowlink[id] != indices[id] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+
+		if len(component) > 1 || selfLoops[id] {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range g.Nodes {
+		if _, visited := indices[node.ID]; !visited {
+			strongConnect(node.ID)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// CycleRule is the rule name of cycle findings.
+const CycleRule = "dependency-cycle"
+
+// CycleFinding is a dependency cycle reported for machine consumption. Its JSON
+// form is stable, so dashboards can depend on it:
+//
+//	{
+//	  "rule": "dependency-cycle",  // always CycleRule
+//	  "severity": "error",         // always "error"
+//	  "members": ["a", "b"],       // sorted node IDs of the cycle
+//	  "message": "..."             // human-readable description and suggestion
+//	}
+type CycleFinding struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`
+	Members  []string `json:"members"`
+	Message  string   `json:"message"`
+}
+
+// CycleFindings returns a finding per cycle of FindCycles, in the same order.
+func CycleFindings(g *Graph) []CycleFinding {
+	cycles := FindCycles(g)
+	findings := make([]CycleFinding, len(cycles))
+	for i, members := range cycles {
+		message := fmt.Sprintf("%d resources depend on each other: %s. Break the cycle by removing one of the references or depends_on entries between them.",
+			len(members), strings.Join(members, ", "))
+		if len(members) == 1 {
+			message = fmt.Sprintf("%s depends on itself. Remove the self-reference.", members[0])
+		}
+		findings[i] = CycleFinding{
+			Rule:     CycleRule,
+			Severity: "error",
+			Members:  members,
+			Message:  message,
+		}
+	}
+	return findings
+}
@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindCycles(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}},
+		Edges: []Edge{
+			{From: "a", To: "b"},
+			{From: "b", To: "c"},
+			{From: "c", To: "a"},
+			{From: "c", To: "d"},
+			{From: "e", To: "e"},
+		},
+	}
+
+	want := [][]string{{"a", "b", "c"}, {"e"}}
+	if got := FindCycles(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindCycles() = %v, want %v", got, want)
+	}
+}
+
+func TestFindCyclesAcyclic(t *testing.T) {
+	if cycles := FindCycles(chainGraph); len(cycles) != 0 {
+		t.Errorf("Expected no cycles, got %v", cycles)
+	}
+}
+
+func TestCycleFindings(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []Edge{
+			{From: "a", To: "b"},
+			{From: "b", To: "a"},
+			{From: "c", To: "c"},
+		},
+	}
+
+	findings := CycleFindings(g)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d", len(findings))
+	}
+
+	first := findings[0]
+	if first.Rule != CycleRule || first.Severity != "error" || strings.Join(first.Members, ",") != "a,b" {
+		t.Errorf("Unexpected finding: %+v", first)
+	}
+	if !strings.Contains(first.Message, "2 resources depend on each other: a, b") {
+		t.Errorf("Unexpected message: %s", first.Message)
+	}
+	if !strings.Contains(findings[1].Message, "c depends on itself") {
+		t.Errorf("Unexpected self-loop message: %s", findings[1].Message)
+	}
+}
@@ -0,0 +1,47 @@
+package graph
+
+import "fmt"
+
+const (
+	// DirectionDependsOn keeps dependency edges as "X DEPENDS_ON Y".
+	DirectionDependsOn = "depends-on"
+	// DirectionRequiredBy turns dependency edges into "Y REQUIRED_BY X".
+	DirectionRequiredBy = "required-by"
+
+	// DependsOnRelation is the relation of dependency edges.
+	DependsOnRelation = "DEPENDS_ON"
+	// RequiredByRelation is the relation of reversed dependency edges.
+	RequiredByRelation = "REQUIRED_BY"
+)
+
+// ValidateEdgeDirection returns an error if direction is not one of the
+// supported edge directions. An empty direction means DirectionDependsOn.
+func ValidateEdgeDirection(direction string) error {
+	switch direction {
+	case "", DirectionDependsOn, DirectionRequiredBy:
+		return nil
+	}
+	return fmt.Errorf("unsupported edge direction %q (supported: %s, %s)", direction, DirectionDependsOn, DirectionRequiredBy)
+}
+
+// OrientEdges returns a copy of g with its dependency edges pointing in the
+// given direction. With DirectionRequiredBy every DEPENDS_ON edge (or edge
+// without a relation) is reversed and becomes REQUIRED_BY; other relations,
+// such as CONTAINS, are kept as they are.
+func OrientEdges(g *Graph, direction string) *Graph {
+	if direction != DirectionRequiredBy {
+		return g
+	}
+
+	result := &Graph{
+		Nodes: g.Nodes,
+		Edges: make([]Edge, len(g.Edges)),
+	}
+	for i, edge := range g.Edges {
+		if edge.Relation == "" || edge.Relation == DependsOnRelation {
+			edge = Edge{From: edge.To, To: edge.From, Relation: RequiredByRelation, Kind: edge.Kind}
+		}
+		result.Edges[i] = edge
+	}
+	return result
+}
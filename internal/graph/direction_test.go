@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrientEdgesRequiredBy(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "module.app"}, {ID: "module.app.aws_instance.web"}, {ID: "aws_vpc.main"}},
+		Edges: []Edge{
+			{From: "module.app.aws_instance.web", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+			{From: "module.app", To: "module.app.aws_instance.web", Relation: ContainsRelation},
+		},
+	}
+
+	got := OrientEdges(g, DirectionRequiredBy)
+
+	expected := []Edge{
+		{From: "aws_vpc.main", To: "module.app.aws_instance.web", Relation: "REQUIRED_BY"},
+		{From: "module.app", To: "module.app.aws_instance.web", Relation: ContainsRelation},
+	}
+	if !reflect.DeepEqual(got.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, got.Edges)
+	}
+	if g.Edges[0].From != "module.app.aws_instance.web" {
+		t.Errorf("OrientEdges must not modify its input, got %v", g.Edges)
+	}
+}
+
+func TestOrientEdgesDependsOn(t *testing.T) {
+	g := &Graph{Edges: []Edge{{From: "a", To: "b", Relation: "DEPENDS_ON"}}}
+
+	for _, direction := range []string{"", DirectionDependsOn} {
+		if got := OrientEdges(g, direction); !reflect.DeepEqual(got.Edges, g.Edges) {
+			t.Errorf("Expected edges unchanged for %q, got %v", direction, got.Edges)
+		}
+	}
+}
+
+func TestValidateEdgeDirection(t *testing.T) {
+	for _, direction := range []string{"", "depends-on", "required-by"} {
+		if err := ValidateEdgeDirection(direction); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", direction, err)
+		}
+	}
+	if err := ValidateEdgeDirection("upstream"); err == nil {
+		t.Error("Expected an error for an unsupported direction")
+	}
+}
@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Subgraph returns a new graph containing the nodes for which keep returns true
+// and the edges whose endpoints are both kept.
+func Subgraph(g *Graph, keep func(Node) bool) *Graph {
+	result := &Graph{
+		Nodes: make([]Node, 0, len(g.Nodes)),
+		Edges: make([]Edge, 0, len(g.Edges)),
+	}
+
+	kept := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		if keep(node) {
+			kept[node.ID] = true
+			result.Nodes = append(result.Nodes, node)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		if kept[edge.From] && kept[edge.To] {
+			result.Edges = append(result.Edges, edge)
+		}
+	}
+
+	return result
+}
+
+// FilterByType returns the subgraph of nodes whose Type is in include (or any
+// type when include is empty) and not in exclude, together with the edges among them.
+func FilterByType(g *Graph, include, exclude []string) *Graph {
+	includeSet := toSet(include)
+	excludeSet := toSet(exclude)
+
+	return Subgraph(g, func(node Node) bool {
+		if len(includeSet) > 0 && !includeSet[node.Type] {
+			return false
+		}
+		return !excludeSet[node.Type]
+	})
+}
+
+// FilterByGlob returns the subgraph of nodes whose address matches any of
+// patterns, or of those matching none of them when include is false, together
+// with the edges among them. A pattern matches an address it equals or, with
+// path.Match semantics, globs, e.g. "random_*.*". Invalid patterns only match
+// exactly; use ValidateGlobs to reject them.
+func FilterByGlob(g *Graph, patterns []string, include bool) *Graph {
+	return Subgraph(g, func(node Node) bool {
+		return matchesAnyGlob(node.ID, patterns) == include
+	})
+}
+
+// ValidateGlobs returns an error if any of patterns is malformed.
+func ValidateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid address pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesAnyGlob reports whether address equals or matches any of patterns.
+// Exact matches are checked first, as addresses like aws_subnet.a["x"] are
+// not valid patterns.
+func matchesAnyGlob(address string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == address {
+			return true
+		}
+		if matched, err := path.Match(pattern, address); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByRelationKind returns g with only the edges whose Kind is in kinds,
+// keeping all nodes. Edges without a kind are dropped too.
+func FilterByRelationKind(g *Graph, kinds []string) *Graph {
+	kindSet := toSet(kinds)
+
+	result := &Graph{
+		Nodes: g.Nodes,
+		Edges: make([]Edge, 0, len(g.Edges)),
+	}
+	for _, edge := range g.Edges {
+		if kindSet[edge.Kind] {
+			result.Edges = append(result.Edges, edge)
+		}
+	}
+	return result
+}
+
+// ValidateRelationKinds returns an error if any of kinds is not a known edge kind.
+func ValidateRelationKinds(kinds []string) error {
+	for _, kind := range kinds {
+		switch kind {
+		case KindExplicit, KindImplicit, KindData:
+		default:
+			return fmt.Errorf("unsupported relation kind %q (supported: %s, %s, %s)", kind, KindExplicit, KindImplicit, KindData)
+		}
+	}
+	return nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// FilterByModulePrefix returns the subgraph of nodes whose address is the module
+// prefix itself or lies within it (including nested child modules), e.g.
+// "module.network" matches "module.network.aws_vpc.main" and
+// "module.network.module.subnets.aws_subnet.a" but not "module.network2.aws_vpc.main".
+func FilterByModulePrefix(g *Graph, prefix string) *Graph {
+	prefix = strings.TrimSuffix(prefix, ".")
+
+	return Subgraph(g, func(node Node) bool {
+		return inModule(node.ID, prefix)
+	})
+}
+
+// inModule reports whether the address id is the module prefix itself or lies within it.
+func inModule(id, prefix string) bool {
+	return id == prefix ||
+		strings.HasPrefix(id, prefix+".") ||
+		strings.HasPrefix(id, prefix+"[")
+}
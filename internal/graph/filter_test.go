@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+var networkGraph = &Graph{
+	Nodes: []Node{
+		{ID: "aws_vpc.main", Type: "aws_vpc", Name: "main"},
+		{ID: "aws_subnet.public", Type: "aws_subnet", Name: "public"},
+		{ID: "aws_instance.web", Type: "aws_instance", Name: "web"},
+	},
+	Edges: []Edge{
+		{From: "aws_subnet.public", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		{From: "aws_instance.web", To: "aws_subnet.public", Relation: "DEPENDS_ON"},
+	},
+}
+
+func nodeIDs(g *Graph) map[string]bool {
+	ids := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		ids[node.ID] = true
+	}
+	return ids
+}
+
+func TestFilterByTypeInclude(t *testing.T) {
+	g := FilterByType(networkGraph, []string{"aws_vpc", "aws_subnet"}, nil)
+
+	ids := nodeIDs(g)
+	if len(ids) != 2 || !ids["aws_vpc.main"] || !ids["aws_subnet.public"] {
+		t.Errorf("Expected only the vpc and subnet nodes, got %v", ids)
+	}
+	if len(g.Edges) != 1 {
+		t.Fatalf("Expected 1 edge, got %d", len(g.Edges))
+	}
+	if g.Edges[0].From != "aws_subnet.public" || g.Edges[0].To != "aws_vpc.main" {
+		t.Errorf("Unexpected edge: %+v", g.Edges[0])
+	}
+}
+
+func TestFilterByTypeExclude(t *testing.T) {
+	g := FilterByType(networkGraph, nil, []string{"aws_subnet"})
+
+	ids := nodeIDs(g)
+	if len(ids) != 2 || ids["aws_subnet.public"] {
+		t.Errorf("Expected the subnet to be excluded, got %v", ids)
+	}
+	if len(g.Edges) != 0 {
+		t.Errorf("Expected edges touching the subnet to be dropped, got %v", g.Edges)
+	}
+}
+
+func TestFilterByTypeNoFilters(t *testing.T) {
+	g := FilterByType(networkGraph, nil, nil)
+
+	if len(g.Nodes) != 3 || len(g.Edges) != 2 {
+		t.Errorf("Expected graph to be unchanged, got %d nodes and %d edges", len(g.Nodes), len(g.Edges))
+	}
+}
+
+func TestFilterByModulePrefix(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "module.network.aws_vpc.main", Type: "aws_vpc", Name: "main"},
+			{ID: "module.network.module.subnets.aws_subnet.a", Type: "aws_subnet", Name: "a"},
+			{ID: "module.network[1].aws_vpc.main", Type: "aws_vpc", Name: "main"},
+			{ID: "module.network2.aws_vpc.main", Type: "aws_vpc", Name: "main"},
+			{ID: "module.compute.aws_instance.web", Type: "aws_instance", Name: "web"},
+		},
+		Edges: []Edge{
+			{From: "module.network.module.subnets.aws_subnet.a", To: "module.network.aws_vpc.main", Relation: "DEPENDS_ON"},
+			{From: "module.compute.aws_instance.web", To: "module.network.module.subnets.aws_subnet.a", Relation: "DEPENDS_ON"},
+		},
+	}
+
+	filtered := FilterByModulePrefix(g, "module.network")
+
+	ids := nodeIDs(filtered)
+	expected := []string{
+		"module.network.aws_vpc.main",
+		"module.network.module.subnets.aws_subnet.a",
+		"module.network[1].aws_vpc.main",
+	}
+	if len(ids) != len(expected) {
+		t.Errorf("Expected %d nodes, got %v", len(expected), ids)
+	}
+	for _, id := range expected {
+		if !ids[id] {
+			t.Errorf("Expected node %s to be kept", id)
+		}
+	}
+
+	if len(filtered.Edges) != 1 {
+		t.Fatalf("Expected only the intra-module edge, got %v", filtered.Edges)
+	}
+	if filtered.Edges[0].From != "module.network.module.subnets.aws_subnet.a" {
+		t.Errorf("Unexpected edge: %+v", filtered.Edges[0])
+	}
+}
+
+func TestFilterByRelationKind(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "aws_instance.web"}, {ID: "aws_subnet.a"}, {ID: "aws_iam_role.app"}, {ID: "data.aws_ami.ubuntu"}},
+		Edges: []Edge{
+			{From: "aws_instance.web", To: "aws_subnet.a", Kind: KindImplicit},
+			{From: "aws_instance.web", To: "aws_iam_role.app", Kind: KindExplicit},
+			{From: "aws_instance.web", To: "data.aws_ami.ubuntu", Kind: KindData},
+			{From: "aws_subnet.a", To: "aws_iam_role.app"},
+		},
+	}
+
+	result := FilterByRelationKind(g, []string{KindExplicit, KindData})
+	if len(result.Nodes) != 4 {
+		t.Errorf("Expected all nodes to be kept, got %v", result.Nodes)
+	}
+	want := []Edge{g.Edges[1], g.Edges[2]}
+	if !reflect.DeepEqual(result.Edges, want) {
+		t.Errorf("FilterByRelationKind() edges = %v, want %v", result.Edges, want)
+	}
+
+	if err := ValidateRelationKinds([]string{KindImplicit, "strong"}); err == nil {
+		t.Error("Expected an error for an unknown relation kind")
+	}
+	if err := ValidateRelationKinds([]string{KindExplicit, KindImplicit, KindData}); err != nil {
+		t.Errorf("Expected every relation kind to be valid, got %v", err)
+	}
+}
+
+var globGraph = &Graph{
+	Nodes: []Node{
+		{ID: "aws_vpc.main", Type: "aws_vpc"},
+		{ID: "random_id.suffix", Type: "random_id"},
+		{ID: "random_password.db", Type: "random_password"},
+		{ID: `aws_subnet.each["a"]`, Type: "aws_subnet"},
+		{ID: "module.app.aws_instance.web", Type: "aws_instance"},
+	},
+	Edges: []Edge{
+		{From: "random_password.db", To: "random_id.suffix"},
+		{From: `aws_subnet.each["a"]`, To: "aws_vpc.main"},
+		{From: "module.app.aws_instance.web", To: "random_id.suffix"},
+	},
+}
+
+func TestFilterByGlobExclude(t *testing.T) {
+	g := FilterByGlob(globGraph, []string{"random_*.*"}, false)
+
+	ids := nodeIDs(g)
+	if len(ids) != 3 || ids["random_id.suffix"] || ids["random_password.db"] {
+		t.Errorf("Expected the random resources to be excluded, got %v", ids)
+	}
+	if len(g.Edges) != 1 || g.Edges[0].To != "aws_vpc.main" {
+		t.Errorf("Expected only the subnet edge to remain, got %v", g.Edges)
+	}
+}
+
+func TestFilterByGlobInclude(t *testing.T) {
+	g := FilterByGlob(globGraph, []string{"module.app.*", "aws_vpc.main"}, true)
+
+	ids := nodeIDs(g)
+	if len(ids) != 2 || !ids["module.app.aws_instance.web"] || !ids["aws_vpc.main"] {
+		t.Errorf("Expected the module resource and the vpc, got %v", ids)
+	}
+	if len(g.Edges) != 0 {
+		t.Errorf("Expected no edges among the included nodes, got %v", g.Edges)
+	}
+}
+
+func TestFilterByGlobExactAddress(t *testing.T) {
+	// The brackets of instance keys would otherwise be read as a character class
+	g := FilterByGlob(globGraph, []string{`aws_subnet.each["a"]`}, true)
+
+	ids := nodeIDs(g)
+	if len(ids) != 1 || !ids[`aws_subnet.each["a"]`] {
+		t.Errorf("Expected only the exact address, got %v", ids)
+	}
+}
+
+func TestValidateGlobs(t *testing.T) {
+	if err := ValidateGlobs([]string{"random_*.*", "aws_vpc.main"}); err != nil {
+		t.Errorf("Expected valid patterns, got %v", err)
+	}
+	if err := ValidateGlobs([]string{"aws_subnet.each[\"a\""}); err == nil {
+		t.Error("Expected an error for an unterminated character class")
+	}
+}
@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// Fingerprint returns a stable SHA-256 hash of g's nodes, with their
+// attributes, and edges. It does not depend on the order of either, so two
+// graphs with the same contents have the same fingerprint. It returns "" if
+// the attributes can't be marshalled, which never happens for attributes
+// decoded from JSON.
+func Fingerprint(g *Graph) string {
+	sorted := &Graph{
+		Nodes: append([]Node{}, g.Nodes...),
+		Edges: append([]Edge{}, g.Edges...),
+	}
+	sort.SliceStable(sorted.Nodes, func(i, j int) bool {
+		return sorted.Nodes[i].ID < sorted.Nodes[j].ID
+	})
+	sort.Slice(sorted.Edges, func(i, j int) bool {
+		a, b := sorted.Edges[i], sorted.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		return a.Kind < b.Kind
+	})
+
+	// Maps are marshalled with sorted keys, so attributes hash stably too
+	data, err := json.Marshal(sorted)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
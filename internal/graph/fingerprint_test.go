@@ -0,0 +1,64 @@
+package graph
+
+import "testing"
+
+func TestFingerprintStable(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_instance.web", Type: "aws_instance", Attributes: map[string]interface{}{"ami": "ami-1", "tags": map[string]interface{}{"b": "2", "a": "1"}}},
+			{ID: "aws_subnet.a", Type: "aws_subnet"},
+		},
+		Edges: []Edge{
+			{From: "aws_instance.web", To: "aws_subnet.a", Kind: KindImplicit},
+			{From: "aws_instance.web", To: "aws_subnet.a", Relation: "CONTAINS"},
+		},
+	}
+	reordered := &Graph{
+		Nodes: []Node{g.Nodes[1], g.Nodes[0]},
+		Edges: []Edge{g.Edges[1], g.Edges[0]},
+	}
+
+	fingerprint := Fingerprint(g)
+	if len(fingerprint) != 64 {
+		t.Errorf("Expected a hex SHA-256, got %q", fingerprint)
+	}
+	if Fingerprint(g) != fingerprint {
+		t.Error("Expected the same fingerprint on every call")
+	}
+	if Fingerprint(reordered) != fingerprint {
+		t.Error("Expected the fingerprint not to depend on the order of nodes and edges")
+	}
+	if g.Nodes[0].ID != "aws_instance.web" || g.Edges[0].Kind != KindImplicit {
+		t.Error("Fingerprint must not reorder the graph")
+	}
+}
+
+func TestFingerprintChanges(t *testing.T) {
+	base := func() *Graph {
+		return &Graph{
+			Nodes: []Node{
+				{ID: "aws_instance.web", Attributes: map[string]interface{}{"ami": "ami-1"}},
+				{ID: "aws_subnet.a"},
+			},
+			Edges: []Edge{{From: "aws_instance.web", To: "aws_subnet.a"}},
+		}
+	}
+	fingerprint := Fingerprint(base())
+
+	changes := map[string]func(g *Graph){
+		"node added":     func(g *Graph) { g.Nodes = append(g.Nodes, Node{ID: "aws_vpc.main"}) },
+		"attribute":      func(g *Graph) { g.Nodes[0].Attributes["ami"] = "ami-2" },
+		"edge removed":   func(g *Graph) { g.Edges = nil },
+		"edge kind":      func(g *Graph) { g.Edges[0].Kind = KindExplicit },
+		"edge relation":  func(g *Graph) { g.Edges[0].Relation = "CONTAINS" },
+		"provider":       func(g *Graph) { g.Nodes[1].Provider = "aws" },
+		"degree counted": func(g *Graph) { AnnotateDegrees(g) },
+	}
+	for name, change := range changes {
+		g := base()
+		change(g)
+		if Fingerprint(g) == fingerprint {
+			t.Errorf("%s: expected the fingerprint to change", name)
+		}
+	}
+}
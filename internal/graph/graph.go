@@ -1,12 +1,16 @@
 package graph
 
+import "sort"
+
 // Node represents a resource, data source, or module in the Terraform graph.
 type Node struct {
-	ID         string                 `json:"id"`
-	Type       string                 `json:"type"`
-	Provider   string                 `json:"provider"`
-	Name       string                 `json:"name"`
-	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	ID                string                 `json:"id"`
+	Type              string                 `json:"type"`
+	Provider          string                 `json:"provider"`
+	Name              string                 `json:"name"`
+	DependentsCount   int                    `json:"dependents_count"`
+	DependenciesCount int                    `json:"dependencies_count"`
+	Attributes        map[string]interface{} `json:"attributes,omitempty"`
 }
 
 // Edge represents a dependency between two nodes in the Terraform graph.
@@ -14,10 +18,57 @@ type Edge struct {
 	From     string `json:"from"`
 	To       string `json:"to"`
 	Relation string `json:"relation"`
+	// Kind tells how the dependency was declared: KindExplicit, KindImplicit
+	// or KindData. It is empty when the source does not tell.
+	Kind string `json:"relation_kind,omitempty"`
 }
 
+// Kinds of dependency edges.
+const (
+	// KindExplicit is a depends_on entry.
+	KindExplicit = "explicit"
+	// KindImplicit is a reference in an expression.
+	KindImplicit = "implicit"
+	// KindData is a dependency on a data source, which is read rather than managed.
+	KindData = "data"
+)
+
 // Graph represents the entire Terraform dependency graph.
 type Graph struct {
 	Nodes []Node `json:"nodes"`
 	Edges []Edge `json:"edges"`
 }
+
+// AnnotateDegrees sets DependentsCount (in-degree) and DependenciesCount
+// (out-degree) on every node from the graph's edges.
+func AnnotateDegrees(g *Graph) {
+	dependents := make(map[string]int)
+	dependencies := make(map[string]int)
+	for _, edge := range g.Edges {
+		dependencies[edge.From]++
+		dependents[edge.To]++
+	}
+
+	for i := range g.Nodes {
+		g.Nodes[i].DependentsCount = dependents[g.Nodes[i].ID]
+		g.Nodes[i].DependenciesCount = dependencies[g.Nodes[i].ID]
+	}
+}
+
+// Sort orders the nodes of g by ID and its edges by From, To and Relation, so
+// that output does not depend on the order in which they were discovered.
+func Sort(g *Graph) {
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Relation < b.Relation
+	})
+}
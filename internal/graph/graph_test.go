@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnnotateDegrees(t *testing.T) {
+	// Two subnets and an instance depend on the VPC; the instance also depends on a subnet.
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_vpc.main"},
+			{ID: "aws_subnet.a"},
+			{ID: "aws_subnet.b"},
+			{ID: "aws_instance.web"},
+		},
+		Edges: []Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_subnet.b", To: "aws_vpc.main"},
+			{From: "aws_instance.web", To: "aws_vpc.main"},
+			{From: "aws_instance.web", To: "aws_subnet.a"},
+		},
+	}
+
+	AnnotateDegrees(g)
+
+	expected := map[string][2]int{
+		"aws_vpc.main":     {3, 0},
+		"aws_subnet.a":     {1, 1},
+		"aws_subnet.b":     {0, 1},
+		"aws_instance.web": {0, 2},
+	}
+	for _, node := range g.Nodes {
+		want := expected[node.ID]
+		if node.DependentsCount != want[0] || node.DependenciesCount != want[1] {
+			t.Errorf("%s: expected dependents=%d dependencies=%d, got dependents=%d dependencies=%d",
+				node.ID, want[0], want[1], node.DependentsCount, node.DependenciesCount)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "aws_vpc.main"}, {ID: "aws_instance.web"}, {ID: "aws_subnet.a"}},
+		Edges: []Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+			{From: "aws_instance.web", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+			{From: "aws_instance.web", To: "aws_subnet.a", Relation: "DEPENDS_ON"},
+			{From: "aws_instance.web", To: "aws_subnet.a", Relation: "CONTAINS"},
+		},
+	}
+
+	Sort(g)
+
+	expectedNodes := []Node{{ID: "aws_instance.web"}, {ID: "aws_subnet.a"}, {ID: "aws_vpc.main"}}
+	if !reflect.DeepEqual(g.Nodes, expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, g.Nodes)
+	}
+	expectedEdges := []Edge{
+		{From: "aws_instance.web", To: "aws_subnet.a", Relation: "CONTAINS"},
+		{From: "aws_instance.web", To: "aws_subnet.a", Relation: "DEPENDS_ON"},
+		{From: "aws_instance.web", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		{From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+	}
+	if !reflect.DeepEqual(g.Edges, expectedEdges) {
+		t.Errorf("Expected edges %v, got %v", expectedEdges, g.Edges)
+	}
+}
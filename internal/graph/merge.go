@@ -0,0 +1,80 @@
+package graph
+
+// AttributePolicy decides how Merge combines the Attributes of nodes that share an ID.
+type AttributePolicy int
+
+const (
+	// AttributesOverwrite replaces the attributes with those of the last graph containing the node.
+	AttributesOverwrite AttributePolicy = iota
+	// AttributesUnion keeps every key seen; on overlapping keys the later non-nil value wins,
+	// so a plan with unknown (nil) computed values doesn't erase known ones.
+	AttributesUnion
+)
+
+// Merge combines graphs into a new graph. Nodes are merged by ID in first-seen
+// order, with later non-empty Type/Provider/Name values winning and attributes
+// combined according to policy. Duplicate edges are dropped.
+func Merge(policy AttributePolicy, graphs ...*Graph) *Graph {
+	result := &Graph{
+		Nodes: make([]Node, 0),
+		Edges: make([]Edge, 0),
+	}
+
+	nodeIndex := make(map[string]int)
+	seenEdges := make(map[Edge]bool)
+
+	for _, g := range graphs {
+		for _, node := range g.Nodes {
+			i, exists := nodeIndex[node.ID]
+			if !exists {
+				nodeIndex[node.ID] = len(result.Nodes)
+				node.Attributes = mergeAttributes(nil, node.Attributes, policy)
+				result.Nodes = append(result.Nodes, node)
+				continue
+			}
+
+			merged := &result.Nodes[i]
+			if node.Type != "" {
+				merged.Type = node.Type
+			}
+			if node.Provider != "" {
+				merged.Provider = node.Provider
+			}
+			if node.Name != "" {
+				merged.Name = node.Name
+			}
+			merged.Attributes = mergeAttributes(merged.Attributes, node.Attributes, policy)
+		}
+
+		for _, edge := range g.Edges {
+			if !seenEdges[edge] {
+				seenEdges[edge] = true
+				result.Edges = append(result.Edges, edge)
+			}
+		}
+	}
+
+	return result
+}
+
+// mergeAttributes returns a new map combining base and next under policy.
+func mergeAttributes(base, next map[string]interface{}, policy AttributePolicy) map[string]interface{} {
+	if policy == AttributesOverwrite && next != nil {
+		base = nil
+	}
+	if base == nil && next == nil {
+		return nil
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(next))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range next {
+		if _, exists := merged[key]; exists && value == nil {
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func attributePlans() (*Graph, *Graph) {
+	first := &Graph{
+		Nodes: []Node{{
+			ID:   "aws_instance.web",
+			Type: "aws_instance",
+			Attributes: map[string]interface{}{
+				"ami":           "ami-123",
+				"instance_type": "t3.micro",
+				"private_ip":    "10.0.0.5",
+			},
+		}},
+		Edges: []Edge{{From: "aws_instance.web", To: "aws_subnet.a", Relation: "DEPENDS_ON"}},
+	}
+	second := &Graph{
+		Nodes: []Node{{
+			ID:       "aws_instance.web",
+			Provider: "aws",
+			Attributes: map[string]interface{}{
+				"instance_type": "t3.large",
+				"private_ip":    nil, // unknown until apply in this plan
+				"arn":           "arn:aws:ec2:instance/i-1",
+			},
+		}},
+		Edges: []Edge{{From: "aws_instance.web", To: "aws_subnet.a", Relation: "DEPENDS_ON"}},
+	}
+	return first, second
+}
+
+func TestMergeAttributesUnion(t *testing.T) {
+	first, second := attributePlans()
+
+	merged := Merge(AttributesUnion, first, second)
+
+	if len(merged.Nodes) != 1 {
+		t.Fatalf("Expected 1 merged node, got %d", len(merged.Nodes))
+	}
+	node := merged.Nodes[0]
+	want := map[string]interface{}{
+		"ami":           "ami-123",
+		"instance_type": "t3.large",
+		"private_ip":    "10.0.0.5",
+		"arn":           "arn:aws:ec2:instance/i-1",
+	}
+	if !reflect.DeepEqual(node.Attributes, want) {
+		t.Errorf("Union attributes = %v, want %v", node.Attributes, want)
+	}
+	if node.Type != "aws_instance" || node.Provider != "aws" {
+		t.Errorf("Expected non-empty fields from both plans, got type=%q provider=%q", node.Type, node.Provider)
+	}
+	if len(merged.Edges) != 1 {
+		t.Errorf("Expected duplicate edges to be dropped, got %d", len(merged.Edges))
+	}
+}
+
+func TestMergeAttributesOverwrite(t *testing.T) {
+	first, second := attributePlans()
+
+	merged := Merge(AttributesOverwrite, first, second)
+
+	want := second.Nodes[0].Attributes
+	if got := merged.Nodes[0].Attributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("Overwrite attributes = %v, want %v", got, want)
+	}
+}
+
+func TestMergeDoesNotMutateInputs(t *testing.T) {
+	first, second := attributePlans()
+
+	Merge(AttributesUnion, first, second)
+
+	if _, ok := first.Nodes[0].Attributes["arn"]; ok {
+		t.Error("Merge must not modify the input attribute maps")
+	}
+}
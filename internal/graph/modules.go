@@ -0,0 +1,78 @@
+package graph
+
+import "strings"
+
+const (
+	// ModuleType is the Type of nodes that represent module instances.
+	ModuleType = "module"
+	// ContainsRelation links a module to its direct resources and child modules.
+	ContainsRelation = "CONTAINS"
+)
+
+// AddModules returns a copy of g with a node per module instance found in the
+// resource addresses (e.g. "module.app" for "module.app.aws_instance.web") and
+// CONTAINS edges from each module to its direct resources and child modules.
+// Module nodes already present in g are reused.
+func AddModules(g *Graph) *Graph {
+	result := &Graph{
+		Nodes: append([]Node(nil), g.Nodes...),
+		Edges: append([]Edge(nil), g.Edges...),
+	}
+
+	exists := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		exists[node.ID] = true
+	}
+	linked := make(map[Edge]bool)
+
+	contain := func(parent, child string) {
+		edge := Edge{From: parent, To: child, Relation: ContainsRelation}
+		if !linked[edge] {
+			linked[edge] = true
+			result.Edges = append(result.Edges, edge)
+		}
+	}
+
+	// addModule adds the module at path and its ancestors, linking each to its parent
+	var addModule func(path string)
+	addModule = func(path string) {
+		if !exists[path] {
+			exists[path] = true
+			result.Nodes = append(result.Nodes, Node{ID: path, Type: ModuleType, Name: moduleName(path)})
+		}
+		if parent := parentModule(path); parent != "" {
+			addModule(parent)
+			contain(parent, path)
+		}
+	}
+
+	for _, node := range g.Nodes {
+		if node.Type == ModuleType {
+			addModule(node.ID)
+			continue
+		}
+		if path := ModulePathOf(node.ID); path != "" {
+			addModule(path)
+			contain(path, node.ID)
+		}
+	}
+
+	return result
+}
+
+// parentModule returns the module path containing the module at path, or ""
+// for a module called from the root module.
+func parentModule(path string) string {
+	segments := splitAddress(path)
+	if len(segments) <= 2 {
+		return ""
+	}
+	return strings.Join(segments[:len(segments)-2], ".")
+}
+
+// moduleName returns the call name of the module at path, including its
+// instance key, e.g. `app["eu"]` for `module.net.module.app["eu"]`.
+func moduleName(path string) string {
+	segments := splitAddress(path)
+	return segments[len(segments)-1]
+}
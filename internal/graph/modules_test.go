@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestAddModules(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc"},
+			{ID: "module.app.aws_instance.web", Type: "aws_instance"},
+			{ID: "module.app.module.db.aws_db_instance.main", Type: "aws_db_instance"},
+		},
+		Edges: []Edge{
+			{From: "module.app.aws_instance.web", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		},
+	}
+
+	result := AddModules(g)
+
+	ids := nodeIDs(result)
+	for _, id := range []string{"module.app", "module.app.module.db"} {
+		if !ids[id] {
+			t.Errorf("Expected module node %s, got %v", id, ids)
+		}
+	}
+	if len(result.Nodes) != 5 {
+		t.Errorf("Expected 5 nodes, got %v", ids)
+	}
+	for _, node := range result.Nodes {
+		if node.ID == "module.app.module.db" && (node.Type != ModuleType || node.Name != "db") {
+			t.Errorf("Unexpected module node: %+v", node)
+		}
+	}
+
+	want := map[Edge]bool{
+		{From: "module.app", To: "module.app.aws_instance.web", Relation: ContainsRelation}:                         true,
+		{From: "module.app", To: "module.app.module.db", Relation: ContainsRelation}:                                true,
+		{From: "module.app.module.db", To: "module.app.module.db.aws_db_instance.main", Relation: ContainsRelation}: true,
+	}
+	contains := 0
+	for _, edge := range result.Edges {
+		if edge.Relation != ContainsRelation {
+			continue
+		}
+		contains++
+		if !want[edge] {
+			t.Errorf("Unexpected CONTAINS edge %+v", edge)
+		}
+	}
+	if contains != len(want) {
+		t.Errorf("Expected %d CONTAINS edges, got %d", len(want), contains)
+	}
+
+	if len(g.Nodes) != 3 || len(g.Edges) != 1 {
+		t.Error("AddModules must not modify its input")
+	}
+}
+
+func TestAddModulesReusesExistingModuleNodes(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "module.app", Type: ModuleType, Name: "app"},
+			{ID: "module.app.aws_instance.web", Type: "aws_instance"},
+		},
+	}
+
+	result := AddModules(g)
+
+	if len(result.Nodes) != 2 {
+		t.Errorf("Expected the existing module node to be reused, got %v", nodeIDs(result))
+	}
+	if len(result.Edges) != 1 || result.Edges[0].From != "module.app" {
+		t.Errorf("Expected one CONTAINS edge from module.app, got %+v", result.Edges)
+	}
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LongestPath returns the node IDs of the longest dependency chain in g, from
+// the resource that depends on the others to the one everything in the chain
+// depends on. It is computed over a topological order, so g must be acyclic;
+// self-loops are ignored rather than reported as cycles when allowSelfEdges is
+// set. Among chains of equal length, the one starting with the smallest ID is
+// returned. An empty graph has an empty path.
+func LongestPath(g *Graph, allowSelfEdges bool) ([]string, error) {
+	ids := make([]string, 0, len(g.Nodes))
+	known := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		if !known[node.ID] {
+			known[node.ID] = true
+			ids = append(ids, node.ID)
+		}
+	}
+	sort.Strings(ids)
+
+	successors := make(map[string][]string)
+	inDegree := make(map[string]int, len(ids))
+	seen := make(map[[2]string]bool, len(g.Edges))
+	for _, edge := range g.Edges {
+		key := [2]string{edge.From, edge.To}
+		if !known[edge.From] || !known[edge.To] || seen[key] || (allowSelfEdges && edge.From == edge.To) {
+			continue
+		}
+		seen[key] = true
+		successors[edge.From] = append(successors[edge.From], edge.To)
+		inDegree[edge.To]++
+	}
+	for _, next := range successors {
+		sort.Strings(next)
+	}
+
+	// Kahn's algorithm; the queue stays sorted so ties resolve by ID
+	var queue []string
+	for _, id := range ids {
+		if inDegree[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+
+	length := make(map[string]int, len(ids))
+	previous := make(map[string]string, len(ids))
+	start := make(map[string]string, len(ids))
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+		if _, ok := start[id]; !ok {
+			start[id] = id
+		}
+
+		var ready []string
+		for _, next := range successors[id] {
+			candidate := length[id] + 1
+			if candidate > length[next] || (candidate == length[next] && start[id] < start[next]) {
+				length[next] = candidate
+				previous[next] = id
+				start[next] = start[id]
+			}
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+		queue = append(queue, ready...)
+		sort.Strings(queue)
+	}
+
+	if visited != len(ids) {
+		var descriptions []string
+		for _, cycle := range FindCycles(g) {
+			descriptions = append(descriptions, "["+strings.Join(cycle, ", ")+"]")
+		}
+		return nil, fmt.Errorf("graph contains dependency cycles, so it has no longest path: %s", strings.Join(descriptions, "; "))
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	end := ids[0]
+	for _, id := range ids[1:] {
+		if length[id] > length[end] || (length[id] == length[end] && start[id] < start[end]) {
+			end = id
+		}
+	}
+
+	path := []string{end}
+	for id := end; previous[id] != ""; id = previous[id] {
+		path = append(path, previous[id])
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
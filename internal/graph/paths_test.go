@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLongestPath(t *testing.T) {
+	// web -> subnet -> vpc -> ipam is the longest chain; db -> vpc is shorter
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_db_instance.db"},
+			{ID: "aws_instance.web"},
+			{ID: "aws_subnet.a"},
+			{ID: "aws_vpc.main"},
+			{ID: "aws_vpc_ipam.main"},
+			{ID: "random_id.unused"},
+		},
+		Edges: []Edge{
+			{From: "aws_db_instance.db", To: "aws_vpc.main"},
+			{From: "aws_instance.web", To: "aws_subnet.a"},
+			{From: "aws_instance.web", To: "aws_vpc.main"},
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_vpc.main", To: "aws_vpc_ipam.main"},
+		},
+	}
+
+	path, err := LongestPath(g, false)
+	if err != nil {
+		t.Fatalf("LongestPath failed: %v", err)
+	}
+	expected := []string{"aws_instance.web", "aws_subnet.a", "aws_vpc.main", "aws_vpc_ipam.main"}
+	if !reflect.DeepEqual(path, expected) {
+		t.Errorf("Expected %v, got %v", expected, path)
+	}
+}
+
+func TestLongestPathTies(t *testing.T) {
+	// Two chains of equal length: the one starting with the smallest ID wins
+	g := &Graph{
+		Nodes: []Node{{ID: "b.start"}, {ID: "b.end"}, {ID: "a.start"}, {ID: "a.end"}},
+		Edges: []Edge{
+			{From: "b.start", To: "b.end"},
+			{From: "a.start", To: "a.end"},
+		},
+	}
+
+	for i := 0; i < 5; i++ {
+		path, err := LongestPath(g, false)
+		if err != nil {
+			t.Fatalf("LongestPath failed: %v", err)
+		}
+		if expected := []string{"a.start", "a.end"}; !reflect.DeepEqual(path, expected) {
+			t.Fatalf("Expected %v, got %v", expected, path)
+		}
+	}
+}
+
+func TestLongestPathWithoutEdges(t *testing.T) {
+	path, err := LongestPath(&Graph{Nodes: []Node{{ID: "b"}, {ID: "a"}}}, false)
+	if err != nil {
+		t.Fatalf("LongestPath failed: %v", err)
+	}
+	if !reflect.DeepEqual(path, []string{"a"}) {
+		t.Errorf("Expected a single node, got %v", path)
+	}
+
+	path, err = LongestPath(&Graph{}, false)
+	if err != nil || len(path) != 0 {
+		t.Errorf("Expected an empty path for an empty graph, got %v, %v", path, err)
+	}
+}
+
+func TestLongestPathCycle(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []Edge{{From: "a", To: "b"}, {From: "b", To: "a"}, {From: "c", To: "a"}},
+	}
+	if _, err := LongestPath(g, false); err == nil {
+		t.Error("Expected an error for a cyclic graph")
+	}
+
+	selfLoop := &Graph{Nodes: []Node{{ID: "a"}}, Edges: []Edge{{From: "a", To: "a"}}}
+	if _, err := LongestPath(selfLoop, false); err == nil {
+		t.Error("Expected an error for a self-loop")
+	}
+}
+
+func TestLongestPathAllowSelfEdges(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []Edge{{From: "a", To: "b"}, {From: "b", To: "b"}, {From: "b", To: "c"}},
+	}
+
+	path, err := LongestPath(g, true)
+	if err != nil {
+		t.Fatalf("Expected the self-loop to be ignored, got %v", err)
+	}
+	if !reflect.DeepEqual(path, []string{"a", "b", "c"}) {
+		t.Errorf("Expected [a b c], got %v", path)
+	}
+
+	// Other cycles are still reported
+	g.Edges = append(g.Edges, Edge{From: "c", To: "a"})
+	if _, err := LongestPath(g, true); err == nil {
+		t.Error("Expected an error for a cycle longer than a self-loop")
+	}
+}
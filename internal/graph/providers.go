@@ -0,0 +1,43 @@
+package graph
+
+const (
+	// ProviderType is the Type of nodes that represent providers.
+	ProviderType = "provider"
+	// ProvidedByRelation links a resource to the provider managing it.
+	ProvidedByRelation = "PROVIDED_BY"
+)
+
+// AddProviders returns a copy of g with a node per distinct provider of its
+// resources, with ID "provider.<name>", and a PROVIDED_BY edge from each
+// resource to its provider. Nodes without a provider, such as modules, are
+// left unlinked.
+func AddProviders(g *Graph) *Graph {
+	result := &Graph{
+		Nodes: append([]Node(nil), g.Nodes...),
+		Edges: append([]Edge(nil), g.Edges...),
+	}
+
+	exists := make(map[string]bool, len(g.Nodes))
+	for _, node := range g.Nodes {
+		exists[node.ID] = true
+	}
+
+	for _, node := range g.Nodes {
+		if node.Provider == "" || node.Type == ProviderType || node.Type == ModuleType {
+			continue
+		}
+		id := ProviderID(node.Provider)
+		if !exists[id] {
+			exists[id] = true
+			result.Nodes = append(result.Nodes, Node{ID: id, Type: ProviderType, Provider: node.Provider, Name: node.Provider})
+		}
+		result.Edges = append(result.Edges, Edge{From: node.ID, To: id, Relation: ProvidedByRelation})
+	}
+
+	return result
+}
+
+// ProviderID returns the ID of the node of the provider called name.
+func ProviderID(name string) string {
+	return "provider." + name
+}
@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddProviders(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws"},
+			{ID: "aws_subnet.a", Type: "aws_subnet", Provider: "aws"},
+			{ID: "google_project.main", Type: "google_project", Provider: "google"},
+			{ID: "module.app", Type: ModuleType, Name: "app"},
+			{ID: "null_resource.legacy", Type: "null_resource"},
+		},
+		Edges: []Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		},
+	}
+
+	result := AddProviders(g)
+
+	expectedNodes := []Node{
+		{ID: "provider.aws", Type: ProviderType, Provider: "aws", Name: "aws"},
+		{ID: "provider.google", Type: ProviderType, Provider: "google", Name: "google"},
+	}
+	if !reflect.DeepEqual(result.Nodes[len(g.Nodes):], expectedNodes) {
+		t.Errorf("Expected one node per provider %v, got %v", expectedNodes, result.Nodes[len(g.Nodes):])
+	}
+
+	expectedEdges := []Edge{
+		{From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		{From: "aws_vpc.main", To: "provider.aws", Relation: ProvidedByRelation},
+		{From: "aws_subnet.a", To: "provider.aws", Relation: ProvidedByRelation},
+		{From: "google_project.main", To: "provider.google", Relation: ProvidedByRelation},
+	}
+	if !reflect.DeepEqual(result.Edges, expectedEdges) {
+		t.Errorf("Expected edges %v, got %v", expectedEdges, result.Edges)
+	}
+
+	if len(g.Nodes) != 5 || len(g.Edges) != 1 {
+		t.Error("AddProviders must not modify its input")
+	}
+}
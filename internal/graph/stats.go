@@ -0,0 +1,66 @@
+package graph
+
+// Stats summarizes the shape of a graph.
+type Stats struct {
+	Nodes      int            `json:"nodes"`
+	Edges      int            `json:"edges"`
+	ByType     map[string]int `json:"by_type"`
+	ByProvider map[string]int `json:"by_provider"`
+	// Roots are nodes nothing depends on; Leaves are nodes that depend on nothing.
+	Roots  int `json:"roots"`
+	Leaves int `json:"leaves"`
+	// MaxFanIn is the node with the most dependents, MaxFanOut the node with
+	// the most dependencies. They are nil for a graph without edges.
+	MaxFanIn  *NodeDegree `json:"max_fan_in,omitempty"`
+	MaxFanOut *NodeDegree `json:"max_fan_out,omitempty"`
+}
+
+// NodeDegree pairs a node ID with a number of edges.
+type NodeDegree struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+// Summarize computes Stats for g. Nodes without a type or provider are counted
+// under "". Ties for the largest fan-in/fan-out go to the smallest ID.
+func Summarize(g *Graph) Stats {
+	stats := Stats{
+		Nodes:      len(g.Nodes),
+		Edges:      len(g.Edges),
+		ByType:     make(map[string]int),
+		ByProvider: make(map[string]int),
+	}
+
+	dependents := make(map[string]int)
+	dependencies := make(map[string]int)
+	for _, edge := range g.Edges {
+		dependencies[edge.From]++
+		dependents[edge.To]++
+	}
+
+	for _, node := range g.Nodes {
+		stats.ByType[node.Type]++
+		stats.ByProvider[node.Provider]++
+		if dependents[node.ID] == 0 {
+			stats.Roots++
+		}
+		if dependencies[node.ID] == 0 {
+			stats.Leaves++
+		}
+		stats.MaxFanIn = maxDegree(stats.MaxFanIn, node.ID, dependents[node.ID])
+		stats.MaxFanOut = maxDegree(stats.MaxFanOut, node.ID, dependencies[node.ID])
+	}
+
+	return stats
+}
+
+// maxDegree returns the larger of current and (id, count), ignoring zero counts.
+func maxDegree(current *NodeDegree, id string, count int) *NodeDegree {
+	if count == 0 {
+		return current
+	}
+	if current == nil || count > current.Count || (count == current.Count && id < current.ID) {
+		return &NodeDegree{ID: id, Count: count}
+	}
+	return current
+}
@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws"},
+			{ID: "aws_subnet.a", Type: "aws_subnet", Provider: "aws"},
+			{ID: "aws_subnet.b", Type: "aws_subnet", Provider: "aws"},
+			{ID: "aws_instance.web", Type: "aws_instance", Provider: "aws"},
+			{ID: "random_id.suffix", Type: "random_id", Provider: "random"},
+		},
+		Edges: []Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_subnet.b", To: "aws_vpc.main"},
+			{From: "aws_instance.web", To: "aws_subnet.a"},
+			{From: "aws_instance.web", To: "aws_subnet.b"},
+			{From: "aws_instance.web", To: "random_id.suffix"},
+		},
+	}
+
+	got := Summarize(g)
+	want := Stats{
+		Nodes:      5,
+		Edges:      5,
+		ByType:     map[string]int{"aws_vpc": 1, "aws_subnet": 2, "aws_instance": 1, "random_id": 1},
+		ByProvider: map[string]int{"aws": 4, "random": 1},
+		Roots:      1,
+		Leaves:     2,
+		MaxFanIn:   &NodeDegree{ID: "aws_vpc.main", Count: 2},
+		MaxFanOut:  &NodeDegree{ID: "aws_instance.web", Count: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeWithoutEdges(t *testing.T) {
+	got := Summarize(&Graph{Nodes: []Node{{ID: "a"}, {ID: "b"}}})
+
+	if got.Roots != 2 || got.Leaves != 2 {
+		t.Errorf("Expected every node to be a root and a leaf, got %+v", got)
+	}
+	if got.MaxFanIn != nil || got.MaxFanOut != nil {
+		t.Errorf("Expected no fan-in/fan-out without edges, got %+v", got)
+	}
+}
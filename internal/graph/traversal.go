@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// closure returns the set of node IDs reachable from seeds (excluding the seeds
+// themselves unless they are reachable through a cycle). When reverse is true
+// edges are followed from To to From, i.e. towards the nodes that depend on the seeds.
+func closure(g *Graph, seeds []string, reverse bool) map[string]bool {
+	adjacency := make(map[string][]string)
+	for _, edge := range g.Edges {
+		if reverse {
+			adjacency[edge.To] = append(adjacency[edge.To], edge.From)
+		} else {
+			adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+		}
+	}
+
+	visited := make(map[string]bool)
+	queue := append([]string(nil), seeds...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacency[current] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return visited
+}
+
+// BlastRadius returns the subgraph made of the seed nodes and every node that
+// transitively depends on any of them.
+func BlastRadius(g *Graph, seeds []string) *Graph {
+	affected := closure(g, seeds, true)
+	for _, seed := range seeds {
+		affected[seed] = true
+	}
+
+	return Subgraph(g, func(node Node) bool {
+		return affected[node.ID]
+	})
+}
+
+// Dependents returns, in sorted order, the IDs of every node that transitively
+// depends on id. Cycles are handled by visiting each node once.
+func Dependents(g *Graph, id string) []string {
+	return sortedWithout(closure(g, []string{id}, true), id)
+}
+
+// Dependencies returns, in sorted order, the IDs of every node that id
+// transitively depends on. Cycles are handled by visiting each node once.
+func Dependencies(g *Graph, id string) []string {
+	return sortedWithout(closure(g, []string{id}, false), id)
+}
+
+// Roots returns, in sorted order, the IDs of the nodes treated as the top of the
+// graph. When rootModule is empty these are the nodes nothing depends on;
+// otherwise they are the resources within rootModule, regardless of their
+// incoming edges.
+func Roots(g *Graph, rootModule string) []string {
+	rootModule = strings.TrimSuffix(rootModule, ".")
+
+	var roots []string
+	if rootModule != "" {
+		for _, node := range g.Nodes {
+			if inModule(node.ID, rootModule) {
+				roots = append(roots, node.ID)
+			}
+		}
+	} else {
+		hasDependents := make(map[string]bool, len(g.Nodes))
+		for _, edge := range g.Edges {
+			hasDependents[edge.To] = true
+		}
+		for _, node := range g.Nodes {
+			if !hasDependents[node.ID] {
+				roots = append(roots, node.ID)
+			}
+		}
+	}
+
+	sort.Strings(roots)
+	return roots
+}
+
+// ReachableFromRoots returns the subgraph made of the Roots of g for rootModule
+// and every node they transitively depend on.
+func ReachableFromRoots(g *Graph, rootModule string) *Graph {
+	return ReachableFrom(g, Roots(g, rootModule))
+}
+
+// EntryPoints returns, in sorted order, the IDs of nodes whose Type is in types.
+func EntryPoints(g *Graph, types []string) []string {
+	typeSet := toSet(types)
+
+	var entries []string
+	for _, node := range g.Nodes {
+		if typeSet[node.Type] {
+			entries = append(entries, node.ID)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// ReachableFrom returns the subgraph made of the seed nodes and every node they
+// transitively depend on (the forward closure of the seeds).
+func ReachableFrom(g *Graph, seeds []string) *Graph {
+	reachable := closure(g, seeds, false)
+	for _, seed := range seeds {
+		reachable[seed] = true
+	}
+
+	return Subgraph(g, func(node Node) bool {
+		return reachable[node.ID]
+	})
+}
+
+// Orphans returns, in sorted order, the IDs of nodes that appear in no edge.
+func Orphans(g *Graph) []string {
+	connected := make(map[string]bool, len(g.Nodes))
+	for _, edge := range g.Edges {
+		connected[edge.From] = true
+		connected[edge.To] = true
+	}
+
+	var orphans []string
+	for _, node := range g.Nodes {
+		if !connected[node.ID] {
+			orphans = append(orphans, node.ID)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// sortedWithout returns the keys of set, minus exclude, in ascending order.
+func sortedWithout(set map[string]bool, exclude string) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		if key != exclude {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,218 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// chainGraph models web -> app -> db -> vpc plus an unrelated bucket.
+var chainGraph = &Graph{
+	Nodes: []Node{
+		{ID: "aws_vpc.main", Type: "aws_vpc"},
+		{ID: "aws_db_instance.db", Type: "aws_db_instance"},
+		{ID: "aws_instance.app", Type: "aws_instance"},
+		{ID: "aws_lb.web", Type: "aws_lb"},
+		{ID: "aws_s3_bucket.logs", Type: "aws_s3_bucket"},
+	},
+	Edges: []Edge{
+		{From: "aws_db_instance.db", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		{From: "aws_instance.app", To: "aws_db_instance.db", Relation: "DEPENDS_ON"},
+		{From: "aws_lb.web", To: "aws_instance.app", Relation: "DEPENDS_ON"},
+	},
+}
+
+func TestBlastRadius(t *testing.T) {
+	g := BlastRadius(chainGraph, []string{"aws_db_instance.db"})
+
+	ids := nodeIDs(g)
+	expected := []string{"aws_db_instance.db", "aws_instance.app", "aws_lb.web"}
+	if len(ids) != len(expected) {
+		t.Errorf("Expected %d nodes, got %v", len(expected), ids)
+	}
+	for _, id := range expected {
+		if !ids[id] {
+			t.Errorf("Expected %s in blast radius", id)
+		}
+	}
+	if ids["aws_vpc.main"] {
+		t.Error("Dependencies of the seed must not be part of its blast radius")
+	}
+	if len(g.Edges) != 2 {
+		t.Errorf("Expected 2 edges, got %d", len(g.Edges))
+	}
+}
+
+func TestBlastRadiusUnknownSeed(t *testing.T) {
+	g := BlastRadius(chainGraph, []string{"aws_iam_role.missing"})
+
+	if len(g.Nodes) != 0 {
+		t.Errorf("Expected empty graph for unknown seed, got %v", nodeIDs(g))
+	}
+}
+
+func TestDependents(t *testing.T) {
+	got := Dependents(chainGraph, "aws_vpc.main")
+	want := []string{"aws_db_instance.db", "aws_instance.app", "aws_lb.web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents() = %v, want %v", got, want)
+	}
+
+	if got := Dependents(chainGraph, "aws_lb.web"); len(got) != 0 {
+		t.Errorf("Expected no dependents for the top of the chain, got %v", got)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	got := Dependencies(chainGraph, "aws_lb.web")
+	want := []string{"aws_db_instance.db", "aws_instance.app", "aws_vpc.main"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependencies() = %v, want %v", got, want)
+	}
+}
+
+func TestTraversalWithCycle(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []Edge{
+			{From: "a", To: "b"},
+			{From: "b", To: "c"},
+			{From: "c", To: "a"},
+		},
+	}
+
+	want := []string{"b", "c"}
+	if got := Dependencies(g, "a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependencies() = %v, want %v", got, want)
+	}
+	if got := Dependents(g, "a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents() = %v, want %v", got, want)
+	}
+}
+
+// moduleGraph models a root module whose resources are consumed by a shared
+// module, so the intended entry points have incoming edges.
+var moduleGraph = &Graph{
+	Nodes: []Node{
+		{ID: "module.app.aws_instance.web"},
+		{ID: "module.app.aws_security_group.web"},
+		{ID: "module.shared.aws_cloudwatch_dashboard.main"},
+		{ID: "module.network.aws_vpc.main"},
+		{ID: "module.legacy.aws_instance.old"},
+	},
+	Edges: []Edge{
+		{From: "module.shared.aws_cloudwatch_dashboard.main", To: "module.app.aws_instance.web"},
+		{From: "module.app.aws_instance.web", To: "module.app.aws_security_group.web"},
+		{From: "module.app.aws_security_group.web", To: "module.network.aws_vpc.main"},
+	},
+}
+
+func TestRoots(t *testing.T) {
+	got := Roots(moduleGraph, "")
+	want := []string{"module.legacy.aws_instance.old", "module.shared.aws_cloudwatch_dashboard.main"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Roots() = %v, want %v", got, want)
+	}
+
+	got = Roots(moduleGraph, "module.app")
+	want = []string{"module.app.aws_instance.web", "module.app.aws_security_group.web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Roots(module.app) = %v, want %v", got, want)
+	}
+}
+
+func TestReachableFromRootsWithRootModule(t *testing.T) {
+	g := ReachableFromRoots(moduleGraph, "module.app")
+
+	ids := nodeIDs(g)
+	expected := []string{
+		"module.app.aws_instance.web",
+		"module.app.aws_security_group.web",
+		"module.network.aws_vpc.main",
+	}
+	if len(ids) != len(expected) {
+		t.Errorf("Expected %d nodes, got %v", len(expected), ids)
+	}
+	for _, id := range expected {
+		if !ids[id] {
+			t.Errorf("Expected %s to be reachable from the root module", id)
+		}
+	}
+	if ids["module.shared.aws_cloudwatch_dashboard.main"] {
+		t.Error("Dependents of the root module must not be reachable from it")
+	}
+	if len(g.Edges) != 2 {
+		t.Errorf("Expected 2 edges, got %d", len(g.Edges))
+	}
+}
+
+func TestReachableFromRootsAutoDetected(t *testing.T) {
+	g := ReachableFromRoots(moduleGraph, "")
+
+	if len(g.Nodes) != len(moduleGraph.Nodes) {
+		t.Errorf("Expected every node to be reachable from the zero in-degree roots, got %v", nodeIDs(g))
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_vpc.main"},
+			{ID: "aws_subnet.a"},
+			{ID: "null_resource.copy_paste"},
+			{ID: "aws_iam_role.self"},
+			{ID: "aws_eip.unused"},
+		},
+		Edges: []Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_iam_role.self", To: "aws_iam_role.self"},
+		},
+	}
+
+	got := Orphans(g)
+	want := []string{"aws_eip.unused", "null_resource.copy_paste"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Orphans() = %v, want %v", got, want)
+	}
+
+	if got := Orphans(chainGraph); !reflect.DeepEqual(got, []string{"aws_s3_bucket.logs"}) {
+		t.Errorf("Orphans(chainGraph) = %v", got)
+	}
+}
+
+func TestReachableFromEntryPoints(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "aws_lb.public", Type: "aws_lb"},
+			{ID: "aws_eip.bastion", Type: "aws_eip"},
+			{ID: "aws_instance.web", Type: "aws_instance"},
+			{ID: "aws_instance.bastion", Type: "aws_instance"},
+			{ID: "aws_db_instance.main", Type: "aws_db_instance"},
+			{ID: "aws_instance.batch", Type: "aws_instance"},
+			{ID: "aws_s3_bucket.reports", Type: "aws_s3_bucket"},
+		},
+		Edges: []Edge{
+			{From: "aws_lb.public", To: "aws_instance.web"},
+			{From: "aws_instance.web", To: "aws_db_instance.main"},
+			{From: "aws_eip.bastion", To: "aws_instance.bastion"},
+			{From: "aws_instance.batch", To: "aws_s3_bucket.reports"},
+			{From: "aws_instance.batch", To: "aws_db_instance.main"},
+		},
+	}
+
+	entries := EntryPoints(g, []string{"aws_lb", "aws_eip"})
+	if want := []string{"aws_eip.bastion", "aws_lb.public"}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("EntryPoints() = %v, want %v", entries, want)
+	}
+
+	reached := nodeIDs(ReachableFrom(g, entries))
+	for _, id := range []string{"aws_lb.public", "aws_eip.bastion", "aws_instance.web", "aws_instance.bastion", "aws_db_instance.main"} {
+		if !reached[id] {
+			t.Errorf("Expected %s to be reachable from the entry points", id)
+		}
+	}
+	for _, id := range []string{"aws_instance.batch", "aws_s3_bucket.reports"} {
+		if reached[id] {
+			t.Errorf("Expected %s not to be reachable from the entry points", id)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+// Log levels, from most to least verbose.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the level name used in JSON logs.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// logger holds the destination, format and minimum level of log messages.
+var logger = struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+	level  Level
+	now    func() time.Time
+}{w: os.Stderr, format: FormatText, level: LevelInfo, now: time.Now}
+
+// Setup configures log messages, including those of the standard logger, to
+// be written to w in the given format. Data written to stdout by commands is
+// not affected.
+func Setup(format string, w io.Writer) error {
+	switch format {
+	case "", FormatText:
+		format = FormatText
+		log.SetOutput(w)
+		log.SetFlags(log.LstdFlags)
+	case FormatJSON:
+		log.SetOutput(&jsonWriter{w: w, now: time.Now})
+		log.SetFlags(0)
+	default:
+		return fmt.Errorf("unsupported log format %q (supported: text, json)", format)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.w = w
+	logger.format = format
+	return nil
+}
+
+// SetLevel drops messages below level: LevelError for --quiet, LevelDebug for --verbose.
+func SetLevel(level Level) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.level = level
+}
+
+// Enabled reports whether messages of level are written.
+func Enabled(level Level) bool {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return level >= logger.level
+}
+
+// Format returns the log format, FormatText or FormatJSON.
+func Format() string {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return logger.format
+}
+
+// Debugf logs details that are only shown with --verbose.
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, nil, format, args...)
+}
+
+// Infof logs progress.
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, nil, format, args...)
+}
+
+// Warnf logs a problem the command recovers from.
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, nil, format, args...)
+}
+
+// Errorf logs a failure.
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, nil, format, args...)
+}
+
+// Fields are values attached to a log message, such as node and edge counts.
+// JSON logs write each as a key of its own, next to time, level and msg; text
+// logs only show the message, which should therefore include them too.
+type Fields map[string]interface{}
+
+// Entry logs messages with fields; see WithFields.
+type Entry struct {
+	fields Fields
+}
+
+// WithFields returns an Entry whose messages carry fields, e.g.
+//
+//	logging.WithFields(logging.Fields{"nodes": 3}).Infof("Wrote %d nodes", 3)
+func WithFields(fields Fields) Entry {
+	return Entry{fields: fields}
+}
+
+// Debugf is Debugf with the entry's fields.
+func (e Entry) Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, e.fields, format, args...)
+}
+
+// Infof is Infof with the entry's fields.
+func (e Entry) Infof(format string, args ...interface{}) {
+	logf(LevelInfo, e.fields, format, args...)
+}
+
+// Warnf is Warnf with the entry's fields.
+func (e Entry) Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, e.fields, format, args...)
+}
+
+// Errorf is Errorf with the entry's fields.
+func (e Entry) Errorf(format string, args ...interface{}) {
+	logf(LevelError, e.fields, format, args...)
+}
+
+func logf(level Level, fields Fields, format string, args ...interface{}) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if level < logger.level {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+	now := logger.now()
+
+	if logger.format == FormatJSON {
+		data, err := encodeJSONEntry(jsonEntry{
+			Time:    now.UTC().Format(time.RFC3339Nano),
+			Level:   level.String(),
+			Message: message,
+		}, fields)
+		if err == nil {
+			logger.w.Write(append(data, '\n'))
+		}
+		return
+	}
+
+	switch level {
+	case LevelDebug:
+		message = "Debug: " + message
+	case LevelWarn:
+		message = "Warning: " + message
+	case LevelError:
+		message = "Error: " + message
+	}
+	fmt.Fprintf(logger.w, "%s %s\n", now.Format("2006/01/02 15:04:05"), message)
+}
+
+// jsonEntry is a single structured log line.
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"msg"`
+}
+
+// encodeJSONEntry encodes entry followed by fields, sorted by key. Fields
+// named time, level or msg are dropped so they can't hide the entry's own.
+func encodeJSONEntry(entry jsonEntry, fields Fields) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil || len(fields) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != "time" && key != "level" && key != "msg" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, key := range keys {
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(fields[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode log field %s: %w", key, err)
+		}
+		buf.WriteByte(',')
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonWriter turns each message of the standard logger into a JSON line.
+type jsonWriter struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+func (j *jsonWriter) Write(p []byte) (int, error) {
+	message := strings.TrimRight(string(p), "\n")
+	level := "info"
+	if strings.HasPrefix(strings.ToLower(message), "warning") {
+		level = "warn"
+	}
+
+	data, err := json.Marshal(jsonEntry{
+		Time:    j.now().UTC().Format(time.RFC3339Nano),
+		Level:   level,
+		Message: message,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := j.w.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetupJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(FormatJSON, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { Setup(FormatText, os.Stderr) })
+
+	log.Printf("Filtered graph by resource type: %d nodes, %d edges", 3, 2)
+	log.Println("Warning: something looks off")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d:\n%s", len(lines), out.String())
+	}
+
+	var entry jsonEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %v\n%s", err, lines[0])
+	}
+	if entry.Message != "Filtered graph by resource type: 3 nodes, 2 edges" || entry.Level != "info" || entry.Time == "" {
+		t.Errorf("Unexpected log entry: %+v", entry)
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %v\n%s", err, lines[1])
+	}
+	if entry.Level != "warn" {
+		t.Errorf("Expected warnings to be logged at warn level, got %+v", entry)
+	}
+}
+
+func TestSetupText(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(FormatText, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { Setup(FormatText, os.Stderr) })
+
+	log.Println("Generating Terraform graph...")
+
+	if strings.HasPrefix(out.String(), "{") || !strings.Contains(out.String(), "Generating Terraform graph...") {
+		t.Errorf("Expected a plain text log line, got: %s", out.String())
+	}
+}
+
+func TestSetupUnsupported(t *testing.T) {
+	if err := Setup("xml", &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an unsupported log format")
+	}
+}
+
+func TestLevels(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(FormatJSON, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() {
+		Setup(FormatText, os.Stderr)
+		SetLevel(LevelInfo)
+	})
+
+	Debugf("hidden by default")
+	Infof("Wrote %d nodes", 3)
+	Warnf("falling back")
+
+	SetLevel(LevelError)
+	Infof("hidden when quiet")
+	Errorf("failed")
+
+	SetLevel(LevelDebug)
+	Debugf("shown when verbose")
+
+	var levels, messages []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var entry jsonEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Log line is not JSON: %v\n%s", err, line)
+		}
+		levels = append(levels, entry.Level)
+		messages = append(messages, entry.Message)
+	}
+
+	expectedLevels := []string{"info", "warn", "error", "debug"}
+	expectedMessages := []string{"Wrote 3 nodes", "falling back", "failed", "shown when verbose"}
+	if strings.Join(levels, ",") != strings.Join(expectedLevels, ",") || strings.Join(messages, ",") != strings.Join(expectedMessages, ",") {
+		t.Errorf("Expected %v %v, got %v %v", expectedLevels, expectedMessages, levels, messages)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(FormatJSON, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { Setup(FormatText, os.Stderr) })
+
+	WithFields(Fields{"nodes": 3, "edges": 2, "msg": "ignored"}).Infof("Filtered graph: %d nodes, %d edges", 3, 2)
+
+	line := strings.TrimSpace(out.String())
+	if !strings.HasPrefix(line, `{"time":`) || !strings.HasSuffix(line, `"level":"info","msg":"Filtered graph: 3 nodes, 2 edges","edges":2,"nodes":3}`) {
+		t.Errorf("Unexpected log line: %s", line)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %v\n%s", err, line)
+	}
+
+	out.Reset()
+	if err := Setup(FormatText, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	WithFields(Fields{"nodes": 3}).Warnf("falling back")
+	if !strings.HasSuffix(strings.TrimSpace(out.String()), " Warning: falling back") {
+		t.Errorf("Expected text logs to only show the message, got: %s", out.String())
+	}
+}
+
+func TestTextLevelPrefixes(t *testing.T) {
+	var out bytes.Buffer
+	if err := Setup(FormatText, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { Setup(FormatText, os.Stderr) })
+
+	Infof("Generating Terraform graph...")
+	Warnf("falling back")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " Generating Terraform graph...") || !strings.HasSuffix(lines[1], " Warning: falling back") {
+		t.Errorf("Unexpected text log:\n%s", out.String())
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Cleanup(func() { SetLevel(LevelInfo) })
+
+	SetLevel(LevelError)
+	if Enabled(LevelInfo) || !Enabled(LevelError) {
+		t.Error("Expected only errors to be enabled at LevelError")
+	}
+	SetLevel(LevelInfo)
+	if !Enabled(LevelInfo) || Enabled(LevelDebug) {
+		t.Error("Expected info but not debug messages at LevelInfo")
+	}
+}
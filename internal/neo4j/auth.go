@@ -0,0 +1,59 @@
+package neo4j
+
+import (
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Authentication modes for Credentials.Mode.
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthNone   = "none"
+)
+
+// Credentials describes how the client authenticates with Neo4j.
+type Credentials struct {
+	// Mode is AuthBasic, AuthBearer or AuthNone; AuthBasic when empty.
+	Mode     string
+	User     string
+	Password string
+	// Realm is the basic auth realm; the server's default when empty.
+	Realm string
+	// BearerToken is the SSO token sent in AuthBearer mode.
+	BearerToken string
+}
+
+// Validate checks that the fields required by the mode are set.
+func (c Credentials) Validate() error {
+	switch c.Mode {
+	case "", AuthBasic:
+		if c.User == "" || c.Password == "" {
+			return fmt.Errorf("basic auth requires a neo4j user and password")
+		}
+	case AuthBearer:
+		if c.BearerToken == "" {
+			return fmt.Errorf("bearer auth requires neo4j.bearer_token (or TFGRAPHX_NEO4J_BEARER_TOKEN)")
+		}
+	case AuthNone:
+	default:
+		return fmt.Errorf("unsupported neo4j auth mode %q (supported: %s, %s, %s)", c.Mode, AuthBasic, AuthBearer, AuthNone)
+	}
+	return nil
+}
+
+// AuthToken returns the driver auth token for the credentials.
+func (c Credentials) AuthToken() (neo4j.AuthToken, error) {
+	if err := c.Validate(); err != nil {
+		return neo4j.AuthToken{}, err
+	}
+	switch c.Mode {
+	case AuthBearer:
+		return neo4j.BearerAuth(c.BearerToken), nil
+	case AuthNone:
+		return neo4j.NoAuth(), nil
+	default:
+		return neo4j.BasicAuth(c.User, c.Password, c.Realm), nil
+	}
+}
@@ -0,0 +1,64 @@
+package neo4j
+
+import "testing"
+
+func TestCredentialsAuthToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		creds    Credentials
+		expected map[string]any
+	}{
+		{
+			name:     "basic by default",
+			creds:    Credentials{User: "neo4j", Password: "secret"},
+			expected: map[string]any{"scheme": "basic", "principal": "neo4j", "credentials": "secret"},
+		},
+		{
+			name:     "basic with realm",
+			creds:    Credentials{Mode: AuthBasic, User: "neo4j", Password: "secret", Realm: "ldap"},
+			expected: map[string]any{"scheme": "basic", "principal": "neo4j", "credentials": "secret", "realm": "ldap"},
+		},
+		{
+			name:     "bearer",
+			creds:    Credentials{Mode: AuthBearer, BearerToken: "token"},
+			expected: map[string]any{"scheme": "bearer", "credentials": "token"},
+		},
+		{
+			name:     "none",
+			creds:    Credentials{Mode: AuthNone},
+			expected: map[string]any{"scheme": "none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.creds.AuthToken()
+			if err != nil {
+				t.Fatalf("AuthToken failed: %v", err)
+			}
+			for key, value := range tt.expected {
+				if token.Tokens[key] != value {
+					t.Errorf("Expected %s=%v, got %v (tokens %v)", key, value, token.Tokens[key], token.Tokens)
+				}
+			}
+		})
+	}
+}
+
+func TestCredentialsValidate(t *testing.T) {
+	invalid := []Credentials{
+		{User: "neo4j"},
+		{Mode: AuthBasic, Password: "secret"},
+		{Mode: AuthBearer, User: "neo4j", Password: "secret"},
+		{Mode: "kerberos", User: "neo4j", Password: "secret"},
+	}
+	for _, creds := range invalid {
+		if _, err := creds.AuthToken(); err == nil {
+			t.Errorf("Expected an error for %+v", creds)
+		}
+	}
+
+	if err := (Credentials{Mode: AuthNone}).Validate(); err != nil {
+		t.Errorf("Expected no auth to need no fields, got %v", err)
+	}
+}
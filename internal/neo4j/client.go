@@ -3,25 +3,58 @@ package neo4j
 import (
 	"context"
 	"fmt"
+	"sort"
 	"terraform-graphx/internal/formatter"
 	"terraform-graphx/internal/graph"
+	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
 // Client handles the connection and communication with a Neo4j database.
 type Client struct {
-	Driver neo4j.DriverWithContext
+	Driver    neo4j.DriverWithContext
+	nodeLabel string
 }
 
 // NewClient creates a new Neo4j client and establishes a connection.
 func NewClient(uri, user, pass string) (*Client, error) {
-	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(user, pass, ""))
+	return NewClientWithCredentials(uri, Credentials{User: user, Password: pass})
+}
+
+// NewClientWithCredentials creates a new Neo4j client authenticating with creds.
+// With a neo4j:// URI the driver routes reads and writes to the cluster
+// members serving them.
+func NewClientWithCredentials(uri string, creds Credentials) (*Client, error) {
+	if err := ValidateURI(uri); err != nil {
+		return nil, err
+	}
+	auth, err := creds.AuthToken()
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := neo4j.NewDriverWithContext(uri, auth)
 	if err != nil {
 		return nil, fmt.Errorf("could not create neo4j driver: %w", err)
 	}
 
-	return &Client{Driver: driver}, nil
+	return &Client{Driver: driver, nodeLabel: formatter.DefaultNodeLabel}, nil
+}
+
+// SetNodeLabel sets the label of resource nodes (Resource by default).
+// The label is validated because it is templated into queries.
+func (c *Client) SetNodeLabel(label string) error {
+	if err := formatter.ValidateLabel(label); err != nil {
+		return err
+	}
+	c.nodeLabel = label
+	return nil
+}
+
+// NodeLabel returns the label of resource nodes.
+func (c *Client) NodeLabel() string {
+	return c.nodeLabel
 }
 
 // Close gracefully shuts down the driver.
@@ -34,13 +67,152 @@ func (c *Client) VerifyConnectivity(ctx context.Context) error {
 	return c.Driver.VerifyConnectivity(ctx)
 }
 
+// WaitForConnectivity polls VerifyConnectivity every interval until it succeeds,
+// calling onRetry after each failed attempt, and fails once timeout elapses.
+func (c *Client) WaitForConnectivity(ctx context.Context, timeout, interval time.Duration, onRetry func(err error)) error {
+	return poll(ctx, timeout, interval, c.VerifyConnectivity, onRetry)
+}
+
+// poll calls check every interval until it succeeds or timeout elapses.
+func poll(ctx context.Context, timeout, interval time.Duration, check func(context.Context) error, onRetry func(err error)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		if onRetry != nil {
+			onRetry(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for neo4j: %w", timeout, err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ResourceIDs returns up to limit resource IDs starting with prefix, in ascending order.
+func (c *Client) ResourceIDs(ctx context.Context, prefix string, limit int) ([]string, error) {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := fmt.Sprintf("MATCH (n:%s) WHERE n.id STARTS WITH $prefix RETURN n.id AS id ORDER BY id LIMIT $limit", c.nodeLabel)
+		res, err := tx.Run(ctx, query, map[string]interface{}{"prefix": prefix, "limit": limit})
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for res.Next(ctx) {
+			if id, ok := res.Record().Get("id"); ok {
+				if idStr, ok := id.(string); ok {
+					ids = append(ids, idStr)
+				}
+			}
+		}
+		return ids, res.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resource ids: %w", err)
+	}
+
+	return result.([]string), nil
+}
+
+// UpdateOptions controls how UpdateGraph reconciles the database.
+type UpdateOptions struct {
+	// SoftDelete marks obsolete resources as deleted instead of removing them.
+	SoftDelete bool
+	// DetachSoftDeleted also removes the relationships of the resources
+	// SoftDelete marks, which otherwise keep them.
+	DetachSoftDeleted bool
+	// KeepObsolete leaves obsolete resources untouched.
+	KeepObsolete bool
+	// Cypher controls how nodes and relationships are written.
+	Cypher formatter.CypherOptions
+	// Changes, when set, makes the update incremental: only the changed
+	// resources are upserted and only the deleted ones removed.
+	Changes *ChangeSet
+	// BatchSize, when positive, upserts nodes and then relationships in
+	// batches of this many, each in its own transaction, instead of writing
+	// everything in one transaction.
+	BatchSize int
+	// BatchRetries is how many more times a failed batch is attempted.
+	BatchRetries int
+	// Progress, when set, is called after each batch is written.
+	Progress func(BatchProgress)
+}
+
+// BatchProgress reports a written batch: its position and what it contained.
+type BatchProgress struct {
+	Batch         int
+	Batches       int
+	Nodes         int
+	Relationships int
+}
+
+// ChangeSet is the set of resources a plan changes, for incremental updates.
+type ChangeSet struct {
+	// Changed lists the IDs of the resources that are created, read, updated or replaced.
+	Changed []string
+	// Deleted lists the IDs of the resources that are destroyed.
+	Deleted []string
+}
+
+// Subgraph returns the nodes of g that changed and the edges touching them.
+// Edges to unchanged nodes are kept, as those nodes are already stored.
+func (cs *ChangeSet) Subgraph(g *graph.Graph) *graph.Graph {
+	changed := make(map[string]bool, len(cs.Changed))
+	for _, id := range cs.Changed {
+		changed[id] = true
+	}
+
+	sub := &graph.Graph{
+		Nodes: make([]graph.Node, 0),
+		Edges: make([]graph.Edge, 0),
+	}
+	for _, node := range g.Nodes {
+		if changed[node.ID] {
+			sub.Nodes = append(sub.Nodes, node)
+		}
+	}
+	for _, edge := range g.Edges {
+		if changed[edge.From] || changed[edge.To] {
+			sub.Edges = append(sub.Edges, edge)
+		}
+	}
+	return sub
+}
+
 // UpdateGraph synchronizes the Neo4j database with the current graph state.
-// It removes obsolete resources and relationships, then upserts the current ones.
-func (c *Client) UpdateGraph(ctx context.Context, g *graph.Graph) error {
+// It removes (or soft-deletes) obsolete resources and relationships, then upserts the current ones.
+// With opts.Changes, only the changed resources are written and only the deleted
+// ones removed; the rest of the database is not reconciled.
+// Everything runs in one transaction, which is rolled back if ctx is cancelled.
+func (c *Client) UpdateGraph(ctx context.Context, g *graph.Graph, opts UpdateOptions) error {
+	opts.Cypher.NodeLabel = c.nodeLabel
+	if opts.BatchSize > 0 {
+		return c.updateGraphInBatches(ctx, g, opts)
+	}
+
 	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		if opts.Changes != nil {
+			if !opts.KeepObsolete {
+				if err := c.deleteResources(ctx, tx, opts.Changes.Deleted, opts); err != nil {
+					return nil, err
+				}
+			}
+			return c.upsertGraph(ctx, tx, opts.Changes.Subgraph(g), opts.Cypher)
+		}
+
 		// Get current state from Neo4j
 		existingIDs, err := c.fetchExistingResourceIDs(ctx, tx)
 		if err != nil {
@@ -48,12 +220,14 @@ func (c *Client) UpdateGraph(ctx context.Context, g *graph.Graph) error {
 		}
 
 		// Remove obsolete resources
-		if err := c.deleteObsoleteResources(ctx, tx, existingIDs, g); err != nil {
-			return nil, err
+		if !opts.KeepObsolete {
+			if err := c.deleteObsoleteResources(ctx, tx, existingIDs, g, opts); err != nil {
+				return nil, err
+			}
 		}
 
 		// Upsert current graph state
-		return c.upsertGraph(ctx, tx, g)
+		return c.upsertGraph(ctx, tx, g, opts.Cypher)
 	})
 
 	if err != nil {
@@ -63,9 +237,97 @@ func (c *Client) UpdateGraph(ctx context.Context, g *graph.Graph) error {
 	return nil
 }
 
+// updateGraphInBatches is UpdateGraph with opts.BatchSize: obsolete resources
+// are removed in a first transaction, then each batch is written and retried on
+// its own, so a failure never repeats the batches already written. An
+// interrupted update leaves the batches written so far in place.
+func (c *Client) updateGraphInBatches(ctx context.Context, g *graph.Graph, opts UpdateOptions) error {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	if !opts.KeepObsolete {
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+			if opts.Changes != nil {
+				return nil, c.deleteResources(ctx, tx, opts.Changes.Deleted, opts)
+			}
+			existingIDs, err := c.fetchExistingResourceIDs(ctx, tx)
+			if err != nil {
+				return nil, err
+			}
+			return nil, c.deleteObsoleteResources(ctx, tx, existingIDs, g, opts)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update graph: %w", err)
+		}
+	}
+
+	if opts.Changes != nil {
+		g = opts.Changes.Subgraph(g)
+	}
+	write := func(ctx context.Context, b *graph.Graph) error {
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+			return c.upsertGraph(ctx, tx, b, opts.Cypher)
+		})
+		return err
+	}
+	if err := writeBatches(ctx, splitBatches(g, opts.BatchSize), write, opts.BatchRetries, opts.Progress); err != nil {
+		return fmt.Errorf("failed to update graph: %w", err)
+	}
+	return nil
+}
+
+// splitBatches splits g into graphs of at most size nodes, followed by graphs
+// of at most size edges. Nodes come first so that every relationship finds
+// both of its ends.
+func splitBatches(g *graph.Graph, size int) []*graph.Graph {
+	var batches []*graph.Graph
+	for start := 0; start < len(g.Nodes); start += size {
+		end := min(start+size, len(g.Nodes))
+		batches = append(batches, &graph.Graph{Nodes: g.Nodes[start:end], Edges: []graph.Edge{}})
+	}
+	for start := 0; start < len(g.Edges); start += size {
+		end := min(start+size, len(g.Edges))
+		batches = append(batches, &graph.Graph{Nodes: []graph.Node{}, Edges: g.Edges[start:end]})
+	}
+	return batches
+}
+
+// batchRetryDelay is the wait before the first retry of a batch; it doubles
+// with each further attempt.
+var batchRetryDelay = time.Second
+
+// writeBatches writes the batches in order with write. A failed batch is
+// attempted up to retries more times before giving up; batches written before
+// it are not repeated. progress, when set, is called after each batch.
+func writeBatches(ctx context.Context, batches []*graph.Graph, write func(context.Context, *graph.Graph) error, retries int, progress func(BatchProgress)) error {
+	for i, b := range batches {
+		err := write(ctx, b)
+		delay := batchRetryDelay
+		for attempt := 1; err != nil && attempt <= retries && ctx.Err() == nil; attempt++ {
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+				delay *= 2
+				err = write(ctx, b)
+			}
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("batch %d/%d failed: %w", i+1, len(batches), err)
+		}
+
+		if progress != nil {
+			progress(BatchProgress{Batch: i + 1, Batches: len(batches), Nodes: len(b.Nodes), Relationships: len(b.Edges)})
+		}
+	}
+	return nil
+}
+
 // fetchExistingResourceIDs retrieves all resource IDs currently in Neo4j.
 func (c *Client) fetchExistingResourceIDs(ctx context.Context, tx neo4j.ManagedTransaction) (map[string]bool, error) {
-	query := "MATCH (n:Resource) RETURN n.id as id"
+	query := fmt.Sprintf("MATCH (n:%s) RETURN n.id as id", c.nodeLabel)
 	result, err := tx.Run(ctx, query, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query existing resources: %w", err)
@@ -89,40 +351,184 @@ func (c *Client) fetchExistingResourceIDs(ctx context.Context, tx neo4j.ManagedT
 }
 
 // deleteObsoleteResources removes resources that exist in Neo4j but not in the new graph.
-func (c *Client) deleteObsoleteResources(ctx context.Context, tx neo4j.ManagedTransaction, existingIDs map[string]bool, g *graph.Graph) error {
-	// Build set of new resource IDs
+// With opts.SoftDelete, the resources are flagged as deleted and, unless
+// opts.DetachSoftDeleted, kept with their relationships.
+func (c *Client) deleteObsoleteResources(ctx context.Context, tx neo4j.ManagedTransaction, existingIDs map[string]bool, g *graph.Graph, opts UpdateOptions) error {
+	return c.deleteResources(ctx, tx, obsoleteIDs(existingIDs, g), opts)
+}
+
+// deleteResources removes (or soft-deletes) the resources with the given IDs
+// and their relationships.
+func (c *Client) deleteResources(ctx context.Context, tx neo4j.ManagedTransaction, ids []string, opts UpdateOptions) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := ObsoleteResourcesQuery(c.nodeLabel, opts.SoftDelete, opts.DetachSoftDeleted)
+	params := map[string]interface{}{"obsoleteIds": ids}
+	if _, err := tx.Run(ctx, query, params); err != nil {
+		return fmt.Errorf("failed to delete obsolete resources: %w", err)
+	}
+	return nil
+}
+
+// ObsoleteResources returns, in sorted order, the IDs of resources stored in
+// Neo4j that are not part of g, i.e. those UpdateGraph would delete. It only reads.
+func (c *Client) ObsoleteResources(ctx context.Context, g *graph.Graph) ([]string, error) {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		existingIDs, err := c.fetchExistingResourceIDs(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		return obsoleteIDs(existingIDs, g), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]string), nil
+}
+
+// StoredTypeLabels returns the type labels the stored resources may carry,
+// those UpdateGraph removes from nodes of another type. It only reads.
+func (c *Client) StoredTypeLabels(ctx context.Context) ([]string, error) {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		return c.storedTypeLabels(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]string), nil
+}
+
+// obsoleteIDs returns, in sorted order, the existing IDs that are not nodes of g.
+func obsoleteIDs(existingIDs map[string]bool, g *graph.Graph) []string {
 	newIDs := make(map[string]bool, len(g.Nodes))
 	for _, node := range g.Nodes {
 		newIDs[node.ID] = true
 	}
 
-	// Find resources to delete
-	var idsToDelete []string
+	var obsolete []string
 	for existingID := range existingIDs {
 		if !newIDs[existingID] {
-			idsToDelete = append(idsToDelete, existingID)
+			obsolete = append(obsolete, existingID)
 		}
 	}
+	sort.Strings(obsolete)
+	return obsolete
+}
 
-	// Delete obsolete resources and their relationships
-	if len(idsToDelete) > 0 {
-		query := "UNWIND $obsoleteIds AS obsoleteId MATCH (n:Resource {id: obsoleteId}) DETACH DELETE n"
-		params := map[string]interface{}{"obsoleteIds": idsToDelete}
+// ObsoleteResourcesQuery returns the query applied to the $obsoleteIds parameter.
+// Soft deletion only stamps resources that are not already marked, so deleted_at
+// records when a resource first disappeared. With detach, it also removes their
+// relationships.
+func ObsoleteResourcesQuery(label string, softDelete, detach bool) string {
+	if softDelete {
+		query := fmt.Sprintf("UNWIND $obsoleteIds AS obsoleteId MATCH (n:%s {id: obsoleteId}) WHERE n.deleted IS NULL SET n.deleted = true, n.deleted_at = timestamp()", label)
+		if detach {
+			query += " WITH n MATCH (n)-[r]-() DELETE r"
+		}
+		return query
+	}
+	return fmt.Sprintf("UNWIND $obsoleteIds AS obsoleteId MATCH (n:%s {id: obsoleteId}) DETACH DELETE n", label)
+}
 
-		if _, err := tx.Run(ctx, query, params); err != nil {
-			return fmt.Errorf("failed to delete obsolete resources: %w", err)
+// SoftDeletedResources returns the IDs of resources marked as deleted, in ascending order.
+func (c *Client) SoftDeletedResources(ctx context.Context) ([]string, error) {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := fmt.Sprintf("MATCH (n:%s) WHERE n.deleted = true RETURN n.id AS id ORDER BY id", c.nodeLabel)
+		res, err := tx.Run(ctx, query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var ids []string
+		for res.Next(ctx) {
+			if id, ok := res.Record().Get("id"); ok {
+				if idStr, ok := id.(string); ok {
+					ids = append(ids, idStr)
+				}
+			}
 		}
+		return ids, res.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query soft-deleted resources: %w", err)
 	}
 
-	return nil
+	return result.([]string), nil
+}
+
+// PurgeSoftDeleted permanently removes resources marked as deleted and returns how many were removed.
+func (c *Client) PurgeSoftDeleted(ctx context.Context) (int64, error) {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := fmt.Sprintf("MATCH (n:%s) WHERE n.deleted = true DETACH DELETE n", c.nodeLabel)
+		res, err := tx.Run(ctx, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := res.Consume(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return int64(summary.Counters().NodesDeleted()), nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge soft-deleted resources: %w", err)
+	}
+
+	return result.(int64), nil
 }
 
-// upsertGraph inserts or updates the current graph state in Neo4j.
-func (c *Client) upsertGraph(ctx context.Context, tx neo4j.ManagedTransaction, g *graph.Graph) (interface{}, error) {
-	query, params := formatter.ToCypherTransaction(g)
+// upsertGraph inserts or updates the current graph state in Neo4j. With type
+// labels, the labels of the types already stored are removed from nodes of
+// another type.
+func (c *Client) upsertGraph(ctx context.Context, tx neo4j.ManagedTransaction, g *graph.Graph, opts formatter.CypherOptions) (interface{}, error) {
+	if opts.TypeLabels && len(g.Nodes) > 0 {
+		stale, err := c.storedTypeLabels(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		opts.StaleTypeLabels = stale
+	}
+
+	query, params := formatter.ToCypherTransaction(g, opts)
 	result, err := tx.Run(ctx, query, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upsert graph: %w", err)
 	}
 	return result.Consume(ctx)
 }
+
+// storedTypeLabels returns the sanitized types of the stored resources, the
+// type labels they may carry.
+func (c *Client) storedTypeLabels(ctx context.Context, tx neo4j.ManagedTransaction) ([]string, error) {
+	result, err := tx.Run(ctx, fmt.Sprintf("MATCH (n:%s) RETURN DISTINCT n.type AS type", c.nodeLabel), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing resource types: %w", err)
+	}
+
+	var labels []string
+	for result.Next(ctx) {
+		if resourceType, ok := result.Record().Get("type"); ok {
+			if resourceType, ok := resourceType.(string); ok && resourceType != "" {
+				labels = append(labels, formatter.SanitizeLabel(resourceType))
+			}
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate existing resource types: %w", err)
+	}
+	return labels, nil
+}
@@ -0,0 +1,285 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"terraform-graphx/internal/formatter"
+	"terraform-graphx/internal/graph"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func TestObsoleteResourcesQuery(t *testing.T) {
+	hard := ObsoleteResourcesQuery("Resource", false, false)
+	if !strings.Contains(hard, "DETACH DELETE n") {
+		t.Errorf("Expected hard delete query to detach delete, got: %s", hard)
+	}
+
+	soft := ObsoleteResourcesQuery("Resource", true, false)
+	if strings.Contains(soft, "DELETE") {
+		t.Errorf("Soft delete query must not delete nodes, got: %s", soft)
+	}
+	if !strings.Contains(soft, "SET n.deleted = true, n.deleted_at = timestamp()") {
+		t.Errorf("Expected soft delete query to mark nodes, got: %s", soft)
+	}
+	// Already-marked resources keep their original deleted_at
+	if !strings.Contains(soft, "WHERE n.deleted IS NULL") {
+		t.Errorf("Expected soft delete query to skip already-deleted nodes, got: %s", soft)
+	}
+
+	detached := ObsoleteResourcesQuery("Resource", true, true)
+	if !strings.HasPrefix(detached, soft) || !strings.HasSuffix(detached, "MATCH (n)-[r]-() DELETE r") {
+		t.Errorf("Expected detached soft delete query to remove relationships only, got: %s", detached)
+	}
+}
+
+func TestObsoleteResourcesQueryLabel(t *testing.T) {
+	for _, softDelete := range []bool{false, true} {
+		query := ObsoleteResourcesQuery("TerraformResource", softDelete, true)
+		if !strings.Contains(query, "MATCH (n:TerraformResource {id: obsoleteId})") {
+			t.Errorf("Expected custom label in query, got: %s", query)
+		}
+	}
+}
+
+func TestObsoleteIDs(t *testing.T) {
+	existing := map[string]bool{"aws_vpc.main": true, "aws_eip.old": true, "aws_instance.gone": true}
+	g := &graph.Graph{Nodes: []graph.Node{{ID: "aws_vpc.main"}, {ID: "aws_subnet.new"}}}
+
+	got := obsoleteIDs(existing, g)
+	want := []string{"aws_eip.old", "aws_instance.gone"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("obsoleteIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestChangeSetSubgraph(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_vpc.main"}, {ID: "aws_subnet.a"}, {ID: "aws_instance.web"}},
+		Edges: []graph.Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main"},
+			{From: "aws_instance.web", To: "aws_subnet.a"},
+		},
+	}
+	changes := &ChangeSet{Changed: []string{"aws_instance.web"}, Deleted: []string{"aws_eip.old"}}
+
+	sub := changes.Subgraph(g)
+	if want := []graph.Node{{ID: "aws_instance.web"}}; !reflect.DeepEqual(sub.Nodes, want) {
+		t.Errorf("Subgraph() nodes = %v, want %v", sub.Nodes, want)
+	}
+	if want := []graph.Edge{{From: "aws_instance.web", To: "aws_subnet.a"}}; !reflect.DeepEqual(sub.Edges, want) {
+		t.Errorf("Subgraph() edges = %v, want %v", sub.Edges, want)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}},
+		Edges: []graph.Edge{{From: "a", To: "b"}, {From: "b", To: "c"}, {From: "c", To: "d"}},
+	}
+
+	batches := splitBatches(g, 2)
+	var sizes [][2]int
+	for _, b := range batches {
+		sizes = append(sizes, [2]int{len(b.Nodes), len(b.Edges)})
+	}
+	want := [][2]int{{2, 0}, {2, 0}, {1, 0}, {0, 2}, {0, 1}}
+	if !reflect.DeepEqual(sizes, want) {
+		t.Errorf("splitBatches() sizes = %v, want %v", sizes, want)
+	}
+}
+
+// fakeWriter records the batches written and fails the attempts listed in failures.
+type fakeWriter struct {
+	attempts int
+	written  []*graph.Graph
+	failures map[int]bool
+}
+
+func (f *fakeWriter) write(ctx context.Context, b *graph.Graph) error {
+	f.attempts++
+	if f.failures[f.attempts] {
+		return errors.New("connection reset")
+	}
+	f.written = append(f.written, b)
+	return nil
+}
+
+func TestWriteBatchesRetriesOnlyTheFailedBatch(t *testing.T) {
+	batchRetryDelay = 0
+	t.Cleanup(func() { batchRetryDelay = time.Second })
+
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []graph.Edge{{From: "a", To: "b"}, {From: "b", To: "c"}},
+	}
+	batches := splitBatches(g, 2)
+	// The third batch, the first of relationships, fails twice before succeeding
+	writer := &fakeWriter{failures: map[int]bool{3: true, 4: true}}
+
+	var progress []BatchProgress
+	err := writeBatches(context.Background(), batches, writer.write, 2, func(p BatchProgress) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatalf("writeBatches failed: %v", err)
+	}
+
+	if writer.attempts != 5 || len(writer.written) != 3 {
+		t.Errorf("Expected 5 attempts writing 3 batches, got %d attempts and %d batches", writer.attempts, len(writer.written))
+	}
+	want := []BatchProgress{
+		{Batch: 1, Batches: 3, Nodes: 2},
+		{Batch: 2, Batches: 3, Nodes: 1},
+		{Batch: 3, Batches: 3, Relationships: 2},
+	}
+	if !reflect.DeepEqual(progress, want) {
+		t.Errorf("Progress = %v, want %v", progress, want)
+	}
+}
+
+func TestWriteBatchesGivesUp(t *testing.T) {
+	batchRetryDelay = 0
+	t.Cleanup(func() { batchRetryDelay = time.Second })
+
+	batches := splitBatches(&graph.Graph{Nodes: []graph.Node{{ID: "a"}, {ID: "b"}}}, 1)
+	writer := &fakeWriter{failures: map[int]bool{2: true, 3: true}}
+
+	err := writeBatches(context.Background(), batches, writer.write, 1, nil)
+	if err == nil || !strings.Contains(err.Error(), "batch 2/2") {
+		t.Errorf("Expected batch 2/2 to fail, got %v", err)
+	}
+	if len(writer.written) != 1 {
+		t.Errorf("Expected the first batch to stay written, got %d batches", len(writer.written))
+	}
+}
+
+func TestPollSucceedsAfterRetries(t *testing.T) {
+	calls, retries := 0, 0
+	check := func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	err := poll(context.Background(), time.Second, time.Millisecond, check, func(error) { retries++ })
+	if err != nil {
+		t.Fatalf("Expected poll to succeed, got: %v", err)
+	}
+	if calls != 3 || retries != 2 {
+		t.Errorf("Expected 3 calls and 2 retries, got %d and %d", calls, retries)
+	}
+}
+
+func TestPollTimesOut(t *testing.T) {
+	check := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	err := poll(context.Background(), 20*time.Millisecond, 5*time.Millisecond, check, nil)
+	if err == nil {
+		t.Fatal("Expected a timeout error")
+	}
+	if !strings.Contains(err.Error(), "timed out after 20ms") || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected a timeout error wrapping the last failure, got: %v", err)
+	}
+}
+
+// fakeStore keeps resource properties by ID and applies the queries of
+// UpdateGraph to them, recognising each by its text.
+type fakeStore struct {
+	nodes map[string]map[string]interface{}
+}
+
+func (s *fakeStore) run(cypher string, params map[string]any) []*neo4j.Record {
+	switch {
+	case strings.HasPrefix(cypher, "MATCH (n:Resource) RETURN n.id"):
+		var records []*neo4j.Record
+		for id := range s.nodes {
+			records = append(records, &neo4j.Record{Keys: []string{"id"}, Values: []interface{}{id}})
+		}
+		return records
+	case strings.HasPrefix(cypher, "UNWIND $obsoleteIds"):
+		for _, id := range params["obsoleteIds"].([]string) {
+			node := s.nodes[id]
+			if strings.Contains(cypher, "WHERE n.deleted IS NULL") && node["deleted"] == nil {
+				node["deleted"] = true
+				node["deleted_at"] = int64(len(s.nodes))
+			}
+		}
+	case strings.HasPrefix(cypher, "UNWIND $nodes"):
+		for _, data := range params["nodes"].([]map[string]interface{}) {
+			id := data["id"].(string)
+			if s.nodes[id] == nil {
+				s.nodes[id] = map[string]interface{}{"id": id}
+			}
+			s.nodes[id]["type"] = data["type"]
+			if strings.Contains(cypher, "REMOVE n.deleted, n.deleted_at") {
+				delete(s.nodes[id], "deleted")
+				delete(s.nodes[id], "deleted_at")
+			}
+		}
+	}
+	return nil
+}
+
+func TestUpdateGraphRestoresSoftDeleted(t *testing.T) {
+	store := &fakeStore{nodes: map[string]map[string]interface{}{}}
+	client := &Client{Driver: &fakeDriver{run: store.run}, nodeLabel: formatter.DefaultNodeLabel}
+	ctx := context.Background()
+	opts := UpdateOptions{SoftDelete: true}
+
+	full := &graph.Graph{Nodes: []graph.Node{{ID: "aws_vpc.main", Type: "aws_vpc"}, {ID: "aws_eip.old", Type: "aws_eip"}}}
+	if err := client.UpdateGraph(ctx, full, opts); err != nil {
+		t.Fatalf("UpdateGraph failed: %v", err)
+	}
+	without := &graph.Graph{Nodes: full.Nodes[:1]}
+	if err := client.UpdateGraph(ctx, without, opts); err != nil {
+		t.Fatalf("UpdateGraph failed: %v", err)
+	}
+	if store.nodes["aws_eip.old"]["deleted"] != true || store.nodes["aws_vpc.main"]["deleted"] != nil {
+		t.Fatalf("Expected only the missing resource to be soft-deleted, got %v", store.nodes)
+	}
+
+	if err := client.UpdateGraph(ctx, full, opts); err != nil {
+		t.Fatalf("UpdateGraph failed: %v", err)
+	}
+	restored := store.nodes["aws_eip.old"]
+	if _, ok := restored["deleted"]; ok {
+		t.Errorf("Expected the re-added resource to be restored, got %v", restored)
+	}
+	if _, ok := restored["deleted_at"]; ok {
+		t.Errorf("Expected deleted_at to be cleared, got %v", restored)
+	}
+}
+
+func TestResourceIDs(t *testing.T) {
+	var query string
+	var params map[string]any
+	driver := &fakeDriver{run: func(cypher string, p map[string]any) []*neo4j.Record {
+		query, params = cypher, p
+		return []*neo4j.Record{
+			{Keys: []string{"id"}, Values: []interface{}{"aws_subnet.a"}},
+			{Keys: []string{"id"}, Values: []interface{}{"aws_subnet.b"}},
+		}
+	}}
+	client := &Client{Driver: driver, nodeLabel: "TerraformResource"}
+
+	ids, err := client.ResourceIDs(context.Background(), "aws_sub", 10)
+	if err != nil {
+		t.Fatalf("ResourceIDs failed: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"aws_subnet.a", "aws_subnet.b"}) {
+		t.Errorf("Expected the returned IDs, got %v", ids)
+	}
+	if !strings.Contains(query, "MATCH (n:TerraformResource) WHERE n.id STARTS WITH $prefix") || !strings.Contains(query, "LIMIT $limit") {
+		t.Errorf("Expected a prefix query on the node label, got %s", query)
+	}
+	if params["prefix"] != "aws_sub" || params["limit"] != 10 {
+		t.Errorf("Expected the prefix and limit as parameters, got %v", params)
+	}
+}
@@ -0,0 +1,360 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"terraform-graphx/internal/formatter"
+	"terraform-graphx/internal/graph"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// EdgeChange identifies a relationship in a GraphDiff.
+type EdgeChange struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// String renders the relationship as (from)-[RELATION]->(to).
+func (e EdgeChange) String() string {
+	return fmt.Sprintf("(%s)-[%s]->(%s)", e.From, e.Relation, e.To)
+}
+
+// GraphDiff lists what an update would change in the database. Node lists
+// hold resource IDs; every list is sorted.
+type GraphDiff struct {
+	AddedNodes   []string     `json:"added_nodes"`
+	UpdatedNodes []string     `json:"updated_nodes"`
+	DeletedNodes []string     `json:"deleted_nodes"`
+	AddedEdges   []EdgeChange `json:"added_edges"`
+	UpdatedEdges []EdgeChange `json:"updated_edges"`
+	DeletedEdges []EdgeChange `json:"deleted_edges"`
+}
+
+// Empty reports whether the update would change nothing.
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedNodes)+len(d.UpdatedNodes)+len(d.DeletedNodes)+
+		len(d.AddedEdges)+len(d.UpdatedEdges)+len(d.DeletedEdges) == 0
+}
+
+// Summary counts the changes, e.g. "2 resource(s) added, 1 updated, 0 deleted;
+// 3 relationship(s) added, 0 updated, 1 deleted".
+func (d *GraphDiff) Summary() string {
+	return fmt.Sprintf("%d resource(s) added, %d updated, %d deleted; %d relationship(s) added, %d updated, %d deleted",
+		len(d.AddedNodes), len(d.UpdatedNodes), len(d.DeletedNodes),
+		len(d.AddedEdges), len(d.UpdatedEdges), len(d.DeletedEdges))
+}
+
+// Diff compares g with the database and returns what UpdateGraph would change
+// with opts. It only reads. Timestamps and run IDs, which every update
+// stamps, are not compared.
+func (c *Client) Diff(ctx context.Context, g *graph.Graph, opts UpdateOptions) (*GraphDiff, error) {
+	opts.Cypher.NodeLabel = c.nodeLabel
+
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		return c.fetchStoredGraph(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	stored := result.(*storedState)
+	return diffGraph(stored.graph, stored.labels, g, opts), nil
+}
+
+// storedState is what fetchStoredGraph reads from the database.
+type storedState struct {
+	graph *graph.Graph
+	// labels holds the labels of each resource by ID.
+	labels map[string][]string
+}
+
+// fetchStoredGraph reads the resources, their labels and the relationships
+// among them. Each node's Attributes hold all of its properties, deleted
+// included.
+func (c *Client) fetchStoredGraph(ctx context.Context, tx neo4j.ManagedTransaction) (*storedState, error) {
+	stored := &graph.Graph{}
+	labels := make(map[string][]string)
+
+	nodes, err := tx.Run(ctx, fmt.Sprintf("MATCH (n:%s) RETURN properties(n) AS properties, labels(n) AS labels", c.nodeLabel), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing resources: %w", err)
+	}
+	for nodes.Next(ctx) {
+		record := nodes.Record()
+		if properties, ok := record.Get("properties"); ok {
+			if properties, ok := properties.(map[string]interface{}); ok {
+				node := storedNode(properties)
+				stored.Nodes = append(stored.Nodes, node)
+				nodeLabels, _ := record.Get("labels")
+				for _, label := range asList(nodeLabels) {
+					if label, ok := label.(string); ok {
+						labels[node.ID] = append(labels[node.ID], label)
+					}
+				}
+			}
+		}
+	}
+	if err := nodes.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate existing resources: %w", err)
+	}
+
+	query := fmt.Sprintf("MATCH (from:%s)-[r]->(to:%s) RETURN from.id AS from, to.id AS to, type(r) AS type, r.relation_kind AS kind", c.nodeLabel, c.nodeLabel)
+	edges, err := tx.Run(ctx, query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing relationships: %w", err)
+	}
+	for edges.Next(ctx) {
+		record := edges.Record()
+		from, _ := record.Get("from")
+		to, _ := record.Get("to")
+		relation, _ := record.Get("type")
+		kind, _ := record.Get("kind")
+		edge := graph.Edge{}
+		edge.From, _ = from.(string)
+		edge.To, _ = to.(string)
+		edge.Relation, _ = relation.(string)
+		edge.Kind, _ = kind.(string)
+		stored.Edges = append(stored.Edges, edge)
+	}
+	if err := edges.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate existing relationships: %w", err)
+	}
+
+	return &storedState{graph: stored, labels: labels}, nil
+}
+
+// asList returns value as a list, or nil if it is not one.
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// storedNode turns the properties of a stored resource into a node.
+func storedNode(properties map[string]interface{}) graph.Node {
+	node := graph.Node{Attributes: properties}
+	node.ID, _ = properties["id"].(string)
+	node.Type, _ = properties["type"].(string)
+	node.Provider, _ = properties["provider"].(string)
+	node.Name, _ = properties["name"].(string)
+	if count, ok := properties["dependents_count"].(int64); ok {
+		node.DependentsCount = int(count)
+	}
+	if count, ok := properties["dependencies_count"].(int64); ok {
+		node.DependenciesCount = int(count)
+	}
+	return node
+}
+
+// diffGraph returns what writing g over stored, whose resources carry labels,
+// with opts changes. It follows UpdateGraph: with opts.Changes only the
+// changed part of g is written and the planned deletions applied, obsolete
+// resources are only removed without opts.KeepObsolete, and soft-deleted
+// resources keep their relationships unless opts.DetachSoftDeleted.
+func diffGraph(stored *graph.Graph, labels map[string][]string, g *graph.Graph, opts UpdateOptions) *GraphDiff {
+	diff := &GraphDiff{}
+
+	storedNodes := make(map[string]graph.Node, len(stored.Nodes))
+	storedIDs := make(map[string]bool, len(stored.Nodes))
+	for _, node := range stored.Nodes {
+		storedNodes[node.ID] = node
+		storedIDs[node.ID] = true
+	}
+
+	if !opts.KeepObsolete {
+		deleted := obsoleteIDs(storedIDs, g)
+		if opts.Changes != nil {
+			deleted = opts.Changes.Deleted
+		}
+		for _, id := range deleted {
+			node, ok := storedNodes[id]
+			if !ok || (opts.SoftDelete && node.Attributes["deleted"] == true) {
+				continue
+			}
+			diff.DeletedNodes = append(diff.DeletedNodes, id)
+		}
+	}
+
+	written := g
+	if opts.Changes != nil {
+		written = opts.Changes.Subgraph(g)
+	}
+	removable := removableTypeLabels(stored, written, opts.Cypher)
+	for _, node := range written.Nodes {
+		existing, ok := storedNodes[node.ID]
+		switch {
+		case !ok:
+			diff.AddedNodes = append(diff.AddedNodes, node.ID)
+		case nodeChanged(existing, node, opts.Cypher):
+			diff.UpdatedNodes = append(diff.UpdatedNodes, node.ID)
+		case opts.Cypher.TypeLabels && typeLabelsChanged(labels[node.ID], formatter.SanitizeLabel(node.Type), removable):
+			diff.UpdatedNodes = append(diff.UpdatedNodes, node.ID)
+		}
+	}
+
+	storedEdges := make(map[EdgeChange]string, len(stored.Edges))
+	for _, edge := range stored.Edges {
+		storedEdges[EdgeChange{edge.From, edge.To, edge.Relation}] = edge.Kind
+	}
+	seen := make(map[EdgeChange]bool, len(written.Edges))
+	for _, edge := range written.Edges {
+		key := EdgeChange{edge.From, edge.To, formatter.EdgeRelation(edge)}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kind, ok := storedEdges[key]
+		switch {
+		case !ok:
+			diff.AddedEdges = append(diff.AddedEdges, key)
+		case kind != edge.Kind:
+			diff.UpdatedEdges = append(diff.UpdatedEdges, key)
+		}
+	}
+
+	// Hard deletes, and soft deletes with DetachSoftDeleted, detach the
+	// relationships of the deleted resources
+	if (!opts.SoftDelete || opts.DetachSoftDeleted) && len(diff.DeletedNodes) > 0 {
+		deleted := make(map[string]bool, len(diff.DeletedNodes))
+		for _, id := range diff.DeletedNodes {
+			deleted[id] = true
+		}
+		for key := range storedEdges {
+			if deleted[key.From] || deleted[key.To] {
+				diff.DeletedEdges = append(diff.DeletedEdges, key)
+			}
+		}
+	}
+
+	sort.Strings(diff.AddedNodes)
+	sort.Strings(diff.UpdatedNodes)
+	sort.Strings(diff.DeletedNodes)
+	for _, edges := range [][]EdgeChange{diff.AddedEdges, diff.UpdatedEdges, diff.DeletedEdges} {
+		sort.Slice(edges, func(i, j int) bool { return edges[i].String() < edges[j].String() })
+	}
+	return diff
+}
+
+// nodeChanged reports whether writing node changes the stored resource: a
+// different core property, persisted attribute or lifted tag, or a
+// soft-deleted resource being restored.
+func nodeChanged(stored, node graph.Node, opts formatter.CypherOptions) bool {
+	if stored.Type != node.Type || stored.Provider != node.Provider || stored.Name != node.Name ||
+		stored.DependentsCount != node.DependentsCount || stored.DependenciesCount != node.DependenciesCount {
+		return true
+	}
+	if stored.Attributes["deleted"] == true || stored.Attributes["module"] != graph.ModulePathOf(node.ID) {
+		return true
+	}
+
+	attributes := formatter.NodeAttributes(node, opts)
+	for key, value := range attributes {
+		if !propertyEqual(stored.Attributes[key], value) {
+			return true
+		}
+	}
+	return false
+}
+
+// removableTypeLabels returns the type labels UpdateGraph removes from the
+// nodes of written that are not of their type: those of the stored types and
+// of written's types.
+func removableTypeLabels(stored, written *graph.Graph, opts formatter.CypherOptions) map[string]bool {
+	if !opts.TypeLabels {
+		return nil
+	}
+	var typeLabels, storedLabels []string
+	for _, node := range written.Nodes {
+		typeLabels = append(typeLabels, formatter.SanitizeLabel(node.Type))
+	}
+	for _, node := range stored.Nodes {
+		storedLabels = append(storedLabels, formatter.SanitizeLabel(node.Type))
+	}
+	removable := make(map[string]bool)
+	for _, label := range formatter.RemovableTypeLabels(typeLabels, storedLabels, opts.NodeLabel) {
+		removable[label] = true
+	}
+	return removable
+}
+
+// typeLabelsChanged reports whether a node with labels needs its type label
+// set, or loses one of the removable labels other than its own.
+func typeLabelsChanged(labels []string, own string, removable map[string]bool) bool {
+	hasOwn := own == ""
+	for _, label := range labels {
+		switch {
+		case label == own:
+			hasOwn = true
+		case removable[label]:
+			return true
+		}
+	}
+	return !hasOwn
+}
+
+// propertyEqual compares a stored property with the value written for it.
+// Neo4j returns integers as int64 while plan JSON numbers are float64, so
+// numbers are compared by value.
+func propertyEqual(stored, value interface{}) bool {
+	if a, ok := toFloat(stored); ok {
+		b, ok := toFloat(value)
+		return ok && a == b
+	}
+
+	storedList, ok := stored.([]interface{})
+	if !ok {
+		return stored == value
+	}
+	list, ok := value.([]interface{})
+	if !ok || len(list) != len(storedList) {
+		return false
+	}
+	for i := range list {
+		if !propertyEqual(storedList[i], list[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// toFloat returns value as a float64 if it is a number.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// FormatDiff renders diff line by line: + for additions, ~ for updates and
+// - for deletions.
+func FormatDiff(diff *GraphDiff) string {
+	var out strings.Builder
+	for _, change := range []struct {
+		marker string
+		ids    []string
+	}{{"+", diff.AddedNodes}, {"~", diff.UpdatedNodes}, {"-", diff.DeletedNodes}} {
+		for _, id := range change.ids {
+			fmt.Fprintf(&out, "%s %s\n", change.marker, id)
+		}
+	}
+	for _, change := range []struct {
+		marker string
+		edges  []EdgeChange
+	}{{"+", diff.AddedEdges}, {"~", diff.UpdatedEdges}, {"-", diff.DeletedEdges}} {
+		for _, edge := range change.edges {
+			fmt.Fprintf(&out, "%s %s\n", change.marker, edge)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,296 @@
+package neo4j
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"terraform-graphx/internal/formatter"
+	"terraform-graphx/internal/graph"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// storedGraph is the database content the diffs are computed against, as
+// fetchStoredGraph reads it.
+func storedGraph() *graph.Graph {
+	return &graph.Graph{
+		Nodes: []graph.Node{
+			storedNode(map[string]interface{}{"id": "aws_vpc.main", "type": "aws_vpc", "provider": "aws", "name": "main", "module": "", "dependents_count": int64(1), "dependencies_count": int64(0), "cidr_block": "10.0.0.0/16"}),
+			storedNode(map[string]interface{}{"id": "aws_subnet.a", "type": "aws_subnet", "provider": "aws", "name": "a", "module": "", "dependents_count": int64(0), "dependencies_count": int64(1)}),
+			storedNode(map[string]interface{}{"id": "aws_eip.old", "type": "aws_eip", "provider": "aws", "name": "old", "module": "", "dependents_count": int64(0), "dependencies_count": int64(1)}),
+		},
+		Edges: []graph.Edge{
+			{From: "aws_subnet.a", To: "aws_vpc.main", Relation: formatter.DefaultRelation, Kind: graph.KindImplicit},
+			{From: "aws_eip.old", To: "aws_vpc.main", Relation: formatter.DefaultRelation},
+		},
+	}
+}
+
+// currentGraph matches storedGraph apart from aws_eip.old.
+func currentGraph() *graph.Graph {
+	return &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws", Name: "main", DependentsCount: 1, Attributes: map[string]interface{}{"cidr_block": "10.0.0.0/16"}},
+			{ID: "aws_subnet.a", Type: "aws_subnet", Provider: "aws", Name: "a", DependenciesCount: 1},
+		},
+		Edges: []graph.Edge{{From: "aws_subnet.a", To: "aws_vpc.main", Kind: graph.KindImplicit}},
+	}
+}
+
+func TestDiffGraphUnchanged(t *testing.T) {
+	diff := diffGraph(storedGraph(), nil, currentGraph(), UpdateOptions{KeepObsolete: true})
+	if !diff.Empty() {
+		t.Errorf("Expected no changes without pruning, got %+v", diff)
+	}
+}
+
+func TestDiffGraphPrune(t *testing.T) {
+	diff := diffGraph(storedGraph(), nil, currentGraph(), UpdateOptions{})
+
+	if !reflect.DeepEqual(diff.DeletedNodes, []string{"aws_eip.old"}) {
+		t.Errorf("Expected the obsolete resource to be deleted, got %v", diff.DeletedNodes)
+	}
+	expected := []EdgeChange{{From: "aws_eip.old", To: "aws_vpc.main", Relation: formatter.DefaultRelation}}
+	if !reflect.DeepEqual(diff.DeletedEdges, expected) {
+		t.Errorf("Expected its relationship to be detached, got %v", diff.DeletedEdges)
+	}
+
+	// Soft deletes keep the relationships, and resources already marked stay unchanged
+	diff = diffGraph(storedGraph(), nil, currentGraph(), UpdateOptions{SoftDelete: true})
+	if len(diff.DeletedNodes) != 1 || len(diff.DeletedEdges) != 0 {
+		t.Errorf("Expected a soft delete without detached relationships, got %+v", diff)
+	}
+	diff = diffGraph(storedGraph(), nil, currentGraph(), UpdateOptions{SoftDelete: true, DetachSoftDeleted: true})
+	if len(diff.DeletedNodes) != 1 || !reflect.DeepEqual(diff.DeletedEdges, expected) {
+		t.Errorf("Expected a soft delete with detached relationships, got %+v", diff)
+	}
+	stored := storedGraph()
+	stored.Nodes[2].Attributes["deleted"] = true
+	if diff := diffGraph(stored, nil, currentGraph(), UpdateOptions{SoftDelete: true}); !diff.Empty() {
+		t.Errorf("Expected an already soft-deleted resource to be unchanged, got %+v", diff)
+	}
+}
+
+func TestDiffGraphAdditionsAndUpdates(t *testing.T) {
+	g := currentGraph()
+	g.Nodes = append(g.Nodes, graph.Node{ID: "aws_instance.web", Type: "aws_instance", Provider: "aws", Name: "web", DependenciesCount: 1})
+	g.Nodes[0].DependentsCount = 2
+	g.Nodes[1].Attributes = map[string]interface{}{"map_public_ip_on_launch": true}
+	g.Edges[0].Kind = graph.KindExplicit
+	g.Edges = append(g.Edges, graph.Edge{From: "aws_instance.web", To: "aws_subnet.a"})
+
+	opts := UpdateOptions{KeepObsolete: true}
+	opts.Cypher.AttributeAllowlist = []string{"cidr_block", "map_public_ip_on_launch"}
+	diff := diffGraph(storedGraph(), nil, g, opts)
+
+	if !reflect.DeepEqual(diff.AddedNodes, []string{"aws_instance.web"}) {
+		t.Errorf("Expected the new resource to be added, got %v", diff.AddedNodes)
+	}
+	// The vpc's dependents count and the subnet's new persisted attribute changed
+	if !reflect.DeepEqual(diff.UpdatedNodes, []string{"aws_subnet.a", "aws_vpc.main"}) {
+		t.Errorf("Expected both existing resources to be updated, got %v", diff.UpdatedNodes)
+	}
+	if !reflect.DeepEqual(diff.AddedEdges, []EdgeChange{{From: "aws_instance.web", To: "aws_subnet.a", Relation: formatter.DefaultRelation}}) {
+		t.Errorf("Expected the new relationship to be added, got %v", diff.AddedEdges)
+	}
+	if !reflect.DeepEqual(diff.UpdatedEdges, []EdgeChange{{From: "aws_subnet.a", To: "aws_vpc.main", Relation: formatter.DefaultRelation}}) {
+		t.Errorf("Expected the relationship with a new kind to be updated, got %v", diff.UpdatedEdges)
+	}
+
+	summary := diff.Summary()
+	if summary != "1 resource(s) added, 2 updated, 0 deleted; 1 relationship(s) added, 1 updated, 0 deleted" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+	formatted := FormatDiff(diff)
+	for _, want := range []string{"+ aws_instance.web\n", "~ aws_vpc.main\n", "+ (aws_instance.web)-[DEPENDS_ON]->(aws_subnet.a)\n"} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("Expected %q in:\n%s", want, formatted)
+		}
+	}
+}
+
+func TestDiffGraphRestoresSoftDeleted(t *testing.T) {
+	stored := storedGraph()
+	stored.Nodes[1].Attributes["deleted"] = true
+
+	diff := diffGraph(stored, nil, currentGraph(), UpdateOptions{KeepObsolete: true})
+	if !reflect.DeepEqual(diff.UpdatedNodes, []string{"aws_subnet.a"}) {
+		t.Errorf("Expected the soft-deleted resource to be restored, got %v", diff.UpdatedNodes)
+	}
+}
+
+func TestDiffGraphIncremental(t *testing.T) {
+	g := currentGraph()
+	g.Nodes[1].Name = "renamed"
+	g.Nodes[0].DependentsCount = 5
+
+	opts := UpdateOptions{Changes: &ChangeSet{Changed: []string{"aws_subnet.a"}, Deleted: []string{"aws_eip.old", "aws_eip.unknown"}}}
+	diff := diffGraph(storedGraph(), nil, g, opts)
+
+	// Only the changed resource is written, so the vpc's count is not compared
+	if !reflect.DeepEqual(diff.UpdatedNodes, []string{"aws_subnet.a"}) {
+		t.Errorf("Expected only the changed resource to be updated, got %v", diff.UpdatedNodes)
+	}
+	if !reflect.DeepEqual(diff.DeletedNodes, []string{"aws_eip.old"}) {
+		t.Errorf("Expected only stored planned deletions, got %v", diff.DeletedNodes)
+	}
+}
+
+func TestDiffGraphModuleProperty(t *testing.T) {
+	stored := storedGraph()
+	delete(stored.Nodes[0].Attributes, "module")
+
+	diff := diffGraph(stored, nil, currentGraph(), UpdateOptions{KeepObsolete: true})
+	if !reflect.DeepEqual(diff.UpdatedNodes, []string{"aws_vpc.main"}) {
+		t.Errorf("Expected a resource stored without its module path to be updated, got %v", diff.UpdatedNodes)
+	}
+}
+
+func TestDiffGraphTypeLabels(t *testing.T) {
+	labels := map[string][]string{
+		"aws_vpc.main": {"Resource", "aws_vpc"},
+		"aws_subnet.a": {"Resource", "aws_subnet"},
+		"aws_eip.old":  {"Resource", "aws_eip"},
+	}
+	opts := UpdateOptions{KeepObsolete: true}
+	opts.Cypher.NodeLabel = "Resource"
+	opts.Cypher.TypeLabels = true
+
+	if diff := diffGraph(storedGraph(), labels, currentGraph(), opts); !diff.Empty() {
+		t.Errorf("Expected no changes with current type labels, got %+v", diff)
+	}
+
+	// The subnet still has the label of its previous type, the vpc no type label yet
+	labels["aws_subnet.a"] = []string{"Resource", "aws_subnet", "aws_eip"}
+	labels["aws_vpc.main"] = []string{"Resource"}
+	diff := diffGraph(storedGraph(), labels, currentGraph(), opts)
+	if !reflect.DeepEqual(diff.UpdatedNodes, []string{"aws_subnet.a", "aws_vpc.main"}) {
+		t.Errorf("Expected both type label changes to be reported, got %v", diff.UpdatedNodes)
+	}
+
+	// Without type labels, update leaves them alone
+	opts.Cypher.TypeLabels = false
+	if diff := diffGraph(storedGraph(), labels, currentGraph(), opts); !diff.Empty() {
+		t.Errorf("Expected labels to be ignored without type labels, got %+v", diff)
+	}
+}
+
+// fakeDriver serves sessions over fixed query results, or over run when set.
+type fakeDriver struct {
+	neo4j.DriverWithContext
+	// results maps the start of a query to the records it returns.
+	results map[string][]*neo4j.Record
+	run     func(cypher string, params map[string]any) []*neo4j.Record
+}
+
+func (d *fakeDriver) NewSession(ctx context.Context, config neo4j.SessionConfig) neo4j.SessionWithContext {
+	return &fakeSession{driver: d}
+}
+
+type fakeSession struct {
+	neo4j.SessionWithContext
+	driver *fakeDriver
+}
+
+func (s *fakeSession) ExecuteRead(ctx context.Context, work neo4j.ManagedTransactionWork, configurers ...func(*neo4j.TransactionConfig)) (any, error) {
+	return work(&fakeTransaction{driver: s.driver})
+}
+
+func (s *fakeSession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork, configurers ...func(*neo4j.TransactionConfig)) (any, error) {
+	return work(&fakeTransaction{driver: s.driver})
+}
+
+func (s *fakeSession) Close(ctx context.Context) error {
+	return nil
+}
+
+type fakeTransaction struct {
+	neo4j.ManagedTransaction
+	driver *fakeDriver
+}
+
+func (tx *fakeTransaction) Run(ctx context.Context, cypher string, params map[string]any) (neo4j.ResultWithContext, error) {
+	if tx.driver.run != nil {
+		return &fakeResult{records: tx.driver.run(cypher, params), next: -1}, nil
+	}
+	for prefix, records := range tx.driver.results {
+		if strings.HasPrefix(cypher, prefix) {
+			return &fakeResult{records: records, next: -1}, nil
+		}
+	}
+	return &fakeResult{next: -1}, nil
+}
+
+type fakeResult struct {
+	neo4j.ResultWithContext
+	records []*neo4j.Record
+	next    int
+}
+
+func (r *fakeResult) Next(ctx context.Context) bool {
+	r.next++
+	return r.next < len(r.records)
+}
+
+func (r *fakeResult) Record() *neo4j.Record {
+	return r.records[r.next]
+}
+
+func (r *fakeResult) Err() error {
+	return nil
+}
+
+func (r *fakeResult) Consume(ctx context.Context) (neo4j.ResultSummary, error) {
+	return nil, nil
+}
+
+func TestDiffReadsTypeLabels(t *testing.T) {
+	node := func(id, resourceType string, labels ...interface{}) *neo4j.Record {
+		properties := map[string]interface{}{"id": id, "type": resourceType, "provider": "aws", "name": strings.Split(id, ".")[1], "module": ""}
+		return &neo4j.Record{Keys: []string{"properties", "labels"}, Values: []interface{}{properties, labels}}
+	}
+	driver := &fakeDriver{results: map[string][]*neo4j.Record{
+		"MATCH (n:Resource) RETURN properties(n)": {
+			node("aws_instance.web", "aws_instance", "Resource", "aws_instance"),
+			// Stored before its type changed from aws_instance
+			node("aws_spot_instance_request.batch", "aws_spot_instance_request", "Resource", "aws_instance", "aws_spot_instance_request"),
+		},
+	}}
+	client := &Client{Driver: driver, nodeLabel: formatter.DefaultNodeLabel}
+
+	g := &graph.Graph{Nodes: []graph.Node{
+		{ID: "aws_instance.web", Type: "aws_instance", Provider: "aws", Name: "web"},
+		{ID: "aws_spot_instance_request.batch", Type: "aws_spot_instance_request", Provider: "aws", Name: "batch"},
+	}}
+	opts := UpdateOptions{}
+	opts.Cypher.TypeLabels = true
+	diff, err := client.Diff(context.Background(), g, opts)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !reflect.DeepEqual(diff.UpdatedNodes, []string{"aws_spot_instance_request.batch"}) {
+		t.Errorf("Expected the stale type label to be reported, got %+v", diff)
+	}
+}
+
+func TestPropertyEqual(t *testing.T) {
+	tests := []struct {
+		stored, value interface{}
+		equal         bool
+	}{
+		{int64(3), 3.0, true},
+		{int64(3), 3, true},
+		{int64(3), 4.0, false},
+		{"a", "a", true},
+		{"3", 3.0, false},
+		{[]interface{}{"a", int64(1)}, []interface{}{"a", 1.0}, true},
+		{[]interface{}{"a"}, []interface{}{"a", "b"}, false},
+		{nil, "a", false},
+	}
+	for _, tt := range tests {
+		if got := propertyEqual(tt.stored, tt.value); got != tt.equal {
+			t.Errorf("propertyEqual(%v, %v) = %v, expected %v", tt.stored, tt.value, got, tt.equal)
+		}
+	}
+}
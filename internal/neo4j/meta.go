@@ -0,0 +1,61 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// GraphMetaLabel is the label of the node holding metadata about the graph
+// last written, one per resource label.
+const GraphMetaLabel = "GraphMeta"
+
+// Fingerprint returns the fingerprint stored by the last SetFingerprint, or
+// "" if there is none.
+func (c *Client) Fingerprint(ctx context.Context) (string, error) {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		query := fmt.Sprintf("MATCH (m:%s {label: $label}) RETURN m.fingerprint AS fingerprint", GraphMetaLabel)
+		res, err := tx.Run(ctx, query, map[string]interface{}{"label": c.nodeLabel})
+		if err != nil {
+			return nil, err
+		}
+
+		var fingerprint string
+		if res.Next(ctx) {
+			if value, ok := res.Record().Get("fingerprint"); ok {
+				fingerprint, _ = value.(string)
+			}
+		}
+		return fingerprint, res.Err()
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the graph fingerprint: %w", err)
+	}
+	return result.(string), nil
+}
+
+// SetFingerprint stores fingerprint on the GraphMeta node of the resource
+// label, creating the node if needed. An empty fingerprint clears it.
+func (c *Client) SetFingerprint(ctx context.Context, fingerprint string) error {
+	session := c.Driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	var value interface{}
+	if fingerprint != "" {
+		value = fingerprint
+	}
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		// Setting a property to null removes it
+		query := fmt.Sprintf("MERGE (m:%s {label: $label}) SET m.fingerprint = $fingerprint, m.updated_at = timestamp()", GraphMetaLabel)
+		_, err := tx.Run(ctx, query, map[string]interface{}{"label": c.nodeLabel, "fingerprint": value})
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store the graph fingerprint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package neo4j
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// uriSchemes lists the URI schemes the driver accepts. The neo4j schemes
+// route through a cluster, the bolt schemes connect to a single server; +s
+// requires a verified TLS certificate and +ssc accepts a self-signed one.
+var uriSchemes = []string{"bolt", "bolt+s", "bolt+ssc", "neo4j", "neo4j+s", "neo4j+ssc"}
+
+// IsRoutingURI reports whether uri uses a neo4j:// scheme, i.e. connects
+// through a cluster's routing table.
+func IsRoutingURI(uri string) bool {
+	return strings.HasPrefix(uri, "neo4j://") || strings.HasPrefix(uri, "neo4j+s://") || strings.HasPrefix(uri, "neo4j+ssc://")
+}
+
+// ValidateURI checks that uri has a supported scheme and a host. Only
+// neo4j:// URIs may carry a routing context in their query string.
+func ValidateURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid neo4j URI %q: %w", uri, err)
+	}
+	supported := false
+	for _, scheme := range uriSchemes {
+		if u.Scheme == scheme {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("invalid neo4j URI %q: unsupported scheme %q (supported: %s)", uri, u.Scheme, strings.Join(uriSchemes, ", "))
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid neo4j URI %q: no host", uri)
+	}
+	if u.RawQuery != "" && !IsRoutingURI(uri) {
+		return fmt.Errorf("invalid neo4j URI %q: a routing context requires a neo4j:// URI", uri)
+	}
+	return nil
+}
+
+// WithRoutingContext returns uri with routingContext added to its query
+// string, where the driver reads it from, e.g. neo4j://host?region=eu. Keys
+// already in uri are overridden. The routing context is only sent to
+// clusters, so it requires a neo4j:// URI.
+func WithRoutingContext(uri string, routingContext map[string]string) (string, error) {
+	if len(routingContext) == 0 {
+		return uri, nil
+	}
+	if !IsRoutingURI(uri) {
+		return "", fmt.Errorf("neo4j.routing_context requires a neo4j:// URI, got %q", uri)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid neo4j URI %q: %w", uri, err)
+	}
+	query := u.Query()
+	for key, value := range routingContext {
+		if key == "" {
+			return "", fmt.Errorf("neo4j.routing_context has an empty key")
+		}
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
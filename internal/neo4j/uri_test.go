@@ -0,0 +1,69 @@
+package neo4j
+
+import "testing"
+
+func TestValidateURI(t *testing.T) {
+	tests := []struct {
+		uri   string
+		valid bool
+	}{
+		{"bolt://localhost:7687", true},
+		{"bolt+s://db.example.com", true},
+		{"bolt+ssc://db.example.com:7687", true},
+		{"neo4j://cluster.example.com", true},
+		{"neo4j+s://cluster.example.com:7687", true},
+		{"neo4j+ssc://cluster.example.com", true},
+		{"neo4j://cluster.example.com?region=eu", true},
+		{"bolt://localhost:7687?region=eu", false},
+		{"http://localhost:7474", false},
+		{"localhost:7687", false},
+		{"neo4j://", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateURI(tt.uri)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateURI(%q) failed: %v", tt.uri, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("ValidateURI(%q) expected an error", tt.uri)
+		}
+	}
+}
+
+func TestIsRoutingURI(t *testing.T) {
+	for uri, want := range map[string]bool{
+		"neo4j://cluster":     true,
+		"neo4j+s://cluster":   true,
+		"neo4j+ssc://cluster": true,
+		"bolt://localhost":    false,
+		"bolt+s://localhost":  false,
+	} {
+		if got := IsRoutingURI(uri); got != want {
+			t.Errorf("IsRoutingURI(%q) = %v, want %v", uri, got, want)
+		}
+	}
+}
+
+func TestWithRoutingContext(t *testing.T) {
+	uri, err := WithRoutingContext("neo4j+s://cluster.example.com:7687?policy=old", map[string]string{"region": "eu", "policy": "reads"})
+	if err != nil {
+		t.Fatalf("WithRoutingContext failed: %v", err)
+	}
+	if want := "neo4j+s://cluster.example.com:7687?policy=reads&region=eu"; uri != want {
+		t.Errorf("WithRoutingContext() = %q, want %q", uri, want)
+	}
+
+	uri, err = WithRoutingContext("bolt://localhost:7687", nil)
+	if err != nil || uri != "bolt://localhost:7687" {
+		t.Errorf("Expected the URI unchanged without a routing context, got %q, %v", uri, err)
+	}
+
+	if _, err := WithRoutingContext("bolt://localhost:7687", map[string]string{"region": "eu"}); err == nil {
+		t.Error("Expected an error for a routing context on a bolt:// URI")
+	}
+	if _, err := WithRoutingContext("neo4j://cluster", map[string]string{"": "eu"}); err == nil {
+		t.Error("Expected an error for an empty routing context key")
+	}
+}
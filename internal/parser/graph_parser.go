@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"terraform-graphx/internal/graph"
 
@@ -23,8 +24,25 @@ func cleanLabel(label string) string {
 	return label
 }
 
+// nodeAddress returns the resource address of a DOT node: its cleaned label if
+// it has one, otherwise its cleaned name. Terraform 1.7 and later label the
+// resources of a module cluster relative to the module, e.g.
+// "aws_instance.web" for the node "module.app.aws_instance.web", so the name
+// is used when it ends with the label.
+func nodeAddress(name, label string) string {
+	address := cleanLabel(name)
+	if label == "" {
+		return address
+	}
+	if cleaned := cleanLabel(label); !strings.HasSuffix(address, "."+cleaned) {
+		return cleaned
+	}
+	return address
+}
+
 // ParseGraph converts a gographviz.Graph directly to our internal graph structure.
-// This eliminates the need for an intermediate JSON conversion step.
+// This eliminates the need for an intermediate JSON conversion step. Nodes and
+// edges are sorted, see graph.Sort.
 func ParseGraph(dotGraph *gographviz.Graph) (*graph.Graph, error) {
 	if dotGraph == nil {
 		return nil, fmt.Errorf("dotGraph cannot be nil")
@@ -38,18 +56,9 @@ func ParseGraph(dotGraph *gographviz.Graph) (*graph.Graph, error) {
 	nodeMap := make(map[string]string) // maps original node name -> cleaned address
 
 	// Extract nodes from gographviz
-	for nodeName, node := range dotGraph.Nodes.Lookup {
-		// Get the label if it exists, otherwise use the node name
-		label := nodeName
-		if node.Attrs != nil {
-			if labelAttr, ok := node.Attrs["label"]; ok {
-				label = labelAttr
-			}
-		}
-
-		// Clean up the label to get the resource address
-		address := cleanLabel(label)
-		nodeMap[nodeName] = address
+	for _, node := range dotGraph.Nodes.Nodes {
+		address := nodeAddress(node.Name, node.Attrs["label"])
+		nodeMap[node.Name] = address
 
 		// Extract type and name from the address
 		// Example: "aws_instance.web" -> type="aws_instance", name="web"
@@ -78,9 +87,69 @@ func ParseGraph(dotGraph *gographviz.Graph) (*graph.Graph, error) {
 				From:     fromAddr,
 				To:       toAddr,
 				Relation: "DEPENDS_ON",
+				Kind:     dataKind(toAddr),
 			})
 		}
 	}
 
+	graph.Sort(g)
 	return g, nil
 }
+
+// DOTInspection is the parser's view of a DOT graph, used for debugging missing
+// nodes or edges.
+type DOTInspection struct {
+	Nodes []DOTNodeInspection `json:"nodes"`
+	Edges []DOTEdgeInspection `json:"edges"`
+}
+
+// DOTNodeInspection shows how a DOT node was mapped to a resource address.
+type DOTNodeInspection struct {
+	Name    string `json:"name"`
+	Label   string `json:"label,omitempty"`
+	Address string `json:"address"`
+}
+
+// DOTEdgeInspection shows a DOT edge and whether both endpoints resolved to nodes.
+type DOTEdgeInspection struct {
+	Src      string `json:"src"`
+	Dst      string `json:"dst"`
+	Resolved bool   `json:"resolved"`
+}
+
+// InspectDOT returns the raw node names, labels and edges of a DOT graph
+// alongside the addresses ParseGraph derives from them, sorted by name.
+func InspectDOT(dotGraph *gographviz.Graph) (*DOTInspection, error) {
+	if dotGraph == nil {
+		return nil, fmt.Errorf("dotGraph cannot be nil")
+	}
+
+	inspection := &DOTInspection{
+		Nodes: make([]DOTNodeInspection, 0, len(dotGraph.Nodes.Nodes)),
+		Edges: make([]DOTEdgeInspection, 0, len(dotGraph.Edges.Edges)),
+	}
+
+	for nodeName, node := range dotGraph.Nodes.Lookup {
+		label := node.Attrs["label"]
+		inspection.Nodes = append(inspection.Nodes, DOTNodeInspection{
+			Name:    nodeName,
+			Label:   label,
+			Address: nodeAddress(nodeName, label),
+		})
+	}
+	sort.Slice(inspection.Nodes, func(i, j int) bool {
+		return inspection.Nodes[i].Name < inspection.Nodes[j].Name
+	})
+
+	for _, edge := range dotGraph.Edges.Edges {
+		_, okFrom := dotGraph.Nodes.Lookup[edge.Src]
+		_, okTo := dotGraph.Nodes.Lookup[edge.Dst]
+		inspection.Edges = append(inspection.Edges, DOTEdgeInspection{
+			Src:      edge.Src,
+			Dst:      edge.Dst,
+			Resolved: okFrom && okTo,
+		})
+	}
+
+	return inspection, nil
+}
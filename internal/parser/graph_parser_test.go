@@ -1,6 +1,11 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"terraform-graphx/internal/graph"
 	"testing"
 
 	"github.com/awalterschulze/gographviz"
@@ -124,3 +129,178 @@ func TestParseGraphNilInput(t *testing.T) {
 		t.Error("Expected error for nil input, got nil")
 	}
 }
+
+// syntheticChain builds a DOT graph of n null_resources where each depends on the previous one.
+func syntheticChain(tb testing.TB, n int) *gographviz.Graph {
+	tb.Helper()
+
+	var dot strings.Builder
+	dot.WriteString("digraph G {\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&dot, "\t\"null_resource.r%d\" [label=\"null_resource.r%d\"];\n", i, i)
+		if i > 0 {
+			fmt.Fprintf(&dot, "\t\"null_resource.r%d\" -> \"null_resource.r%d\";\n", i, i-1)
+		}
+	}
+	dot.WriteString("}\n")
+
+	graphAst, err := gographviz.ParseString(dot.String())
+	if err != nil {
+		tb.Fatalf("Failed to parse DOT string: %v", err)
+	}
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		tb.Fatalf("Failed to analyse graph: %v", err)
+	}
+	return dotGraph
+}
+
+func TestInspectDOT(t *testing.T) {
+	dotGraph := syntheticChain(t, 2)
+
+	inspection, err := InspectDOT(dotGraph)
+	if err != nil {
+		t.Fatalf("InspectDOT failed: %v", err)
+	}
+
+	if len(inspection.Nodes) != 2 || len(inspection.Edges) != 1 {
+		t.Fatalf("Expected 2 nodes and 1 edge, got %+v", inspection)
+	}
+	node := inspection.Nodes[0]
+	if node.Name != `"null_resource.r0"` || node.Address != "null_resource.r0" {
+		t.Errorf("Unexpected node inspection: %+v", node)
+	}
+	if !inspection.Edges[0].Resolved {
+		t.Errorf("Expected edge endpoints to resolve: %+v", inspection.Edges[0])
+	}
+}
+
+func TestParseGraphEmptyDigraph(t *testing.T) {
+	graphAst, err := gographviz.ParseString("digraph {\n}\n")
+	if err != nil {
+		t.Fatalf("Failed to parse DOT: %v", err)
+	}
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		t.Fatalf("Failed to analyse DOT: %v", err)
+	}
+
+	g, err := ParseGraph(dotGraph)
+	if err != nil {
+		t.Fatalf("ParseGraph failed: %v", err)
+	}
+	if len(g.Nodes) != 0 || len(g.Edges) != 0 {
+		t.Errorf("Expected an empty graph, got %d nodes and %d edges", len(g.Nodes), len(g.Edges))
+	}
+}
+
+func TestParseGraphIsSorted(t *testing.T) {
+	dotString := `digraph G {
+		"null_resource.c" [label="null_resource.c"];
+		"null_resource.a" [label="null_resource.a"];
+		"null_resource.b" [label="null_resource.b"];
+		"null_resource.c" -> "null_resource.b";
+		"null_resource.b" -> "null_resource.a";
+		"null_resource.c" -> "null_resource.a";
+	}`
+
+	var first []byte
+	for i := 0; i < 10; i++ {
+		graphAst, err := gographviz.ParseString(dotString)
+		if err != nil {
+			t.Fatalf("Failed to parse DOT string: %v", err)
+		}
+		dotGraph := gographviz.NewGraph()
+		if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+			t.Fatalf("Failed to analyse graph: %v", err)
+		}
+
+		g, err := ParseGraph(dotGraph)
+		if err != nil {
+			t.Fatalf("ParseGraph failed: %v", err)
+		}
+		data, err := json.Marshal(g)
+		if err != nil {
+			t.Fatalf("Failed to marshal graph: %v", err)
+		}
+
+		if first == nil {
+			first = data
+			if g.Nodes[0].ID != "null_resource.a" || g.Edges[0].From != "null_resource.b" {
+				t.Errorf("Expected sorted nodes and edges, got %+v", g)
+			}
+		} else if string(data) != string(first) {
+			t.Fatalf("Expected identical output across runs:\n%s\n%s", first, data)
+		}
+	}
+}
+
+func TestParseGraphDataSourceKind(t *testing.T) {
+	graphAst, err := gographviz.ParseString(`digraph G {
+		"null_resource.app" [label="null_resource.app"];
+		"null_resource.cluster" [label="null_resource.cluster"];
+		"data.external.config" [label="data.external.config"];
+		"null_resource.app" -> "null_resource.cluster";
+		"null_resource.app" -> "data.external.config";
+	}`)
+	if err != nil {
+		t.Fatalf("Failed to parse DOT string: %v", err)
+	}
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		t.Fatalf("Failed to analyse graph: %v", err)
+	}
+
+	g, err := ParseGraph(dotGraph)
+	if err != nil {
+		t.Fatalf("ParseGraph failed: %v", err)
+	}
+
+	for _, edge := range g.Edges {
+		wantKind := ""
+		if edge.To == "data.external.config" {
+			wantKind = graph.KindData
+		}
+		if edge.Kind != wantKind {
+			t.Errorf("%s -> %s: expected kind %q, got %q", edge.From, edge.To, wantKind, edge.Kind)
+		}
+	}
+	if len(g.Edges) != 2 {
+		t.Errorf("Expected 2 edges, got %v", g.Edges)
+	}
+}
+
+// parseTestDOT parses and analyses a DOT string.
+func parseTestDOT(t *testing.T, dot string) *gographviz.Graph {
+	t.Helper()
+
+	graphAst, err := gographviz.ParseString(dot)
+	if err != nil {
+		t.Fatalf("Failed to parse DOT string: %v", err)
+	}
+	dotGraph := gographviz.NewGraph()
+	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
+		t.Fatalf("Failed to analyse graph: %v", err)
+	}
+	return dotGraph
+}
+
+func TestParseGraphModuleClusters(t *testing.T) {
+	g, err := ParseGraph(parseTestDOT(t, testGraphModuleReferences))
+	if err != nil {
+		t.Fatalf("ParseGraph failed: %v", err)
+	}
+
+	// Module resources are labelled relative to their cluster
+	ids := make([]string, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		ids = append(ids, node.ID)
+	}
+	expected := []string{"module.app.terraform_data.server", "terraform_data.dns", "terraform_data.network", "terraform_data.subnet"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected nodes %v, got %v", expected, ids)
+	}
+	if len(g.Edges) != 3 || g.Edges[0].From != "module.app.terraform_data.server" {
+		t.Errorf("Expected edges between the full addresses, got %v", g.Edges)
+	}
+}
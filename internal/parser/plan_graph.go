@@ -0,0 +1,453 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+	"terraform-graphx/internal/graph"
+)
+
+// Graph converts the plan to a graph. Each planned resource instance becomes a
+// node carrying its provider and planned values as attributes. Edges come from
+// the configuration: the explicit depends_on, count, for_each, expression and
+// provisioner references of a resource block link its instances to the
+// instances of the referenced resources. References to input variables and
+// module outputs are followed to the resources they are computed from, across
+// module boundaries, and resources inherit the depends_on, count and for_each
+// references of the module calls containing them. Locals are not part of the
+// plan JSON, so references made through them are lost; see AddConfigEdges.
+// Instances of the same module call are only linked to each other's resources,
+// while a reference into a module call links to the resources of all of its
+// instances.
+// References to self only become edges, from each instance to itself, when
+// AllowSelfEdges is set. Edges to data sources are of kind data, other edges
+// explicit when declared with depends_on and implicit otherwise.
+func (p *TerraformPlan) Graph() *graph.Graph {
+	g := &graph.Graph{
+		Nodes: make([]graph.Node, 0),
+		Edges: make([]graph.Edge, 0),
+	}
+
+	for _, resource := range p.PlannedResources() {
+		g.Nodes = append(g.Nodes, graph.Node{
+			ID:         resource.Address,
+			Type:       resource.Type,
+			Provider:   planProviderName(resource.ProviderName),
+			Name:       resource.Name,
+			Attributes: resource.Values,
+		})
+	}
+	instances := instancesOf(g)
+
+	references := p.configuredReferences()
+	seen := make(map[graph.Edge]bool)
+	for _, from := range sortedKeys(references) {
+		for _, to := range sortedKeys(references[from]) {
+			kind := references[from][to]
+			for _, fromInstance := range instances[from] {
+				if from == to && p.AllowSelfEdges {
+					edge := graph.Edge{From: fromInstance, To: fromInstance, Relation: "DEPENDS_ON", Kind: kind}
+					if !seen[edge] {
+						seen[edge] = true
+						g.Edges = append(g.Edges, edge)
+					}
+					continue
+				}
+
+				for _, toInstance := range instances[to] {
+					if !sameModuleInstances(fromInstance, toInstance) {
+						continue
+					}
+					edge := graph.Edge{From: fromInstance, To: toInstance, Relation: "DEPENDS_ON", Kind: kind}
+					if fromInstance != toInstance && !seen[edge] {
+						seen[edge] = true
+						g.Edges = append(g.Edges, edge)
+					}
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// AddConfigEdges returns g, a graph of resource instances such as built by
+// Graph, with the dependencies of configGraph added, e.g. the graph of
+// `terraform graph`, whose nodes are resource blocks. It recovers the
+// dependencies Graph can't see, such as those made through locals. An edge
+// between two blocks links their instances like Graph does, unless the
+// instances are already linked; added edges are of kind data or implicit.
+func AddConfigEdges(g, configGraph *graph.Graph) *graph.Graph {
+	result := &graph.Graph{
+		Nodes: append([]graph.Node(nil), g.Nodes...),
+		Edges: append([]graph.Edge(nil), g.Edges...),
+	}
+
+	type pair struct{ from, to string }
+	linked := make(map[pair]bool, len(g.Edges))
+	for _, edge := range g.Edges {
+		linked[pair{edge.From, edge.To}] = true
+	}
+
+	instances := instancesOf(g)
+	for _, edge := range configGraph.Edges {
+		from, to := ConfigAddress(edge.From), ConfigAddress(edge.To)
+		for _, fromInstance := range instances[from] {
+			for _, toInstance := range instances[to] {
+				if fromInstance == toInstance || linked[pair{fromInstance, toInstance}] || !sameModuleInstances(fromInstance, toInstance) {
+					continue
+				}
+				linked[pair{fromInstance, toInstance}] = true
+				result.Edges = append(result.Edges, graph.Edge{
+					From:     fromInstance,
+					To:       toInstance,
+					Relation: "DEPENDS_ON",
+					Kind:     relationKind(toInstance, false),
+				})
+			}
+		}
+	}
+
+	graph.Sort(result)
+	return result
+}
+
+// instancesOf maps the configuration address of every node of g to the
+// addresses of its instances.
+func instancesOf(g *graph.Graph) map[string][]string {
+	instances := make(map[string][]string)
+	for _, node := range g.Nodes {
+		base := ConfigAddress(node.ID)
+		instances[base] = append(instances[base], node.ID)
+	}
+	return instances
+}
+
+// sameModuleInstances reports whether two resource instances agree on the
+// instance keys of the module calls they share, e.g. module.app["eu"] resources
+// only reach module.app["eu"] resources, while any instance reaches the root
+// module.
+func sameModuleInstances(a, b string) bool {
+	callsA, callsB := moduleCalls(a), moduleCalls(b)
+	for i := 0; i < len(callsA) && i < len(callsB); i++ {
+		if ConfigAddress(callsA[i]) != ConfigAddress(callsB[i]) {
+			break
+		}
+		if callsA[i] != callsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// moduleCalls returns the module calls, with their instance keys, on the path
+// of a resource address, e.g. [`app["eu"]`, "db"] for
+// `module.app["eu"].module.db.aws_instance.web`.
+func moduleCalls(address string) []string {
+	segments := splitReference(address)
+	var calls []string
+	for i := 0; i+1 < len(segments) && segments[i] == "module"; i += 2 {
+		calls = append(calls, segments[i+1])
+	}
+	return calls
+}
+
+// splitReference splits an address or reference on the dots outside instance
+// keys, e.g. `module.app["a.b"].id` into "module", `app["a.b"]` and "id".
+func splitReference(ref string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(ref); i++ {
+		switch ref[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, ref[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, ref[start:])
+}
+
+// configScope is a module of the configuration, with the call instantiating
+// it in its parent; the root module has neither parent nor call.
+type configScope struct {
+	prefix string
+	module ConfigModule
+	parent *configScope
+	call   ModuleCall
+}
+
+// child returns the scope of the module call name of s, or nil if there is none.
+func (s *configScope) child(name string) *configScope {
+	call, ok := s.module.ModuleCalls[name]
+	if !ok {
+		return nil
+	}
+	return &configScope{prefix: s.prefix + "module." + name + ".", module: call.Module, parent: s, call: call}
+}
+
+// configuredReferences maps the configuration address of every resource block
+// to the configuration addresses of the resources it references, each with the
+// kind of the dependency. A depends_on entry makes a dependency explicit even
+// when it is also referenced otherwise.
+func (p *TerraformPlan) configuredReferences() map[string]map[string]string {
+	references := make(map[string]map[string]string)
+	maxDepth := p.maxReferenceDepth()
+
+	var walk func(scope *configScope)
+	walk = func(scope *configScope) {
+		for _, resource := range scope.module.Resources {
+			targets := make(map[string]string)
+			refs := resource.expressionReferences(maxDepth)
+			for _, ref := range refs {
+				if p.AllowSelfEdges && isSelfReference(ref) {
+					targets[scope.prefix+resource.Address] = relationKind(resource.Address, false)
+				}
+			}
+			for _, target := range scope.resolve(refs, maxDepth) {
+				targets[target] = relationKind(target, false)
+			}
+			// Resources depend on what the calls of their modules depend on
+			for s := scope; s.parent != nil; s = s.parent {
+				for _, target := range s.parent.resolve(s.call.repetitionReferences(maxDepth), maxDepth) {
+					targets[target] = relationKind(target, false)
+				}
+			}
+			for _, target := range scope.resolveDependsOn(resource.DependsOn, maxDepth) {
+				targets[target] = relationKind(target, true)
+			}
+			for s := scope; s.parent != nil; s = s.parent {
+				for _, target := range s.parent.resolveDependsOn(s.call.DependsOn, maxDepth) {
+					targets[target] = relationKind(target, true)
+				}
+			}
+			references[scope.prefix+resource.Address] = targets
+		}
+		for _, name := range sortedKeys(scope.module.ModuleCalls) {
+			walk(scope.child(name))
+		}
+	}
+	walk(&configScope{module: p.Configuration.RootModule})
+	return references
+}
+
+// resolve returns the addresses of the resources that refs, references made in
+// the module of s, are computed from. Input variables are followed to the
+// module call's argument in the parent module, module outputs to the output's
+// expression in the child module. A whole-module reference such as module.app
+// stands for all of its outputs unless refs also name one of them, as
+// Terraform lists module.app next to module.app.id.
+func (s *configScope) resolve(refs []string, maxDepth int) []string {
+	return s.resolveReferences(refs, maxDepth, make(map[string]bool))
+}
+
+func (s *configScope) resolveReferences(refs []string, maxDepth int, visited map[string]bool) []string {
+	namedOutputs := make(map[string]bool)
+	for _, ref := range refs {
+		if parts := splitReference(ref); parts[0] == "module" && len(parts) >= 3 {
+			namedOutputs[callName(parts[1])] = true
+		}
+	}
+
+	var targets []string
+	for _, ref := range refs {
+		// Guard against references that loop back through variables and outputs
+		key := s.prefix + ref
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+
+		parts := splitReference(ref)
+		switch {
+		case parts[0] == "var" && len(parts) >= 2:
+			if s.parent == nil {
+				continue
+			}
+			argument := s.call.Expressions[callName(parts[1])]
+			found, _ := collectReferences(argument, maxDepth)
+			targets = append(targets, s.parent.resolveReferences(found, maxDepth, visited)...)
+		case parts[0] == "module" && len(parts) >= 2:
+			child := s.child(callName(parts[1]))
+			if child == nil {
+				continue
+			}
+			outputs := sortedKeys(child.module.Outputs)
+			if len(parts) >= 3 {
+				outputs = []string{callName(parts[2])}
+			} else if namedOutputs[callName(parts[1])] {
+				continue
+			}
+			for _, name := range outputs {
+				output, ok := child.module.Outputs[name]
+				if !ok {
+					continue
+				}
+				found, _ := collectReferences(output.Expression, maxDepth)
+				targets = append(targets, child.resolveReferences(found, maxDepth, visited)...)
+				targets = append(targets, child.resolveDependsOn(output.DependsOn, maxDepth)...)
+			}
+		default:
+			if target := referencedResource(ref); target != "" {
+				targets = append(targets, s.prefix+target)
+			}
+		}
+	}
+	return targets
+}
+
+// resolveDependsOn returns the addresses of the resources named by depends_on
+// entries of the module of s. A module entry stands for every resource of the
+// module and of the modules it calls.
+func (s *configScope) resolveDependsOn(entries []string, maxDepth int) []string {
+	var targets []string
+	for _, entry := range entries {
+		parts := splitReference(entry)
+		if parts[0] != "module" || len(parts) < 2 {
+			if target := referencedResource(entry); target != "" {
+				targets = append(targets, s.prefix+target)
+			}
+			continue
+		}
+		if child := s.child(callName(parts[1])); child != nil {
+			targets = append(targets, child.resources()...)
+		}
+	}
+	return targets
+}
+
+// resources returns the addresses of the resources of the module of s and of
+// the modules it calls.
+func (s *configScope) resources() []string {
+	var addresses []string
+	for _, resource := range s.module.Resources {
+		addresses = append(addresses, s.prefix+resource.Address)
+	}
+	for _, name := range sortedKeys(s.module.ModuleCalls) {
+		addresses = append(addresses, s.child(name).resources()...)
+	}
+	return addresses
+}
+
+// callName strips the instance key from a module call, variable or output
+// name in a reference, e.g. app["eu"] becomes app.
+func callName(name string) string {
+	name, _, _ = strings.Cut(name, "[")
+	return name
+}
+
+// maxReferenceDepth returns the configured MaxReferenceDepth, or
+// DefaultMaxReferenceDepth when it is unset.
+func (p *TerraformPlan) maxReferenceDepth() int {
+	if p.MaxReferenceDepth > 0 {
+		return p.MaxReferenceDepth
+	}
+	return DefaultMaxReferenceDepth
+}
+
+// collectReferences returns the "references" of every expression, including
+// those of nested blocks, down to maxDepth levels of nesting. It walks an
+// explicit stack so arbitrarily nested input can't exhaust the goroutine stack,
+// and reports whether values deeper than maxDepth were skipped.
+func collectReferences(value interface{}, maxDepth int) (refs []string, truncated bool) {
+	type item struct {
+		value interface{}
+		depth int
+	}
+	stack := []item{{value, 0}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		switch v := current.value.(type) {
+		case map[string]interface{}:
+			if list, ok := v["references"].([]interface{}); ok {
+				for _, ref := range list {
+					if s, ok := ref.(string); ok {
+						refs = append(refs, s)
+					}
+				}
+			}
+			for key, nested := range v {
+				if key == "references" {
+					continue
+				}
+				if current.depth >= maxDepth {
+					truncated = true
+					continue
+				}
+				stack = append(stack, item{nested, current.depth + 1})
+			}
+		case []interface{}:
+			for _, nested := range v {
+				if current.depth >= maxDepth {
+					truncated = true
+					continue
+				}
+				stack = append(stack, item{nested, current.depth + 1})
+			}
+		}
+	}
+	return refs, truncated
+}
+
+// referencedResource returns the resource address, without instance key, that
+// a reference such as aws_subnet.public[0].id or data.aws_ami.ubuntu points
+// to, or "" if it does not point to a resource.
+func referencedResource(ref string) string {
+	parts := strings.Split(ref, ".")
+	switch parts[0] {
+	case "var", "local", "module", "each", "count", "path", "terraform", "self":
+		return ""
+	case "data":
+		if len(parts) < 3 {
+			return ""
+		}
+		name, _, _ := strings.Cut(parts[2], "[")
+		return "data." + parts[1] + "." + name
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	name, _, _ := strings.Cut(parts[1], "[")
+	return parts[0] + "." + name
+}
+
+// relationKind returns the kind of a dependency on the resource at target,
+// declared with depends_on when explicit is set.
+func relationKind(target string, explicit bool) string {
+	switch {
+	case graph.IsDataSource(target):
+		return graph.KindData
+	case explicit:
+		return graph.KindExplicit
+	default:
+		return graph.KindImplicit
+	}
+}
+
+// isSelfReference reports whether ref refers to the resource it appears in,
+// e.g. self.private_ip in a provisioner.
+func isSelfReference(ref string) bool {
+	return ref == "self" || strings.HasPrefix(ref, "self.")
+}
+
+// planProviderName extracts the short provider name from a plan provider
+// source, e.g. registry.terraform.io/hashicorp/aws -> aws.
+func planProviderName(source string) string {
+	return source[strings.LastIndex(source, "/")+1:]
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,541 @@
+package parser
+
+import (
+	"reflect"
+	"terraform-graphx/internal/graph"
+	"testing"
+)
+
+// testPlanGraph has two instances of a module whose instances reference a
+// root resource, a data source and each other through expressions.
+const testPlanGraph = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws", "values": {"cidr_block": "10.0.0.0/16"}},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "data.aws_ami.ubuntu", "mode": "data", "type": "aws_ami", "name": "ubuntu", "provider_name": "registry.terraform.io/hashicorp/aws"}
+      ],
+      "child_modules": [
+        {
+          "address": "module.app[\"eu\"]",
+          "resources": [
+            {"address": "module.app[\"eu\"].aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"},
+            {"address": "module.app[\"eu\"].aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"}
+          ]
+        },
+        {
+          "address": "module.app[\"us\"]",
+          "resources": [
+            {"address": "module.app[\"us\"].aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"},
+            {"address": "module.app[\"us\"].aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"}
+          ]
+        }
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main",
+         "expressions": {"cidr_block": {"references": ["var.cidr"]}}},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a",
+         "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+        {"address": "data.aws_ami.ubuntu", "mode": "data", "type": "aws_ami", "name": "ubuntu"}
+      ],
+      "module_calls": {
+        "app": {
+          "source": "./modules/app",
+          "module": {
+            "resources": [
+              {"address": "aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web"},
+              {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+               "expressions": {
+                 "ebs_block_device": [{"snapshot_id": {"references": ["local.snapshot"]}}],
+                 "network_interface": [{"security_groups": {"references": ["aws_security_group.web.id", "aws_security_group.web"]}}]
+               },
+               "depends_on": ["module.other"]}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestPlanGraph(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanGraph))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	g := plan.Graph()
+
+	if len(g.Nodes) != 7 {
+		t.Fatalf("Expected 7 nodes, got %d", len(g.Nodes))
+	}
+	vpc := g.Nodes[0]
+	if vpc.Provider != "aws" || vpc.Type != "aws_vpc" || vpc.Name != "main" || vpc.Attributes["cidr_block"] != "10.0.0.0/16" {
+		t.Errorf("Unexpected node: %+v", vpc)
+	}
+
+	// Module instances only depend on resources of the same instance
+	expected := []graph.Edge{
+		{From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: `module.app["eu"].aws_instance.web`, To: `module.app["eu"].aws_security_group.web`, Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: `module.app["us"].aws_instance.web`, To: `module.app["us"].aws_security_group.web`, Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+	}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
+
+// testPlanExplicitDependencies has a bucket that only depends on the role
+// through depends_on and an instance that only references the bucket from a
+// provisioner.
+const testPlanExplicitDependencies = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_iam_role.app", "mode": "managed", "type": "aws_iam_role", "name": "app", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"}
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_iam_role.app", "mode": "managed", "type": "aws_iam_role", "name": "app"},
+        {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs",
+         "expressions": {"bucket": {"constant_value": "logs"}},
+         "depends_on": ["aws_iam_role.app"]},
+        {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+         "provisioners": [{"type": "local-exec", "expressions": {"command": {"references": ["aws_s3_bucket.logs.arn", "aws_s3_bucket.logs"]}}}]}
+      ]
+    }
+  }
+}`
+
+func TestPlanGraphExplicitDependencies(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanExplicitDependencies))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	g := plan.Graph()
+
+	expected := []graph.Edge{
+		{From: "aws_instance.web", To: "aws_s3_bucket.logs", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "aws_s3_bucket.logs", To: "aws_iam_role.app", Relation: "DEPENDS_ON", Kind: graph.KindExplicit},
+	}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
+
+// testPlanRelationKinds has an instance that reads a data source, references
+// a subnet and both references and explicitly depends on a security group.
+const testPlanRelationKinds = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "data.aws_ami.ubuntu", "mode": "data", "type": "aws_ami", "name": "ubuntu", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"}
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "data.aws_ami.ubuntu", "mode": "data", "type": "aws_ami", "name": "ubuntu"},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a"},
+        {"address": "aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web"},
+        {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+         "expressions": {
+           "ami": {"references": ["data.aws_ami.ubuntu.id", "data.aws_ami.ubuntu"]},
+           "subnet_id": {"references": ["aws_subnet.a.id", "aws_subnet.a"]},
+           "vpc_security_group_ids": {"references": ["aws_security_group.web.id", "aws_security_group.web"]}
+         },
+         "depends_on": ["aws_security_group.web"]}
+      ]
+    }
+  }
+}`
+
+func TestPlanGraphRelationKinds(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanRelationKinds))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	g := plan.Graph()
+
+	expected := []graph.Edge{
+		{From: "aws_instance.web", To: "aws_security_group.web", Relation: "DEPENDS_ON", Kind: graph.KindExplicit},
+		{From: "aws_instance.web", To: "aws_subnet.a", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "aws_instance.web", To: "data.aws_ami.ubuntu", Relation: "DEPENDS_ON", Kind: graph.KindData},
+	}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
+
+// testPlanSelfReference has two instances of a resource whose provisioner
+// refers to the instance itself.
+const testPlanSelfReference = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_instance.web[0]", "mode": "managed", "type": "aws_instance", "name": "web", "index": 0, "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_instance.web[1]", "mode": "managed", "type": "aws_instance", "name": "web", "index": 1, "provider_name": "registry.terraform.io/hashicorp/aws"}
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+         "provisioners": [{"type": "local-exec", "expressions": {"command": {"references": ["self.private_ip", "self"]}}}]}
+      ]
+    }
+  }
+}`
+
+func TestPlanGraphSelfEdges(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanSelfReference))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	if g := plan.Graph(); len(g.Edges) != 0 {
+		t.Errorf("Expected self references to be dropped by default, got %v", g.Edges)
+	}
+
+	plan.AllowSelfEdges = true
+	expected := []graph.Edge{
+		{From: "aws_instance.web[0]", To: "aws_instance.web[0]", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "aws_instance.web[1]", To: "aws_instance.web[1]", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+	}
+	if g := plan.Graph(); !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
+
+func TestReferencesOfDeeplyNestedExpressions(t *testing.T) {
+	// A block nested far beyond the limit, with a reference at the bottom
+	var nested interface{} = map[string]interface{}{"references": []interface{}{"aws_kms_key.deep"}}
+	for i := 0; i < 100000; i++ {
+		nested = []interface{}{map[string]interface{}{"block": nested}}
+	}
+	resource := ConfigResource{
+		Address: "aws_instance.web",
+		Expressions: map[string]interface{}{
+			"subnet_id": map[string]interface{}{"references": []interface{}{"aws_subnet.a.id", "aws_subnet.a"}},
+			"dynamic":   nested,
+		},
+	}
+
+	expected := []string{"aws_subnet.a.id", "aws_subnet.a"}
+	if refs := resource.References(); !reflect.DeepEqual(refs, expected) {
+		t.Errorf("Expected references %v, got %v", expected, refs)
+	}
+
+	// The limit counts maps and lists alike
+	shallow := ConfigResource{Expressions: map[string]interface{}{
+		"block": []interface{}{map[string]interface{}{"references": []interface{}{"aws_kms_key.key"}}},
+	}}
+	if refs := shallow.references(2); !reflect.DeepEqual(refs, []string{"aws_kms_key.key"}) {
+		t.Errorf("Expected the reference within the limit, got %v", refs)
+	}
+	if refs := shallow.references(1); len(refs) != 0 {
+		t.Errorf("Expected no references beyond the limit, got %v", refs)
+	}
+}
+
+func TestReferencedResource(t *testing.T) {
+	tests := map[string]string{
+		"aws_vpc.main.id":         "aws_vpc.main",
+		"aws_subnet.public[0].id": "aws_subnet.public",
+		`aws_subnet.public["a"]`:  "aws_subnet.public",
+		"data.aws_ami.ubuntu.id":  "data.aws_ami.ubuntu",
+		"var.region":              "",
+		"local.tags":              "",
+		"module.network.vpc_id":   "",
+		"each.value":              "",
+		"count.index":             "",
+		"aws_vpc":                 "",
+	}
+
+	for ref, expected := range tests {
+		if got := referencedResource(ref); got != expected {
+			t.Errorf("referencedResource(%s): expected %q, got %q", ref, expected, got)
+		}
+	}
+}
+
+// testPlanModuleReferences is the output of `terraform show -json` (Terraform
+// 1.9, unused fields removed) for a root module whose network reaches the two
+// instances of module.app through the network_id variable, whose dns record
+// reads the module's server_id output and whose subnet only references the
+// network through a local:
+//
+//	locals { network = terraform_data.network.id }
+//	resource "terraform_data" "network" { input = "10.0.0.0/16" }
+//	resource "terraform_data" "subnet" { input = local.network }
+//	module "app" {
+//	  source     = "./modules/app"
+//	  for_each   = toset(["eu", "us"])
+//	  network_id = terraform_data.network.id
+//	}
+//	resource "terraform_data" "dns" { input = module.app["eu"].server_id }
+//
+// with modules/app declaring variable network_id, a terraform_data.server
+// whose input is var.network_id and output server_id = terraform_data.server.id.
+const testPlanModuleReferences = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "terraform_data.dns", "mode": "managed", "type": "terraform_data", "name": "dns", "provider_name": "terraform.io/builtin/terraform", "values": {"triggers_replace": null}},
+        {"address": "terraform_data.network", "mode": "managed", "type": "terraform_data", "name": "network", "provider_name": "terraform.io/builtin/terraform", "values": {"input": "10.0.0.0/16", "triggers_replace": null}},
+        {"address": "terraform_data.subnet", "mode": "managed", "type": "terraform_data", "name": "subnet", "provider_name": "terraform.io/builtin/terraform", "values": {"triggers_replace": null}}
+      ],
+      "child_modules": [
+        {"resources": [{"address": "module.app[\"eu\"].terraform_data.server", "mode": "managed", "type": "terraform_data", "name": "server", "provider_name": "terraform.io/builtin/terraform", "values": {"triggers_replace": null}}], "address": "module.app[\"eu\"]"},
+        {"resources": [{"address": "module.app[\"us\"].terraform_data.server", "mode": "managed", "type": "terraform_data", "name": "server", "provider_name": "terraform.io/builtin/terraform", "values": {"triggers_replace": null}}], "address": "module.app[\"us\"]"}
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "terraform_data.dns", "mode": "managed", "type": "terraform_data", "name": "dns", "provider_config_key": "terraform",
+         "expressions": {"input": {"references": ["module.app[\"eu\"].server_id", "module.app[\"eu\"]"]}}},
+        {"address": "terraform_data.network", "mode": "managed", "type": "terraform_data", "name": "network", "provider_config_key": "terraform",
+         "expressions": {"input": {"constant_value": "10.0.0.0/16"}}},
+        {"address": "terraform_data.subnet", "mode": "managed", "type": "terraform_data", "name": "subnet", "provider_config_key": "terraform",
+         "expressions": {"input": {"references": ["local.network"]}}}
+      ],
+      "module_calls": {
+        "app": {
+          "source": "./modules/app",
+          "expressions": {"network_id": {"references": ["terraform_data.network.id", "terraform_data.network"]}},
+          "module": {
+            "outputs": {"server_id": {"expression": {"references": ["terraform_data.server.id", "terraform_data.server"]}}},
+            "resources": [
+              {"address": "terraform_data.server", "mode": "managed", "type": "terraform_data", "name": "server", "provider_config_key": "terraform",
+               "expressions": {"input": {"references": ["var.network_id"]}}}
+            ],
+            "variables": {"network_id": {}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+// testGraphModuleReferences is the output of `terraform graph` (Terraform 1.9)
+// for the configuration of testPlanModuleReferences.
+const testGraphModuleReferences = `digraph G {
+  rankdir = "RL";
+  node [shape = rect, fontname = "sans-serif"];
+  "terraform_data.dns" [label="terraform_data.dns"];
+  "terraform_data.network" [label="terraform_data.network"];
+  "terraform_data.subnet" [label="terraform_data.subnet"];
+  subgraph "cluster_module.app" {
+    label = "module.app"
+    fontname = "sans-serif"
+    "module.app.terraform_data.server" [label="terraform_data.server"];
+  }
+  "terraform_data.dns" -> "module.app.terraform_data.server";
+  "terraform_data.subnet" -> "terraform_data.network";
+  "module.app.terraform_data.server" -> "terraform_data.network";
+}`
+
+func TestPlanGraphFollowsModuleVariablesAndOutputs(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanModuleReferences))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	g := plan.Graph()
+
+	// The subnet's reference through local.network is not in the plan JSON
+	expected := []graph.Edge{
+		{From: `module.app["eu"].terraform_data.server`, To: "terraform_data.network", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: `module.app["us"].terraform_data.server`, To: "terraform_data.network", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "terraform_data.dns", To: `module.app["eu"].terraform_data.server`, Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "terraform_data.dns", To: `module.app["us"].terraform_data.server`, Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+	}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
+
+func TestAddConfigEdges(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanModuleReferences))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+	configGraph, err := ParseGraph(parseTestDOT(t, testGraphModuleReferences))
+	if err != nil {
+		t.Fatalf("ParseGraph failed: %v", err)
+	}
+
+	g := AddConfigEdges(plan.Graph(), configGraph)
+
+	expected := []graph.Edge{
+		{From: `module.app["eu"].terraform_data.server`, To: "terraform_data.network", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: `module.app["us"].terraform_data.server`, To: "terraform_data.network", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "terraform_data.dns", To: `module.app["eu"].terraform_data.server`, Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "terraform_data.dns", To: `module.app["us"].terraform_data.server`, Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "terraform_data.subnet", To: "terraform_data.network", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+	}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+	if len(g.Nodes) != 5 {
+		t.Errorf("Expected the plan's 5 nodes, got %v", g.Nodes)
+	}
+}
+
+// testPlanReplaceTriggeredBy is the plan JSON of Terraform 1.9, unused fields
+// removed, for a dns record replaced whenever the subnet is:
+//
+//	resource "terraform_data" "subnet" { input = "10.0.1.0/24" }
+//	resource "terraform_data" "dns" {
+//	  input = "app"
+//	  lifecycle { replace_triggered_by = [terraform_data.subnet] }
+//	}
+//
+// Terraform leaves the lifecycle block out of the configuration, so only the
+// output of `terraform graph`, testGraphReplaceTriggeredBy, has the dependency.
+const testPlanReplaceTriggeredBy = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "terraform_data.dns", "mode": "managed", "type": "terraform_data", "name": "dns", "provider_name": "terraform.io/builtin/terraform", "values": {"input": "app", "triggers_replace": null}},
+        {"address": "terraform_data.subnet", "mode": "managed", "type": "terraform_data", "name": "subnet", "provider_name": "terraform.io/builtin/terraform", "values": {"input": "10.0.1.0/24", "triggers_replace": null}}
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "terraform_data.dns", "mode": "managed", "type": "terraform_data", "name": "dns", "provider_config_key": "terraform",
+         "expressions": {"input": {"constant_value": "app"}}, "schema_version": 0},
+        {"address": "terraform_data.subnet", "mode": "managed", "type": "terraform_data", "name": "subnet", "provider_config_key": "terraform",
+         "expressions": {"input": {"constant_value": "10.0.1.0/24"}}, "schema_version": 0}
+      ]
+    }
+  }
+}`
+
+const testGraphReplaceTriggeredBy = `digraph G {
+  rankdir = "RL";
+  node [shape = rect, fontname = "sans-serif"];
+  "terraform_data.dns" [label="terraform_data.dns"];
+  "terraform_data.subnet" [label="terraform_data.subnet"];
+  "terraform_data.dns" -> "terraform_data.subnet";
+}`
+
+func TestPlanGraphReplaceTriggeredBy(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanReplaceTriggeredBy))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+	g := plan.Graph()
+	if len(g.Edges) != 0 {
+		t.Errorf("Expected no edges from the plan JSON, got %v", g.Edges)
+	}
+
+	configGraph, err := ParseGraph(parseTestDOT(t, testGraphReplaceTriggeredBy))
+	if err != nil {
+		t.Fatalf("ParseGraph failed: %v", err)
+	}
+	expected := []graph.Edge{
+		{From: "terraform_data.dns", To: "terraform_data.subnet", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+	}
+	if edges := AddConfigEdges(g, configGraph).Edges; !reflect.DeepEqual(edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, edges)
+	}
+}
+
+// testPlanModuleDependencies has a module called with depends_on and count, a
+// module output with depends_on, and a resource depending on a whole module.
+const testPlanModuleDependencies = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_iam_role.app", "mode": "managed", "type": "aws_iam_role", "name": "app", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_route53_record.web", "mode": "managed", "type": "aws_route53_record", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_s3_bucket.audit", "mode": "managed", "type": "aws_s3_bucket", "name": "audit", "provider_name": "registry.terraform.io/hashicorp/aws"}
+      ],
+      "child_modules": [
+        {"address": "module.web[0]", "resources": [
+          {"address": "module.web[0].aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"},
+          {"address": "module.web[0].aws_eip.web", "mode": "managed", "type": "aws_eip", "name": "web", "provider_name": "registry.terraform.io/hashicorp/aws"}
+        ]}
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_iam_role.app", "mode": "managed", "type": "aws_iam_role", "name": "app"},
+        {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a"},
+        {"address": "aws_route53_record.web", "mode": "managed", "type": "aws_route53_record", "name": "web",
+         "expressions": {"records": {"references": ["module.web[0].ip", "module.web[0]"]}}},
+        {"address": "aws_s3_bucket.audit", "mode": "managed", "type": "aws_s3_bucket", "name": "audit",
+         "depends_on": ["module.web"]}
+      ],
+      "module_calls": {
+        "web": {
+          "source": "./modules/web",
+          "count_expression": {"references": ["aws_subnet.a"]},
+          "depends_on": ["aws_iam_role.app"],
+          "module": {
+            "outputs": {"ip": {"expression": {"references": ["aws_eip.web.public_ip", "aws_eip.web"]}, "depends_on": ["aws_instance.web"]}},
+            "resources": [
+              {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web"},
+              {"address": "aws_eip.web", "mode": "managed", "type": "aws_eip", "name": "web"}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestPlanGraphModuleDependencies(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanModuleDependencies))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	g := plan.Graph()
+
+	expected := []graph.Edge{
+		{From: "aws_route53_record.web", To: "module.web[0].aws_eip.web", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "aws_route53_record.web", To: "module.web[0].aws_instance.web", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "aws_s3_bucket.audit", To: "module.web[0].aws_eip.web", Relation: "DEPENDS_ON", Kind: graph.KindExplicit},
+		{From: "aws_s3_bucket.audit", To: "module.web[0].aws_instance.web", Relation: "DEPENDS_ON", Kind: graph.KindExplicit},
+		{From: "module.web[0].aws_eip.web", To: "aws_iam_role.app", Relation: "DEPENDS_ON", Kind: graph.KindExplicit},
+		{From: "module.web[0].aws_eip.web", To: "aws_subnet.a", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+		{From: "module.web[0].aws_instance.web", To: "aws_iam_role.app", Relation: "DEPENDS_ON", Kind: graph.KindExplicit},
+		{From: "module.web[0].aws_instance.web", To: "aws_subnet.a", Relation: "DEPENDS_ON", Kind: graph.KindImplicit},
+	}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
@@ -0,0 +1,296 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"terraform-graphx/internal/logging"
+)
+
+// DefaultMaxReferenceDepth is how many levels of nested expressions are
+// searched for references unless a plan sets MaxReferenceDepth.
+const DefaultMaxReferenceDepth = 64
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// TerraformPlan is the subset of the `terraform show -json` plan output used
+// by terraform-graphx.
+type TerraformPlan struct {
+	FormatVersion   string            `json:"format_version"`
+	PlannedValues   PlanValues        `json:"planned_values"`
+	ResourceChanges []ResourceChange  `json:"resource_changes,omitempty"`
+	Configuration   PlanConfiguration `json:"configuration"`
+	// MaxReferenceDepth limits how deeply expressions are searched for
+	// references; DefaultMaxReferenceDepth when 0.
+	MaxReferenceDepth int `json:"-"`
+	// AllowSelfEdges keeps edges from a resource instance to itself, as made by
+	// references to self in provisioners; they are dropped otherwise.
+	AllowSelfEdges bool `json:"-"`
+}
+
+// PlanValues holds the planned resources of every module.
+type PlanValues struct {
+	RootModule PlanModule `json:"root_module"`
+}
+
+// PlanModule is a module instance of the planned values; the root module has
+// no address.
+type PlanModule struct {
+	Address      string         `json:"address,omitempty"`
+	Resources    []PlanResource `json:"resources,omitempty"`
+	ChildModules []PlanModule   `json:"child_modules,omitempty"`
+}
+
+// PlanResource is a planned resource instance.
+type PlanResource struct {
+	Address      string                 `json:"address"`
+	Mode         string                 `json:"mode"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	Index        interface{}            `json:"index,omitempty"`
+	ProviderName string                 `json:"provider_name"`
+	Values       map[string]interface{} `json:"values,omitempty"`
+}
+
+// ResourceChange is the planned change of a resource instance.
+type ResourceChange struct {
+	Address string `json:"address"`
+	Change  Change `json:"change"`
+}
+
+// Change holds the actions planned for a resource instance, e.g. ["create"],
+// ["no-op"] or ["delete", "create"] for a replacement.
+type Change struct {
+	Actions []string `json:"actions"`
+}
+
+// PlanConfiguration is the configuration the plan was made from.
+type PlanConfiguration struct {
+	RootModule ConfigModule `json:"root_module"`
+}
+
+// ConfigModule is a module of the configuration, with its resources and the
+// modules it calls.
+type ConfigModule struct {
+	Outputs     map[string]ConfigOutput `json:"outputs,omitempty"`
+	Resources   []ConfigResource        `json:"resources,omitempty"`
+	ModuleCalls map[string]ModuleCall   `json:"module_calls,omitempty"`
+}
+
+// ConfigOutput is an output block of a module.
+type ConfigOutput struct {
+	Expression interface{} `json:"expression,omitempty"`
+	DependsOn  []string    `json:"depends_on,omitempty"`
+}
+
+// ConfigResource is a resource block of the configuration. Its address is
+// relative to the module declaring it.
+type ConfigResource struct {
+	Address           string                 `json:"address"`
+	Mode              string                 `json:"mode"`
+	Type              string                 `json:"type"`
+	Name              string                 `json:"name"`
+	ProviderConfigKey string                 `json:"provider_config_key,omitempty"`
+	DependsOn         []string               `json:"depends_on,omitempty"`
+	Expressions       map[string]interface{} `json:"expressions,omitempty"`
+	CountExpression   interface{}            `json:"count_expression,omitempty"`
+	ForEachExpression interface{}            `json:"for_each_expression,omitempty"`
+	Provisioners      []ConfigProvisioner    `json:"provisioners,omitempty"`
+}
+
+// ConfigProvisioner is a provisioner block of a resource.
+type ConfigProvisioner struct {
+	Type        string                 `json:"type"`
+	Expressions map[string]interface{} `json:"expressions,omitempty"`
+}
+
+// References returns everything the resource block refers to: its explicit
+// depends_on entries and the references of its expressions and provisioners.
+func (r ConfigResource) References() []string {
+	return r.references(DefaultMaxReferenceDepth)
+}
+
+// references is References, searching expressions at most maxDepth levels deep.
+func (r ConfigResource) references(maxDepth int) []string {
+	return append(append([]string(nil), r.DependsOn...), r.expressionReferences(maxDepth)...)
+}
+
+// expressionReferences returns the references of the resource's expressions,
+// count or for_each and provisioners, searched at most maxDepth levels deep.
+func (r ConfigResource) expressionReferences(maxDepth int) []string {
+	var refs []string
+	expressions := []interface{}{r.Expressions, r.CountExpression, r.ForEachExpression}
+	for _, provisioner := range r.Provisioners {
+		expressions = append(expressions, provisioner.Expressions)
+	}
+
+	truncated := false
+	for _, e := range expressions {
+		found, deep := collectReferences(e, maxDepth)
+		refs = append(refs, found...)
+		truncated = truncated || deep
+	}
+	if truncated {
+		logging.Debugf("Expressions of %s are nested deeper than %d levels; references below that depth are ignored", r.Address, maxDepth)
+	}
+	return refs
+}
+
+// ModuleCall is a module block of the configuration. Expressions holds the
+// arguments passed to the module's input variables.
+type ModuleCall struct {
+	Source            string                 `json:"source,omitempty"`
+	Expressions       map[string]interface{} `json:"expressions,omitempty"`
+	CountExpression   interface{}            `json:"count_expression,omitempty"`
+	ForEachExpression interface{}            `json:"for_each_expression,omitempty"`
+	DependsOn         []string               `json:"depends_on,omitempty"`
+	Module            ConfigModule           `json:"module"`
+}
+
+// repetitionReferences returns the references of the call's count or
+// for_each, searched at most maxDepth levels deep.
+func (c ModuleCall) repetitionReferences(maxDepth int) []string {
+	count, _ := collectReferences(c.CountExpression, maxDepth)
+	forEach, _ := collectReferences(c.ForEachExpression, maxDepth)
+	return append(count, forEach...)
+}
+
+// IsPlanJSON reports whether path names a plan JSON file, plain (.json) or
+// gzip-compressed (.json.gz), rather than a saved binary plan.
+func IsPlanJSON(path string) bool {
+	return strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".json.gz")
+}
+
+// ParsePlanFile reads a plan JSON file as written by `terraform show -json`,
+// optionally gzip-compressed.
+func ParsePlanFile(path string) (*TerraformPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan JSON: %w", err)
+	}
+	return ParsePlan(data)
+}
+
+// ParsePlan decodes `terraform show -json` plan output. Gzip-compressed
+// output, recognized by its magic bytes, is decompressed first.
+func ParsePlan(data []byte) (*TerraformPlan, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress plan JSON: %w", err)
+		}
+		defer reader.Close()
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("failed to decompress plan JSON: %w", err)
+		}
+	}
+
+	var plan TerraformPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+	if plan.FormatVersion == "" {
+		return nil, fmt.Errorf("plan JSON has no format_version; is it the output of 'terraform show -json <plan>'?")
+	}
+	return &plan, nil
+}
+
+// PlannedResources returns every planned resource instance, depth first.
+func (p *TerraformPlan) PlannedResources() []PlanResource {
+	var resources []PlanResource
+	var walk func(m PlanModule)
+	walk = func(m PlanModule) {
+		resources = append(resources, m.Resources...)
+		for _, child := range m.ChildModules {
+			walk(child)
+		}
+	}
+	walk(p.PlannedValues.RootModule)
+	return resources
+}
+
+// ChangedResources returns, in sorted order, the addresses of the resource
+// instances the plan creates, reads, updates or replaces, and of those it only
+// deletes. Instances whose action is no-op are in neither.
+func (p *TerraformPlan) ChangedResources() (changed, deleted []string) {
+	for _, rc := range p.ResourceChanges {
+		actions := rc.Change.Actions
+		switch {
+		case len(actions) == 0, len(actions) == 1 && actions[0] == "no-op":
+		case len(actions) == 1 && actions[0] == "delete":
+			deleted = append(deleted, rc.Address)
+		default:
+			changed = append(changed, rc.Address)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(deleted)
+	return changed, deleted
+}
+
+// ConfiguredAddresses returns the set of configuration addresses, i.e.
+// resource addresses without instance keys such as "module.app.aws_instance.web".
+func (p *TerraformPlan) ConfiguredAddresses() map[string]bool {
+	addresses := make(map[string]bool)
+	var walk func(m ConfigModule, prefix string)
+	walk = func(m ConfigModule, prefix string) {
+		for _, resource := range m.Resources {
+			addresses[prefix+resource.Address] = true
+		}
+		for name, call := range m.ModuleCalls {
+			walk(call.Module, prefix+"module."+name+".")
+		}
+	}
+	walk(p.Configuration.RootModule, "")
+	return addresses
+}
+
+// UnconfiguredResources returns, in sorted order, the addresses of planned
+// resources that have no resource block in the configuration. Dependencies of
+// such resources can't be discovered from the configuration.
+func (p *TerraformPlan) UnconfiguredResources() []string {
+	configured := p.ConfiguredAddresses()
+
+	var missing []string
+	for _, resource := range p.PlannedResources() {
+		if !configured[ConfigAddress(resource.Address)] {
+			missing = append(missing, resource.Address)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// ConfigAddress strips every instance key from an address, e.g.
+// `module.app["eu"].aws_instance.web[0]` becomes "module.app.aws_instance.web".
+func ConfigAddress(address string) string {
+	var out strings.Builder
+	depth := 0
+	quoted := false
+	for i := 0; i < len(address); i++ {
+		c := address[i]
+		switch {
+		case quoted:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+		case c == '"' && depth > 0:
+			quoted = true
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testPlan plans aws_eip.generated, which has no resource block in the
+// configuration, next to configured resources in the root and a module.
+const testPlan = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws"},
+        {"address": "aws_eip.generated", "mode": "managed", "type": "aws_eip", "name": "generated", "provider_name": "registry.terraform.io/hashicorp/aws"}
+      ],
+      "child_modules": [
+        {
+          "address": "module.app[\"eu\"]",
+          "resources": [
+            {"address": "module.app[\"eu\"].aws_instance.web[0]", "mode": "managed", "type": "aws_instance", "name": "web", "index": 0, "provider_name": "registry.terraform.io/hashicorp/aws"},
+            {"address": "module.app[\"eu\"].aws_instance.web[1]", "mode": "managed", "type": "aws_instance", "name": "web", "index": 1, "provider_name": "registry.terraform.io/hashicorp/aws"}
+          ]
+        }
+      ]
+    }
+  },
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main"}
+      ],
+      "module_calls": {
+        "app": {
+          "source": "./modules/app",
+          "module": {
+            "resources": [
+              {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web", "depends_on": ["aws_security_group.web"]}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestParsePlan(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlan))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	if got := len(plan.PlannedResources()); got != 4 {
+		t.Errorf("Expected 4 planned resources, got %d", got)
+	}
+	if !plan.ConfiguredAddresses()["module.app.aws_instance.web"] {
+		t.Errorf("Expected module resources to be prefixed with their call, got %v", plan.ConfiguredAddresses())
+	}
+}
+
+func TestParsePlanGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write([]byte(testPlan)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	plainFile := filepath.Join(dir, "plan.json")
+	gzipFile := filepath.Join(dir, "plan.json.gz")
+	if err := os.WriteFile(plainFile, []byte(testPlan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(gzipFile, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := ParsePlanFile(plainFile)
+	if err != nil {
+		t.Fatalf("ParsePlanFile(%s) failed: %v", plainFile, err)
+	}
+	gzipped, err := ParsePlanFile(gzipFile)
+	if err != nil {
+		t.Fatalf("ParsePlanFile(%s) failed: %v", gzipFile, err)
+	}
+	if !reflect.DeepEqual(gzipped, plain) {
+		t.Errorf("Expected the gzipped plan to parse like the plain one:\ngot  %+v\nwant %+v", gzipped, plain)
+	}
+	if !reflect.DeepEqual(gzipped.Graph(), plain.Graph()) {
+		t.Error("Expected the gzipped plan to build the same graph")
+	}
+
+	if _, err := ParsePlan(compressed.Bytes()[:compressed.Len()/2]); err == nil {
+		t.Error("Expected an error for a truncated gzip stream")
+	}
+}
+
+func TestIsPlanJSON(t *testing.T) {
+	for path, want := range map[string]bool{
+		"plan.json":      true,
+		"plan.json.gz":   true,
+		"plan.tfplan":    false,
+		"tfplan.binary":  false,
+		"plan.tfplan.gz": false,
+	} {
+		if got := IsPlanJSON(path); got != want {
+			t.Errorf("IsPlanJSON(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestParsePlanRejectsNonPlan(t *testing.T) {
+	if _, err := ParsePlan([]byte(`{"version": 4}`)); err == nil {
+		t.Error("Expected an error for JSON without format_version")
+	}
+	if _, err := ParsePlan([]byte(`not json`)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+// testPlanChanges has one resource instance for each kind of planned action.
+const testPlanChanges = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.main", "change": {"actions": ["no-op"]}},
+    {"address": "aws_subnet.a", "change": {"actions": ["update"]}},
+    {"address": "aws_instance.web[1]", "change": {"actions": ["create"]}},
+    {"address": "aws_instance.web[0]", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_eip.old", "change": {"actions": ["delete"]}},
+    {"address": "data.aws_ami.ubuntu", "change": {"actions": ["read"]}}
+  ]
+}`
+
+func TestChangedResources(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlanChanges))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	changed, deleted := plan.ChangedResources()
+	expectedChanged := []string{"aws_instance.web[0]", "aws_instance.web[1]", "aws_subnet.a", "data.aws_ami.ubuntu"}
+	if !reflect.DeepEqual(changed, expectedChanged) {
+		t.Errorf("Expected changed resources %v, got %v", expectedChanged, changed)
+	}
+	if !reflect.DeepEqual(deleted, []string{"aws_eip.old"}) {
+		t.Errorf("Expected deleted resources [aws_eip.old], got %v", deleted)
+	}
+}
+
+func TestUnconfiguredResources(t *testing.T) {
+	plan, err := ParsePlan([]byte(testPlan))
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+
+	got := plan.UnconfiguredResources()
+	want := []string{"aws_eip.generated"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnconfiguredResources() = %v, want %v", got, want)
+	}
+}
+
+func TestConfigAddress(t *testing.T) {
+	tests := map[string]string{
+		"aws_vpc.main":                         "aws_vpc.main",
+		"aws_subnet.public[0]":                 "aws_subnet.public",
+		`module.app["eu"].aws_instance.web[1]`: "module.app.aws_instance.web",
+		`aws_s3_bucket.b["a]b"]`:               "aws_s3_bucket.b",
+		"module.a[0].module.b.data.aws_ami.x":  "module.a.module.b.data.aws_ami.x",
+	}
+	for address, want := range tests {
+		if got := ConfigAddress(address); got != want {
+			t.Errorf("ConfigAddress(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"terraform-graphx/internal/graph"
+)
+
+// TerraformState is the subset of the terraform.tfstate (version 4) schema used
+// to build a graph.
+type TerraformState struct {
+	Version   int             `json:"version"`
+	Resources []StateResource `json:"resources"`
+}
+
+// StateResource is a resource block of the state; counted and for_each
+// resources have one instance per index key.
+type StateResource struct {
+	Module    string          `json:"module,omitempty"`
+	Mode      string          `json:"mode"`
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Provider  string          `json:"provider"`
+	Instances []StateInstance `json:"instances"`
+}
+
+// StateInstance is a single instance of a resource.
+type StateInstance struct {
+	IndexKey     interface{}            `json:"index_key,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Dependencies []string               `json:"dependencies,omitempty"`
+}
+
+// address returns the resource address without an instance key,
+// e.g. "module.network.data.aws_ami.ubuntu".
+func (r StateResource) address() string {
+	address := r.Type + "." + r.Name
+	if r.Mode == "data" {
+		address = "data." + address
+	}
+	if r.Module != "" {
+		address = r.Module + "." + address
+	}
+	return address
+}
+
+// ParseStateFile reads a terraform.tfstate file and converts it to a graph.
+func ParseStateFile(path string) (*graph.Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return ParseState(data)
+}
+
+// LoadStateFile reads and decodes a terraform.tfstate file without building a graph.
+func LoadStateFile(path string) (*TerraformState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return LoadState(data)
+}
+
+// LoadState decodes terraform.tfstate JSON without building a graph.
+func LoadState(data []byte) (*TerraformState, error) {
+	return decodeState(data)
+}
+
+// decodeState unmarshals terraform.tfstate JSON and checks its version.
+func decodeState(data []byte) (*TerraformState, error) {
+	var state TerraformState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state JSON: %w", err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version %d (expected 4)", state.Version)
+	}
+	return &state, nil
+}
+
+// ParseState converts terraform.tfstate JSON to a graph. Each resource instance
+// becomes a node; the resource-level addresses in an instance's dependencies
+// become edges to every instance of the referenced resource in the same module
+// instance. Both managed resources and data sources are included, as in
+// `terraform graph`.
+func ParseState(data []byte) (*graph.Graph, error) {
+	state, err := decodeState(data)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &graph.Graph{
+		Nodes: make([]graph.Node, 0),
+		Edges: make([]graph.Edge, 0),
+	}
+
+	// Map configuration addresses to their instance addresses to resolve
+	// dependencies, which carry no module instance keys
+	instances := make(resourceInstances)
+	for _, resource := range state.Resources {
+		base := resource.address()
+		for _, instance := range resource.Instances {
+			address := base + indexSuffix(instance.IndexKey)
+			config := ConfigAddress(address)
+			instances[config] = append(instances[config], address)
+
+			g.Nodes = append(g.Nodes, graph.Node{
+				ID:         address,
+				Type:       resource.Type,
+				Provider:   providerName(resource.Provider),
+				Name:       resource.Name,
+				Attributes: instance.Attributes,
+			})
+		}
+	}
+
+	seen := make(map[graph.Edge]bool)
+	for _, resource := range state.Resources {
+		base := resource.address()
+		for _, instance := range resource.Instances {
+			from := base + indexSuffix(instance.IndexKey)
+			for _, dependency := range instance.Dependencies {
+				for _, to := range instances.resolve(from, dependency) {
+					edge := graph.Edge{From: from, To: to, Relation: "DEPENDS_ON", Kind: dataKind(to)}
+					if from != to && !seen[edge] {
+						seen[edge] = true
+						g.Edges = append(g.Edges, edge)
+					}
+				}
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// dataKind returns graph.KindData for a dependency on the data source at to,
+// and "" otherwise, as state and `terraform graph` don't record whether a
+// dependency was declared with depends_on.
+func dataKind(to string) string {
+	if graph.IsDataSource(to) {
+		return graph.KindData
+	}
+	return ""
+}
+
+// resourceInstances maps a configuration address (without module or resource
+// instance keys) to the addresses of its instances.
+type resourceInstances map[string][]string
+
+// resolve returns the instance addresses a reference from the instance at from
+// points to. A reference to a single instance (aws_subnet.public[0],
+// aws_subnet.public["a"]) resolves to that instance; a reference to the whole
+// collection (aws_subnet.public) resolves to every instance. An indexed
+// reference that matches no instance is collapsed to its base address. Inside
+// counted or for_each modules, a reference only reaches the instances of the
+// same module instance, e.g. module.net[0] resources reach module.net[0]
+// resources.
+func (r resourceInstances) resolve(from, ref string) []string {
+	all := r[ConfigAddress(ref)]
+	for _, address := range all {
+		if address == ref {
+			return []string{address}
+		}
+	}
+
+	var targets []string
+	for _, address := range all {
+		if sameModuleInstances(from, address) {
+			targets = append(targets, address)
+		}
+	}
+	return targets
+}
+
+// indexSuffix renders an instance key as it appears in addresses:
+// [0] for count, ["key"] for for_each, and nothing for single instances.
+func indexSuffix(key interface{}) string {
+	switch k := key.(type) {
+	case nil:
+		return ""
+	case string:
+		return fmt.Sprintf("[%q]", k)
+	case float64:
+		return fmt.Sprintf("[%d]", int64(k))
+	default:
+		return fmt.Sprintf("[%v]", k)
+	}
+}
+
+// providerName extracts the short provider name from a state provider string,
+// e.g. provider["registry.terraform.io/hashicorp/aws"].west -> aws.
+func providerName(provider string) string {
+	start := strings.Index(provider, `["`)
+	end := strings.LastIndex(provider, `"]`)
+	if start == -1 || end <= start {
+		return provider
+	}
+	source := provider[start+2 : end]
+	return source[strings.LastIndex(source, "/")+1:]
+}
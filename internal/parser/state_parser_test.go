@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testState = `{
+  "version": 4,
+  "terraform_version": "1.9.0",
+  "resources": [
+    {
+      "mode": "data",
+      "type": "aws_ami",
+      "name": "ubuntu",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"attributes": {"id": "ami-123"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_vpc",
+      "name": "main",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [{"attributes": {"cidr_block": "10.0.0.0/16"}}]
+    },
+    {
+      "module": "module.network",
+      "mode": "managed",
+      "type": "aws_subnet",
+      "name": "public",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"].west",
+      "instances": [
+        {"index_key": 0, "dependencies": ["aws_vpc.main"]},
+        {"index_key": 1, "dependencies": ["aws_vpc.main"]}
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "web",
+      "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+      "instances": [
+        {"index_key": "blue", "dependencies": ["data.aws_ami.ubuntu", "module.network.aws_subnet.public"]}
+      ]
+    }
+  ]
+}`
+
+func TestParseState(t *testing.T) {
+	g, err := ParseState([]byte(testState))
+	if err != nil {
+		t.Fatalf("ParseState failed: %v", err)
+	}
+
+	expectedNodes := map[string]string{
+		"data.aws_ami.ubuntu":                 "aws_ami",
+		"aws_vpc.main":                        "aws_vpc",
+		"module.network.aws_subnet.public[0]": "aws_subnet",
+		"module.network.aws_subnet.public[1]": "aws_subnet",
+		`aws_instance.web["blue"]`:            "aws_instance",
+	}
+	if len(g.Nodes) != len(expectedNodes) {
+		t.Errorf("Expected %d nodes, got %d", len(expectedNodes), len(g.Nodes))
+	}
+	for _, node := range g.Nodes {
+		wantType, ok := expectedNodes[node.ID]
+		if !ok {
+			t.Errorf("Unexpected node %s", node.ID)
+			continue
+		}
+		if node.Type != wantType {
+			t.Errorf("%s: expected type %s, got %s", node.ID, wantType, node.Type)
+		}
+		if node.Provider != "aws" {
+			t.Errorf("%s: expected provider aws, got %s", node.ID, node.Provider)
+		}
+	}
+
+	expectedEdges := map[[2]string]bool{
+		{"module.network.aws_subnet.public[0]", "aws_vpc.main"}:             true,
+		{"module.network.aws_subnet.public[1]", "aws_vpc.main"}:             true,
+		{`aws_instance.web["blue"]`, "data.aws_ami.ubuntu"}:                 true,
+		{`aws_instance.web["blue"]`, "module.network.aws_subnet.public[0]"}: true,
+		{`aws_instance.web["blue"]`, "module.network.aws_subnet.public[1]"}: true,
+	}
+	if len(g.Edges) != len(expectedEdges) {
+		t.Errorf("Expected %d edges, got %d: %v", len(expectedEdges), len(g.Edges), g.Edges)
+	}
+	for _, edge := range g.Edges {
+		if !expectedEdges[[2]string{edge.From, edge.To}] {
+			t.Errorf("Unexpected edge %s -> %s", edge.From, edge.To)
+		}
+		if edge.Relation != "DEPENDS_ON" {
+			t.Errorf("Expected DEPENDS_ON relation, got %s", edge.Relation)
+		}
+		// State does not record depends_on, so only data source reads have a kind
+		wantKind := ""
+		if edge.To == "data.aws_ami.ubuntu" {
+			wantKind = "data"
+		}
+		if edge.Kind != wantKind {
+			t.Errorf("%s -> %s: expected kind %q, got %q", edge.From, edge.To, wantKind, edge.Kind)
+		}
+	}
+}
+
+func TestParseStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terraform.tfstate")
+	if err := os.WriteFile(path, []byte(testState), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	g, err := ParseStateFile(path)
+	if err != nil {
+		t.Fatalf("ParseStateFile failed: %v", err)
+	}
+	if len(g.Nodes) != 5 {
+		t.Errorf("Expected 5 nodes, got %d", len(g.Nodes))
+	}
+}
+
+func TestParseStateUnsupportedVersion(t *testing.T) {
+	if _, err := ParseState([]byte(`{"version": 3, "modules": []}`)); err == nil {
+		t.Error("Expected error for unsupported state version")
+	}
+}
+
+func TestParseStateModuleInstances(t *testing.T) {
+	// Dependencies name the configuration address, without module instance keys
+	const state = `{
+  "version": 4,
+  "resources": [
+    {"module": "module.net[0]", "mode": "managed", "type": "aws_vpc", "name": "this",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{}]},
+    {"module": "module.net[0]", "mode": "managed", "type": "aws_subnet", "name": "this",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{"dependencies": ["module.net.aws_vpc.this"]}]},
+    {"module": "module.app[\"eu\"]", "mode": "managed", "type": "aws_vpc", "name": "this",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{}]},
+    {"module": "module.app[\"eu\"]", "mode": "managed", "type": "aws_subnet", "name": "this",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{"dependencies": ["module.app.aws_vpc.this"]}]},
+    {"module": "module.app[\"us\"]", "mode": "managed", "type": "aws_vpc", "name": "this",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{}]},
+    {"module": "module.app[\"us\"]", "mode": "managed", "type": "aws_subnet", "name": "this",
+     "provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+     "instances": [{"dependencies": ["module.app.aws_vpc.this"]}]}
+  ]
+}`
+
+	g, err := ParseState([]byte(state))
+	if err != nil {
+		t.Fatalf("ParseState failed: %v", err)
+	}
+	if len(g.Nodes) != 6 {
+		t.Errorf("Expected 6 nodes, got %d", len(g.Nodes))
+	}
+
+	var got [][2]string
+	for _, edge := range g.Edges {
+		got = append(got, [2]string{edge.From, edge.To})
+	}
+	want := [][2]string{
+		{"module.net[0].aws_subnet.this", "module.net[0].aws_vpc.this"},
+		{`module.app["eu"].aws_subnet.this`, `module.app["eu"].aws_vpc.this`},
+		{`module.app["us"].aws_subnet.this`, `module.app["us"].aws_vpc.this`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected edges %v, got %v", want, got)
+	}
+}
+
+func TestResourceInstancesResolve(t *testing.T) {
+	instances := resourceInstances{
+		"aws_subnet.public":      {"aws_subnet.public[0]", "aws_subnet.public[1]"},
+		"aws_subnet.private":     {`aws_subnet.private["a"]`, `aws_subnet.private["b"]`},
+		"module.net.aws_vpc.vpc": {"module.net[0].aws_vpc.vpc", "module.net[1].aws_vpc.vpc"},
+	}
+
+	tests := []struct {
+		from string
+		ref  string
+		want []string
+	}{
+		{"aws_instance.web", "aws_subnet.public", []string{"aws_subnet.public[0]", "aws_subnet.public[1]"}},
+		{"aws_instance.web", "aws_subnet.public[1]", []string{"aws_subnet.public[1]"}},
+		{"aws_instance.web", `aws_subnet.private["b"]`, []string{`aws_subnet.private["b"]`}},
+		{"aws_instance.web", "aws_subnet.private", []string{`aws_subnet.private["a"]`, `aws_subnet.private["b"]`}},
+		// Unknown index collapses to the whole collection
+		{"aws_instance.web", "aws_subnet.public[7]", []string{"aws_subnet.public[0]", "aws_subnet.public[1]"}},
+		// Within a module instance, only that instance's resources are reached
+		{"module.net[1].aws_subnet.a", "module.net.aws_vpc.vpc", []string{"module.net[1].aws_vpc.vpc"}},
+		// From outside the module, every module instance is reached
+		{"aws_instance.web", "module.net.aws_vpc.vpc", []string{"module.net[0].aws_vpc.vpc", "module.net[1].aws_vpc.vpc"}},
+		{"aws_instance.web", "module.net[0].aws_vpc.vpc", []string{"module.net[0].aws_vpc.vpc"}},
+		{"aws_instance.web", "aws_subnet.missing", nil},
+	}
+
+	for _, tt := range tests {
+		got := instances.resolve(tt.from, tt.ref)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolve(%s, %s) = %v, want %v", tt.from, tt.ref, got, tt.want)
+		}
+	}
+}
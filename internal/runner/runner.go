@@ -1,51 +1,623 @@
 package runner
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"os"
 	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/formatter"
 	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/logging"
 	"terraform-graphx/internal/neo4j"
 	graphparser "terraform-graphx/internal/parser"
+	"terraform-graphx/internal/s3state"
 
 	"github.com/awalterschulze/gographviz"
 )
 
 // Run executes the main logic of terraform-graphx.
-func Run(cfg *config.Config) error {
+func Run(ctx context.Context, cfg *config.Config) error {
 	// Validate Neo4j configuration early
-	if err := validateNeo4jConfig(&cfg.Neo4j); err != nil {
+	if err := validateNeo4jConfig(ctx, &cfg.Neo4j); err != nil {
 		return err
 	}
+	// Obsolete resources are found by comparing the database with the graph, so
+	// a filtered graph would mark everything it leaves out as obsolete
+	if filter := resourceFilter(cfg); cfg.Prune && filter != "" {
+		return fmt.Errorf("--prune can't be used with %s: every stored resource outside the filter would be deleted", filter)
+	}
+	if cfg.SoftDeleteDetach && !cfg.SoftDelete {
+		logging.Warnf("--soft-delete-detach has no effect without --soft-delete")
+	}
+	if cfg.SoftDelete && !cfg.Prune {
+		logging.Warnf("--soft-delete has no effect without --prune: obsolete resources are kept")
+	}
+
+	var g *graph.Graph
+	var changes *neo4j.ChangeSet
+	var err error
+	if cfg.Incremental {
+		g, changes, err = buildIncrementalGraph(ctx, cfg)
+	} else {
+		g, err = buildOutputGraph(ctx, cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if nothingToUpdate(g, changes, cfg) {
+		logging.Infof("Nothing to update: the graph is empty")
+		return nil
+	}
+
+	if cfg.FailOnCycle {
+		if err := checkCycles(g, cfg.AllowSelfEdges); err != nil {
+			return err
+		}
+	}
+
+	if cfg.FailOnChange {
+		return failOnChange(ctx, os.Stdout, g, changes, cfg)
+	}
+
+	if cfg.DryRun {
+		return dryRun(ctx, g, changes, cfg)
+	}
+
+	// Update Neo4j database
+	return updateNeo4jDatabase(ctx, g, changes, cfg)
+}
+
+// graphFields returns the node and edge counts of g as log fields.
+func graphFields(g *graph.Graph) logging.Fields {
+	return logging.Fields{"nodes": len(g.Nodes), "edges": len(g.Edges)}
+}
+
+// resourceFilter returns the flag of a configured filter that drops resources
+// from the graph, or "" when every resource is kept.
+func resourceFilter(cfg *config.Config) string {
+	switch {
+	case len(cfg.IncludeTypes) > 0:
+		return "--type"
+	case len(cfg.ExcludeTypes) > 0:
+		return "--exclude-type"
+	case len(cfg.Include) > 0:
+		return "--include"
+	case len(cfg.Exclude) > 0:
+		return "--exclude"
+	case len(cfg.Attributes.Filter) > 0:
+		return "--filter-attr"
+	case cfg.Module != "":
+		return "--module"
+	case cfg.RootModule != "":
+		return "--root-module"
+	case len(cfg.EntryTypes) > 0:
+		return "--entry-type"
+	}
+	return ""
+}
+
+// nothingToUpdate reports whether an update of g would neither write nor
+// delete anything. An empty graph still deletes stored resources with --prune,
+// e.g. after terraform destroy or for a destroy-only incremental plan.
+func nothingToUpdate(g *graph.Graph, changes *neo4j.ChangeSet, cfg *config.Config) bool {
+	if len(g.Nodes) > 0 {
+		return false
+	}
+	if changes != nil {
+		return !cfg.Prune || len(changes.Deleted) == 0
+	}
+	return !cfg.Prune
+}
+
+// Export builds the graph and writes it in cfg.Format to cfg.Output (or stdout).
+func Export(ctx context.Context, cfg *config.Config) error {
+	if err := ValidateFormat(cfg.Format); err != nil {
+		return err
+	}
+
+	g, err := buildOutputGraph(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	return handleOutput(g, cfg)
+}
+
+// BuildGraph generates the Terraform graph, parses it and applies the configured
+// filters. Nodes and edges are returned sorted so output is stable across runs.
+func BuildGraph(ctx context.Context, cfg *config.Config) (*graph.Graph, error) {
+	g, err := loadGraph(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return prepareGraph(g, cfg)
+}
+
+// prepareGraph applies the configured filters to a loaded graph, annotates it
+// and adds the optional module and provider nodes.
+func prepareGraph(g *graph.Graph, cfg *config.Config) (*graph.Graph, error) {
+	var err error
+
+	// Keep only what the configured root module reaches
+	if cfg.RootModule != "" {
+		g = graph.ReachableFromRoots(g, cfg.RootModule)
+		logging.WithFields(graphFields(g)).Infof("Pruned graph to resources reachable from %s: %d nodes, %d edges", cfg.RootModule, len(g.Nodes), len(g.Edges))
+	}
+
+	// Keep only what the entry point types reach
+	if len(cfg.EntryTypes) > 0 {
+		g = graph.ReachableFrom(g, graph.EntryPoints(g, cfg.EntryTypes))
+		logging.WithFields(graphFields(g)).Infof("Pruned graph to resources reachable from %s: %d nodes, %d edges", strings.Join(cfg.EntryTypes, ", "), len(g.Nodes), len(g.Edges))
+	}
+
+	// Apply resource type filters
+	if len(cfg.IncludeTypes) > 0 || len(cfg.ExcludeTypes) > 0 {
+		g = graph.FilterByType(g, cfg.IncludeTypes, cfg.ExcludeTypes)
+		logging.WithFields(graphFields(g)).Infof("Filtered graph by resource type: %d nodes, %d edges", len(g.Nodes), len(g.Edges))
+	}
+
+	// Apply address filters
+	if len(cfg.Include) > 0 || len(cfg.Exclude) > 0 {
+		if err := graph.ValidateGlobs(append(append([]string(nil), cfg.Include...), cfg.Exclude...)); err != nil {
+			return nil, err
+		}
+		if len(cfg.Include) > 0 {
+			g = graph.FilterByGlob(g, cfg.Include, true)
+		}
+		if len(cfg.Exclude) > 0 {
+			g = graph.FilterByGlob(g, cfg.Exclude, false)
+		}
+		logging.WithFields(graphFields(g)).Infof("Filtered graph by address: %d nodes, %d edges", len(g.Nodes), len(g.Edges))
+	}
+
+	// Keep only resources with matching attributes
+	if len(cfg.Attributes.Filter) > 0 {
+		matchers := make([]graph.AttributeMatcher, len(cfg.Attributes.Filter))
+		for i, filter := range cfg.Attributes.Filter {
+			if matchers[i], err = graph.ParseAttributeMatcher(filter); err != nil {
+				return nil, err
+			}
+		}
+		g = graph.FilterByAttribute(g, matchers)
+		logging.WithFields(graphFields(g)).Infof("Filtered graph by attributes: %d nodes, %d edges", len(g.Nodes), len(g.Edges))
+	}
+
+	// Scope the graph to a module subtree
+	if cfg.Module != "" {
+		g = graph.FilterByModulePrefix(g, cfg.Module)
+		logging.WithFields(graphFields(g)).Infof("Scoped graph to %s: %d nodes, %d edges", cfg.Module, len(g.Nodes), len(g.Edges))
+	}
+
+	// Keep only dependencies of the requested kinds
+	if len(cfg.RelationKinds) > 0 {
+		if err := graph.ValidateRelationKinds(cfg.RelationKinds); err != nil {
+			return nil, err
+		}
+		g = graph.FilterByRelationKind(g, cfg.RelationKinds)
+		logging.WithFields(logging.Fields{"edges": len(g.Edges)}).Infof("Filtered edges by relation kind (%s): %d edges", strings.Join(cfg.RelationKinds, ", "), len(g.Edges))
+	}
+
+	graph.AnnotateDegrees(g)
+
+	// Modules and providers are added last so they don't affect filters or dependency counts
+	if cfg.WithModules {
+		g = graph.AddModules(g)
+	}
+	if cfg.WithProviders {
+		g = graph.AddProviders(g)
+	}
+
+	graph.Sort(g)
+	return g, nil
+}
+
+// buildOutputGraph builds the graph that is written to Neo4j or exported, with
+// its dependency edges in the configured direction. Analyses keep using
+// BuildGraph, whose edges always point from a resource to its dependencies.
+func buildOutputGraph(ctx context.Context, cfg *config.Config) (*graph.Graph, error) {
+	if err := graph.ValidateEdgeDirection(cfg.EdgeDirection); err != nil {
+		return nil, err
+	}
+
+	g, err := BuildGraph(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return orientGraph(g, cfg), nil
+}
+
+// orientGraph points the dependency edges of g in the configured direction.
+func orientGraph(g *graph.Graph, cfg *config.Config) *graph.Graph {
+	if cfg.EdgeDirection == graph.DirectionRequiredBy {
+		g = graph.OrientEdges(g, cfg.EdgeDirection)
+		graph.Sort(g)
+	}
+	return g
+}
+
+// buildIncrementalGraph builds the output graph from the plan, like
+// buildOutputGraph, and returns the resources the plan changes. There is no
+// fallback to `terraform graph`, which has no change set.
+func buildIncrementalGraph(ctx context.Context, cfg *config.Config) (*graph.Graph, *neo4j.ChangeSet, error) {
+	cfg, err := withReusedPlan(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.PlanFile == "" || cfg.StateFile != "" || cfg.StateS3 != "" {
+		return nil, nil, fmt.Errorf("--incremental requires a plan and can't be used with --state or --state-s3")
+	}
+	if len(cfg.PlanFiles) > 1 {
+		return nil, nil, fmt.Errorf("--incremental requires a single plan")
+	}
+	if err := graph.ValidateEdgeDirection(cfg.EdgeDirection); err != nil {
+		return nil, nil, err
+	}
+
+	resolver := newTerraformResolver(&cfg.Terraform)
+	plan, err := loadPlan(ctx, cfg, resolver)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := prepareGraph(planGraph(ctx, cfg, plan, resolver), cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, deleted := plan.ChangedResources()
+	logging.WithFields(logging.Fields{"changed": len(changed), "deleted": len(deleted)}).Infof("Plan changes %d resource(s) and deletes %d", len(changed), len(deleted))
+	return orientGraph(g, cfg), &neo4j.ChangeSet{Changed: changed, Deleted: deleted}, nil
+}
+
+// Inspect returns the parser's raw view of the input loadGraph would read, for
+// debugging: the decoded state of a state file or S3 object, the parsed plan
+// JSON of a plan file (a list of them, in path order, for several), otherwise
+// the DOT nodes and edges of `terraform graph` with the addresses derived from
+// them.
+func Inspect(ctx context.Context, cfg *config.Config) (interface{}, error) {
+	if err := checkGraphSources(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.StateS3 != "" {
+		logging.Infof("Downloading Terraform state from %s...", cfg.StateS3)
+		data, err := s3state.Fetch(ctx, cfg.StateS3)
+		if err != nil {
+			return nil, err
+		}
+		return graphparser.LoadState(data)
+	}
+	if cfg.StateFile != "" {
+		return graphparser.LoadStateFile(cfg.StateFile)
+	}
+
+	resolver := newTerraformResolver(&cfg.Terraform)
+
+	if len(cfg.PlanFiles) > 1 {
+		paths := append([]string(nil), cfg.PlanFiles...)
+		sort.Strings(paths)
+		plans := make([]PlanInspection, 0, len(paths))
+		for _, path := range paths {
+			planCfg := *cfg
+			planCfg.PlanFile = path
+			plan, err := loadPlan(ctx, &planCfg, resolver)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read plan %s: %w", path, err)
+			}
+			plans = append(plans, PlanInspection{PlanFile: path, Plan: plan})
+		}
+		return plans, nil
+	}
+
+	cfg, err := withReusedPlan(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PlanFile != "" {
+		plan, err := loadPlan(ctx, cfg, resolver)
+		if err == nil {
+			return plan, nil
+		}
+		if graphparser.IsPlanJSON(cfg.PlanFile) {
+			return nil, err
+		}
+		logging.Warnf("Falling back to terraform graph: %v", err)
+	}
+
+	tf, err := resolver.cli(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dotGraph, err := generateTerraformGraph(ctx, tf, cfg.PlanFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate graph data: %w", err)
+	}
+	return graphparser.InspectDOT(dotGraph)
+}
+
+// PlanInspection is the parsed plan JSON of one of several plan files.
+type PlanInspection struct {
+	PlanFile string                     `json:"plan_file"`
+	Plan     *graphparser.TerraformPlan `json:"plan"`
+}
+
+// checkGraphSources rejects combinations of graph sources loadGraph can't
+// read, and warns that a plan is ignored when a state is read.
+func checkGraphSources(cfg *config.Config) error {
+	if cfg.StateFile != "" && cfg.StateS3 != "" {
+		return fmt.Errorf("--state and --state-s3 can't be used together")
+	}
+	if cfg.StateFile != "" || cfg.StateS3 != "" {
+		if len(cfg.PlanFiles) > 1 {
+			return fmt.Errorf("several plan files can't be used with --state or --state-s3")
+		}
+		if cfg.PlanFile != "" {
+			logging.Warnf("Ignoring plan %s: the graph is read from the state", cfg.PlanFile)
+		}
+	}
+	return nil
+}
+
+// loadGraph reads the graph from the configured state file or S3 object, or
+// from the plan JSON of the configured plan file, which unlike `terraform graph`
+// carries providers and attributes. `terraform graph` is used when none is set,
+// or when the plan JSON can't be read.
+func loadGraph(ctx context.Context, cfg *config.Config) (*graph.Graph, error) {
+	if err := checkGraphSources(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.StateS3 != "" {
+		logging.Infof("Downloading Terraform state from %s...", cfg.StateS3)
+		data, err := s3state.Fetch(ctx, cfg.StateS3)
+		if err != nil {
+			return nil, err
+		}
+		g, err := graphparser.ParseState(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse state file: %w", err)
+		}
+		return g, nil
+	}
+
+	if cfg.StateFile != "" {
+		logging.Infof("Reading Terraform state from %s...", cfg.StateFile)
+		g, err := graphparser.ParseStateFile(cfg.StateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse state file: %w", err)
+		}
+		return g, nil
+	}
+
+	// Every Terraform command of the run shares one executable
+	resolver := newTerraformResolver(&cfg.Terraform)
+
+	if len(cfg.PlanFiles) > 1 {
+		return loadPlanGraphs(ctx, cfg, resolver)
+	}
+
+	cfg, err := withReusedPlan(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PlanFile != "" {
+		plan, err := loadPlan(ctx, cfg, resolver)
+		if err == nil {
+			g := planGraph(ctx, cfg, plan, resolver)
+			if len(g.Nodes) == 0 {
+				logging.Infof("No resources found in plan")
+			}
+			return g, nil
+		}
+		// A plan JSON file has no DOT fallback
+		if graphparser.IsPlanJSON(cfg.PlanFile) {
+			return nil, err
+		}
+		logging.Warnf("Falling back to terraform graph: %v", err)
+	}
+
+	tf, err := resolver.cli(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate and parse Terraform graph
-	log.Println("Generating Terraform graph...")
-	dotGraph, err := generateTerraformGraph(cfg.PlanFile)
+	logging.Infof("Generating Terraform graph...")
+	dotGraph, err := generateTerraformGraph(ctx, tf, cfg.PlanFile)
 	if err != nil {
-		return fmt.Errorf("failed to generate graph data: %w", err)
+		return nil, fmt.Errorf("failed to generate graph data: %w", err)
 	}
 
 	// Parse the graph data directly from gographviz
-	log.Println("Parsing graph data...")
+	logging.Debugf("Parsing graph data...")
 	g, err := graphparser.ParseGraph(dotGraph)
 	if err != nil {
-		return fmt.Errorf("failed to parse graph data: %w", err)
+		return nil, fmt.Errorf("failed to parse graph data: %w", err)
+	}
+	if len(g.Nodes) == 0 {
+		logging.Infof("No resources found in configuration")
 	}
 
-	// Update Neo4j database
-	return updateNeo4jDatabase(g, &cfg.Neo4j)
+	return g, nil
+}
+
+// loadPlanGraphs builds the graph of each of cfg.PlanFiles with up to
+// cfg.Concurrency workers and merges them, with attributes combined. Graphs are
+// merged in the order of their paths, so the result doesn't depend on which
+// plan is read first. Unlike a single plan, there is no fallback to
+// `terraform graph`.
+func loadPlanGraphs(ctx context.Context, cfg *config.Config, resolver *terraformResolver) (*graph.Graph, error) {
+	paths := append([]string(nil), cfg.PlanFiles...)
+	sort.Strings(paths)
+
+	workers := cfg.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	// The first failure stops the plans not read yet
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	graphs := make([]*graph.Graph, len(paths))
+	errs := make([]error, len(paths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				planCfg := *cfg
+				planCfg.PlanFile = paths[i]
+				plan, err := loadPlan(ctx, &planCfg, resolver)
+				if err != nil {
+					errs[i] = fmt.Errorf("failed to read plan %s: %w", paths[i], err)
+					cancel()
+					continue
+				}
+				graphs[i] = plan.Graph()
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Report the first plan that failed by itself, rather than one cancelled
+	// because of it
+	var cancelled error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if cancelled == nil {
+			cancelled = err
+		}
+	}
+	if cancelled != nil {
+		return nil, cancelled
+	}
+	logging.WithFields(logging.Fields{"plans": len(paths)}).Infof("Merged %d plans", len(paths))
+	return graph.Merge(graph.AttributesUnion, graphs...), nil
+}
+
+// LoadPlan reads the plan JSON of cfg.PlanFile: the file itself when it ends in
+// .json or .json.gz, otherwise the output of `terraform show -json` for the
+// saved plan.
+func LoadPlan(ctx context.Context, cfg *config.Config) (*graphparser.TerraformPlan, error) {
+	return loadPlan(ctx, cfg, newTerraformResolver(&cfg.Terraform))
+}
+
+// loadPlan is LoadPlan with the executable of the run, for saved plans.
+func loadPlan(ctx context.Context, cfg *config.Config, resolver *terraformResolver) (*graphparser.TerraformPlan, error) {
+	if cfg.PlanFile == "" {
+		return nil, fmt.Errorf("a plan file is required")
+	}
+
+	var plan *graphparser.TerraformPlan
+	if graphparser.IsPlanJSON(cfg.PlanFile) {
+		var err error
+		if plan, err = graphparser.ParsePlanFile(cfg.PlanFile); err != nil {
+			return nil, err
+		}
+	} else {
+		tf, err := resolver.cli(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		logging.Infof("Reading plan %s...", cfg.PlanFile)
+		data, err := showPlanJSON(ctx, tf, cfg.PlanFile)
+		if err != nil {
+			return nil, err
+		}
+		if plan, err = graphparser.ParsePlan(data); err != nil {
+			return nil, err
+		}
+	}
+	plan.MaxReferenceDepth = cfg.MaxReferenceDepth
+	plan.AllowSelfEdges = cfg.AllowSelfEdges
+	return plan, nil
+}
+
+// planGraph builds the graph of plan. For a saved plan, the dependencies of
+// `terraform graph` are added to it: the plan JSON has no locals, so
+// dependencies made through them are only found there. When `terraform graph`
+// fails, the plan's own dependencies are kept and a warning is logged.
+func planGraph(ctx context.Context, cfg *config.Config, plan *graphparser.TerraformPlan, resolver *terraformResolver) *graph.Graph {
+	g := plan.Graph()
+	if graphparser.IsPlanJSON(cfg.PlanFile) {
+		return g
+	}
+
+	configGraph, err := loadConfigGraph(ctx, resolver)
+	if err != nil {
+		logging.Warnf("Dependencies through locals may be missing: %v", err)
+		return g
+	}
+	return graphparser.AddConfigEdges(g, configGraph)
+}
+
+// loadConfigGraph parses the resource graph of the configuration, as written
+// by `terraform graph` without a plan.
+func loadConfigGraph(ctx context.Context, resolver *terraformResolver) (*graph.Graph, error) {
+	tf, err := resolver.cli(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dotGraph, err := generateTerraformGraph(ctx, tf, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate graph data: %w", err)
+	}
+	return graphparser.ParseGraph(dotGraph)
+}
+
+// showPlanJSON runs `terraform show -json` for a saved plan file.
+func showPlanJSON(ctx context.Context, tf *terraformCLI, planFile string) ([]byte, error) {
+	output, err := tf.command(ctx, "show", "-json", planFile).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("terraform show command failed: %w - %s", err, string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("terraform show command failed: %w", err)
+	}
+	return output, nil
 }
 
 // generateTerraformGraph runs `terraform graph` and parses the DOT output.
-func generateTerraformGraph(planFile string) (*gographviz.Graph, error) {
-	var graphArgs []string
+// Neither Terraform nor OpenTofu can write the graph in another format.
+func generateTerraformGraph(ctx context.Context, tf *terraformCLI, planFile string) (*gographviz.Graph, error) {
+	graphArgs := []string{"graph"}
 	if planFile != "" {
 		graphArgs = append(graphArgs, "-plan="+planFile)
 	}
 
-	terraformGraphCmd := exec.Command("terraform", append([]string{"graph"}, graphArgs...)...)
+	terraformGraphCmd := tf.command(ctx, graphArgs...)
 
 	// Get DOT output from terraform graph
 	dotOutput, err := terraformGraphCmd.CombinedOutput()
@@ -53,47 +625,577 @@ func generateTerraformGraph(planFile string) (*gographviz.Graph, error) {
 		return nil, fmt.Errorf("terraform graph command failed: %w - %s", err, string(dotOutput))
 	}
 
+	return parseDOT(string(dotOutput))
+}
+
+// parseDOT parses DOT output into a gographviz graph. Empty output, as produced
+// for an empty configuration, yields an empty graph. Errors include the first
+// lines of the output.
+func parseDOT(dotOutput string) (*gographviz.Graph, error) {
+	dotGraph := gographviz.NewGraph()
+	if strings.TrimSpace(dotOutput) == "" {
+		return dotGraph, nil
+	}
+
 	// Parse DOT using gographviz
-	graphAst, err := gographviz.ParseString(string(dotOutput))
+	graphAst, err := gographviz.ParseString(dotOutput)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse DOT output: %w", err)
+		return nil, fmt.Errorf("failed to parse DOT output: %w\n%s", err, excerpt(dotOutput, dotExcerptLines))
 	}
 
 	// Convert AST to Graph structure
-	dotGraph := gographviz.NewGraph()
 	if err := gographviz.Analyse(graphAst, dotGraph); err != nil {
-		return nil, fmt.Errorf("failed to analyse graph: %w", err)
+		return nil, fmt.Errorf("failed to analyse graph: %w\n%s", err, excerpt(dotOutput, dotExcerptLines))
 	}
 
 	return dotGraph, nil
 }
 
-func updateNeo4jDatabase(g *graph.Graph, neo4jCfg *config.Neo4jConfig) error {
-	log.Printf("Connecting to Neo4j at %s...", neo4jCfg.URI)
-	ctx := context.Background()
+// dotExcerptLines is the number of output lines included in DOT parse errors.
+const dotExcerptLines = 5
+
+// excerpt returns the first n lines of s, indented, noting how many were left out.
+func excerpt(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	omitted := 0
+	if len(lines) > n {
+		omitted = len(lines) - n
+		lines = lines[:n]
+	}
+
+	var b strings.Builder
+	b.WriteString("output was:\n")
+	for _, line := range lines {
+		b.WriteString("  " + line + "\n")
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "  ... (%d more lines)\n", omitted)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// outputFormat is an export format and the function rendering a graph in it.
+type outputFormat struct {
+	name   string
+	format func(g *graph.Graph, cfg *config.Config) (string, error)
+}
+
+// outputFormats lists the export formats, in the order they are reported.
+var outputFormats = []outputFormat{
+	{"json", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		if cfg.Sanitize {
+			g = formatter.SanitizeGraph(g)
+		}
+		return formatter.ToJSON(g)
+	}},
+	{"cypher", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		// The label is templated into the script, as it is into update's queries
+		if cfg.Neo4j.NodeLabel != "" {
+			if err := formatter.ValidateLabel(cfg.Neo4j.NodeLabel); err != nil {
+				return "", err
+			}
+		}
+		if cfg.CompactCypher {
+			return formatter.ToCypherCompact(g, cypherOptions(cfg))
+		}
+		return formatter.ToCypher(g, cypherOptions(cfg))
+	}},
+	{"graphml", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		return formatter.ToGraphML(g)
+	}},
+	{"dot", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		return formatter.ToDOT(g, DOTOptions(cfg))
+	}},
+	{"cytoscape", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		return formatter.ToCytoscape(g)
+	}},
+	{"plantuml", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		return formatter.ToPlantUML(g)
+	}},
+	{"gexf", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		return formatter.ToGEXF(g)
+	}},
+	{"adjacency", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		return formatter.ToAdjacencyJSON(g)
+	}},
+	{"deps", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		var out strings.Builder
+		if err := formatter.ToDependencyList(g, &out, cfg.DepsSeparator); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}},
+	{"table", func(g *graph.Graph, cfg *config.Config) (string, error) {
+		var out strings.Builder
+		if err := formatter.ToTable(g, &out); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}},
+}
+
+// SupportedFormats returns the names of the export formats.
+func SupportedFormats() []string {
+	names := make([]string, len(outputFormats))
+	for i, f := range outputFormats {
+		names[i] = f.name
+	}
+	return names
+}
+
+// ValidateFormat returns an error naming the supported formats if name is not one of them.
+func ValidateFormat(name string) error {
+	if lookupFormat(name) == nil {
+		return fmt.Errorf("unsupported format %q (supported: %s)", name, strings.Join(SupportedFormats(), ", "))
+	}
+	return nil
+}
+
+func lookupFormat(name string) *outputFormat {
+	for i := range outputFormats {
+		if outputFormats[i].name == name {
+			return &outputFormats[i]
+		}
+	}
+	return nil
+}
+
+// handleOutput formats g and writes it to cfg.Output, or stdout when unset.
+func handleOutput(g *graph.Graph, cfg *config.Config) error {
+	f := lookupFormat(cfg.Format)
+	if f == nil {
+		return ValidateFormat(cfg.Format)
+	}
+
+	output, err := f.format(g, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to format graph: %w", err)
+	}
+
+	if cfg.Output == "" {
+		_, err = fmt.Fprint(os.Stdout, output)
+		return err
+	}
+
+	if err := os.WriteFile(cfg.Output, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	logging.Infof("Wrote %s graph to %s", cfg.Format, cfg.Output)
+	return nil
+}
+
+// checkCycles returns an error listing every dependency cycle in g. Self-loops
+// are not counted when allowSelfEdges is set, since they were asked for.
+func checkCycles(g *graph.Graph, allowSelfEdges bool) error {
+	var cycles [][]string
+	for _, cycle := range graph.FindCycles(g) {
+		if len(cycle) > 1 || !allowSelfEdges {
+			cycles = append(cycles, cycle)
+		}
+	}
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(cycles))
+	for i, cycle := range cycles {
+		descriptions[i] = "[" + strings.Join(cycle, ", ") + "]"
+	}
+	return fmt.Errorf("graph contains %d dependency cycle(s): %s", len(cycles), strings.Join(descriptions, "; "))
+}
+
+// updateNeo4jDatabase writes g to Neo4j, incrementally when changes is set.
+func updateNeo4jDatabase(ctx context.Context, g *graph.Graph, changes *neo4j.ChangeSet, cfg *config.Config) error {
+	neo4jCfg := &cfg.Neo4j
+	logging.Infof("Connecting to Neo4j at %s...", neo4jCfg.URI)
 
-	client, err := neo4j.NewClient(neo4jCfg.URI, neo4jCfg.User, neo4jCfg.Password)
+	client, err := NewNeo4jClient(ctx, neo4jCfg)
 	if err != nil {
 		return fmt.Errorf("failed to create neo4j client: %w", err)
 	}
 	defer client.Close(ctx)
 
+	if err := client.SetNodeLabel(neo4jCfg.NodeLabel); err != nil {
+		return fmt.Errorf("invalid neo4j.node_label: %w", err)
+	}
+
 	if err := client.VerifyConnectivity(ctx); err != nil {
 		return fmt.Errorf("failed to connect to neo4j: %w", err)
 	}
 
-	log.Println("Updating Neo4j database...")
-	if err := client.UpdateGraph(ctx, g); err != nil {
+	opts := updateOptions(cfg)
+	opts.Changes = changes
+	if opts.BatchSize > 0 && showProgress() {
+		opts.Progress = printBatchProgress(os.Stderr)
+	}
+
+	// Incremental updates don't reconcile the whole graph, so they are never
+	// skipped and invalidate the stored fingerprint
+	fingerprint := ""
+	if changes == nil {
+		fingerprint = updateFingerprint(g, opts)
+		stored, err := client.Fingerprint(ctx)
+		if err != nil {
+			logging.Warnf("%v", err)
+		}
+		if skipUpdate(stored, fingerprint, cfg.Force) {
+			logging.Infof("No changes. The graph matches the last update; pass --force to write anyway.")
+			return nil
+		}
+	}
+
+	if cfg.Prune && !cfg.SoftDelete {
+		obsolete, err := obsoleteResources(ctx, client, g, changes)
+		if err != nil {
+			return fmt.Errorf("failed to count obsolete resources: %w", err)
+		}
+		ask := func(question string) (bool, error) {
+			return askYesNo(os.Stdin, os.Stderr, question)
+		}
+		proceed, err := confirmDeletion(len(obsolete), cfg.AssumeYes, stdinIsTerminal(), ask)
+		if err != nil {
+			return err
+		}
+		opts.KeepObsolete = !proceed
+	}
+
+	logging.Infof("Updating Neo4j database...")
+	if err := client.UpdateGraph(ctx, g, opts); err != nil {
 		return fmt.Errorf("failed to update neo4j graph: %w", err)
 	}
+	if changes == nil {
+		// Declining to prune changes what was written
+		fingerprint = updateFingerprint(g, opts)
+	}
+	if err := client.SetFingerprint(ctx, fingerprint); err != nil {
+		logging.Warnf("%v", err)
+	}
+
+	logging.WithFields(graphFields(g)).Infof("Successfully updated Neo4j database.")
+	return nil
+}
+
+// failOnChange compares g with the database and fails, listing the changes to
+// w, if an update would change anything. Nothing is written.
+func failOnChange(ctx context.Context, w io.Writer, g *graph.Graph, changes *neo4j.ChangeSet, cfg *config.Config) error {
+	neo4jCfg := &cfg.Neo4j
+	client, err := NewNeo4jClient(ctx, neo4jCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create neo4j client: %w", err)
+	}
+	defer client.Close(ctx)
+
+	if err := client.SetNodeLabel(neo4jCfg.NodeLabel); err != nil {
+		return fmt.Errorf("invalid neo4j.node_label: %w", err)
+	}
+	if err := client.VerifyConnectivity(ctx); err != nil {
+		return fmt.Errorf("failed to connect to neo4j: %w", err)
+	}
+
+	opts := updateOptions(cfg)
+	opts.Changes = changes
+	diff, err := client.Diff(ctx, g, opts)
+	if err != nil {
+		return fmt.Errorf("failed to compare the graph with neo4j: %w", err)
+	}
+	return reportDiff(w, diff)
+}
+
+// reportDiff writes the changes of diff to w and returns an error if there are any.
+func reportDiff(w io.Writer, diff *neo4j.GraphDiff) error {
+	if diff.Empty() {
+		logging.Infof("Neo4j is up to date: the update would change nothing")
+		return nil
+	}
+	fmt.Fprint(w, neo4j.FormatDiff(diff))
+	return fmt.Errorf("the update would change neo4j: %s", diff.Summary())
+}
+
+// obsoleteResources returns the resources an update deletes: those the plan
+// deletes for an incremental update, otherwise those missing from g.
+func obsoleteResources(ctx context.Context, client *neo4j.Client, g *graph.Graph, changes *neo4j.ChangeSet) ([]string, error) {
+	if changes != nil {
+		return changes.Deleted, nil
+	}
+	return client.ObsoleteResources(ctx, g)
+}
+
+// confirmDeletion decides whether count obsolete resources may be deleted.
+// With assumeYes they are; otherwise an interactive user is asked, and a
+// non-interactive run keeps them with a warning.
+func confirmDeletion(count int, assumeYes, interactive bool, ask func(question string) (bool, error)) (bool, error) {
+	if count == 0 || assumeYes {
+		return true, nil
+	}
+
+	if !interactive {
+		logging.WithFields(logging.Fields{"obsolete": count}).Warnf("Keeping %d obsolete resource(s): pass --yes to delete them when not running interactively", count)
+		return false, nil
+	}
+
+	ok, err := ask(fmt.Sprintf("About to delete %d obsolete resource(s), continue?", count))
+	if err != nil {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if !ok {
+		logging.WithFields(logging.Fields{"obsolete": count}).Infof("Keeping %d obsolete resource(s)", count)
+	}
+	return ok, nil
+}
+
+// askYesNo writes question to w and reads the answer from r; only y or yes
+// (in any case) counts as yes.
+func askYesNo(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// updateFingerprint identifies what an update writes: the graph and the
+// options deciding how it is written, such as the label, the persisted
+// attributes and whether obsolete resources are removed.
+func updateFingerprint(g *graph.Graph, opts neo4j.UpdateOptions) string {
+	data, err := json.Marshal(struct {
+		Graph             string
+		SoftDelete        bool
+		DetachSoftDeleted bool
+		KeepObsolete      bool
+		Cypher            formatter.CypherOptions
+	}{graph.Fingerprint(g), opts.SoftDelete, opts.DetachSoftDeleted, opts.KeepObsolete, opts.Cypher})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// skipUpdate reports whether an update with the given fingerprint can be
+// skipped because it matches the stored one.
+func skipUpdate(stored, fingerprint string, force bool) bool {
+	return !force && fingerprint != "" && stored == fingerprint
+}
+
+// updateOptions returns the options UpdateGraph is called with for cfg.
+func updateOptions(cfg *config.Config) neo4j.UpdateOptions {
+	return neo4j.UpdateOptions{
+		SoftDelete:        cfg.SoftDelete,
+		DetachSoftDeleted: cfg.SoftDeleteDetach,
+		KeepObsolete:      !cfg.Prune,
+		Cypher:            cypherOptions(cfg),
+		BatchSize:         cfg.Neo4j.BatchSize,
+		BatchRetries:      cfg.Neo4j.BatchRetries,
+	}
+}
+
+// showProgress reports whether batch progress is shown: only for text logs on
+// a terminal, and not with --quiet.
+func showProgress() bool {
+	if !logging.Enabled(logging.LevelInfo) || logging.Format() != logging.FormatText {
+		return false
+	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printBatchProgress returns a progress callback that rewrites one line of w,
+// e.g. "batch 3/12 (500 nodes)", and ends it after the last batch.
+func printBatchProgress(w io.Writer) func(neo4j.BatchProgress) {
+	return func(p neo4j.BatchProgress) {
+		what := fmt.Sprintf("%d nodes", p.Nodes)
+		if p.Nodes == 0 {
+			what = fmt.Sprintf("%d relationships", p.Relationships)
+		}
+		fmt.Fprintf(w, "\r\033[Kbatch %d/%d (%s)", p.Batch, p.Batches, what)
+		if p.Batch == p.Batches {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// cypherOptions returns how nodes and relationships are written to Neo4j.
+func cypherOptions(cfg *config.Config) formatter.CypherOptions {
+	return formatter.CypherOptions{
+		NodeLabel:          cfg.Neo4j.NodeLabel,
+		AttributeAllowlist: cfg.Attributes.Allowlist,
+		AttributeDenylist:  cfg.Attributes.Denylist,
+		TypeLabels:         cfg.Neo4j.TypeLabels,
+		TagProperties:      cfg.Neo4j.TagProperties,
+		RunID:              cfg.RunID,
+	}
+}
+
+// DOTOptions returns the layout of DOT output, for export and previews.
+func DOTOptions(cfg *config.Config) formatter.DOTOptions {
+	return formatter.DOTOptions{
+		GroupBy:  cfg.DOT.GroupBy,
+		RankDir:  cfg.DOT.RankDir,
+		FontName: cfg.DOT.FontName,
+	}
+}
+
+// dryRun prints the Cypher an update would run, without writing anything.
+// Counting the obsolete resources when pruning, and listing the stale type
+// labels when type labels are on, need a read, so they are only done when the
+// database is reachable.
+func dryRun(ctx context.Context, g *graph.Graph, changes *neo4j.ChangeSet, cfg *config.Config) error {
+	opts := updateOptions(cfg)
+	opts.Changes = changes
+	if opts.KeepObsolete && !opts.Cypher.TypeLabels {
+		return writeDryRun(os.Stdout, g, opts)
+	}
+
+	client, err := dryRunClient(ctx, &cfg.Neo4j)
+	if err != nil {
+		return err
+	}
+	if client != nil {
+		defer client.Close(ctx)
+	}
+
+	if opts.Cypher.TypeLabels {
+		if client == nil {
+			logging.Warnf("Not showing the removal of stale type labels: it depends on the types stored in Neo4j")
+		} else {
+			stale, err := client.StoredTypeLabels(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to read existing resource types: %w", err)
+			}
+			opts.Cypher.StaleTypeLabels = stale
+		}
+	}
 
-	log.Println("Successfully updated Neo4j database.")
+	if err := writeDryRun(os.Stdout, g, opts); err != nil {
+		return err
+	}
+	if opts.KeepObsolete || client == nil {
+		return nil
+	}
+
+	obsolete, err := obsoleteResources(ctx, client, g, changes)
+	if err != nil {
+		return fmt.Errorf("failed to read existing resources: %w", err)
+	}
+	action := "delete"
+	if cfg.SoftDelete {
+		action = "soft-delete"
+	}
+	logging.WithFields(logging.Fields{"obsolete": len(obsolete)}).Infof("Would %s %d obsolete resource(s)", action, len(obsolete))
+	for _, id := range obsolete {
+		logging.Infof("  - %s", id)
+	}
 	return nil
 }
 
-func validateNeo4jConfig(cfg *config.Neo4jConfig) error {
-	if cfg.URI == "" || cfg.User == "" || cfg.Password == "" {
+// dryRunClient connects to the Neo4j of cfg for the reads of a dry run. It
+// returns a nil client, with a warning, when the database is not reachable.
+func dryRunClient(ctx context.Context, cfg *config.Neo4jConfig) (*neo4j.Client, error) {
+	client, err := NewNeo4jClient(ctx, cfg)
+	if err != nil {
+		logging.Warnf("Skipping the reads of the dry run: %v", err)
+		return nil, nil
+	}
+
+	if err := client.SetNodeLabel(cfg.NodeLabel); err != nil {
+		client.Close(ctx)
+		return nil, fmt.Errorf("invalid neo4j.node_label: %w", err)
+	}
+	if err := client.VerifyConnectivity(ctx); err != nil {
+		client.Close(ctx)
+		logging.Warnf("Neo4j at %s is not reachable, skipping the reads of the dry run: %v", cfg.URI, err)
+		return nil, nil
+	}
+	return client, nil
+}
+
+// writeDryRun writes the obsolete-resource query when pruning, the upsert
+// query and its pretty-printed parameters to w. An incremental update only upserts the
+// changed part of g.
+func writeDryRun(w io.Writer, g *graph.Graph, opts neo4j.UpdateOptions) error {
+	if opts.Changes != nil {
+		g = opts.Changes.Subgraph(g)
+	}
+	query, params := formatter.ToCypherTransaction(g, opts.Cypher)
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode query parameters: %w", err)
+	}
+
+	if opts.KeepObsolete {
+		fmt.Fprintln(w, "// Obsolete resources are kept; pass --prune to remove them")
+	} else {
+		fmt.Fprintln(w, "// Obsolete resources ($obsoleteIds) are removed with:")
+		fmt.Fprintln(w, neo4j.ObsoleteResourcesQuery(opts.Cypher.NodeLabel, opts.SoftDelete, opts.DetachSoftDeleted))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// Upsert query:")
+	fmt.Fprint(w, query)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// Parameters:")
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// NewNeo4jClient connects to the Neo4j of cfg, adding its routing context to
+// the URI. The password is resolved first, under ctx.
+func NewNeo4jClient(ctx context.Context, cfg *config.Neo4jConfig) (*neo4j.Client, error) {
+	if err := cfg.ResolvePassword(ctx); err != nil {
+		return nil, err
+	}
+	uri, err := neo4j.WithRoutingContext(cfg.URI, cfg.RoutingContext)
+	if err != nil {
+		return nil, err
+	}
+	return neo4j.NewClientWithCredentials(uri, Neo4jCredentials(cfg))
+}
+
+// Neo4jCredentials returns how to authenticate with the Neo4j of cfg.
+func Neo4jCredentials(cfg *config.Neo4jConfig) neo4j.Credentials {
+	return neo4j.Credentials{
+		Mode:        cfg.Auth,
+		User:        cfg.User,
+		Password:    cfg.Password,
+		Realm:       cfg.Realm,
+		BearerToken: cfg.BearerToken,
+	}
+}
+
+func validateNeo4jConfig(ctx context.Context, cfg *config.Neo4jConfig) error {
+	if err := cfg.ResolvePassword(ctx); err != nil {
+		return err
+	}
+	creds := Neo4jCredentials(cfg)
+	if cfg.URI == "" || ((creds.Mode == "" || creds.Mode == neo4j.AuthBasic) && (cfg.User == "" || cfg.Password == "")) {
 		return fmt.Errorf("neo4j-uri, neo4j-user, and neo4j-pass are required when using the update command. Please configure them in .terraform-graphx.yaml or pass them as flags")
 	}
+	if err := creds.Validate(); err != nil {
+		return fmt.Errorf("invalid neo4j.auth: %w", err)
+	}
+	if err := neo4j.ValidateURI(cfg.URI); err != nil {
+		return err
+	}
+	if _, err := neo4j.WithRoutingContext(cfg.URI, cfg.RoutingContext); err != nil {
+		return err
+	}
+	if err := formatter.ValidateLabel(cfg.NodeLabel); err != nil {
+		return fmt.Errorf("invalid neo4j.node_label: %w", err)
+	}
+	if cfg.BatchSize < 0 || cfg.BatchRetries < 0 {
+		return fmt.Errorf("neo4j.batch_size and neo4j.batch_retries must not be negative")
+	}
 	return nil
 }
@@ -0,0 +1,883 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/graph"
+	"terraform-graphx/internal/logging"
+	"terraform-graphx/internal/neo4j"
+	graphparser "terraform-graphx/internal/parser"
+	"testing"
+)
+
+func TestCheckCycles(t *testing.T) {
+	cyclic := &graph.Graph{
+		Nodes: []graph.Node{{ID: "null_resource.a"}, {ID: "null_resource.b"}},
+		Edges: []graph.Edge{
+			{From: "null_resource.a", To: "null_resource.b"},
+			{From: "null_resource.b", To: "null_resource.a"},
+		},
+	}
+
+	err := checkCycles(cyclic, false)
+	if err == nil {
+		t.Fatal("Expected error for cyclic graph")
+	}
+	if !strings.Contains(err.Error(), "[null_resource.a, null_resource.b]") {
+		t.Errorf("Expected error to list the cycle, got: %v", err)
+	}
+
+	acyclic := &graph.Graph{
+		Nodes: cyclic.Nodes,
+		Edges: cyclic.Edges[:1],
+	}
+	if err := checkCycles(acyclic, false); err != nil {
+		t.Errorf("Expected no error for acyclic graph, got: %v", err)
+	}
+}
+
+func TestCheckCyclesSelfLoops(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "null_resource.a"}},
+		Edges: []graph.Edge{{From: "null_resource.a", To: "null_resource.a"}},
+	}
+
+	if err := checkCycles(g, false); err == nil {
+		t.Error("Expected a self-loop to be reported as a cycle")
+	}
+	if err := checkCycles(g, true); err != nil {
+		t.Errorf("Expected allowed self-loops to be ignored, got: %v", err)
+	}
+}
+
+func TestWriteDryRun(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "null_resource.a", Type: "null_resource"}, {ID: "null_resource.b", Type: "null_resource"}},
+		Edges: []graph.Edge{{From: "null_resource.a", To: "null_resource.b"}},
+	}
+	cfg := &config.Config{Prune: true, SoftDelete: true}
+	cfg.Neo4j.NodeLabel = "TerraformResource"
+
+	var out bytes.Buffer
+	if err := writeDryRun(&out, g, updateOptions(cfg)); err != nil {
+		t.Fatalf("writeDryRun failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"MATCH (n:TerraformResource {id: obsoleteId}) WHERE n.deleted IS NULL SET n.deleted = true",
+		"MERGE (n:TerraformResource {id: node_data.id})",
+		`"id": "null_resource.a"`,
+		`"from": "null_resource.a"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected dry run output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestWriteDryRunStaleTypeLabels(t *testing.T) {
+	g := &graph.Graph{Nodes: []graph.Node{{ID: "aws_instance.a", Type: "aws_instance"}}}
+	cfg := &config.Config{}
+	cfg.Neo4j.NodeLabel = "Resource"
+	cfg.Neo4j.TypeLabels = true
+
+	opts := updateOptions(cfg)
+	opts.Cypher.StaleTypeLabels = []string{"aws_spot_instance"}
+	var out bytes.Buffer
+	if err := writeDryRun(&out, g, opts); err != nil {
+		t.Fatalf("writeDryRun failed: %v", err)
+	}
+
+	if want := "THEN [1] ELSE [] END | REMOVE n:aws_spot_instance)"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected dry run output to contain %q, got:\n%s", want, out.String())
+	}
+}
+
+func TestWriteDryRunWithoutPrune(t *testing.T) {
+	g := &graph.Graph{Nodes: []graph.Node{{ID: "null_resource.a", Type: "null_resource"}}}
+	cfg := &config.Config{}
+	cfg.Neo4j.NodeLabel = "Resource"
+
+	var out bytes.Buffer
+	if err := writeDryRun(&out, g, updateOptions(cfg)); err != nil {
+		t.Fatalf("writeDryRun failed: %v", err)
+	}
+
+	if strings.Contains(out.String(), "DETACH DELETE") || strings.Contains(out.String(), "obsoleteId") {
+		t.Errorf("Expected no deletion query without --prune, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "MERGE (n:Resource {id: node_data.id})") {
+		t.Errorf("Expected the upsert query, got:\n%s", out.String())
+	}
+}
+
+func TestUpdateOptionsPrune(t *testing.T) {
+	if opts := updateOptions(&config.Config{}); !opts.KeepObsolete {
+		t.Error("Expected obsolete resources to be kept by default")
+	}
+	if opts := updateOptions(&config.Config{Prune: true}); opts.KeepObsolete {
+		t.Error("Expected obsolete resources to be deleted with --prune")
+	}
+}
+
+func TestUpdateFingerprint(t *testing.T) {
+	g := &graph.Graph{Nodes: []graph.Node{{ID: "null_resource.a"}}}
+	opts := updateOptions(&config.Config{})
+	fingerprint := updateFingerprint(g, opts)
+	if fingerprint == "" || updateFingerprint(g, opts) != fingerprint {
+		t.Fatalf("Expected a stable fingerprint, got %q", fingerprint)
+	}
+
+	changed := map[string]neo4j.UpdateOptions{
+		"prune":       updateOptions(&config.Config{Prune: true}),
+		"soft delete": updateOptions(&config.Config{Prune: true, SoftDelete: true}),
+		"label":       updateOptions(&config.Config{Neo4j: config.Neo4jConfig{NodeLabel: "Infra"}}),
+		"attributes":  updateOptions(&config.Config{Attributes: config.AttributesConfig{Allowlist: []string{"ami"}}}),
+		"run id":      updateOptions(&config.Config{RunID: "ci-1"}),
+	}
+	for name, opts := range changed {
+		if updateFingerprint(g, opts) == fingerprint {
+			t.Errorf("%s: expected the fingerprint to change", name)
+		}
+	}
+	if updateFingerprint(&graph.Graph{Nodes: []graph.Node{{ID: "null_resource.b"}}}, opts) == fingerprint {
+		t.Error("Expected the fingerprint to change with the graph")
+	}
+}
+
+func TestSkipUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		stored      string
+		fingerprint string
+		force       bool
+		skip        bool
+	}{
+		{"unchanged", "abc", "abc", false, true},
+		{"changed", "abc", "def", false, false},
+		{"first update", "", "abc", false, false},
+		{"forced", "abc", "abc", true, false},
+		{"no fingerprint", "", "", false, false},
+	}
+	for _, tt := range tests {
+		if got := skipUpdate(tt.stored, tt.fingerprint, tt.force); got != tt.skip {
+			t.Errorf("%s: skipUpdate() = %v, want %v", tt.name, got, tt.skip)
+		}
+	}
+}
+
+func TestNothingToUpdate(t *testing.T) {
+	empty := &graph.Graph{}
+	nonEmpty := &graph.Graph{Nodes: []graph.Node{{ID: "aws_vpc.main"}}}
+	destroy := &neo4j.ChangeSet{Deleted: []string{"aws_vpc.main"}}
+
+	tests := []struct {
+		name    string
+		g       *graph.Graph
+		changes *neo4j.ChangeSet
+		prune   bool
+		nothing bool
+	}{
+		{"empty graph", empty, nil, false, true},
+		{"empty graph with prune", empty, nil, true, false},
+		{"destroy-only incremental plan", empty, destroy, true, false},
+		{"destroy-only incremental plan without prune", empty, destroy, false, true},
+		{"incremental plan without deletions", empty, &neo4j.ChangeSet{}, true, true},
+		{"non-empty graph", nonEmpty, nil, false, false},
+	}
+	for _, tt := range tests {
+		if got := nothingToUpdate(tt.g, tt.changes, &config.Config{Prune: tt.prune}); got != tt.nothing {
+			t.Errorf("%s: nothingToUpdate() = %v, want %v", tt.name, got, tt.nothing)
+		}
+	}
+}
+
+func TestRunPrunesAfterDestroy(t *testing.T) {
+	dir := t.TempDir()
+	destroyed := filepath.Join(dir, "destroyed.json")
+	if err := os.WriteFile(destroyed, []byte(`{"format_version": "1.2", "planned_values": {"root_module": {}}}`), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+	destroyOnly := filepath.Join(dir, "destroy-only.json")
+	plan := `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {}},
+  "resource_changes": [{"address": "aws_vpc.main", "change": {"actions": ["delete"]}}]
+}`
+	if err := os.WriteFile(destroyOnly, []byte(plan), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	// Nothing listens on the port, so reaching Neo4j shows the update wasn't skipped
+	neo4jCfg := config.DefaultConfig().Neo4j
+	neo4jCfg.URI, neo4jCfg.Password = "bolt://127.0.0.1:1", "secret"
+	tests := []struct {
+		name string
+		cfg  config.Config
+	}{
+		{"prune after destroy", config.Config{PlanFile: destroyed, Prune: true}},
+		{"destroy-only incremental plan", config.Config{PlanFile: destroyOnly, Prune: true, Incremental: true}},
+	}
+	for _, tt := range tests {
+		cfg := tt.cfg
+		cfg.Neo4j = neo4jCfg
+		err := Run(context.Background(), &cfg)
+		if err == nil || !strings.Contains(err.Error(), "failed to connect to neo4j") {
+			t.Errorf("%s: expected the update to reach neo4j, got %v", tt.name, err)
+		}
+	}
+
+	cfg := config.Config{PlanFile: destroyed, Neo4j: neo4jCfg}
+	if err := Run(context.Background(), &cfg); err != nil {
+		t.Errorf("Expected an empty graph without --prune to be skipped, got %v", err)
+	}
+}
+
+func TestRunRejectsPruneWithFilters(t *testing.T) {
+	neo4jCfg := config.DefaultConfig().Neo4j
+	neo4jCfg.Password = "secret"
+
+	for _, cfg := range []config.Config{
+		{IncludeTypes: []string{"aws_vpc"}},
+		{ExcludeTypes: []string{"aws_vpc"}},
+		{Include: []string{"module.app.*"}},
+		{Exclude: []string{"random_*.*"}},
+		{Attributes: config.AttributesConfig{Filter: []string{"tags.Environment=prod"}}},
+		{Module: "module.app"},
+		{RootModule: "module.app"},
+		{EntryTypes: []string{"aws_lb"}},
+	} {
+		cfg.Neo4j = neo4jCfg
+		cfg.Prune = true
+		err := Run(context.Background(), &cfg)
+		if err == nil || !strings.Contains(err.Error(), "--prune can't be used with") {
+			t.Errorf("Expected --prune to be rejected with %s, got %v", resourceFilter(&cfg), err)
+		}
+	}
+
+	if filter := resourceFilter(&config.Config{RelationKinds: []string{"explicit"}}); filter != "" {
+		t.Errorf("Expected relation kinds, which keep every resource, not to count as a filter, got %s", filter)
+	}
+}
+
+func TestPrepareGraphLogsCounts(t *testing.T) {
+	var out bytes.Buffer
+	if err := logging.Setup(logging.FormatJSON, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { logging.Setup(logging.FormatText, os.Stderr) })
+
+	g := &graph.Graph{
+		Nodes: []graph.Node{{ID: "aws_vpc.main", Type: "aws_vpc"}, {ID: "aws_subnet.a", Type: "aws_subnet"}},
+		Edges: []graph.Edge{{From: "aws_subnet.a", To: "aws_vpc.main"}},
+	}
+	if _, err := prepareGraph(g, &config.Config{ExcludeTypes: []string{"aws_subnet"}}); err != nil {
+		t.Fatalf("prepareGraph failed: %v", err)
+	}
+
+	var entry struct {
+		Nodes *int `json:"nodes"`
+		Edges *int `json:"edges"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(out.Bytes()), &entry); err != nil {
+		t.Fatalf("Log line is not JSON: %v\n%s", err, out.String())
+	}
+	if entry.Nodes == nil || *entry.Nodes != 1 || entry.Edges == nil || *entry.Edges != 0 {
+		t.Errorf("Expected nodes and edges fields, got:\n%s", out.String())
+	}
+}
+
+func TestReportDiff(t *testing.T) {
+	var out bytes.Buffer
+	if err := reportDiff(&out, &neo4j.GraphDiff{}); err != nil {
+		t.Errorf("Expected no error without changes, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output without changes, got %q", out.String())
+	}
+
+	diff := &neo4j.GraphDiff{AddedNodes: []string{"null_resource.a"}, DeletedNodes: []string{"null_resource.b"}}
+	err := reportDiff(&out, diff)
+	if err == nil || !strings.Contains(err.Error(), "1 resource(s) added, 0 updated, 1 deleted") {
+		t.Errorf("Expected an error with the summary, got %v", err)
+	}
+	if out.String() != "+ null_resource.a\n- null_resource.b\n" {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+}
+
+func TestPrintBatchProgress(t *testing.T) {
+	var out bytes.Buffer
+	progress := printBatchProgress(&out)
+	progress(neo4j.BatchProgress{Batch: 1, Batches: 2, Nodes: 500})
+	if !strings.HasSuffix(out.String(), "batch 1/2 (500 nodes)") {
+		t.Errorf("Unexpected progress line %q", out.String())
+	}
+
+	progress(neo4j.BatchProgress{Batch: 2, Batches: 2, Relationships: 120})
+	if !strings.HasSuffix(out.String(), "batch 2/2 (120 relationships)\n") {
+		t.Errorf("Expected the last batch to end the line, got %q", out.String())
+	}
+}
+
+func TestValidateNeo4jConfigAuth(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Neo4jConfig
+		valid bool
+	}{
+		{"basic", config.Neo4jConfig{URI: "bolt://localhost:7687", User: "neo4j", Password: "secret"}, true},
+		{"basic without password", config.Neo4jConfig{URI: "bolt://localhost:7687", User: "neo4j"}, false},
+		{"bearer", config.Neo4jConfig{URI: "bolt://localhost:7687", Auth: neo4j.AuthBearer, BearerToken: "token"}, true},
+		{"bearer without token", config.Neo4jConfig{URI: "bolt://localhost:7687", Auth: neo4j.AuthBearer, Password: "secret"}, false},
+		{"none", config.Neo4jConfig{URI: "bolt://localhost:7687", Auth: neo4j.AuthNone}, true},
+		{"none without uri", config.Neo4jConfig{Auth: neo4j.AuthNone}, false},
+		{"unknown mode", config.Neo4jConfig{URI: "bolt://localhost:7687", Auth: "kerberos"}, false},
+		{"unsupported scheme", config.Neo4jConfig{URI: "http://localhost:7474", Auth: neo4j.AuthNone}, false},
+		{"routing context", config.Neo4jConfig{URI: "neo4j+s://cluster.example.com", Auth: neo4j.AuthNone, RoutingContext: map[string]string{"region": "eu"}}, true},
+		{"routing context without cluster", config.Neo4jConfig{URI: "bolt://localhost:7687", Auth: neo4j.AuthNone, RoutingContext: map[string]string{"region": "eu"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.NodeLabel = "Resource"
+			err := validateNeo4jConfig(context.Background(), &tt.cfg)
+			if tt.valid && err != nil {
+				t.Errorf("Expected a valid config, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestParseDOTEmpty(t *testing.T) {
+	for _, output := range []string{"", "\n  \n", "digraph {\n}\n"} {
+		dotGraph, err := parseDOT(output)
+		if err != nil {
+			t.Errorf("parseDOT(%q) failed: %v", output, err)
+			continue
+		}
+		if len(dotGraph.Nodes.Nodes) != 0 {
+			t.Errorf("Expected no nodes for %q, got %d", output, len(dotGraph.Nodes.Nodes))
+		}
+	}
+}
+
+func TestParseDOTMalformed(t *testing.T) {
+	output := "Error: Failed to load plugin\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\n"
+
+	_, err := parseDOT(output)
+	if err == nil {
+		t.Fatal("Expected an error for garbage input")
+	}
+	for _, want := range []string{"failed to parse DOT output", "  Error: Failed to load plugin", "  line 5", "(2 more lines)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "line 6") {
+		t.Errorf("Expected the excerpt to be truncated, got: %v", err)
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, name := range SupportedFormats() {
+		if err := ValidateFormat(name); err != nil {
+			t.Errorf("Expected %s to be supported, got: %v", name, err)
+		}
+	}
+
+	err := ValidateFormat("yaml")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown format")
+	}
+	expected := `unsupported format "yaml" (supported: ` + strings.Join(SupportedFormats(), ", ") + ")"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestExportRejectsUnknownFormatEarly(t *testing.T) {
+	// The state file does not exist, so the format must be checked before loading the graph
+	cfg := &config.Config{Format: "yaml", StateFile: "does-not-exist.tfstate"}
+
+	err := Export(context.Background(), cfg)
+	if err == nil || !strings.Contains(err.Error(), `unsupported format "yaml"`) {
+		t.Errorf("Expected an unsupported format error, got: %v", err)
+	}
+}
+
+func TestHandleOutputJSONAndCypher(t *testing.T) {
+	g := &graph.Graph{
+		Nodes: []graph.Node{
+			{ID: "aws_vpc.main", Type: "aws_vpc", Provider: "aws", Name: "main"},
+			{ID: "aws_subnet.a", Type: "aws_subnet", Provider: "aws", Name: "a"},
+		},
+		Edges: []graph.Edge{{From: "aws_subnet.a", To: "aws_vpc.main"}},
+	}
+
+	tests := map[string][]string{
+		"json":   {`"id": "aws_vpc.main"`, `"from": "aws_subnet.a"`},
+		"cypher": {"MERGE (n:Resource {id: node_data.id})", "id: 'aws_vpc.main'", "{from: 'aws_subnet.a', to: 'aws_vpc.main'}"},
+	}
+
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "graph."+format)
+			cfg := &config.Config{Format: format, Output: output, Neo4j: config.Neo4jConfig{NodeLabel: "Resource"}}
+
+			if err := handleOutput(g, cfg); err != nil {
+				t.Fatalf("handleOutput failed: %v", err)
+			}
+
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			for _, e := range expected {
+				if !strings.Contains(string(data), e) {
+					t.Errorf("Expected %q in %s output:\n%s", e, format, data)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadGraphFromPlanJSON(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	plan := `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws"},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}},
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main"},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+  ]}}
+}`
+	if err := os.WriteFile(planFile, []byte(plan), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	g, err := loadGraph(context.Background(), &config.Config{PlanFile: planFile})
+	if err != nil {
+		t.Fatalf("loadGraph failed: %v", err)
+	}
+
+	if len(g.Nodes) != 2 || g.Nodes[0].Provider != "aws" {
+		t.Errorf("Expected 2 nodes with providers, got %+v", g.Nodes)
+	}
+	if len(g.Edges) != 1 || g.Edges[0].From != "aws_subnet.a" || g.Edges[0].To != "aws_vpc.main" {
+		t.Errorf("Expected aws_subnet.a -> aws_vpc.main, got %+v", g.Edges)
+	}
+}
+
+func TestInspectPlanJSON(t *testing.T) {
+	// PATH holds no terraform: a plan JSON is read without it
+	fakePath(t)
+	dir := t.TempDir()
+	var planFiles []string
+	for _, name := range []string{"b", "a"} {
+		planFile := filepath.Join(dir, name+".json")
+		plan := fmt.Sprintf(`{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_subnet.%[1]s", "mode": "managed", "type": "aws_subnet", "name": "%[1]s", "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}},
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_subnet.%[1]s", "mode": "managed", "type": "aws_subnet", "name": "%[1]s", "depends_on": ["aws_vpc.main"], "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+  ]}}
+}`, name)
+		if err := os.WriteFile(planFile, []byte(plan), 0644); err != nil {
+			t.Fatalf("Failed to write plan: %v", err)
+		}
+		planFiles = append(planFiles, planFile)
+	}
+
+	view, err := Inspect(context.Background(), &config.Config{PlanFile: planFiles[0]})
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	plan, ok := view.(*graphparser.TerraformPlan)
+	if !ok {
+		t.Fatalf("Expected the parsed plan, got %T", view)
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Failed to encode the plan: %v", err)
+	}
+	for _, want := range []string{`"address":"aws_subnet.b"`, `"depends_on":["aws_vpc.main"]`, `"aws_vpc.main.id"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected the inspected plan to contain %s, got %s", want, data)
+		}
+	}
+
+	view, err = Inspect(context.Background(), &config.Config{PlanFile: planFiles[0], PlanFiles: planFiles})
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	plans, ok := view.([]PlanInspection)
+	if !ok || len(plans) != 2 || plans[0].PlanFile != planFiles[1] || plans[1].PlanFile != planFiles[0] {
+		t.Errorf("Expected both plans in path order, got %+v", view)
+	}
+
+	if _, err := Inspect(context.Background(), &config.Config{StateFile: "terraform.tfstate", StateS3: "s3://tf-state/terraform.tfstate"}); err == nil {
+		t.Error("Expected an error for both --state and --state-s3")
+	}
+}
+
+func TestLoadGraphFromSavedPlanAddsConfigEdges(t *testing.T) {
+	// The subnet only references the VPC through a local, which the plan JSON
+	// leaves out but `terraform graph` follows
+	dir := fakePath(t)
+	plan := `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws"},
+    {"address": "aws_subnet.a[0]", "mode": "managed", "type": "aws_subnet", "name": "a", "index": 0, "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}},
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main"},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "expressions": {"vpc_id": {"references": ["local.vpc_id"]}}}
+  ]}}
+}`
+	dot := `digraph G {
+  "aws_subnet.a" [label="aws_subnet.a"];
+  "aws_vpc.main" [label="aws_vpc.main"];
+  "aws_subnet.a" -> "aws_vpc.main";
+}`
+	// PATH only holds the fake, so the script uses builtins alone
+	script := fmt.Sprintf("#!/bin/sh\ncase \"$1\" in\nshow) echo '%s' ;;\ngraph) echo '%s' ;;\nesac\n", plan, dot)
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create fake terraform: %v", err)
+	}
+
+	g, err := loadGraph(context.Background(), &config.Config{PlanFile: "tfplan.binary", Terraform: config.TerraformConfig{Engine: EngineTerraform}})
+	if err != nil {
+		t.Fatalf("loadGraph failed: %v", err)
+	}
+
+	expected := []graph.Edge{{From: "aws_subnet.a[0]", To: "aws_vpc.main", Relation: "DEPENDS_ON", Kind: graph.KindImplicit}}
+	if !reflect.DeepEqual(g.Edges, expected) {
+		t.Errorf("Expected edges %v, got %v", expected, g.Edges)
+	}
+}
+
+func TestLoadGraphResolvesTerraformOnce(t *testing.T) {
+	dir := fakePath(t)
+	calls := filepath.Join(dir, "workspace-calls")
+	plan := `{"format_version": "1.2", "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}}}`
+	// Reading a saved plan runs both `show` and `graph`, and each would check
+	// the workspace again if the executable were resolved per command
+	script := fmt.Sprintf("#!/bin/sh\ncase \"$1\" in\nworkspace) echo x >> '%s'; echo '* prod' ;;\nshow) echo '%s' ;;\ngraph) echo 'digraph G {}' ;;\nesac\n", calls, plan)
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create fake terraform: %v", err)
+	}
+
+	cfg := &config.Config{PlanFile: "tfplan.binary", Terraform: config.TerraformConfig{Engine: EngineTerraform, Workspace: "prod"}}
+	if _, err := loadGraph(context.Background(), cfg); err != nil {
+		t.Fatalf("loadGraph failed: %v", err)
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("Failed to read workspace calls: %v", err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("Expected terraform workspace list to run once, ran %d times", n)
+	}
+}
+
+func TestLoadPlanGraphsMatchesSequentialMerge(t *testing.T) {
+	dir := t.TempDir()
+	var planFiles []string
+	for i, module := range []string{"network", "app", "db", "cache", "dns"} {
+		planFile := filepath.Join(dir, fmt.Sprintf("%d-%s.json", i, module))
+		plan := fmt.Sprintf(`{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.shared", "mode": "managed", "type": "aws_vpc", "name": "shared", "provider_name": "registry.terraform.io/hashicorp/aws", "values": {"%[1]s_id": "%[1]s"}},
+    {"address": "aws_subnet.%[1]s", "mode": "managed", "type": "aws_subnet", "name": "%[1]s", "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}},
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_subnet.%[1]s", "mode": "managed", "type": "aws_subnet", "name": "%[1]s", "expressions": {"vpc_id": {"references": ["aws_vpc.shared.id", "aws_vpc.shared"]}}}
+  ]}}
+}`, module)
+		if err := os.WriteFile(planFile, []byte(plan), 0644); err != nil {
+			t.Fatalf("Failed to write plan: %v", err)
+		}
+		planFiles = append(planFiles, planFile)
+	}
+
+	// The sequential result, merged in the order of the paths
+	sorted := append([]string(nil), planFiles...)
+	sort.Strings(sorted)
+	var graphs []*graph.Graph
+	for _, planFile := range sorted {
+		g, err := loadGraph(context.Background(), &config.Config{PlanFile: planFile})
+		if err != nil {
+			t.Fatalf("loadGraph(%s) failed: %v", planFile, err)
+		}
+		graphs = append(graphs, g)
+	}
+	want := graph.Merge(graph.AttributesUnion, graphs...)
+
+	// Given in reverse order, with every worker count
+	reversed := make([]string, len(planFiles))
+	for i, planFile := range planFiles {
+		reversed[len(planFiles)-1-i] = planFile
+	}
+	for _, concurrency := range []int{0, 1, 2, 8} {
+		cfg := &config.Config{PlanFile: reversed[0], PlanFiles: reversed, Concurrency: concurrency}
+		got, err := loadGraph(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("concurrency %d: loadGraph failed: %v", concurrency, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("concurrency %d: merged graph differs from the sequential merge:\ngot  %+v\nwant %+v", concurrency, got, want)
+		}
+	}
+	if len(want.Nodes) != 6 || len(want.Nodes[0].Attributes) != 5 {
+		t.Errorf("Expected the shared VPC with every plan's attributes and 5 subnets, got %+v", want.Nodes)
+	}
+
+	missing := &config.Config{PlanFile: planFiles[0], PlanFiles: append(planFiles, filepath.Join(dir, "missing.json"))}
+	if _, err := loadGraph(context.Background(), missing); err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("Expected an error naming the missing plan, got %v", err)
+	}
+}
+
+func TestBuildIncrementalGraph(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	plan := `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws"},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}},
+  "resource_changes": [
+    {"address": "aws_vpc.main", "change": {"actions": ["no-op"]}},
+    {"address": "aws_subnet.a", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_eip.old", "change": {"actions": ["delete"]}}
+  ],
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main"},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+  ]}}
+}`
+	if err := os.WriteFile(planFile, []byte(plan), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	cfg := &config.Config{PlanFile: planFile}
+	g, changes, err := buildIncrementalGraph(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("buildIncrementalGraph failed: %v", err)
+	}
+	if len(g.Nodes) != 2 {
+		t.Errorf("Expected the whole planned graph, got %+v", g.Nodes)
+	}
+	if !reflect.DeepEqual(changes.Changed, []string{"aws_subnet.a"}) || !reflect.DeepEqual(changes.Deleted, []string{"aws_eip.old"}) {
+		t.Errorf("Unexpected change set %+v", changes)
+	}
+
+	// Only the replaced subnet and its edge to the unchanged VPC are written
+	opts := updateOptions(cfg)
+	opts.Changes = changes
+	var out bytes.Buffer
+	if err := writeDryRun(&out, g, opts); err != nil {
+		t.Fatalf("writeDryRun failed: %v", err)
+	}
+	if strings.Contains(out.String(), `"id": "aws_vpc.main"`) {
+		t.Errorf("Expected the unchanged VPC not to be upserted, got:\n%s", out.String())
+	}
+	for _, want := range []string{`"id": "aws_subnet.a"`, `"to": "aws_vpc.main"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected dry run output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if _, _, err := buildIncrementalGraph(context.Background(), &config.Config{StateFile: "terraform.tfstate"}); err == nil {
+		t.Error("Expected an error for an incremental update without a plan")
+	}
+}
+
+func TestConfirmDeletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		count       int
+		assumeYes   bool
+		interactive bool
+		answer      bool
+		expected    bool
+		asked       bool
+	}{
+		{name: "nothing to delete", count: 0, expected: true},
+		{name: "yes flag", count: 3, assumeYes: true, expected: true},
+		{name: "yes flag when interactive", count: 3, assumeYes: true, interactive: true, expected: true},
+		{name: "non-interactive without yes", count: 3, expected: false},
+		{name: "interactive confirmed", count: 3, interactive: true, answer: true, expected: true, asked: true},
+		{name: "interactive declined", count: 3, interactive: true, answer: false, expected: false, asked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := false
+			ask := func(question string) (bool, error) {
+				asked = true
+				if !strings.Contains(question, "delete 3 obsolete") {
+					t.Errorf("Unexpected question: %s", question)
+				}
+				return tt.answer, nil
+			}
+
+			got, err := confirmDeletion(tt.count, tt.assumeYes, tt.interactive, ask)
+			if err != nil {
+				t.Fatalf("confirmDeletion failed: %v", err)
+			}
+			if got != tt.expected || asked != tt.asked {
+				t.Errorf("Expected proceed=%v asked=%v, got proceed=%v asked=%v", tt.expected, tt.asked, got, asked)
+			}
+		})
+	}
+}
+
+func TestAskYesNo(t *testing.T) {
+	tests := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"yep\n": false,
+	}
+
+	for input, expected := range tests {
+		var out bytes.Buffer
+		got, err := askYesNo(strings.NewReader(input), &out, "Continue?")
+		if err != nil {
+			t.Fatalf("askYesNo(%q) failed: %v", input, err)
+		}
+		if got != expected {
+			t.Errorf("askYesNo(%q): expected %v, got %v", input, expected, got)
+		}
+		if out.String() != "Continue? [y/N] " {
+			t.Errorf("Unexpected prompt: %q", out.String())
+		}
+	}
+}
+
+func TestBuildGraphIsDeterministic(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	plan := `{
+  "format_version": "1.2",
+  "planned_values": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main", "provider_name": "registry.terraform.io/hashicorp/aws"},
+    {"address": "aws_subnet.b", "mode": "managed", "type": "aws_subnet", "name": "b", "provider_name": "registry.terraform.io/hashicorp/aws"},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "provider_name": "registry.terraform.io/hashicorp/aws"}
+  ]}},
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main"},
+    {"address": "aws_subnet.b", "mode": "managed", "type": "aws_subnet", "name": "b", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}},
+    {"address": "aws_subnet.a", "mode": "managed", "type": "aws_subnet", "name": "a", "expressions": {"vpc_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}}
+  ]}}
+}`
+	if err := os.WriteFile(planFile, []byte(plan), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	for _, format := range []string{"json", "cypher"} {
+		t.Run(format, func(t *testing.T) {
+			var outputs []string
+			for i := 0; i < 2; i++ {
+				output := filepath.Join(t.TempDir(), "graph."+format)
+				cfg := &config.Config{PlanFile: planFile, Format: format, Output: output, Neo4j: config.Neo4jConfig{NodeLabel: "Resource"}}
+
+				g, err := BuildGraph(context.Background(), cfg)
+				if err != nil {
+					t.Fatalf("BuildGraph failed: %v", err)
+				}
+				if g.Nodes[0].ID != "aws_subnet.a" {
+					t.Errorf("Expected nodes sorted by ID, got %+v", g.Nodes)
+				}
+				if err := handleOutput(g, cfg); err != nil {
+					t.Fatalf("handleOutput failed: %v", err)
+				}
+
+				data, err := os.ReadFile(output)
+				if err != nil {
+					t.Fatalf("Failed to read output: %v", err)
+				}
+				outputs = append(outputs, string(data))
+			}
+			if outputs[0] != outputs[1] {
+				t.Errorf("Expected identical output across runs:\n%s\n%s", outputs[0], outputs[1])
+			}
+		})
+	}
+}
+
+func TestBuildOutputGraphEdgeDirection(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "terraform.tfstate")
+	content := `{"version": 4, "resources": [
+  {"mode": "managed", "type": "aws_vpc", "name": "main", "instances": [{}]},
+  {"mode": "managed", "type": "aws_subnet", "name": "a", "instances": [{"dependencies": ["aws_vpc.main"]}]}
+]}`
+	if err := os.WriteFile(state, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	tests := map[string]graph.Edge{
+		"":            {From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		"depends-on":  {From: "aws_subnet.a", To: "aws_vpc.main", Relation: "DEPENDS_ON"},
+		"required-by": {From: "aws_vpc.main", To: "aws_subnet.a", Relation: "REQUIRED_BY"},
+	}
+	for direction, expected := range tests {
+		t.Run(direction, func(t *testing.T) {
+			g, err := buildOutputGraph(context.Background(), &config.Config{StateFile: state, EdgeDirection: direction})
+			if err != nil {
+				t.Fatalf("buildOutputGraph failed: %v", err)
+			}
+			if len(g.Edges) != 1 || g.Edges[0] != expected {
+				t.Errorf("Expected %v, got %v", expected, g.Edges)
+			}
+			// Degree counts keep their meaning whatever the direction
+			for _, node := range g.Nodes {
+				if node.ID == "aws_vpc.main" && node.DependentsCount != 1 {
+					t.Errorf("Expected aws_vpc.main to have 1 dependent, got %+v", node)
+				}
+			}
+		})
+	}
+
+	if _, err := buildOutputGraph(context.Background(), &config.Config{StateFile: state, EdgeDirection: "upstream"}); err == nil {
+		t.Error("Expected an error for an unsupported edge direction")
+	}
+}
+
+func TestLoadGraphRejectsPlansWithState(t *testing.T) {
+	cfg := &config.Config{StateFile: "terraform.tfstate", PlanFile: "a.tfplan", PlanFiles: []string{"a.tfplan", "b.tfplan"}}
+	if _, err := loadGraph(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "several plan files") {
+		t.Errorf("Expected an error for plan files with --state, got %v", err)
+	}
+}
+
+func TestLoadGraphRejectsTwoStateSources(t *testing.T) {
+	cfg := &config.Config{StateFile: "terraform.tfstate", StateS3: "s3://tf-state/terraform.tfstate"}
+	if _, err := loadGraph(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "--state-s3") {
+		t.Errorf("Expected an error for both --state and --state-s3, got %v", err)
+	}
+}
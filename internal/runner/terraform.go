@@ -0,0 +1,242 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/logging"
+)
+
+// Supported values for terraform.engine / --engine.
+const (
+	EngineAuto      = "auto"
+	EngineTerraform = "terraform"
+	EngineTofu      = "tofu"
+)
+
+// ReusablePlanFile is the saved plan --reuse-plan looks for in the working
+// directory, as written by `terraform plan -out=tfplan.binary`.
+const ReusablePlanFile = "tfplan.binary"
+
+// terraformCLI is a resolved Terraform (or OpenTofu) executable.
+type terraformCLI struct {
+	name      string
+	path      string
+	chdir     string
+	workspace string
+}
+
+// newTerraformCLI resolves the executable to run. An explicit terraform.binary
+// wins; otherwise the engine decides, with auto preferring terraform and
+// falling back to tofu. When a workspace is configured, it must exist.
+func newTerraformCLI(ctx context.Context, tfCfg *config.TerraformConfig) (*terraformCLI, error) {
+	if tfCfg.Binary != "" {
+		path, err := exec.LookPath(tfCfg.Binary)
+		if err != nil {
+			return nil, fmt.Errorf("terraform binary %q not found: %w (set terraform.binary in .terraform-graphx.yaml)", tfCfg.Binary, err)
+		}
+		return withWorkspace(ctx, &terraformCLI{name: tfCfg.Binary, path: path, chdir: tfCfg.Chdir}, tfCfg.Workspace)
+	}
+
+	var candidates []string
+	switch tfCfg.Engine {
+	case "", EngineAuto:
+		candidates = []string{EngineTerraform, EngineTofu}
+	case EngineTerraform, EngineTofu:
+		candidates = []string{tfCfg.Engine}
+	default:
+		return nil, fmt.Errorf("unsupported engine %q (supported: %s, %s, %s)", tfCfg.Engine, EngineAuto, EngineTerraform, EngineTofu)
+	}
+
+	for _, name := range candidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return withWorkspace(ctx, &terraformCLI{name: name, path: path, chdir: tfCfg.Chdir}, tfCfg.Workspace)
+		}
+	}
+
+	if len(candidates) > 1 {
+		return nil, fmt.Errorf("neither terraform nor tofu was found on PATH; install one or set terraform.binary")
+	}
+	return nil, fmt.Errorf("%s was not found on PATH", candidates[0])
+}
+
+// withWorkspace sets the workspace tf runs against after checking that it
+// exists. An empty workspace keeps the selected one.
+func withWorkspace(ctx context.Context, tf *terraformCLI, workspace string) (*terraformCLI, error) {
+	if workspace == "" {
+		return tf, nil
+	}
+
+	workspaces, err := tf.workspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range workspaces {
+		if name == workspace {
+			tf.workspace = workspace
+			return tf, nil
+		}
+	}
+	return nil, fmt.Errorf("workspace %q does not exist (available: %s)", workspace, strings.Join(workspaces, ", "))
+}
+
+// terraformResolver resolves the executable of a run on first use, so a run
+// that only reads plan JSON or state never needs one, and a run that needs it
+// several times looks it up, and checks the workspace, only once. It is safe
+// for concurrent use.
+type terraformResolver struct {
+	cfg  *config.TerraformConfig
+	once sync.Once
+	tf   *terraformCLI
+	err  error
+}
+
+func newTerraformResolver(tfCfg *config.TerraformConfig) *terraformResolver {
+	return &terraformResolver{cfg: tfCfg}
+}
+
+// cli returns the resolved executable, or the error of the first attempt.
+func (r *terraformResolver) cli(ctx context.Context) (*terraformCLI, error) {
+	r.once.Do(func() {
+		r.tf, r.err = newTerraformCLI(ctx, r.cfg)
+		if r.err != nil {
+			return
+		}
+		// The engine is always logged, so users know what ran; looking up
+		// its version costs another process and waits for --verbose
+		logging.Infof("Using %s (%s)", r.tf.name, r.tf.path)
+		if logging.Enabled(logging.LevelDebug) {
+			if version, err := r.tf.version(ctx); err == nil {
+				logging.Debugf("%s version %s", r.tf.name, version)
+			} else {
+				logging.Debugf("Failed to read the %s version: %v", r.tf.name, err)
+			}
+		}
+	})
+	return r.tf, r.err
+}
+
+// workspaces returns the workspaces listed by `terraform workspace list`.
+func (tf *terraformCLI) workspaces(ctx context.Context) ([]string, error) {
+	output, err := tf.command(ctx, "workspace", "list").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("terraform workspace list failed: %w - %s", err, string(output))
+	}
+
+	var workspaces []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// The selected workspace is marked with "*"
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		if name != "" {
+			workspaces = append(workspaces, name)
+		}
+	}
+	return workspaces, nil
+}
+
+// version returns the version reported by `terraform version -json`, e.g.
+// "1.9.5". OpenTofu reports its own version under the same key.
+func (tf *terraformCLI) version(ctx context.Context) (string, error) {
+	output, err := tf.command(ctx, "version", "-json").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s version failed: %w", tf.name, err)
+	}
+	return parseVersion(output)
+}
+
+// parseVersion extracts the version from `terraform version -json` output.
+// Versions before 0.13 have no -json flag and print text, which is rejected.
+func parseVersion(output []byte) (string, error) {
+	var info struct {
+		Version string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", fmt.Errorf("failed to parse version output: %w", err)
+	}
+	if info.Version == "" {
+		return "", fmt.Errorf("version output has no terraform_version")
+	}
+	return info.Version, nil
+}
+
+// command builds an exec.Cmd for the resolved executable, prepending -chdir
+// when a working directory is configured. The process is killed when ctx is
+// cancelled. A configured workspace is passed as
+// TF_WORKSPACE, so the selected workspace is never switched and needs no
+// restoring, even if the run is interrupted.
+func (tf *terraformCLI) command(ctx context.Context, args ...string) *exec.Cmd {
+	if tf.chdir != "" {
+		args = append([]string{"-chdir=" + tf.chdir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, tf.path, args...)
+	if tf.workspace != "" {
+		cmd.Env = append(os.Environ(), "TF_WORKSPACE="+tf.workspace)
+	}
+	return cmd
+}
+
+// withReusedPlan returns cfg with the saved plan of the working directory, by
+// absolute path, as its plan file when --reuse-plan is set, no other source is
+// configured and the plan is not stale. Otherwise cfg is returned unchanged.
+func withReusedPlan(cfg *config.Config) (*config.Config, error) {
+	if !cfg.ReusePlan || cfg.PlanFile != "" || cfg.StateFile != "" || cfg.StateS3 != "" {
+		return cfg, nil
+	}
+
+	planFile, err := reusablePlan(cfg.Terraform.Chdir)
+	if err != nil {
+		return nil, err
+	}
+	if planFile == "" {
+		logging.Infof("No up-to-date %s to reuse", ReusablePlanFile)
+		return cfg, nil
+	}
+
+	// Terraform resolves the plan path after -chdir, so a path relative to
+	// the current directory would point elsewhere
+	planFile, err = filepath.Abs(planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("Reusing plan %s", planFile)
+	reused := *cfg
+	reused.PlanFile = planFile
+	return &reused, nil
+}
+
+// reusablePlan returns the path of ReusablePlanFile in dir ("" for the
+// current directory), or "" if it doesn't exist or any .tf file in dir was
+// modified after it.
+func reusablePlan(dir string) (string, error) {
+	planFile := filepath.Join(dir, ReusablePlanFile)
+	info, err := os.Stat(planFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", planFile, err)
+	}
+
+	sources, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+	for _, source := range sources {
+		sourceInfo, err := os.Stat(source)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if sourceInfo.ModTime().After(info.ModTime()) {
+			logging.Debugf("%s is stale: %s was modified after it", planFile, source)
+			return "", nil
+		}
+	}
+	return planFile, nil
+}
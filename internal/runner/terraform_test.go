@@ -0,0 +1,325 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"terraform-graphx/internal/config"
+	"terraform-graphx/internal/logging"
+	"testing"
+	"time"
+)
+
+// fakePath points PATH at a temporary directory containing the named executables.
+func fakePath(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("Failed to create fake %s: %v", name, err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestNewTerraformCLIAuto(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []string
+		want      string
+		wantErr   bool
+	}{
+		{name: "prefers terraform", available: []string{"terraform", "tofu"}, want: "terraform"},
+		{name: "falls back to tofu", available: []string{"tofu"}, want: "tofu"},
+		{name: "neither available", available: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakePath(t, tt.available...)
+
+			tf, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineAuto})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected error when no engine is available")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newTerraformCLI failed: %v", err)
+			}
+			if tf.name != tt.want {
+				t.Errorf("Expected engine %s, got %s", tt.want, tf.name)
+			}
+		})
+	}
+}
+
+func TestNewTerraformCLIExplicitEngine(t *testing.T) {
+	fakePath(t, "terraform")
+
+	if _, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineTofu}); err == nil {
+		t.Error("Expected error when tofu is requested but missing")
+	}
+	if _, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: "pulumi"}); err == nil {
+		t.Error("Expected error for unsupported engine")
+	}
+}
+
+func TestTerraformCLICommandChdir(t *testing.T) {
+	dir := fakePath(t, "my-terraform")
+
+	tf, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Binary: "my-terraform", Chdir: "infra"})
+	if err != nil {
+		t.Fatalf("newTerraformCLI failed: %v", err)
+	}
+
+	cmd := tf.command(context.Background(), "graph", "-plan=tfplan")
+	if cmd.Path != filepath.Join(dir, "my-terraform") {
+		t.Errorf("Unexpected binary path: %s", cmd.Path)
+	}
+	want := []string{"-chdir=infra", "graph", "-plan=tfplan"}
+	if !reflect.DeepEqual(cmd.Args[1:], want) {
+		t.Errorf("Expected args %v, got %v", want, cmd.Args[1:])
+	}
+}
+
+func TestNewTerraformCLIMissingBinary(t *testing.T) {
+	fakePath(t)
+
+	if _, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Binary: "terraform-graphx-no-such-binary"}); err == nil {
+		t.Fatal("Expected error for missing binary")
+	}
+}
+
+func TestNewTerraformCLIWorkspace(t *testing.T) {
+	dir := fakePath(t)
+	script := "#!/bin/sh\nprintf '  default\\n* staging\\n  prod\\n'\n"
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create fake terraform: %v", err)
+	}
+
+	tf, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineTerraform, Workspace: "prod"})
+	if err != nil {
+		t.Fatalf("newTerraformCLI failed: %v", err)
+	}
+	cmd := tf.command(context.Background(), "graph")
+	if env := cmd.Env; len(env) == 0 || env[len(env)-1] != "TF_WORKSPACE=prod" {
+		t.Errorf("Expected TF_WORKSPACE=prod in the environment, got %v", env)
+	}
+
+	_, err = newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineTerraform, Workspace: "dev"})
+	if err == nil {
+		t.Fatal("Expected error for a missing workspace")
+	}
+	if !strings.Contains(err.Error(), `workspace "dev" does not exist (available: default, staging, prod)`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestTerraformCLICommandWithoutWorkspace(t *testing.T) {
+	fakePath(t, "terraform")
+
+	tf, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineTerraform})
+	if err != nil {
+		t.Fatalf("newTerraformCLI failed: %v", err)
+	}
+	if env := tf.command(context.Background(), "graph").Env; env != nil {
+		t.Errorf("Expected the inherited environment, got %v", env)
+	}
+}
+
+func TestTerraformCLICommandCancelled(t *testing.T) {
+	dir := fakePath(t)
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatalf("Failed to create fake terraform: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/bin:/usr/bin")
+
+	tf, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineTerraform})
+	if err != nil {
+		t.Fatalf("newTerraformCLI failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := generateTerraformGraph(ctx, tf, ""); err == nil {
+		t.Fatal("Expected an error when the context is cancelled")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected terraform to be killed on cancellation, took %s", elapsed)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{"terraform", `{"terraform_version":"1.9.5","platform":"linux_amd64","provider_selections":{},"terraform_outdated":false}`, "1.9.5"},
+		{"tofu", `{"terraform_version":"1.8.2","platform":"darwin_arm64","provider_selections":{}}`, "1.8.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVersion([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("parseVersion failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	// Versions without -json print text
+	for _, output := range []string{"Terraform v0.12.31\n", `{"platform":"linux_amd64"}`} {
+		if _, err := parseVersion([]byte(output)); err == nil {
+			t.Errorf("Expected an error for %q", output)
+		}
+	}
+}
+
+func TestTerraformCLIVersion(t *testing.T) {
+	dir := fakePath(t)
+	script := "#!/bin/sh\necho '{\"terraform_version\":\"1.7.0\"}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create fake terraform: %v", err)
+	}
+
+	tf, err := newTerraformCLI(context.Background(), &config.TerraformConfig{Engine: EngineTerraform})
+	if err != nil {
+		t.Fatalf("newTerraformCLI failed: %v", err)
+	}
+	version, err := tf.version(context.Background())
+	if err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if version != "1.7.0" {
+		t.Errorf("Expected 1.7.0, got %s", version)
+	}
+}
+
+func TestReusablePlan(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		plan    time.Duration // plan mtime relative to now; 0 means no plan
+		sources []time.Duration
+		reused  bool
+	}{
+		{"no plan", 0, []time.Duration{-time.Hour}, false},
+		{"plan newer than sources", -time.Minute, []time.Duration{-time.Hour, -2 * time.Hour}, true},
+		{"source modified after plan", -time.Hour, []time.Duration{-2 * time.Hour, -time.Minute}, false},
+		{"no sources", -time.Minute, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			touch := func(name string, age time.Duration) {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(path, now.Add(age), now.Add(age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.plan != 0 {
+				touch(ReusablePlanFile, tt.plan)
+			}
+			for i, age := range tt.sources {
+				touch(fmt.Sprintf("main%d.tf", i), age)
+			}
+			// Files other than .tf don't make the plan stale
+			touch("README.md", 0)
+
+			planFile, err := reusablePlan(dir)
+			if err != nil {
+				t.Fatalf("reusablePlan() error: %v", err)
+			}
+			if reused := planFile != ""; reused != tt.reused {
+				t.Errorf("reusablePlan() = %q, want reused %v", planFile, tt.reused)
+			}
+			if tt.reused && planFile != filepath.Join(dir, ReusablePlanFile) {
+				t.Errorf("reusablePlan() = %q, want the plan in %s", planFile, dir)
+			}
+		})
+	}
+}
+
+func TestWithReusedPlan(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ReusablePlanFile), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{ReusePlan: true, Terraform: config.TerraformConfig{Chdir: dir}}
+	reused, err := withReusedPlan(cfg)
+	if err != nil {
+		t.Fatalf("withReusedPlan() error: %v", err)
+	}
+	if reused.PlanFile != filepath.Join(dir, ReusablePlanFile) {
+		t.Errorf("Expected the saved plan to be used, got %q", reused.PlanFile)
+	}
+	if cfg.PlanFile != "" {
+		t.Error("Expected the original configuration to be left unchanged")
+	}
+
+	// A relative chdir is resolved against the current directory, not again
+	// against the chdir when terraform runs
+	t.Chdir(filepath.Dir(dir))
+	relative := &config.Config{ReusePlan: true, Terraform: config.TerraformConfig{Chdir: filepath.Base(dir)}}
+	reused, err = withReusedPlan(relative)
+	if err != nil {
+		t.Fatalf("withReusedPlan() error: %v", err)
+	}
+	if reused.PlanFile != filepath.Join(dir, ReusablePlanFile) {
+		t.Errorf("Expected the absolute path of the saved plan, got %q", reused.PlanFile)
+	}
+
+	for _, cfg := range []*config.Config{
+		{Terraform: config.TerraformConfig{Chdir: dir}},
+		{ReusePlan: true, PlanFile: "other.tfplan", Terraform: config.TerraformConfig{Chdir: dir}},
+		{ReusePlan: true, StateFile: "terraform.tfstate", Terraform: config.TerraformConfig{Chdir: dir}},
+	} {
+		got, err := withReusedPlan(cfg)
+		if err != nil {
+			t.Fatalf("withReusedPlan() error: %v", err)
+		}
+		if got != cfg {
+			t.Errorf("Expected %+v to be returned unchanged", cfg)
+		}
+	}
+}
+
+func TestTerraformResolverLogsEngineOnce(t *testing.T) {
+	dir := fakePath(t, "terraform")
+	var out bytes.Buffer
+	if err := logging.Setup(logging.FormatText, &out); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	t.Cleanup(func() { logging.Setup(logging.FormatText, os.Stderr) })
+
+	resolver := newTerraformResolver(&config.TerraformConfig{Engine: EngineTerraform})
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.cli(context.Background()); err != nil {
+			t.Fatalf("cli failed: %v", err)
+		}
+	}
+
+	want := fmt.Sprintf("Using terraform (%s)", filepath.Join(dir, "terraform"))
+	if n := strings.Count(out.String(), want); n != 1 {
+		t.Errorf("Expected %q to be logged once at the default level, got:\n%s", want, out.String())
+	}
+	if strings.Contains(out.String(), "version") {
+		t.Errorf("Expected the version lookup to wait for --verbose, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,119 @@
+// Package s3state downloads Terraform state stored in Amazon S3, such as the
+// state of an S3 backend. It is kept separate from the parsers so that other
+// remote sources can be added alongside it.
+package s3state
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Location is an S3 object, optionally pinned to a version.
+type Location struct {
+	Bucket    string
+	Key       string
+	VersionID string
+}
+
+// String renders the location as an s3:// URL.
+func (l Location) String() string {
+	s := "s3://" + l.Bucket + "/" + l.Key
+	if l.VersionID != "" {
+		s += "?versionId=" + url.QueryEscape(l.VersionID)
+	}
+	return s
+}
+
+// ParseURL parses s3://bucket/key, with an optional ?versionId= query.
+func ParseURL(raw string) (Location, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid S3 URL %q: %w", raw, err)
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return Location{}, fmt.Errorf("invalid S3 URL %q: expected s3://bucket/key", raw)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" {
+		return Location{}, fmt.Errorf("invalid S3 URL %q: missing object key", raw)
+	}
+
+	query := u.Query()
+	for name := range query {
+		if name != "versionId" {
+			return Location{}, fmt.Errorf("invalid S3 URL %q: unsupported query parameter %q (only versionId is supported)", raw, name)
+		}
+	}
+	return Location{Bucket: u.Host, Key: key, VersionID: query.Get("versionId")}, nil
+}
+
+// getObjectAPI is the part of the S3 client used to download objects.
+type getObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Client downloads objects from S3.
+type Client struct {
+	api getObjectAPI
+}
+
+// NewClient creates a client using the standard AWS credential and region
+// resolution: environment variables, shared config and credentials files
+// (including AWS_PROFILE), SSO, and instance or task roles. It fails early
+// when no region or no credentials can be found.
+func NewClient(ctx context.Context) (*Client, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("no AWS region configured: set AWS_REGION or a region in the AWS config file")
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, AWS_PROFILE, or use an instance role: %w", err)
+	}
+	return &Client{api: s3.NewFromConfig(cfg)}, nil
+}
+
+// Download returns the contents of the object at loc.
+func (c *Client) Download(ctx context.Context, loc Location) ([]byte, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(loc.Bucket),
+		Key:    aws.String(loc.Key),
+	}
+	if loc.VersionID != "" {
+		input.VersionId = aws.String(loc.VersionID)
+	}
+
+	output, err := c.api.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", loc, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", loc, err)
+	}
+	return data, nil
+}
+
+// Fetch downloads the object at the s3:// URL raw.
+func Fetch(ctx context.Context, raw string) ([]byte, error) {
+	loc, err := ParseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.Download(ctx, loc)
+}
@@ -0,0 +1,127 @@
+package s3state
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := map[string]Location{
+		"s3://tf-state/prod/terraform.tfstate":                {Bucket: "tf-state", Key: "prod/terraform.tfstate"},
+		"s3://tf-state/env:/prod/network.tfstate?versionId=3": {Bucket: "tf-state", Key: "env:/prod/network.tfstate", VersionID: "3"},
+	}
+	for raw, expected := range tests {
+		got, err := ParseURL(raw)
+		if err != nil {
+			t.Errorf("ParseURL(%s) failed: %v", raw, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("ParseURL(%s): expected %+v, got %+v", raw, expected, got)
+		}
+	}
+
+	for _, raw := range []string{
+		"https://tf-state/terraform.tfstate",
+		"s3:///terraform.tfstate",
+		"s3://tf-state",
+		"s3://tf-state/",
+		"s3://tf-state/terraform.tfstate?region=eu-west-1",
+	} {
+		if _, err := ParseURL(raw); err == nil {
+			t.Errorf("ParseURL(%s): expected an error", raw)
+		}
+	}
+}
+
+func TestLocationString(t *testing.T) {
+	loc := Location{Bucket: "tf-state", Key: "prod/terraform.tfstate", VersionID: "a b"}
+	if got := loc.String(); got != "s3://tf-state/prod/terraform.tfstate?versionId=a+b" {
+		t.Errorf("Unexpected location string: %s", got)
+	}
+}
+
+// fakeS3 serves objects by bucket, key and version.
+type fakeS3 struct {
+	objects map[string]string
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	id := aws.ToString(params.Bucket) + "/" + aws.ToString(params.Key) + "@" + aws.ToString(params.VersionId)
+	body, ok := f.objects[id]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func TestDownload(t *testing.T) {
+	client := &Client{api: &fakeS3{objects: map[string]string{
+		"tf-state/terraform.tfstate@":  `{"version": 4, "serial": 2}`,
+		"tf-state/terraform.tfstate@1": `{"version": 4, "serial": 1}`,
+	}}}
+	ctx := context.Background()
+
+	data, err := client.Download(ctx, Location{Bucket: "tf-state", Key: "terraform.tfstate"})
+	if err != nil || !strings.Contains(string(data), `"serial": 2`) {
+		t.Errorf("Expected the latest version, got %s (%v)", data, err)
+	}
+
+	data, err = client.Download(ctx, Location{Bucket: "tf-state", Key: "terraform.tfstate", VersionID: "1"})
+	if err != nil || !strings.Contains(string(data), `"serial": 1`) {
+		t.Errorf("Expected version 1, got %s (%v)", data, err)
+	}
+
+	_, err = client.Download(ctx, Location{Bucket: "tf-state", Key: "missing.tfstate"})
+	if err == nil || !strings.Contains(err.Error(), "s3://tf-state/missing.tfstate") {
+		t.Errorf("Expected an error naming the object, got %v", err)
+	}
+}
+
+// isolateAWS hides any AWS configuration of the environment running the tests.
+func isolateAWS(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", dir+"/config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", dir+"/credentials")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestNewClientMissingRegion(t *testing.T) {
+	isolateAWS(t)
+
+	_, err := NewClient(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no AWS region configured") {
+		t.Errorf("Expected a missing region error, got %v", err)
+	}
+}
+
+func TestNewClientMissingCredentials(t *testing.T) {
+	isolateAWS(t)
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	_, err := NewClient(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no AWS credentials found") {
+		t.Errorf("Expected a missing credentials error, got %v", err)
+	}
+}
+
+func TestNewClientStaticCredentials(t *testing.T) {
+	isolateAWS(t)
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	if _, err := NewClient(context.Background()); err != nil {
+		t.Errorf("NewClient failed: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package viewer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Preview writes a DOT document to a temporary file, renders it to SVG when
+// Graphviz `dot` is installed, and opens the result with the OS default viewer.
+// It returns the path of the file that was opened.
+func Preview(dot string) (string, error) {
+	dir, err := os.MkdirTemp("", "terraform-graphx-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "graph.dot")
+	if err := os.WriteFile(path, []byte(dot), 0644); err != nil {
+		return "", fmt.Errorf("failed to write DOT file: %w", err)
+	}
+
+	if svgPath, err := renderSVG(path); err == nil {
+		path = svgPath
+	}
+
+	return path, Open(path)
+}
+
+// renderSVG converts a DOT file to SVG with Graphviz, if available.
+func renderSVG(dotPath string) (string, error) {
+	dotBinary, err := exec.LookPath("dot")
+	if err != nil {
+		return "", err
+	}
+
+	svgPath := strings.TrimSuffix(dotPath, filepath.Ext(dotPath)) + ".svg"
+	if output, err := exec.Command(dotBinary, "-Tsvg", "-o", svgPath, dotPath).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("dot failed: %w - %s", err, string(output))
+	}
+	return svgPath, nil
+}
+
+// Open opens path with the default application of the current platform.
+func Open(path string) error {
+	name, args := openCommand(runtime.GOOS, path)
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("no viewer available (%s not found); open %s manually", name, path)
+	}
+
+	if err := exec.Command(name, args...).Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return nil
+}
+
+// openCommand returns the command that opens path on the given platform.
+func openCommand(goos, path string) (string, []string) {
+	switch goos {
+	case "darwin":
+		return "open", []string{path}
+	case "windows":
+		// The empty argument is the window title expected by `start`
+		return "cmd", []string{"/c", "start", "", path}
+	default:
+		return "xdg-open", []string{path}
+	}
+}
@@ -0,0 +1,26 @@
+package viewer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenCommand(t *testing.T) {
+	tests := []struct {
+		goos     string
+		wantName string
+		wantArgs []string
+	}{
+		{"darwin", "open", []string{"/tmp/graph.svg"}},
+		{"linux", "xdg-open", []string{"/tmp/graph.svg"}},
+		{"freebsd", "xdg-open", []string{"/tmp/graph.svg"}},
+		{"windows", "cmd", []string{"/c", "start", "", "/tmp/graph.svg"}},
+	}
+
+	for _, tt := range tests {
+		name, args := openCommand(tt.goos, "/tmp/graph.svg")
+		if name != tt.wantName || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("openCommand(%q) = %s %v, want %s %v", tt.goos, name, args, tt.wantName, tt.wantArgs)
+		}
+	}
+}